@@ -1,161 +1,703 @@
-package main
-
-import (
-	"fmt"
-	"os"
-	"time"
-
-	"github.com/local-video-server/internal/config"
-	"github.com/local-video-server/internal/models"
-	"github.com/local-video-server/internal/scanner"
-	"github.com/local-video-server/pkg/utils"
-	"github.com/spf13/cobra"
-)
-
-var (
-	configPath string
-	verbose    bool
-)
-
-func main() {
-	rootCmd := &cobra.Command{
-		Use:   "local-video-server",
-		Short: "Local Video Server - обнаружение видеокамер в локальной сети",
-		Long: `Local-video-server - это кроссплатформенное приложение на Go,
-которое сканирует локальную сеть на наличие видеокамер и определяет
-поддерживаемые протоколы (RTSP, RTMP, HLS, WebRTC, ONVIF, MJPEG, etc.)`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			// Загружаем конфигурацию
-			cfg, err := config.LoadConfig(configPath)
-			if err != nil {
-				fmt.Printf("Warning: failed to load config: %v\n", err)
-				cfg = models.DefaultConfig()
-			}
-
-			// Инициализируем логирование
-			logLevel := cfg.Log.Level
-			if verbose {
-				logLevel = "debug"
-			}
-
-			if err := utils.InitLogger(logLevel, cfg.Log.Format, cfg.Log.File); err != nil {
-				fmt.Printf("Failed to initialize logger: %v\n", err)
-				os.Exit(1)
-			}
-
-			utils.GetLogger().Info("Local-video-server started")
-		},
-	}
-
-	// Флаги
-	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "путь к конфигурационному файлу")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "подробный вывод (debug уровень)")
-
-	// Команды
-	rootCmd.AddCommand(initCmd)
-	rootCmd.AddCommand(scanCmd)
-	rootCmd.AddCommand(versionCmd)
-
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-}
-
-var initCmd = &cobra.Command{
-	Use:   "init",
-	Short: "Инициализировать конфигурационный файл",
-	Long:  "Создает файл конфигурации по умолчанию в configs/config.yaml",
-	Run: func(cmd *cobra.Command, args []string) {
-		configPath := "configs/config.yaml"
-		if err := config.CreateDefaultConfigFile(configPath); err != nil {
-			fmt.Printf("Failed to create config file: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Configuration file created: %s\n", configPath)
-	},
-}
-
-var scanCmd = &cobra.Command{
-	Use:   "scan",
-	Short: "Сканировать сеть на наличие видеокамер",
-	Long:  "Сканирует указанную подсеть и обнаруживает видеокамеры",
-	Run: func(cmd *cobra.Command, args []string) {
-		logger := utils.GetLogger()
-
-		// Загружаем конфигурацию
-		cfg, err := config.LoadConfig(configPath)
-		if err != nil {
-			logger.Errorf("Failed to load config: %v", err)
-			os.Exit(1)
-		}
-
-		// Логируем информацию о сети
-		utils.LogNetworkInfo()
-
-		// Определяем подсеть для сканирования
-		subnet := cfg.Scan.Subnet
-		if subnet == "" && cfg.Network.AutoDetectSubnet {
-			detectedSubnet, err := utils.GetDefaultSubnet()
-			if err != nil {
-				logger.Errorf("Failed to detect subnet: %v", err)
-				os.Exit(1)
-			}
-			subnet = detectedSubnet
-			logger.Infof("Auto-detected subnet: %s", subnet)
-		}
-
-		if subnet == "" {
-			logger.Error("Subnet not specified and auto-detection failed")
-			os.Exit(1)
-		}
-
-		logger.Infof("Starting network scan: %s", subnet)
-
-		// Создаем детектор для сканирования
-		detector := scanner.NewDetector(&cfg.Scan)
-
-		// Выполняем сканирование с таймаутом
-		timeout := cfg.Scan.DiscoveryTimeout
-		if timeout == 0 {
-			timeout = 30 * time.Second
-		}
-
-		devices, err := detector.ScanWithTimeout(subnet, timeout)
-		if err != nil {
-			logger.Errorf("Scan failed: %v", err)
-			os.Exit(1)
-		}
-
-		// Выводим результаты
-		logger.Infof("Scan completed. Found %d device(s):", len(devices))
-		for i, device := range devices {
-			logger.Infof("\nDevice %d:", i+1)
-			logger.Infof("  IP: %s", device.IP)
-			if device.Hostname != "" {
-				logger.Infof("  Hostname: %s", device.Hostname)
-			}
-			if device.Manufacturer != "" {
-				logger.Infof("  Manufacturer: %s", device.Manufacturer)
-			}
-			if device.Model != "" {
-				logger.Infof("  Model: %s", device.Model)
-			}
-			if len(device.Protocols) > 0 {
-				logger.Infof("  Protocols:")
-				for _, protocol := range device.Protocols {
-					logger.Infof("    - %s (port %d): %s", protocol.Type, protocol.Port, protocol.URL)
-				}
-			}
-		}
-	},
-}
-
-var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Показать версию приложения",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Local-video-server v0.1.0")
-		fmt.Println("Build: development")
-	},
-}
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/local-video-server/internal/config"
+	"github.com/local-video-server/internal/licensing"
+	"github.com/local-video-server/internal/metrics"
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/registry"
+	"github.com/local-video-server/internal/rtsp"
+	"github.com/local-video-server/internal/scanner"
+	"github.com/local-video-server/pkg/events"
+	"github.com/local-video-server/pkg/formatter"
+	"github.com/local-video-server/pkg/scanner/igd"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/local-video-server/pkg/webrtc"
+	"github.com/local-video-server/vigilos/pkg/camera"
+	"github.com/local-video-server/vigilos/pkg/media"
+	"github.com/local-video-server/vigilos/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configPath   string
+	verbose      bool
+	outputFormat string
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "local-video-server",
+		Short: "Local Video Server - обнаружение видеокамер в локальной сети",
+		Long: `Local-video-server - это кроссплатформенное приложение на Go,
+которое сканирует локальную сеть на наличие видеокамер и определяет
+поддерживаемые протоколы (RTSP, RTMP, HLS, WebRTC, ONVIF, MJPEG, etc.)`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Загружаем конфигурацию
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				fmt.Printf("Warning: failed to load config: %v\n", err)
+				cfg = models.DefaultConfig()
+			}
+
+			// Инициализируем логирование
+			logLevel := cfg.Log.Level
+			if verbose {
+				logLevel = "debug"
+			}
+
+			if err := utils.InitLogger(logLevel, cfg.Log.Format, cfg.Log.File); err != nil {
+				fmt.Printf("Failed to initialize logger: %v\n", err)
+				os.Exit(1)
+			}
+
+			utils.GetLogger().Info("Local-video-server started")
+		},
+	}
+
+	// Флаги
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "путь к конфигурационному файлу")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "подробный вывод (debug уровень)")
+	scanCmd.Flags().StringVar(&outputFormat, "format", "text", "формат вывода результатов сканирования: text, json, ndjson, yaml, csv")
+
+	// Команды
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(licenseCmd)
+	rootCmd.AddCommand(portMapCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Инициализировать конфигурационный файл",
+	Long:  "Создает файл конфигурации по умолчанию в configs/config.yaml",
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath := "configs/config.yaml"
+		if err := config.CreateDefaultConfigFile(configPath); err != nil {
+			fmt.Printf("Failed to create config file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Configuration file created: %s\n", configPath)
+	},
+}
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Сканировать сеть на наличие видеокамер",
+	Long:  "Сканирует указанную подсеть и обнаруживает видеокамеры",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := utils.GetLogger()
+
+		// Загружаем конфигурацию
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			logger.Errorf("Failed to load config: %v", err)
+			os.Exit(1)
+		}
+
+		// Логируем информацию о сети
+		utils.LogNetworkInfo()
+
+		// Поднимаем сервер метрик/pprof в фоне на время долгого сканирования, если
+		// включено в конфигурации
+		if cfg.Metrics.Enabled {
+			metricsServer := metrics.NewServer(&cfg.Metrics)
+			go func() {
+				if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Errorf("Metrics server failed: %v", err)
+				}
+			}()
+		}
+
+		// Определяем подсеть для сканирования
+		subnet := cfg.Scan.Subnet
+		if subnet == "" && cfg.Network.AutoDetectSubnet {
+			detectedSubnet, err := utils.GetDefaultSubnet()
+			if err != nil {
+				logger.Errorf("Failed to detect subnet: %v", err)
+				os.Exit(1)
+			}
+			subnet = detectedSubnet
+			logger.Infof("Auto-detected subnet: %s", subnet)
+		}
+
+		if subnet == "" {
+			logger.Error("Subnet not specified and auto-detection failed")
+			os.Exit(1)
+		}
+
+		logger.Infof("Starting network scan: %s", subnet)
+
+		// Создаем детектор для сканирования
+		detector := scanner.NewDetector(&cfg.Scan)
+
+		if cfg.Events.Enabled {
+			dispatcher, err := setupEventDispatcher(&cfg.Events)
+			if err != nil {
+				logger.Errorf("Failed to set up event dispatcher: %v", err)
+				os.Exit(1)
+			}
+			defer dispatcher.Close()
+			detector.SetDispatcher(dispatcher)
+		}
+
+		// Выполняем сканирование с таймаутом
+		timeout := cfg.Scan.DiscoveryTimeout
+		if timeout == 0 {
+			timeout = 30 * time.Second
+		}
+
+		devices, err := detector.ScanWithTimeout(subnet, timeout)
+		if err != nil {
+			logger.Errorf("Scan failed: %v", err)
+			os.Exit(1)
+		}
+
+		f, err := formatter.NewFormatter(outputFormat)
+		if err != nil {
+			logger.Errorf("Invalid output format: %v", err)
+			os.Exit(1)
+		}
+
+		logger.Infof("Scan completed. Found %d device(s)", len(devices))
+		f.PrintDevices(devices)
+		f.PrintSummary(devices)
+	},
+}
+
+var scanEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Слушать ONVIF события камер (motion/tamper/analytics)",
+	Long: `Запускает персистентный WS-Discovery listener (scanner.ONVIFScanner.Listen), PullPoint
+подписку на Events сервис каждой найденной камеры и, если включен scan.enable_upnp, параллельный
+SSDP listener (scanner.UPnPScanner.Listen) для устройств, анонсирующих себя только через UPnP,
+печатая полученные события в stdout по мере поступления. Работает, пока не будет прерван (Ctrl+C).
+Требует редакцию Enterprise (licensing.FeatureContinuousMonitoring) - это длительный/повторяющийся
+мониторинг устройств, а не разовое сканирование, которое делает scan.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := utils.GetLogger()
+
+		if !licensing.DefaultGates().Allow(licensing.FeatureContinuousMonitoring) {
+			logger.Errorf("Continuous monitoring requires %s edition (current: %s)",
+				licensing.EditionEnterprise, licensing.DefaultGates().Edition())
+			os.Exit(1)
+		}
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			logger.Errorf("Failed to load config: %v", err)
+			os.Exit(1)
+		}
+
+		reg := registry.NewDeviceRegistry(cfg.Scan.DiscoveryTimeout)
+		if store, err := setupRegistryStore(&cfg.Registry); err != nil {
+			logger.Errorf("Failed to open registry store: %v", err)
+			os.Exit(1)
+		} else if store != nil {
+			defer store.Close()
+			reg.SetStore(store)
+		}
+
+		onvifScanner := scanner.NewONVIFScanner(&cfg.Scan)
+
+		var recorder *storage.LocalRecorder
+		if cfg.Recording.Enabled {
+			recorder = storage.NewLocalRecorder(toRecorderConfig(&cfg.Recording))
+			defer recorder.StopAll()
+		}
+
+		if cfg.WebRTC.Enabled {
+			relay, err := media.NewWebRTCRelay(webrtcStreamResolver(reg), toWebRTCConfig(&cfg.WebRTC), cfg.WebRTC.ListenAddr)
+			if err != nil {
+				logger.Errorf("Failed to create WebRTC relay: %v", err)
+				os.Exit(1)
+			}
+			if err := relay.Start(); err != nil {
+				logger.Errorf("Failed to start WebRTC relay: %v", err)
+				os.Exit(1)
+			}
+			defer relay.Stop()
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			cancel()
+		}()
+
+		go func() {
+			if err := onvifScanner.Listen(ctx, reg, 0); err != nil {
+				logger.Errorf("ONVIF Listen failed: %v", err)
+				cancel()
+			}
+		}()
+
+		if len(cfg.Discovery.Sources) > 0 {
+			manager := newDiscoveryManager(&cfg.Discovery, &cfg.Scan)
+			iface := cfg.Discovery.Interface
+			if iface == "" {
+				iface = cfg.Network.Interface
+			}
+			devices, err := manager.Discover(ctx, iface)
+			if err != nil {
+				logger.Errorf("Discovery failed to start: %v", err)
+			} else {
+				go func() {
+					for device := range devices {
+						reg.AddDevice(device)
+					}
+				}()
+			}
+		}
+
+		if cfg.Scan.EnableUPnP {
+			upnpScanner := scanner.NewUPnPScanner(&cfg.Scan)
+			go func() {
+				if err := upnpScanner.Listen(ctx, reg, 0); err != nil {
+					logger.Errorf("UPnP Listen failed: %v", err)
+				}
+			}()
+		}
+
+		cameraEvents := reg.Subscribe()
+		registryEvents := reg.Events()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-cameraEvents:
+				fmt.Printf("[%s] %s %s active=%v %v\n",
+					event.Timestamp.Format(time.RFC3339), event.DeviceIP, event.Topic, event.IsActive, event.Data)
+			case regEvent := <-registryEvents:
+				if recorder != nil {
+					// handleRecordingEvent dials the stream (RTSP SETUP/PLAY or RTMP
+					// connect/play, each up to several seconds) - run it off this loop so a
+					// slow/unreachable camera can't stall delivery of other registry/ONVIF
+					// events behind it
+					go handleRecordingEvent(recorder, regEvent)
+				}
+			}
+		}
+	},
+}
+
+// toWebRTCConfig translates models.WebRTCConfig (the serialized config shape) into
+// webrtc.Config (what media.NewWebRTCRelay/webrtc.NewPublisher expect).
+func toWebRTCConfig(cfg *models.WebRTCConfig) webrtc.Config {
+	return webrtc.Config{
+		ICEServers: cfg.ICEServers,
+		PublicIP:   cfg.PublicIP,
+		PortMin:    cfg.PortMin,
+		PortMax:    cfg.PortMax,
+	}
+}
+
+// webrtcStreamResolver resolves a media.StreamResolver's streamID as a device IP, looking up
+// its first Available RTSP stream in reg - the same "device IP as stream ID" convention the
+// WHIP/WHEP relay's own doc comment calls out as the simple deployment default.
+func webrtcStreamResolver(reg *registry.DeviceRegistry) media.StreamResolver {
+	return func(streamID string) (rtspURL, username, password string, ok bool) {
+		device, exists := reg.GetDevice(streamID)
+		if !exists {
+			return "", "", "", false
+		}
+		for _, stream := range device.RTSPStreams {
+			if stream.Available {
+				username, password = storage.CredentialsFromURL(stream.URL)
+				return stream.URL, username, password, true
+			}
+		}
+		return "", "", "", false
+	}
+}
+
+// newDiscoveryManager builds a camera.DiscoveryManager from the sources named in
+// discoveryCfg.Sources, reusing scanCfg for the settings each source already has a config
+// shape for (portscan's concurrency/rate limit, onvif's credentials) instead of introducing a
+// second place to configure them.
+func newDiscoveryManager(discoveryCfg *models.DiscoveryConfig, scanCfg *models.ScanConfig) *camera.DiscoveryManager {
+	var sources []camera.DiscoverySource
+	for _, name := range discoveryCfg.Sources {
+		switch name {
+		case "portscan":
+			sources = append(sources, camera.NewPortScanDiscovery(scanCfg))
+		case "onvif":
+			sources = append(sources, camera.NewONVIFDiscovery(camera.Credentials{
+				Username: scanCfg.ONVIFUsername,
+				Password: scanCfg.ONVIFPassword,
+			}))
+		case "mdns":
+			sources = append(sources, camera.NewMDNSDiscovery())
+		case "ssdp":
+			sources = append(sources, camera.NewSSDPDiscovery())
+		default:
+			utils.GetLogger().Warnf("discovery: unknown source %q in config, ignoring", name)
+		}
+	}
+	return camera.NewDiscoveryManager(sources...)
+}
+
+// toRecorderConfig translates models.RecordingConfig (the serialized config shape) into
+// storage.Config (LocalRecorder's own shape) - kept as separate types so
+// vigilos/pkg/storage doesn't need to import internal/models just for its own config
+func toRecorderConfig(cfg *models.RecordingConfig) storage.Config {
+	overrides := make(map[string]storage.Override, len(cfg.Overrides))
+	for streamID, o := range cfg.Overrides {
+		overrides[streamID] = storage.Override{
+			SegmentDuration: o.SegmentDuration,
+			LiveWindow:      o.LiveWindow,
+			RetentionAge:    time.Duration(o.RetentionHours * float64(time.Hour)),
+			MaxDiskBytes:    o.MaxDiskBytes,
+		}
+	}
+	return storage.Config{
+		OutputDir:       cfg.OutputDir,
+		SegmentDuration: cfg.SegmentDuration,
+		LiveWindow:      cfg.LiveWindow,
+		RetentionAge:    time.Duration(cfg.RetentionHours * float64(time.Hour)),
+		MaxDiskBytes:    cfg.MaxDiskBytes,
+		Overrides:       overrides,
+	}
+}
+
+// recordingStreamID identifies one of a device's streams for LocalRecorder.Start/Stop. Unlike
+// registry.deviceIdentity it is derived from the device's current IP, not its WS-Discovery
+// EndpointReference - a DHCP lease change starts a fresh recording under a new streamID
+// instead of continuing the old one. Accepted for now: fixing it would mean threading
+// EndpointRef through models.Device's stream lists, which no other consumer needs yet
+func recordingStreamID(ip, kind string, index int) string {
+	return fmt.Sprintf("%s-%s%d", ip, kind, index)
+}
+
+// handleRecordingEvent starts or stops auto-recording for a device's RTSP/RTMP streams as it
+// is added, updated or removed from the registry. On DeviceUpdated a stream that is still
+// listed but no longer Available is stopped, same as on DeviceRemoved - a stream dropped from
+// the list entirely (rather than reported unavailable) is not noticed here, since nothing
+// records which indices a device used to have; this mirrors the accepted IP-based-identity
+// limitation of recordingStreamID above.
+func handleRecordingEvent(recorder *storage.LocalRecorder, event registry.RegistryEvent) {
+	logger := utils.GetLogger()
+	device := event.Device
+
+	switch event.Type {
+	case registry.DeviceAdded, registry.DeviceUpdated:
+		for i, stream := range device.RTSPStreams {
+			streamID := recordingStreamID(device.IP, "rtsp", i)
+			if stream.Available {
+				if err := recorder.Start(streamID, stream.URL); err != nil {
+					logger.Debugf("recording: %v", err)
+				}
+			} else {
+				recorder.Stop(streamID)
+			}
+		}
+		for i, stream := range device.RTMPStreams {
+			streamID := recordingStreamID(device.IP, "rtmp", i)
+			if stream.Available {
+				if err := recorder.Start(streamID, stream.URL); err != nil {
+					logger.Debugf("recording: %v", err)
+				}
+			} else {
+				recorder.Stop(streamID)
+			}
+		}
+
+	case registry.DeviceRemoved:
+		for i := range device.RTSPStreams {
+			recorder.Stop(recordingStreamID(device.IP, "rtsp", i))
+		}
+		for i := range device.RTMPStreams {
+			recorder.Stop(recordingStreamID(device.IP, "rtmp", i))
+		}
+	}
+}
+
+var (
+	scanRTSPUsername string
+	scanRTSPPassword string
+	scanRTSPTimeout  time.Duration
+)
+
+var scanRTSPCmd = &cobra.Command{
+	Use:   "rtsp <url>",
+	Short: "Разовая проверка одного RTSP потока (OPTIONS+DESCRIBE)",
+	Long: `Подключается напрямую к переданному rtsp:// URL и выполняет OPTIONS+DESCRIBE (см.
+rtsp.CheckStream), печатая обнаруженный кодек, разрешение, FPS и битрейт. В отличие от scan,
+не делает сетевое обнаружение и не обращается к ONVIF - для точечной проверки уже известного URL`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := utils.GetLogger()
+		rtspURL := args[0]
+
+		info, err := rtsp.CheckStream(rtspURL, scanRTSPUsername, scanRTSPPassword, scanRTSPTimeout)
+		if err != nil {
+			logger.Errorf("RTSP check failed: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("URL: %s\n", info.URL)
+		fmt.Printf("Available: %v\n", info.Available)
+		fmt.Printf("Video codec: %s\n", info.Codec)
+		fmt.Printf("Resolution: %s\n", info.Resolution)
+		fmt.Printf("FPS: %.2f\n", info.FPS)
+		fmt.Printf("Bitrate: %d kbps\n", info.Bitrate)
+		if info.AudioCodec != "" {
+			fmt.Printf("Audio codec: %s (%d channels)\n", info.AudioCodec, info.Channels)
+		}
+	},
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Показать версию приложения",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Local-video-server v0.1.0")
+		fmt.Println("Build: development")
+	},
+}
+
+var licenseCmd = &cobra.Command{
+	Use:   "license",
+	Short: "Управление лицензией",
+}
+
+var licenseShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Показать текущую редакцию и доступные возможности",
+	Run: func(cmd *cobra.Command, args []string) {
+		gates := licensing.DefaultGates()
+
+		fmt.Printf("Edition: %s\n", gates.Edition())
+
+		if lic := gates.License(); lic != nil {
+			fmt.Printf("Expires: %s\n", lic.Exp.Format(time.RFC3339))
+			if len(lic.Features) > 0 {
+				fmt.Printf("Additional features: %s\n", lic.Features)
+			}
+		} else {
+			path, err := licensing.DefaultLicensePath()
+			if err != nil {
+				path = "unknown"
+			}
+			fmt.Printf("No valid license found at %s\n", path)
+		}
+	},
+}
+
+var (
+	portMapExternalPort int
+	portMapInternalPort int
+	portMapProtocol     string
+	portMapDescription  string
+)
+
+var portMapCmd = &cobra.Command{
+	Use:   "port-map",
+	Short: "Проброс портов сервера через Internet Gateway Device (UPnP IGD)",
+}
+
+var portMapAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Добавить временную проброску порта на найденном в сети шлюзе",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := utils.GetLogger()
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			logger.Errorf("Failed to load config: %v", err)
+			os.Exit(1)
+		}
+
+		client, err := discoverGateway(cfg)
+		if err != nil {
+			logger.Errorf("Failed to discover Internet Gateway Device: %v", err)
+			os.Exit(1)
+		}
+
+		internalPort := portMapInternalPort
+		if internalPort == 0 {
+			internalPort = defaultStreamPort(cfg)
+		}
+		if internalPort == 0 {
+			logger.Error("Could not determine internal port: pass --port or set webrtc.listen_addr in the config")
+			os.Exit(1)
+		}
+		externalPort := portMapExternalPort
+		if externalPort == 0 {
+			externalPort = internalPort
+		}
+
+		internalIP, err := utils.GetLocalIP()
+		if err != nil {
+			logger.Errorf("Failed to determine local IP: %v", err)
+			os.Exit(1)
+		}
+
+		mapping := igd.PortMapping{
+			ExternalPort:   externalPort,
+			Protocol:       strings.ToUpper(portMapProtocol),
+			InternalPort:   internalPort,
+			InternalClient: internalIP,
+			Enabled:        true,
+			Description:    portMapDescription,
+			LeaseDuration:  3600,
+		}
+
+		if err := client.AddPortMapping(mapping); err != nil {
+			logger.Errorf("AddPortMapping failed: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Mapped %s:%d -> %s:%d (%s)\n", "0.0.0.0", externalPort, internalIP, internalPort, mapping.Protocol)
+	},
+}
+
+var portMapRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Удалить проброску порта на найденном в сети шлюзе",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := utils.GetLogger()
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			logger.Errorf("Failed to load config: %v", err)
+			os.Exit(1)
+		}
+
+		client, err := discoverGateway(cfg)
+		if err != nil {
+			logger.Errorf("Failed to discover Internet Gateway Device: %v", err)
+			os.Exit(1)
+		}
+
+		externalPort := portMapExternalPort
+		if externalPort == 0 {
+			externalPort = defaultStreamPort(cfg)
+		}
+		if externalPort == 0 {
+			logger.Error("Could not determine external port: pass --external-port or set webrtc.listen_addr in the config")
+			os.Exit(1)
+		}
+
+		if err := client.DeletePortMapping("", externalPort, strings.ToUpper(portMapProtocol)); err != nil {
+			logger.Errorf("DeletePortMapping failed: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Removed mapping for external port %d (%s)\n", externalPort, strings.ToUpper(portMapProtocol))
+	},
+}
+
+// setupRegistryStore открывает устойчивое хранилище реестра по cfg: BoltStore, если задан
+// BoltPath, иначе nil, означающий "оставить MemoryStore по умолчанию из NewDeviceRegistry"
+func setupRegistryStore(cfg *models.RegistryConfig) (registry.Store, error) {
+	if cfg.BoltPath == "" {
+		return nil, nil
+	}
+	return registry.NewBoltStore(cfg.BoltPath)
+}
+
+// setupEventDispatcher создает Dispatcher по cfg и регистрирует на нем WebhookSender для
+// каждого настроенного вебхука
+func setupEventDispatcher(cfg *models.EventsConfig) (*events.Dispatcher, error) {
+	dispatcher, err := events.NewDispatcher(events.DispatcherConfig{
+		QueuePath:     cfg.QueuePath,
+		MaxBatchSize:  cfg.MaxBatchSize,
+		FlushInterval: cfg.FlushInterval,
+		MaxQueueSize:  cfg.MaxQueueSize,
+		RetryBackoff:  cfg.RetryBackoff,
+		MaxRetries:    cfg.MaxRetries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, wh := range cfg.Webhooks {
+		if err := dispatcher.Register(events.NewWebhookSender(wh.Name, wh.URL, wh.Secret)); err != nil {
+			return nil, fmt.Errorf("failed to register webhook %s: %w", wh.Name, err)
+		}
+	}
+
+	return dispatcher, nil
+}
+
+// discoverGateway выполняет короткое UPnP/SSDP обнаружение и возвращает IGD клиент первого
+// найденного устройства с сервисом WANIPConnection/WANPPPConnection
+func discoverGateway(cfg *models.Config) (*igd.Client, error) {
+	us := scanner.NewUPnPScanner(&cfg.Scan)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	devices, err := us.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		for _, service := range device.Services {
+			if service.ServiceType == igd.WANIPConnectionServiceType || service.ServiceType == igd.WANPPPConnectionServiceType {
+				return igd.NewClient(service.ControlURL, service.ServiceType), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no Internet Gateway Device found on the network")
+}
+
+// defaultStreamPort извлекает номер порта из WebRTCConfig.ListenAddr - адреса, на котором
+// собственный WHIP/WHEP шлюз сервера отдает потоки наружу
+func defaultStreamPort(cfg *models.Config) int {
+	_, portStr, err := net.SplitHostPort(cfg.WebRTC.ListenAddr)
+	if err != nil {
+		return 0
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+func init() {
+	scanCmd.AddCommand(scanEventsCmd)
+
+	scanRTSPCmd.Flags().StringVar(&scanRTSPUsername, "username", "", "имя пользователя для RTSP аутентификации")
+	scanRTSPCmd.Flags().StringVar(&scanRTSPPassword, "password", "", "пароль для RTSP аутентификации")
+	scanRTSPCmd.Flags().DurationVar(&scanRTSPTimeout, "timeout", 5*time.Second, "таймаут подключения")
+	scanCmd.AddCommand(scanRTSPCmd)
+
+	licenseCmd.AddCommand(licenseShowCmd)
+
+	portMapAddCmd.Flags().IntVar(&portMapExternalPort, "external-port", 0, "внешний порт (по умолчанию - совпадает с внутренним)")
+	portMapAddCmd.Flags().IntVar(&portMapInternalPort, "port", 0, "внутренний порт сервера (по умолчанию - порт WebRTC шлюза из конфигурации)")
+	portMapAddCmd.Flags().StringVar(&portMapProtocol, "protocol", "TCP", "протокол проброски: TCP или UDP")
+	portMapAddCmd.Flags().StringVar(&portMapDescription, "description", "local-video-server", "описание проброски в таблице шлюза")
+
+	portMapRemoveCmd.Flags().IntVar(&portMapExternalPort, "external-port", 0, "внешний порт проброски (по умолчанию - порт WebRTC шлюза из конфигурации)")
+	portMapRemoveCmd.Flags().StringVar(&portMapProtocol, "protocol", "TCP", "протокол проброски: TCP или UDP")
+
+	portMapCmd.AddCommand(portMapAddCmd)
+	portMapCmd.AddCommand(portMapRemoveCmd)
+}
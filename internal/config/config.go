@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/local-video-server/internal/models"
 	"github.com/spf13/viper"
@@ -49,6 +50,8 @@ func SaveConfig(cfg *models.Config, configPath string) error {
 	viper.Set("log", cfg.Log)
 	viper.Set("network", cfg.Network)
 	viper.Set("rtsp", cfg.RTSP)
+	viper.Set("webrtc", cfg.WebRTC)
+	viper.Set("recording", cfg.Recording)
 
 	// Создаем директорию, если её нет
 	dir := filepath.Dir(configPath)
@@ -113,4 +116,25 @@ func setDefaults(cfg *models.Config) {
 			"/cam/realmonitor",
 		}
 	}
+
+	// Устанавливаем значение по умолчанию для режима RTSP транспорта
+	if cfg.RTSP.Transport == "" {
+		cfg.RTSP.Transport = "auto"
+	}
+
+	// Устанавливаем значения по умолчанию для ICE серверов WHIP/WHEP шлюза
+	if len(cfg.WebRTC.ICEServers) == 0 {
+		cfg.WebRTC.ICEServers = []string{"stun:stun.l.google.com:19302"}
+	}
+
+	// Устанавливаем значения по умолчанию для записи на диск
+	if cfg.Recording.OutputDir == "" {
+		cfg.Recording.OutputDir = "recordings"
+	}
+	if cfg.Recording.SegmentDuration <= 0 {
+		cfg.Recording.SegmentDuration = 4 * time.Second
+	}
+	if cfg.Recording.LiveWindow <= 0 {
+		cfg.Recording.LiveWindow = 7
+	}
 }
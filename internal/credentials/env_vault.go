@@ -0,0 +1,51 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envKeyPattern - символы, недопустимые в имени переменной окружения, заменяются на "_" при
+// построении EnvVault.Get ключа из произвольного key (обычно EndpointRef вида
+// "urn:uuid:4fe9..." - двоеточия в имени переменной окружения недопустимы на большинстве
+// платформ)
+var envKeyPattern = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// EnvVault читает учетные данные из переменных окружения <prefix>_<KEY>_USERNAME/
+// <prefix>_<KEY>_PASSWORD - подходит для развертываний, где секреты уже приходят в окружение
+// контейнера (Kubernetes Secret, systemd EnvironmentFile и т.п.), и не требует ни файла, ни
+// сетевого сервиса. В отличие от FileVault/HashicorpVault, только для чтения: окружение
+// самого процесса нельзя переписать так, чтобы это пережило его перезапуск, поэтому Set/
+// Delete возвращают ошибку вместо того, чтобы создавать иллюзию персистентности
+type EnvVault struct {
+	prefix string
+}
+
+// NewEnvVault создает EnvVault, использующий prefix (обычно "LVS_CRED") для имен переменных
+func NewEnvVault(prefix string) *EnvVault {
+	return &EnvVault{prefix: prefix}
+}
+
+func (v *EnvVault) envName(key, suffix string) string {
+	sanitized := envKeyPattern.ReplaceAllString(strings.ToUpper(key), "_")
+	return fmt.Sprintf("%s_%s_%s", v.prefix, sanitized, suffix)
+}
+
+func (v *EnvVault) Get(key string) (Credential, bool, error) {
+	username, hasUsername := os.LookupEnv(v.envName(key, "USERNAME"))
+	password, hasPassword := os.LookupEnv(v.envName(key, "PASSWORD"))
+	if !hasUsername && !hasPassword {
+		return Credential{}, false, nil
+	}
+	return Credential{Username: username, Password: password}, true, nil
+}
+
+func (v *EnvVault) Set(key string, c Credential) error {
+	return fmt.Errorf("env vault is read-only: cannot persist credential for %q beyond process environment", key)
+}
+
+func (v *EnvVault) Delete(key string) error {
+	return fmt.Errorf("env vault is read-only: cannot delete credential for %q", key)
+}
@@ -0,0 +1,117 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileVault хранит учетные данные в одном YAML файле вида {key: {username, password}} -
+// простейший Vault, подходящий для одиночного сервера без внешних зависимостей. Каждый
+// Set/Delete перечитывает файл, изменяет запись и атомарно перезаписывает его целиком
+// (временный файл + os.Rename), чтобы процесс, упавший посреди записи, не оставил файл
+// наполовину переписанным
+type FileVault struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileVault создает FileVault, хранящий данные в файле path. Файл не обязан существовать
+// заранее - Get на отсутствующем файле ведет себя как на пустом хранилище, первый Set его
+// создаст
+func NewFileVault(path string) *FileVault {
+	return &FileVault{path: path}
+}
+
+func (v *FileVault) load() (map[string]Credential, error) {
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		return make(map[string]Credential), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential vault %s: %w", v.path, err)
+	}
+
+	creds := make(map[string]Credential)
+	if err := yaml.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse credential vault %s: %w", v.path, err)
+	}
+	return creds, nil
+}
+
+// save атомарно перезаписывает весь файл: сначала во временный файл в той же директории
+// (чтобы os.Rename остался на той же файловой системе), затем переименовывает его поверх
+// v.path
+func (v *FileVault) save(creds map[string]Credential) error {
+	data, err := yaml.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("failed to encode credential vault: %w", err)
+	}
+
+	dir := filepath.Dir(v.path)
+	tmp, err := os.CreateTemp(dir, ".credentials-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for credential vault: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // не-op после успешного Rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write credential vault: %w", err)
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions on credential vault: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to finalize credential vault write: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, v.path); err != nil {
+		return fmt.Errorf("failed to replace credential vault %s: %w", v.path, err)
+	}
+	return nil
+}
+
+func (v *FileVault) Get(key string) (Credential, bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	creds, err := v.load()
+	if err != nil {
+		return Credential{}, false, err
+	}
+	c, ok := creds[key]
+	return c, ok, nil
+}
+
+func (v *FileVault) Set(key string, c Credential) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	creds, err := v.load()
+	if err != nil {
+		return err
+	}
+	creds[key] = c
+	return v.save(creds)
+}
+
+func (v *FileVault) Delete(key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	creds, err := v.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := creds[key]; !ok {
+		return nil
+	}
+	delete(creds, key)
+	return v.save(creds)
+}
@@ -0,0 +1,150 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// hashicorpTimeout - таймаут отдельных запросов к Vault - срабатывает на каждую операцию
+// (Get/Set/Delete), как httpTimeout у internal/protocols/onvif.Client
+const hashicorpTimeout = 5 * time.Second
+
+// HashicorpVault - клиент KV v2 секретного движка HashiCorp Vault. Реализован напрямую
+// через net/http вместо официального github.com/hashicorp/vault/api, той же логикой, что
+// internal/protocols/onvif (hand-rolled SOAP) и pkg/scanner/igd (hand-rolled SOAP) - KV v2
+// REST API достаточно мал, чтобы не тянуть целый SDK ради трех операций
+type HashicorpVault struct {
+	addr       string // например, "https://vault.example.com:8200"
+	token      string
+	mountPath  string // обычно "secret" - имя включенного KV v2 движка
+	httpClient *http.Client
+}
+
+// NewHashicorpVault создает клиент Vault KV v2 по адресу addr, с токеном аутентификации
+// token и точкой монтирования mountPath (пусто - используется "secret", движок по
+// умолчанию в dev-режиме Vault)
+func NewHashicorpVault(addr, token, mountPath string) *HashicorpVault {
+	if mountPath == "" {
+		mountPath = "secret"
+	}
+	return &HashicorpVault{
+		addr:       strings.TrimSuffix(addr, "/"),
+		token:      token,
+		mountPath:  mountPath,
+		httpClient: &http.Client{Timeout: hashicorpTimeout},
+	}
+}
+
+// dataURL строит URL KV v2 эндпоинта для key - "data" в пути обязателен для KV v2 (в
+// отличие от KV v1, где секрет лежит прямо по mountPath/key)
+func (v *HashicorpVault) dataURL(key string) string {
+	return fmt.Sprintf("%s/v1/%s/data/%s", v.addr, v.mountPath, url.PathEscape(key))
+}
+
+func (v *HashicorpVault) do(method, endpoint string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Vault request to %s failed: %w", endpoint, err)
+	}
+	return resp, nil
+}
+
+type kvV2ReadResponse struct {
+	Data struct {
+		Data struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"data"`
+	} `json:"data"`
+}
+
+func (v *HashicorpVault) Get(key string) (Credential, bool, error) {
+	resp, err := v.do(http.MethodGet, v.dataURL(key), nil)
+	if err != nil {
+		return Credential{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Credential{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Credential{}, false, fmt.Errorf("Vault GET %s returned status %d", key, resp.StatusCode)
+	}
+
+	var parsed kvV2ReadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Credential{}, false, fmt.Errorf("failed to parse Vault response for %s: %w", key, err)
+	}
+	if parsed.Data.Data.Username == "" && parsed.Data.Data.Password == "" {
+		return Credential{}, false, nil
+	}
+	return Credential{Username: parsed.Data.Data.Username, Password: parsed.Data.Data.Password}, true, nil
+}
+
+type kvV2WriteRequest struct {
+	Data struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"data"`
+}
+
+func (v *HashicorpVault) Set(key string, c Credential) error {
+	var payload kvV2WriteRequest
+	payload.Data.Username = c.Username
+	payload.Data.Password = c.Password
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode Vault payload for %s: %w", key, err)
+	}
+
+	resp, err := v.do(http.MethodPost, v.dataURL(key), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("Vault write for %s returned status %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+func (v *HashicorpVault) Delete(key string) error {
+	// Удаляем через metadata эндпоинт, а не data - это полное уничтожение всех версий
+	// секрета (KV v2 "destroy"), а не просто создание новой версии с пустым значением,
+	// которое у data DELETE означало бы "soft delete" последней версии
+	metadataURL := fmt.Sprintf("%s/v1/%s/metadata/%s", v.addr, v.mountPath, url.PathEscape(key))
+
+	resp, err := v.do(http.MethodDelete, metadataURL, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("Vault delete for %s returned status %d", key, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build darwin
+
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// KeychainVault хранит учетные данные в системном keychain через CLI `security` - тот же
+// подход, что arptable_other.go использует для "arp -a": внешний инструмент вместо
+// недостающей кроссплатформенной библиотеки (для keychain это был бы CGo-биндинг, которого
+// этот проект нигде больше не использует)
+type KeychainVault struct {
+	service string // security -s - разделяет записи этого приложения от остальных в keychain
+}
+
+// NewKeychainVault создает KeychainVault, хранящий записи под именем service (обычно
+// "local-video-server")
+func NewKeychainVault(service string) *KeychainVault {
+	return &KeychainVault{service: service}
+}
+
+// keychain -w хранит ровно одну секретную строку на запись ("account"), без отдельного
+// поля под имя пользователя - для хранения пары Username+Password под одним ключом запись
+// сериализуется в password как JSON, а не разбивается на Username=account/Password=-w:
+// последнее потребовало бы адресовать Get по заранее известному Username, а не по
+// произвольному key (обычно EndpointRef устройства), которым адресуются все остальные Vault
+func (v *KeychainVault) Get(key string) (Credential, bool, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", v.service, "-a", key, "-w")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 44 {
+			// security возвращает 44, если запись не найдена - это не ошибка
+			return Credential{}, false, nil
+		}
+		return Credential{}, false, fmt.Errorf("security find-generic-password failed for %q: %w", key, err)
+	}
+
+	var c Credential
+	if err := json.Unmarshal(stdout.Bytes(), &c); err != nil {
+		return Credential{}, false, fmt.Errorf("failed to parse keychain entry for %q: %w", key, err)
+	}
+	return c, true, nil
+}
+
+func (v *KeychainVault) Set(key string, c Credential) error {
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode keychain entry for %q: %w", key, err)
+	}
+
+	// -U обновляет существующую запись вместо ошибки "already exists"
+	cmd := exec.Command("security", "add-generic-password", "-s", v.service, "-a", key, "-w", string(encoded), "-U")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security add-generic-password failed for %q: %w (%s)", key, err, string(out))
+	}
+	return nil
+}
+
+func (v *KeychainVault) Delete(key string) error {
+	cmd := exec.Command("security", "delete-generic-password", "-s", v.service, "-a", key)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 44 {
+			return nil
+		}
+		return fmt.Errorf("security delete-generic-password failed for %q: %w (%s)", key, err, string(out))
+	}
+	return nil
+}
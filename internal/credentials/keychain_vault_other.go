@@ -0,0 +1,31 @@
+//go:build !darwin
+
+package credentials
+
+import "fmt"
+
+// KeychainVault - заглушка вне macOS: ни Windows Credential Manager, ни Linux Secret
+// Service (libsecret/D-Bus) не имеют здесь готового CLI-эквивалента `security` без новой
+// внешней зависимости (CGo-биндинг или D-Bus клиент, которых этот проект нигде больше не
+// использует), так что на этих платформах KeychainVault честно отказывается работать вместо
+// того, чтобы притворяться персистентным хранилищем. Используйте FileVault или
+// HashicorpVault на этих платформах
+type KeychainVault struct{}
+
+// NewKeychainVault возвращает KeychainVault, все методы которого отказывают -
+// service игнорируется (есть только ради одинаковой сигнатуры конструктора с keychain_vault_darwin.go)
+func NewKeychainVault(service string) *KeychainVault {
+	return &KeychainVault{}
+}
+
+func (v *KeychainVault) Get(key string) (Credential, bool, error) {
+	return Credential{}, false, fmt.Errorf("OS keychain vault is only supported on macOS")
+}
+
+func (v *KeychainVault) Set(key string, c Credential) error {
+	return fmt.Errorf("OS keychain vault is only supported on macOS")
+}
+
+func (v *KeychainVault) Delete(key string) error {
+	return fmt.Errorf("OS keychain vault is only supported on macOS")
+}
@@ -0,0 +1,65 @@
+// Package credentials централизует хранение учетных данных камер, которые до сих пор
+// держались только в models.ScanConfig (ONVIFUsername/ONVIFPassword/ONVIFCredentialsByNetwork).
+// Vault - точка расширения для бэкендов хранения (YAML файл, переменные окружения, HashiCorp
+// Vault, системный keychain); registry.DeviceRegistry.SetCredential/RotateCredential работают
+// с любым Vault через этот интерфейс, не зная, где именно данные персистентны
+package credentials
+
+import (
+	"sort"
+	"strings"
+)
+
+// Credential - логин/пароль одного устройства или учетной записи, ключ для Vault - обычно
+// device.EndpointRef (устройство без него через Vault не адресуется, см.
+// registry.DeviceRegistry.SetCredential)
+type Credential struct {
+	Username string
+	Password string
+}
+
+// Vault - хранилище учетных данных, ключ - обычно EndpointRef устройства. Get возвращает
+// (Credential{}, false, nil), если ключ не найден - это не ошибка
+type Vault interface {
+	Get(key string) (Credential, bool, error)
+	Set(key string, c Credential) error
+	Delete(key string) error
+}
+
+// vendorDefaults - заводские пары логин/пароль по производителям, которые ONVIF
+// GetDeviceInformation сообщает в Manufacturer. Ключи - нижний регистр, подстрока сравнения,
+// поскольку камеры сообщают полное название вида "Hikvision Digital Technology Co., Ltd.",
+// а не короткое "Hikvision"
+var vendorDefaults = map[string][]Credential{
+	"hikvision": {{Username: "admin", Password: "12345"}},
+	"dahua":     {{Username: "admin", Password: "admin"}},
+	"axis":      {{Username: "root", Password: "pass"}},
+	"amcrest":   {{Username: "admin", Password: "admin"}},
+}
+
+// DefaultsForManufacturer возвращает заводские пары логин/пароль для manufacturer (обычно
+// device.Manufacturer) - поиск по подстроке без учета регистра, поскольку ONVIF сообщает
+// manufacturer в виде полного названия компании, а не нормализованного идентификатора
+// вендора. Пустой слайс, если производитель не распознан ни по одному известному вендору
+func DefaultsForManufacturer(manufacturer string) []Credential {
+	lower := strings.ToLower(manufacturer)
+
+	// Перебираем вендоров в отсортированном порядке - map не гарантирует порядок итерации,
+	// а если имя производителя когда-нибудь совпадет с подстрокой сразу двух вендоров,
+	// результат не должен зависеть от случайного порядка выдачи map
+	vendors := make([]string, 0, len(vendorDefaults))
+	for vendor := range vendorDefaults {
+		vendors = append(vendors, vendor)
+	}
+	sort.Strings(vendors)
+
+	for _, vendor := range vendors {
+		if strings.Contains(lower, vendor) {
+			creds := vendorDefaults[vendor]
+			out := make([]Credential, len(creds))
+			copy(out, creds)
+			return out
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package events реализует ONVIF PullPoint-подписку на уведомления устройства
+// (motion/tamper/analytics) - тот же паттерн, что PullPointSubscriber в EdgeX
+// device-onvif-camera: устройство само ничего не рассылает (в отличие от WS-BaseNotification
+// push), подписчик обязан сам long-poll'ить PullMessages и периодически продлевать подписку
+// Renew, пока не вызовет Unsubscribe.
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols/onvif"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// subscriptionDuration - срок действия одной PullPoint подписки, запрашиваемый у
+	// CreatePullPointSubscription/Renew
+	subscriptionDuration = 5 * time.Minute
+
+	// renewMargin - подписка продлевается за это время до истечения subscriptionDuration,
+	// чтобы сетевая задержка или пропущенный цикл PullMessages не дали ей истечь
+	renewMargin = 30 * time.Second
+
+	// pullTimeout - сколько PullMessages ждет накопления новых уведомлений перед тем, как
+	// ответить (пустым, если их не было)
+	pullTimeout = 30 * time.Second
+
+	// pullMessageLimit - MessageLimit одного PullMessages запроса
+	pullMessageLimit = 10
+
+	// resubscribeBackoff - пауза перед повторной подпиской после неудачи (сеть недоступна,
+	// устройство перезагружается)
+	resubscribeBackoff = 10 * time.Second
+)
+
+// Sink - получатель разобранных device-level событий, публикуемых Watch.
+// registry.DeviceRegistry.PublishEvent реализует этот интерфейс
+type Sink interface {
+	PublishEvent(event *models.Event)
+}
+
+// Watch открывает PullPoint подписку на Events сервисе устройства (xaddr - Capabilities.Events
+// из onvif.Client.GetCapabilities) и пересылает в sink каждое полученное уведомление, пока не
+// отменен ctx. Подписка продлевается (Renew) заранее, на resubscribeBackoff до истечения
+// subscriptionDuration; если Renew или PullMessages завершаются ошибкой (например, устройство
+// перезагрузилось и забыло подписку), Watch переподписывается заново вместо того, чтобы
+// прекратить работу - так одна перезагрузка камеры не останавливает поток событий навсегда.
+// Watch блокирует вызывающего, пока ctx не отменен, и вызывает Unsubscribe перед возвратом
+func Watch(ctx context.Context, xaddr string, creds onvif.Credentials, deviceIP, endpointRef string, sink Sink) error {
+	logger := utils.GetLogger()
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		sub, err := onvif.CreatePullPointSubscription(xaddr, creds, subscriptionDuration)
+		if err != nil {
+			logger.Debugf("ONVIF PullPoint subscribe failed for %s: %v", deviceIP, err)
+			if !sleepOrDone(ctx, resubscribeBackoff) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		pullErr := pullLoop(ctx, sub, deviceIP, endpointRef, sink, logger)
+		if unsubErr := sub.Unsubscribe(); unsubErr != nil {
+			logger.Debugf("ONVIF PullPoint unsubscribe failed for %s: %v", deviceIP, unsubErr)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Debugf("ONVIF PullPoint subscription for %s ended, re-subscribing: %v", deviceIP, pullErr)
+	}
+}
+
+// pullLoop вычитывает уведомления одной подписки до тех пор, пока не отменен ctx или
+// Renew/PullMessages не вернут ошибку - тогда Watch откроет новую подписку
+func pullLoop(ctx context.Context, sub *onvif.Subscription, deviceIP, endpointRef string, sink Sink, logger *logrus.Logger) error {
+	renewAt := time.Now().Add(subscriptionDuration - renewMargin)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if time.Now().After(renewAt) {
+			if err := sub.Renew(subscriptionDuration); err != nil {
+				return fmt.Errorf("renew failed: %w", err)
+			}
+			renewAt = time.Now().Add(subscriptionDuration - renewMargin)
+		}
+
+		messages, err := sub.PullMessages(pullTimeout, pullMessageLimit)
+		if err != nil {
+			return fmt.Errorf("pull failed: %w", err)
+		}
+
+		for _, msg := range messages {
+			sink.PublishEvent(&models.Event{
+				DeviceIP:    deviceIP,
+				EndpointRef: endpointRef,
+				Topic:       msg.Topic,
+				Timestamp:   msg.Timestamp,
+				Data:        msg.Data,
+				IsActive:    msg.IsActive,
+			})
+		}
+	}
+}
+
+// sleepOrDone ждет d или отмены ctx, смотря что наступит раньше - возвращает false, если ждать
+// пришлось из-за отмены ctx
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
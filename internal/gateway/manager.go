@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/local-video-server/internal/hls"
+)
+
+// Manager - шлюз поверх hls.Manager: добавляет подсчет зрителей на каждый поток (чтобы
+// показывать, кто сейчас смотрит) и HTTP API для явного старта/остановки/просмотра
+// списка потоков, не дожидаясь первого запроса плейлиста браузером. Фактическое
+// перепаковывание в HLS по прежнему делает hls.Manager - Manager лишь дирижирует им
+type Manager struct {
+	hls *hls.Manager
+
+	mu      sync.Mutex
+	viewers map[string]int
+}
+
+// NewManager создает шлюз поверх уже настроенного hls.Manager
+func NewManager(hlsManager *hls.Manager) *Manager {
+	return &Manager{
+		hls:     hlsManager,
+		viewers: make(map[string]int),
+	}
+}
+
+// Watch регистрирует нового зрителя потока, лениво запуская репакер при первом
+// обращении (см. hls.Manager.Start), и возвращает URL плейлиста HLS
+func (m *Manager) Watch(deviceIP string) (string, error) {
+	if err := m.hls.Start(deviceIP); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	m.viewers[deviceIP]++
+	m.mu.Unlock()
+
+	return fmt.Sprintf("/hls/%s/index.m3u8", deviceIP), nil
+}
+
+// Unwatch отписывает одного зрителя от потока. Сам репакер не останавливается -
+// он будет реапнут hls.Manager по IdleTimeout, как только перестанут приходить запросы
+// сегментов (см. hls.Manager.reapIdleLoop)
+func (m *Manager) Unwatch(deviceIP string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.viewers[deviceIP] <= 1 {
+		delete(m.viewers, deviceIP)
+		return
+	}
+	m.viewers[deviceIP]--
+}
+
+// Stop принудительно останавливает поток немедленно, сбрасывая счетчик зрителей, в
+// обход IdleTimeout - используется эндпоинтом /gateway/stop для ручного управления
+func (m *Manager) Stop(deviceIP string) {
+	m.mu.Lock()
+	delete(m.viewers, deviceIP)
+	m.mu.Unlock()
+
+	m.hls.Stop(deviceIP)
+}
+
+// List возвращает число зрителей для каждого сейчас активного потока
+func (m *Manager) List() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]int, len(m.viewers))
+	for deviceIP, count := range m.viewers {
+		result[deviceIP] = count
+	}
+	return result
+}
+
+// Handler возвращает http.Handler с эндпоинтами старта/остановки/списка потоков и
+// минимальной HTML страницей плеера на /gateway/watch/{deviceIP}
+func (m *Manager) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gateway/start/", m.handleStart)
+	mux.HandleFunc("/gateway/stop/", m.handleStop)
+	mux.HandleFunc("/gateway/list", m.handleList)
+	mux.HandleFunc("/gateway/watch/", m.handleWatch)
+	return mux
+}
+
+func (m *Manager) handleStart(w http.ResponseWriter, req *http.Request) {
+	deviceIP := strings.TrimPrefix(req.URL.Path, "/gateway/start/")
+	if deviceIP == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	url, err := m.Watch(deviceIP)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	fmt.Fprint(w, url)
+}
+
+func (m *Manager) handleStop(w http.ResponseWriter, req *http.Request) {
+	deviceIP := strings.TrimPrefix(req.URL.Path, "/gateway/stop/")
+	if deviceIP == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	m.Stop(deviceIP)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Manager) handleList(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.List())
+}
+
+func (m *Manager) handleWatch(w http.ResponseWriter, req *http.Request) {
+	deviceIP := strings.TrimPrefix(req.URL.Path, "/gateway/watch/")
+	if deviceIP == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	url, err := m.Watch(deviceIP)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, playerHTML, deviceIP, url)
+}
@@ -0,0 +1,14 @@
+package gateway
+
+// playerHTML - минимальная страница плеера без JS зависимостей: нативный <video> тег,
+// проигрывающий HLS там, где браузер поддерживает его из коробки (Safari/iOS). Для
+// остальных браузеров нужен полноценный плеер (например, hls.js) - это забота
+// вызывающей стороны, встраивающей URL плейлиста из Watch в свой собственный UI
+const playerHTML = `<!DOCTYPE html>
+<html>
+<head><title>%s</title></head>
+<body>
+<video controls autoplay muted playsinline src="%s" style="width:100%%;max-width:960px"></video>
+</body>
+</html>
+`
@@ -0,0 +1,99 @@
+package hls
+
+import "fmt"
+
+// aacSampleRatesADTS - таблица частот дискретизации ADTS (ISO/IEC 13818-7, раздел 1.6.2.1),
+// индекс используется в заголовке ADTS
+var aacSampleRatesADTS = [16]int{
+	96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050,
+	16000, 12000, 11025, 8000, 7350, 0, 0, 0,
+}
+
+// aacDepacketizer извлекает сырые AAC фреймы из RTP payload'ов формата AAC-hbr (RFC 3640,
+// раздел 3.3.6). Предполагается, что в одном RTP пакете передается один AU - это покрывает
+// подавляющее большинство RTSP камер
+type aacDepacketizer struct{}
+
+// onPacket разбирает AU-headers-length, AU-header(ы) и данные AU, возвращая сырые
+// raw_data_block'и (без заголовка ADTS)
+func (d *aacDepacketizer) onPacket(payload []byte) ([][]byte, error) {
+	if len(payload) < 2 {
+		return nil, fmt.Errorf("AAC RTP payload too short")
+	}
+
+	auHeadersLengthBits := int(payload[0])<<8 | int(payload[1])
+	auHeadersLen := (auHeadersLengthBits + 7) / 8
+	offset := 2 + auHeadersLen
+	if offset > len(payload) {
+		return nil, fmt.Errorf("AAC AU-headers exceed payload length")
+	}
+
+	headers := payload[2 : 2+auHeadersLen]
+	numHeaders := auHeadersLengthBits / 16 // каждый AU-header в режиме hbr занимает 16 бит
+
+	frames := make([][]byte, 0, numHeaders)
+	bitPos := 0
+	for i := 0; i < numHeaders; i++ {
+		size := int(readBitsFromBytes(headers, bitPos, 13))
+		bitPos += 16 // 13 бит размера + 3 бита index-delta
+
+		if offset+size > len(payload) {
+			return nil, fmt.Errorf("AAC AU data exceeds payload length")
+		}
+		frame := make([]byte, size)
+		copy(frame, payload[offset:offset+size])
+		frames = append(frames, frame)
+		offset += size
+	}
+
+	return frames, nil
+}
+
+// readBitsFromBytes читает n бит начиная с bitPos (от начала data, старший бит вперед)
+func readBitsFromBytes(data []byte, bitPos, n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		byteIdx := (bitPos + i) / 8
+		if byteIdx >= len(data) {
+			break
+		}
+		bitIdx := uint(7 - (bitPos+i)%8)
+		v = (v << 1) | uint32((data[byteIdx]>>bitIdx)&1)
+	}
+	return v
+}
+
+// sampleRateToADTSIndex переводит частоту дискретизации в индекс таблицы ADTS
+func sampleRateToADTSIndex(sampleRate int) int {
+	for i, rate := range aacSampleRatesADTS {
+		if rate == sampleRate {
+			return i
+		}
+	}
+	return 4 // 44100 Гц - самый распространенный случай
+}
+
+// buildADTSHeader строит 7-байтовый заголовок ADTS без CRC (ISO/IEC 13818-7, приложение E)
+// для одного AAC фрейма длиной frameLen байт (включая сам заголовок)
+func buildADTSHeader(objectType, sampleRate, channels, frameLen int) []byte {
+	sampleRateIdx := sampleRateToADTSIndex(sampleRate)
+	profile := objectType - 1 // ADTS profile = AudioObjectType - 1
+
+	header := make([]byte, 7)
+	header[0] = 0xFF
+	header[1] = 0xF1 // syncword + MPEG-4 + Layer=0 + no CRC
+	header[2] = byte(profile<<6) | byte((sampleRateIdx&0xF)<<2) | byte((channels>>2)&0x1)
+	header[3] = byte((channels&0x3)<<6) | byte((frameLen>>11)&0x3)
+	header[4] = byte((frameLen >> 3) & 0xFF)
+	header[5] = byte((frameLen&0x7)<<5) | 0x1F
+	header[6] = 0xFC
+	return header
+}
+
+// BuildADTSFrame оборачивает сырой AAC raw_data_block (без заголовка ADTS) в ADTS фрейм -
+// экспортированная обертка над buildADTSHeader для источников, не проходящих через
+// aacDepacketizer (RTP), например vigilos/pkg/storage для RTMP записи
+func BuildADTSFrame(objectType, sampleRate, channels int, rawFrame []byte) []byte {
+	header := buildADTSHeader(objectType, sampleRate, channels, len(rawFrame)+7)
+	return append(header, rawFrame...)
+}
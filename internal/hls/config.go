@@ -0,0 +1,63 @@
+package hls
+
+import "time"
+
+// Config - параметры сегментирования и раздачи одного потока. Применяется Manager'ом ко
+// всем Repackager'ам, которые он поднимает
+type Config struct {
+	// SegmentDuration - целевая длительность сегмента. Сегмент режется на ближайшей
+	// границе IDR кадра не раньше, чем пройдет это время (обычная практика HLS
+	// сегментеров - не резать внутри GOP)
+	SegmentDuration time.Duration
+
+	// SegmentCount - сколько сегментов хранить в скользящем окне плейлиста
+	SegmentCount int
+
+	// PartDuration - целевая длительность LL-HLS части (EXT-X-PART, RFC 8216bis).
+	// 0 отключает LL-HLS: плейлист будет содержать только обычные EXTINF сегменты
+	PartDuration time.Duration
+
+	// IdleTimeout - как долго Repackager может простаивать без обращений клиентов,
+	// прежде чем Manager его остановит
+	IdleTimeout time.Duration
+
+	// OnSegment, если задан, вызывается синхронно при нарезке каждого сегмента - в
+	// дополнение к тому, что сегмент попадает в скользящее окно. Используется
+	// vigilos/pkg/storage.LocalRecorder, чтобы сбрасывать сегменты на диск под
+	// собственной политикой хранения
+	OnSegment func(Segment)
+
+	// Transport - режим RTSP транспорта: "auto" (по умолчанию, пробует UDP и при
+	// отсутствии пакетов переключается на TCP), "tcp", "udp" или "udp-multicast".
+	// Пустое значение равносильно "tcp" - так Repackager вел себя до появления этого поля
+	Transport string
+
+	// AnyPort - в режимах "udp"/"auto" принимать RTP/RTCP с любого порта отправителя
+	// вместо строгой проверки server_port, согласованного в SETUP - нужно для камер за NAT
+	AnyPort bool
+}
+
+// DefaultConfig возвращает параметры по умолчанию: 6-секундные сегменты, окно в
+// DefaultSegmentWindow сегментов, LL-HLS выключен, IdleTimeout - DefaultIdleTimeout
+func DefaultConfig() Config {
+	return Config{
+		SegmentDuration: 6 * time.Second,
+		SegmentCount:    DefaultSegmentWindow,
+		PartDuration:    0,
+		IdleTimeout:     DefaultIdleTimeout,
+	}
+}
+
+// withDefaults заполняет нулевые поля значениями по умолчанию
+func (c Config) withDefaults() Config {
+	if c.SegmentDuration <= 0 {
+		c.SegmentDuration = 6 * time.Second
+	}
+	if c.SegmentCount <= 0 {
+		c.SegmentCount = DefaultSegmentWindow
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = DefaultIdleTimeout
+	}
+	return c
+}
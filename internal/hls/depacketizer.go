@@ -0,0 +1,100 @@
+package hls
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// accessUnit - набор NAL unit'ов одного кадра (без старт-кодов Annex-B), готовых к муксированию
+type accessUnit [][]byte
+
+// h264Depacketizer собирает NAL unit'ы из RTP payload'ов H.264 (RFC 6184) в access unit'ы:
+// одиночные NAL unit'ы, агрегированные STAP-A и фрагментированные FU-A
+type h264Depacketizer struct {
+	fuBuf     []byte // накопленный payload текущего FU-A фрагмента, включая восстановленный NAL заголовок
+	fuStarted bool
+	au        accessUnit
+}
+
+// onPacket разбирает один RTP payload (уже без RTP заголовка) и возвращает завершенный access
+// unit, если marker bit сигнализирует об окончании кадра
+func (d *h264Depacketizer) onPacket(payload []byte, marker bool) (accessUnit, error) {
+	if len(payload) < 1 {
+		return nil, fmt.Errorf("empty H.264 RTP payload")
+	}
+
+	nalType := payload[0] & 0x1F
+
+	switch {
+	case nalType >= 1 && nalType <= 23:
+		// Single NAL Unit Packet
+		nal := make([]byte, len(payload))
+		copy(nal, payload)
+		d.au = append(d.au, nal)
+
+	case nalType == 24:
+		// STAP-A: несколько NAL unit'ов, каждый предварен 2-байтовой длиной
+		offset := 1
+		for offset+2 <= len(payload) {
+			size := int(binary.BigEndian.Uint16(payload[offset : offset+2]))
+			offset += 2
+			if offset+size > len(payload) {
+				return nil, fmt.Errorf("STAP-A: NAL size exceeds payload")
+			}
+			nal := make([]byte, size)
+			copy(nal, payload[offset:offset+size])
+			d.au = append(d.au, nal)
+			offset += size
+		}
+
+	case nalType == 28:
+		// FU-A: фрагмент одного NAL unit'а
+		if len(payload) < 2 {
+			return nil, fmt.Errorf("FU-A payload too short")
+		}
+		fuHeader := payload[1]
+		start := fuHeader&0x80 != 0
+		end := fuHeader&0x40 != 0
+		nalHeader := (payload[0] & 0xE0) | (fuHeader & 0x1F)
+
+		switch {
+		case start:
+			d.fuBuf = append([]byte{nalHeader}, payload[2:]...)
+			d.fuStarted = true
+		case d.fuStarted:
+			d.fuBuf = append(d.fuBuf, payload[2:]...)
+		default:
+			return nil, fmt.Errorf("FU-A continuation received without a start fragment")
+		}
+
+		if end && d.fuStarted {
+			nal := make([]byte, len(d.fuBuf))
+			copy(nal, d.fuBuf)
+			d.au = append(d.au, nal)
+			d.fuBuf = nil
+			d.fuStarted = false
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported H.264 NAL type in RTP payload: %d", nalType)
+	}
+
+	if marker && len(d.au) > 0 {
+		au := d.au
+		d.au = nil
+		return au, nil
+	}
+
+	return nil, nil
+}
+
+// accessUnitHasIDR сообщает, содержит ли access unit IDR слайс (NAL type 5) - по нему
+// определяется начало нового GOP и, соответственно, граница HLS сегмента
+func accessUnitHasIDR(au accessUnit) bool {
+	for _, nal := range au {
+		if len(nal) > 0 && nal[0]&0x1F == 5 {
+			return true
+		}
+	}
+	return false
+}
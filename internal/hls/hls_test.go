@@ -0,0 +1,266 @@
+package hls
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// This sandbox has no ffmpeg/ffprobe to produce real TS/fMP4 captures, so the fixtures below are
+// synthetic: built by hand against the RTP (RFC 6184/3640) and MPEG-TS (ISO/IEC 13818-1) layouts
+// these functions themselves implement, then confirmed against a standalone run of this package's
+// own code before being hardcoded here. This package has no fMP4 path - only the MPEG-TS muxer
+// below - so fMP4 fixtures aren't applicable to what's actually implemented.
+
+func TestBuildPlaylist(t *testing.T) {
+	tests := []struct {
+		name           string
+		segments       []Segment
+		targetDuration int
+		want           string
+	}{
+		{
+			name:           "empty window",
+			segments:       nil,
+			targetDuration: 1,
+			want:           "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:1\n",
+		},
+		{
+			name: "multiple segments, no parts",
+			segments: []Segment{
+				{Seq: 5, Duration: 6.02},
+				{Seq: 6, Duration: 5.98},
+			},
+			targetDuration: 7,
+			want: "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:7\n#EXT-X-MEDIA-SEQUENCE:5\n" +
+				"#EXTINF:6.020,\nsegment5.ts\n#EXTINF:5.980,\nsegment6.ts\n",
+		},
+		{
+			name: "segment with LL-HLS parts",
+			segments: []Segment{
+				{Seq: 0, Duration: 6.0, Parts: []Part{{Index: 0, Duration: 1.0}, {Index: 1, Duration: 1.2}}},
+			},
+			targetDuration: 6,
+			want: "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-PART-INF:PART-TARGET=1.200\n" +
+				"#EXT-X-SERVER-CONTROL:PART-HOLD-BACK=3.600\n#EXT-X-TARGETDURATION:6\n" +
+				"#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PART:DURATION=1.000,URI=\"part0.0.ts\"\n" +
+				"#EXT-X-PART:DURATION=1.200,URI=\"part0.1.ts\"\n#EXTINF:6.000,\nsegment0.ts\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildPlaylist(tt.segments, tt.targetDuration)
+			if got != tt.want {
+				t.Fatalf("BuildPlaylist(%s) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessUnitHasIDR(t *testing.T) {
+	tests := []struct {
+		name string
+		au   accessUnit
+		want bool
+	}{
+		{name: "IDR slice present", au: accessUnit{{0x05, 0x01}}, want: true},
+		{name: "non-IDR slice only", au: accessUnit{{0x61, 0x01}}, want: false},
+		{name: "SPS+PPS+IDR", au: accessUnit{{0x67}, {0x68}, {0x05, 0xAA}}, want: true},
+		{name: "empty access unit", au: accessUnit{}, want: false},
+		{name: "empty NAL in access unit", au: accessUnit{{}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accessUnitHasIDR(tt.au); got != tt.want {
+				t.Fatalf("accessUnitHasIDR(%s) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestH264DepacketizerSingleNAL(t *testing.T) {
+	d := &h264Depacketizer{}
+	au, err := d.onPacket([]byte{0x65, 0xAA, 0xBB}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(au) != 1 || hex.EncodeToString(au[0]) != "65aabb" {
+		t.Fatalf("got %v, want single NAL 65aabb", au)
+	}
+}
+
+func TestH264DepacketizerSTAPA(t *testing.T) {
+	d := &h264Depacketizer{}
+	// STAP-A (type 24): 2-byte length + NAL, repeated - here two 1-byte NALs: 0x67, 0x68
+	payload := []byte{24, 0x00, 0x01, 0x67, 0x00, 0x01, 0x68}
+	au, err := d.onPacket(payload, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(au) != 2 || hex.EncodeToString(au[0]) != "67" || hex.EncodeToString(au[1]) != "68" {
+		t.Fatalf("got %v, want [67 68]", au)
+	}
+}
+
+func TestH264DepacketizerFUA(t *testing.T) {
+	d := &h264Depacketizer{}
+	// FU indicator nal_type=28, FU header start=1 end=0 type=5 (IDR), then end fragment
+	if _, err := d.onPacket([]byte{28, 0x80 | 5, 0xDE, 0xAD}, false); err != nil {
+		t.Fatalf("start fragment: unexpected error: %v", err)
+	}
+	au, err := d.onPacket([]byte{28, 0x40 | 5, 0xBE, 0xEF}, true)
+	if err != nil {
+		t.Fatalf("end fragment: unexpected error: %v", err)
+	}
+	if len(au) != 1 || hex.EncodeToString(au[0]) != "05deadbeef" {
+		t.Fatalf("got %v, want reassembled NAL 05deadbeef", au)
+	}
+	if !accessUnitHasIDR(au) {
+		t.Fatalf("reassembled FU-A NAL should be recognized as IDR")
+	}
+}
+
+func TestH264DepacketizerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		marker  bool
+		setup   func() *h264Depacketizer
+	}{
+		{name: "empty payload", payload: nil, marker: true, setup: func() *h264Depacketizer { return &h264Depacketizer{} }},
+		{name: "unsupported NAL type", payload: []byte{31, 0x00}, marker: true, setup: func() *h264Depacketizer { return &h264Depacketizer{} }},
+		{name: "FU-A continuation without start", payload: []byte{28, 0x00 | 5, 0xAA}, marker: true, setup: func() *h264Depacketizer { return &h264Depacketizer{} }},
+		{name: "STAP-A size exceeds payload", payload: []byte{24, 0x00, 0xFF, 0x67}, marker: true, setup: func() *h264Depacketizer { return &h264Depacketizer{} }},
+		{name: "FU-A payload too short", payload: []byte{28}, marker: true, setup: func() *h264Depacketizer { return &h264Depacketizer{} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.setup()
+			if _, err := d.onPacket(tt.payload, tt.marker); err == nil {
+				t.Fatalf("onPacket(%s) = nil error, want error", tt.name)
+			}
+		})
+	}
+}
+
+// buildAACRTPPayload constructs a minimal AAC-hbr RTP payload (RFC 3640 §3.3.6): a 16-bit
+// AU-headers-length, one 16-bit AU-header per frame (13-bit size + 3-bit index-delta=0), then the
+// raw frame bytes back to back.
+func buildAACRTPPayload(frames [][]byte) []byte {
+	auHeadersLengthBits := len(frames) * 16
+	out := []byte{byte(auHeadersLengthBits >> 8), byte(auHeadersLengthBits)}
+	for _, f := range frames {
+		v := uint16(len(f)<<3) & 0xFFF8
+		out = append(out, byte(v>>8), byte(v))
+	}
+	for _, f := range frames {
+		out = append(out, f...)
+	}
+	return out
+}
+
+func TestAACDepacketizer(t *testing.T) {
+	d := &aacDepacketizer{}
+	payload := buildAACRTPPayload([][]byte{{0xDE, 0xAD, 0xBE}})
+	frames, err := d.onPacket(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(frames) != 1 || hex.EncodeToString(frames[0]) != "deadbe" {
+		t.Fatalf("got %v, want [deadbe]", frames)
+	}
+}
+
+func TestAACDepacketizerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{name: "payload too short", payload: []byte{0x00}},
+		{name: "AU-headers exceed payload", payload: []byte{0xFF, 0xFF, 0x00}},
+		{name: "AU data exceeds payload", payload: buildAACRTPPayload([][]byte{{0x01, 0x02, 0x03, 0x04, 0x05}})[:5]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &aacDepacketizer{}
+			if _, err := d.onPacket(tt.payload); err == nil {
+				t.Fatalf("onPacket(%s) = nil error, want error", tt.name)
+			}
+		})
+	}
+}
+
+func TestBuildADTSFrame(t *testing.T) {
+	got := BuildADTSFrame(2, 44100, 2, []byte{0xAA, 0xBB})
+	want := "fff15080013ffcaabb"
+	if hex.EncodeToString(got) != want {
+		t.Fatalf("BuildADTSFrame() = %x, want %s", got, want)
+	}
+}
+
+func TestWriteTimestampRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		ts   uint64
+	}{
+		{name: "zero", ts: 0},
+		{name: "small value", ts: 12345},
+		{name: "max 33-bit value", ts: 0x1FFFFFFFF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := make([]byte, 5)
+			writeTimestamp(buf, 0x02, tt.ts)
+			if got := decodeTimestamp(buf); got != tt.ts {
+				t.Fatalf("writeTimestamp round-trip = %d, want %d", got, tt.ts)
+			}
+		})
+	}
+}
+
+// decodeTimestamp decodes a 5-byte PTS/DTS field written by writeTimestamp, reversing
+// ISO/IEC 13818-1 §2.4.3.6 - used only to verify writeTimestamp's bit-packing in tests above.
+func decodeTimestamp(buf []byte) uint64 {
+	var ts uint64
+	ts |= uint64(buf[0]>>1&0x07) << 30
+	ts |= uint64(buf[1]) << 22
+	ts |= uint64(buf[2]>>1) << 15
+	ts |= uint64(buf[3]) << 7
+	ts |= uint64(buf[4] >> 1)
+	return ts
+}
+
+func TestMuxerWritesValidTSPackets(t *testing.T) {
+	m := NewMuxer(false)
+	buf := m.Bytes()
+
+	if len(buf) != 2*tsPacketSize {
+		t.Fatalf("NewMuxer(false) wrote %d bytes, want %d (PAT+PMT)", len(buf), 2*tsPacketSize)
+	}
+	if buf[0] != 0x47 || buf[tsPacketSize] != 0x47 {
+		t.Fatalf("TS packets must start with sync byte 0x47")
+	}
+	patPID := (int(buf[1]&0x1F) << 8) | int(buf[2])
+	if patPID != 0x0000 {
+		t.Fatalf("PAT packet PID = %#x, want 0x0000", patPID)
+	}
+	pmtPID := (int(buf[tsPacketSize+1]&0x1F) << 8) | int(buf[tsPacketSize+2])
+	if pmtPID != defaultPMTPID {
+		t.Fatalf("PMT packet PID = %#x, want %#x", pmtPID, defaultPMTPID)
+	}
+
+	m2 := NewMuxer(true)
+	m2.WriteVideoNALUs([][]byte{{0x65, 0x01, 0x02}}, 90000, 90000, true)
+	m2.WriteAudioFrame(BuildADTSFrame(2, 44100, 2, []byte{0xAA, 0xBB}), 90000)
+	buf2 := m2.Bytes()
+	if len(buf2)%tsPacketSize != 0 {
+		t.Fatalf("muxer output length %d is not a multiple of %d", len(buf2), tsPacketSize)
+	}
+	if len(buf2) <= 2*tsPacketSize {
+		t.Fatalf("expected video/audio writes to add packets beyond PAT+PMT, got %d bytes", len(buf2))
+	}
+}
@@ -0,0 +1,68 @@
+package hls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Part - часть сегмента для LL-HLS (EXT-X-PART, RFC 8216bis), адресуемая отдельным URI,
+// чтобы проигрыватель мог забирать сегмент до того, как он будет полностью дорезан
+type Part struct {
+	Index    int
+	Duration float64
+	Offset   int // смещение в байтах внутри Segment.Data
+	Length   int // длина части в байтах
+}
+
+// Segment - один сегмент MPEG-TS, хранящийся в скользящем окне и отдаваемый по HTTP
+type Segment struct {
+	Seq      uint64
+	Duration float64
+	Data     []byte
+	Parts    []Part // заполнено, только если у Repackager задан Config.PartDuration
+}
+
+// BuildPlaylist строит live плейлист index.m3u8 (RFC 8216) по текущему окну сегментов.
+// Если хотя бы один сегмент содержит части, плейлист дополняется EXT-X-PART-INF и
+// EXT-X-SERVER-CONTROL (RFC 8216bis, LL-HLS) и версией протокола 6, как того требуют части
+func BuildPlaylist(segments []Segment, targetDuration int) string {
+	partDuration, hasParts := maxPartDuration(segments)
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	if hasParts {
+		b.WriteString("#EXT-X-VERSION:6\n")
+		fmt.Fprintf(&b, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", partDuration)
+		fmt.Fprintf(&b, "#EXT-X-SERVER-CONTROL:PART-HOLD-BACK=%.3f\n", partDuration*3)
+	} else {
+		b.WriteString("#EXT-X-VERSION:3\n")
+	}
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", targetDuration)
+	if len(segments) > 0 {
+		fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", segments[0].Seq)
+	}
+	for _, seg := range segments {
+		for _, part := range seg.Parts {
+			fmt.Fprintf(&b, "#EXT-X-PART:DURATION=%.3f,URI=\"part%d.%d.ts\"\n", part.Duration, seg.Seq, part.Index)
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", seg.Duration)
+		fmt.Fprintf(&b, "segment%d.ts\n", seg.Seq)
+	}
+	return b.String()
+}
+
+// maxPartDuration возвращает наибольшую длительность части среди всех сегментов окна
+// (используется как PART-TARGET) и сообщает, есть ли в окне хотя бы одна часть
+func maxPartDuration(segments []Segment) (float64, bool) {
+	max := 0.0
+	found := false
+	for _, seg := range segments {
+		for _, part := range seg.Parts {
+			found = true
+			if part.Duration > max {
+				max = part.Duration
+			}
+		}
+	}
+	return max, found
+}
@@ -0,0 +1,345 @@
+package hls
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/internal/rtsp"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// DefaultSegmentWindow - число сегментов, которое хранится в плейлисте (скользящее окно)
+const DefaultSegmentWindow = 7
+
+// Repackager подключается к RTSP потоку через rtsp.Client, депакетизирует H.264 RTP и
+// перепаковывает access unit'ы в скользящее окно сегментов MPEG-TS для раздачи по HLS.
+//
+// Аудио трек пока не задействован: rtsp.Client поддерживает один транспорт на соединение,
+// а аудио обычно приходит отдельной m= секцией со своим SETUP - это станет возможным, когда
+// Client сможет поднимать несколько интерливд-каналов одновременно. Муксер (см. tsmux.go)
+// уже умеет паковать AAC-ADTS поток, как только аудио RTP станет доступен.
+type Repackager struct {
+	rtspURL  string
+	username string
+	password string
+
+	cfg Config
+
+	mu                sync.Mutex
+	segments          []Segment
+	nextSeq           uint64
+	lastAccessed      time.Time
+	resolvedTransport rtsp.TransportMode
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRepackager создает репакер для указанного RTSP потока с заданными параметрами
+// сегментирования. Подключение и перепаковка запускаются вызовом Start
+func NewRepackager(rtspURL, username, password string, cfg Config) *Repackager {
+	cfg = cfg.withDefaults()
+	return &Repackager{
+		rtspURL:      rtspURL,
+		username:     username,
+		password:     password,
+		cfg:          cfg,
+		lastAccessed: time.Now(),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start подключается к RTSP источнику (OPTIONS/DESCRIBE/SETUP/PLAY) и запускает фоновую
+// горутину, которая читает RTP пакеты и режет их на сегменты HLS
+func (r *Repackager) Start() error {
+	client, err := rtsp.NewClient(r.rtspURL, r.username, r.password, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to create RTSP client: %w", err)
+	}
+
+	if _, err := client.Options(); err != nil {
+		client.Close()
+		return fmt.Errorf("OPTIONS failed: %w", err)
+	}
+
+	describeResp, err := client.Describe()
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("DESCRIBE failed: %w", err)
+	}
+	if describeResp.StatusCode != 200 {
+		client.Close()
+		return fmt.Errorf("DESCRIBE returned status %d", describeResp.StatusCode)
+	}
+
+	streamInfo, err := rtsp.ParseSDP(describeResp.Body)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("failed to parse SDP: %w", err)
+	}
+	if len(streamInfo.VideoTracks) == 0 {
+		client.Close()
+		return fmt.Errorf("no H.264 video track found in SDP")
+	}
+	if len(streamInfo.AudioTracks) > 0 {
+		utils.GetLogger().Debugf("HLS repackager: %s has an audio track, but audio repackaging is not yet supported", r.rtspURL)
+	}
+
+	client.SetAnyPort(r.cfg.AnyPort)
+
+	resolved, err := r.setupAndPlay(client)
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	r.mu.Lock()
+	r.resolvedTransport = resolved
+	r.mu.Unlock()
+
+	client.StartKeepalive(rtsp.DefaultKeepaliveInterval)
+
+	go r.run(client)
+	return nil
+}
+
+// setupAndPlay выбирает SETUP+PLAY согласно Config.Transport и возвращает фактически
+// использованный транспорт - в режиме "auto" он может отличаться от запрошенного, если
+// Client.PlayAuto переключился на TCP
+func (r *Repackager) setupAndPlay(client *rtsp.Client) (rtsp.TransportMode, error) {
+	if r.cfg.Transport == "auto" {
+		_, mode, err := client.PlayAuto(rtsp.DefaultAutoFallbackWait)
+		if err != nil {
+			return 0, fmt.Errorf("auto transport failed: %w", err)
+		}
+		return mode, nil
+	}
+
+	mode := rtsp.TransportModeInterleaved
+	if r.cfg.Transport != "" {
+		parsed, err := rtsp.ParseTransportMode(r.cfg.Transport)
+		if err != nil {
+			return 0, err
+		}
+		mode = parsed
+	}
+
+	if _, err := client.SetupTransport(mode); err != nil {
+		return 0, fmt.Errorf("SETUP failed: %w", err)
+	}
+	if _, err := client.Play(); err != nil {
+		return 0, fmt.Errorf("PLAY failed: %w", err)
+	}
+	return mode, nil
+}
+
+// ResolvedTransport возвращает транспортный режим, который Repackager фактически
+// использует для текущего подключения (в частности, реальный выбор "auto")
+func (r *Repackager) ResolvedTransport() rtsp.TransportMode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resolvedTransport
+}
+
+// Stop останавливает перепаковку и закрывает RTSP соединение, дожидаясь завершения горутины
+// Done returns a channel that is closed once the capture goroutine has stopped, whether
+// because Stop was called or because the RTSP source itself ended/errored.
+func (r *Repackager) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *Repackager) Stop() {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	<-r.done
+}
+
+// Touch обновляет время последнего обращения - используется Manager для idle-таймаута
+func (r *Repackager) Touch() {
+	r.mu.Lock()
+	r.lastAccessed = time.Now()
+	r.mu.Unlock()
+}
+
+// IdleSince возвращает время, прошедшее с последнего обращения к репакеру
+func (r *Repackager) IdleSince() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Since(r.lastAccessed)
+}
+
+// Playlist возвращает текущий index.m3u8, собранный по сегментам в окне. Возвращает
+// false, пока не накоплен ни один сегмент
+func (r *Repackager) Playlist() (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.segments) == 0 {
+		return "", false
+	}
+	return BuildPlaylist(r.segments, r.targetDuration()), true
+}
+
+// targetDuration возвращает EXT-X-TARGETDURATION - округленную вверх длительность самого
+// долгого сегмента в текущем окне (RFC 8216, раздел 4.3.3.1)
+func (r *Repackager) targetDuration() int {
+	max := 0.0
+	for _, seg := range r.segments {
+		if seg.Duration > max {
+			max = seg.Duration
+		}
+	}
+	return int(max) + 1
+}
+
+// Segment возвращает данные сегмента по его номеру, если он еще присутствует в окне
+func (r *Repackager) Segment(seq uint64) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, seg := range r.segments {
+		if seg.Seq == seq {
+			return seg.Data, true
+		}
+	}
+	return nil, false
+}
+
+// Part возвращает байты одной LL-HLS части сегмента, если сегмент еще в окне и часть с
+// таким индексом была нарезана
+func (r *Repackager) Part(seq uint64, index int) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, seg := range r.segments {
+		if seg.Seq != seq {
+			continue
+		}
+		for _, part := range seg.Parts {
+			if part.Index == index {
+				return seg.Data[part.Offset : part.Offset+part.Length], true
+			}
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// cutSegment завершает текущий muxer и добавляет его в скользящее окно вместе с его LL-HLS
+// частями (если они были накоплены), отбрасывая самые старые сегменты сверх SegmentCount
+func (r *Repackager) cutSegment(muxer *Muxer, start time.Time, parts []Part) {
+	if muxer == nil {
+		return
+	}
+	duration := time.Since(start).Seconds()
+	if duration <= 0 {
+		duration = 0.1
+	}
+
+	r.mu.Lock()
+	segment := Segment{Seq: r.nextSeq, Duration: duration, Data: muxer.Bytes(), Parts: parts}
+	r.nextSeq++
+	r.segments = append(r.segments, segment)
+	if len(r.segments) > r.cfg.SegmentCount {
+		r.segments = r.segments[len(r.segments)-r.cfg.SegmentCount:]
+	}
+	r.mu.Unlock()
+
+	if r.cfg.OnSegment != nil {
+		r.cfg.OnSegment(segment)
+	}
+}
+
+type rtpPacket struct {
+	channel int
+	payload []byte
+}
+
+// run читает RTP пакеты из RTSP соединения, депакетизирует H.264 и режет сегменты на
+// границах IDR кадров не раньше, чем пройдет Config.SegmentDuration, а также (если задан
+// Config.PartDuration) нарезает LL-HLS части внутри текущего сегмента
+func (r *Repackager) run(client *rtsp.Client) {
+	logger := utils.GetLogger()
+	defer close(r.done)
+	defer client.Close()
+
+	depay := &h264Depacketizer{}
+	var muxer *Muxer
+	var segmentStart time.Time
+	var partStart time.Time
+	var partIndex int
+	var partOffset int
+	var parts []Part
+
+	packets := make(chan rtpPacket, 256)
+	go func() {
+		defer close(packets)
+		for {
+			channel, payload, err := client.ReadPacket()
+			if err != nil {
+				return
+			}
+			select {
+			case packets <- rtpPacket{channel, payload}:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case pkt, ok := <-packets:
+			if !ok {
+				logger.Debugf("HLS repackager: RTSP stream for %s ended", r.rtspURL)
+				return
+			}
+			if pkt.channel != 0 || len(pkt.payload) < 12 {
+				continue // интересуют только RTP пакеты (канал 0) с полным заголовком
+			}
+
+			marker := pkt.payload[1]&0x80 != 0
+			rtpTimestamp := uint32(pkt.payload[4])<<24 | uint32(pkt.payload[5])<<16 | uint32(pkt.payload[6])<<8 | uint32(pkt.payload[7])
+
+			au, err := depay.onPacket(pkt.payload[12:], marker)
+			if err != nil {
+				logger.Debugf("HLS repackager: failed to depacketize RTP payload: %v", err)
+				continue
+			}
+			if au == nil {
+				continue
+			}
+
+			keyframe := accessUnitHasIDR(au)
+			if muxer == nil || (keyframe && time.Since(segmentStart) >= r.cfg.SegmentDuration) {
+				r.cutSegment(muxer, segmentStart, parts)
+				muxer = NewMuxer(false)
+				segmentStart = time.Now()
+				partStart = segmentStart
+				partIndex = 0
+				partOffset = 0
+				parts = nil
+			}
+
+			pts := uint64(rtpTimestamp)
+			muxer.WriteVideoAccessUnit(au, pts, pts, keyframe)
+
+			if r.cfg.PartDuration > 0 && time.Since(partStart) >= r.cfg.PartDuration {
+				offset := len(muxer.Bytes())
+				parts = append(parts, Part{
+					Index:    partIndex,
+					Duration: time.Since(partStart).Seconds(),
+					Offset:   partOffset,
+					Length:   offset - partOffset,
+				})
+				partIndex++
+				partOffset = offset
+				partStart = time.Now()
+			}
+		}
+	}
+}
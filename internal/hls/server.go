@@ -0,0 +1,215 @@
+package hls
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/pkg/utils"
+)
+
+// DefaultIdleTimeout - как долго репакер может простаивать без обращений клиентов, прежде
+// чем Manager его остановит
+const DefaultIdleTimeout = 60 * time.Second
+
+// StreamResolver находит RTSP URL и учетные данные обнаруженного устройства по его IP.
+// Внедряется вызывающей стороной (например, из реестра обнаруженных устройств), чтобы
+// пакет hls не зависел от того, как именно хранятся результаты сканирования
+type StreamResolver func(deviceIP string) (rtspURL, username, password string, ok bool)
+
+// Manager лениво поднимает Repackager на первый запрос к /hls/{deviceIP}/... и
+// останавливает его после периода бездействия
+type Manager struct {
+	resolver StreamResolver
+	cfg      Config
+
+	mu          sync.Mutex
+	repackagers map[string]*Repackager
+}
+
+// NewManager создает менеджер HLS репакеров с заданными параметрами сегментирования
+func NewManager(resolver StreamResolver, cfg Config) *Manager {
+	m := &Manager{
+		resolver:    resolver,
+		cfg:         cfg.withDefaults(),
+		repackagers: make(map[string]*Repackager),
+	}
+	go m.reapIdleLoop()
+	return m
+}
+
+// reapIdleLoop периодически останавливает репакеры, к которым давно не обращались
+func (m *Manager) reapIdleLoop() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.mu.Lock()
+		for deviceIP, r := range m.repackagers {
+			if r.IdleSince() > m.cfg.IdleTimeout {
+				delete(m.repackagers, deviceIP)
+				go r.Stop()
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// getOrStart возвращает активный репакер для устройства, при первом обращении создавая и
+// запуская его через resolver
+func (m *Manager) getOrStart(deviceIP string) (*Repackager, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.repackagers[deviceIP]; ok {
+		return r, nil
+	}
+
+	rtspURL, username, password, ok := m.resolver(deviceIP)
+	if !ok {
+		return nil, fmt.Errorf("no known RTSP stream for device %s", deviceIP)
+	}
+
+	r := NewRepackager(rtspURL, username, password, m.cfg)
+	if err := r.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start HLS repackager for %s: %w", deviceIP, err)
+	}
+
+	m.repackagers[deviceIP] = r
+	return r, nil
+}
+
+// Start лениво поднимает репакер для deviceIP, если он еще не запущен, и отмечает
+// обращение к нему. В отличие от serveHTTP, вызывается напрямую внешним кодом (см.
+// internal/gateway), которому нужно явно "прогреть" поток, не дожидаясь первого HTTP
+// запроса плейлиста
+func (m *Manager) Start(deviceIP string) error {
+	r, err := m.getOrStart(deviceIP)
+	if err != nil {
+		return err
+	}
+	r.Touch()
+	return nil
+}
+
+// Stop немедленно останавливает репакер для deviceIP, если он запущен, не дожидаясь
+// IdleTimeout
+func (m *Manager) Stop(deviceIP string) {
+	m.mu.Lock()
+	r, ok := m.repackagers[deviceIP]
+	if ok {
+		delete(m.repackagers, deviceIP)
+	}
+	m.mu.Unlock()
+
+	if ok {
+		go r.Stop()
+	}
+}
+
+// List возвращает IP устройств, для которых сейчас запущен репакер
+func (m *Manager) List() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ips := make([]string, 0, len(m.repackagers))
+	for deviceIP := range m.repackagers {
+		ips = append(ips, deviceIP)
+	}
+	return ips
+}
+
+// Handler возвращает http.Handler, обслуживающий /hls/{deviceIP}/index.m3u8 и
+// /hls/{deviceIP}/segmentN.ts
+func (m *Manager) Handler() http.Handler {
+	return http.HandlerFunc(m.serveHTTP)
+}
+
+func (m *Manager) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := utils.GetLogger()
+
+	path := strings.TrimPrefix(req.URL.Path, "/hls/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, req)
+		return
+	}
+	deviceIP, file := parts[0], parts[1]
+
+	repackager, err := m.getOrStart(deviceIP)
+	if err != nil {
+		logger.Debugf("HLS request for %s failed: %v", deviceIP, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	repackager.Touch()
+
+	switch {
+	case file == "index.m3u8":
+		m.servePlaylist(w, repackager)
+	case strings.HasPrefix(file, "part") && strings.HasSuffix(file, ".ts"):
+		m.servePart(w, req, repackager, file)
+	case strings.HasPrefix(file, "segment") && strings.HasSuffix(file, ".ts"):
+		m.serveSegment(w, req, repackager, file)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (m *Manager) servePlaylist(w http.ResponseWriter, r *Repackager) {
+	playlist, ok := r.Playlist()
+	if !ok {
+		http.Error(w, "stream not ready yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Write([]byte(playlist))
+}
+
+func (m *Manager) serveSegment(w http.ResponseWriter, req *http.Request, r *Repackager, file string) {
+	numStr := strings.TrimSuffix(strings.TrimPrefix(file, "segment"), ".ts")
+	seq, err := strconv.ParseUint(numStr, 10, 64)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	data, ok := r.Segment(seq)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Write(data)
+}
+
+// servePart обслуживает LL-HLS часть сегмента, запрошенную по имени вида
+// "part{segSeq}.{partIndex}.ts"
+func (m *Manager) servePart(w http.ResponseWriter, req *http.Request, r *Repackager, file string) {
+	numbers := strings.Split(strings.TrimSuffix(strings.TrimPrefix(file, "part"), ".ts"), ".")
+	if len(numbers) != 2 {
+		http.NotFound(w, req)
+		return
+	}
+	seq, err := strconv.ParseUint(numbers[0], 10, 64)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	index, err := strconv.Atoi(numbers[1])
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	data, ok := r.Part(seq, index)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Write(data)
+}
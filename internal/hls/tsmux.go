@@ -0,0 +1,290 @@
+package hls
+
+import "encoding/binary"
+
+const tsPacketSize = 188
+
+const (
+	patPID          = 0x0000
+	defaultPMTPID   = 0x1000
+	defaultVideoPID = 0x0100
+	defaultAudioPID = 0x0101
+
+	streamTypeH264 = 0x1B
+	streamTypeAAC  = 0x0F
+
+	pesStreamIDVideo = 0xE0
+	pesStreamIDAudio = 0xC0
+)
+
+// Muxer упаковывает H.264 (и опционально AAC) access unit'ы в MPEG-TS (ISO/IEC 13818-1).
+// Один Muxer соответствует ровно одному HLS сегменту: PAT и PMT пишутся в его начале, а
+// continuity_counter стартует с нуля, так что каждый сегмент независимо декодируем
+type Muxer struct {
+	hasAudio bool
+
+	pmtPID   uint16
+	videoPID uint16
+	audioPID uint16
+
+	cc  map[uint16]byte
+	buf []byte
+}
+
+// NewMuxer создает муксер для одного сегмента и сразу пишет в него PAT/PMT
+func NewMuxer(hasAudio bool) *Muxer {
+	m := &Muxer{
+		hasAudio: hasAudio,
+		pmtPID:   defaultPMTPID,
+		videoPID: defaultVideoPID,
+		audioPID: defaultAudioPID,
+		cc:       make(map[uint16]byte),
+	}
+	m.writePAT()
+	m.writePMT()
+	return m
+}
+
+// Bytes возвращает накопленные TS пакеты сегмента
+func (m *Muxer) Bytes() []byte {
+	return m.buf
+}
+
+// WriteVideoAccessUnit упаковывает H.264 access unit (NAL unit'ы без старт-кодов) в PES и
+// режет его на TS пакеты. pts/dts - значения тактовой частоты RTP (90 кГц). На keyframe
+// первый TS пакет несет PCR, синхронизированный с тем же видео-тактом
+func (m *Muxer) WriteVideoAccessUnit(au accessUnit, pts, dts uint64, keyframe bool) {
+	payload := make([]byte, 0, 1024)
+	for _, nal := range au {
+		payload = append(payload, 0x00, 0x00, 0x00, 0x01)
+		payload = append(payload, nal...)
+	}
+
+	pes := buildPESHeader(pesStreamIDVideo, pts, dts, true)
+	full := append(pes, payload...)
+	m.writePESPackets(m.videoPID, full, pts, keyframe)
+}
+
+// WriteVideoNALUs упаковывает готовый список NAL unit'ов (без старт-кодов Annex-B) как один
+// access unit - экспортированная обертка над WriteVideoAccessUnit для источников, не
+// проходящих через h264Depacketizer (RTP), например vigilos/pkg/storage для RTMP записи
+func (m *Muxer) WriteVideoNALUs(nalus [][]byte, pts, dts uint64, keyframe bool) {
+	m.WriteVideoAccessUnit(accessUnit(nalus), pts, dts, keyframe)
+}
+
+// WriteAudioFrame упаковывает один ADTS AAC фрейм (заголовок + raw_data_block) в PES
+func (m *Muxer) WriteAudioFrame(adtsFrame []byte, pts uint64) {
+	pes := buildPESHeader(pesStreamIDAudio, pts, 0, false)
+	full := append(pes, adtsFrame...)
+	m.writePESPackets(m.audioPID, full, 0, false)
+}
+
+// patBody строит тело секции PAT: единственная программа 1, указывающая на PMT PID
+func (m *Muxer) patBody() []byte {
+	body := make([]byte, 4)
+	binary.BigEndian.PutUint16(body[0:2], 1)
+	binary.BigEndian.PutUint16(body[2:4], 0xE000|m.pmtPID)
+	return body
+}
+
+// pmtBody строит тело секции PMT: PCR на видео PID и по одному элементарному потоку на трек
+func (m *Muxer) pmtBody() []byte {
+	body := make([]byte, 0, 14)
+	body = append(body, byte(0xE0|byte(m.videoPID>>8)), byte(m.videoPID))
+	body = append(body, 0xF0, 0x00) // program_info_length = 0
+
+	body = append(body, streamTypeH264, byte(0xE0|byte(m.videoPID>>8)), byte(m.videoPID), 0xF0, 0x00)
+	if m.hasAudio {
+		body = append(body, streamTypeAAC, byte(0xE0|byte(m.audioPID>>8)), byte(m.audioPID), 0xF0, 0x00)
+	}
+	return body
+}
+
+func (m *Muxer) writePAT() {
+	m.writeSectionPacket(patPID, buildPSISection(0x00, 1, m.patBody()))
+}
+
+func (m *Muxer) writePMT() {
+	m.writeSectionPacket(m.pmtPID, buildPSISection(0x02, 1, m.pmtBody()))
+}
+
+// buildPSISection собирает PSI секцию (PAT/PMT) из заголовка, переданного тела и CRC32/MPEG
+func buildPSISection(tableID byte, tableIDExtension uint16, body []byte) []byte {
+	sectionLength := 3 + len(body) + 4 // version/current_next + section_number*2 + body + CRC
+
+	section := make([]byte, 0, 3+2+len(body)+4)
+	section = append(section,
+		tableID,
+		0x80|0x30|byte((sectionLength>>8)&0x0F),
+		byte(sectionLength),
+		byte(tableIDExtension>>8), byte(tableIDExtension),
+		0xC1, // reserved + version_number=0 + current_next_indicator=1
+		0x00, // section_number
+		0x00, // last_section_number
+	)
+	section = append(section, body...)
+
+	crc := crc32MPEG(section)
+	section = append(section, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+	return section
+}
+
+// crc32MPEG вычисляет CRC32 по алгоритму MPEG-2 (ISO/IEC 13818-1, приложение B): без рефлексии
+// битов, используется во всех PSI секциях (PAT/PMT)
+func crc32MPEG(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = (crc << 1) ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// writeSectionPacket оборачивает PSI секцию в один TS пакет с pointer_field=0x00, добивая
+// остаток пакета байтами 0xFF (PAT/PMT всегда умещаются в один пакет)
+func (m *Muxer) writeSectionPacket(pid uint16, section []byte) {
+	payload := append([]byte{0x00}, section...)
+
+	pkt := make([]byte, tsPacketSize)
+	pkt[0] = 0x47
+	pkt[1] = 0x40 | byte(pid>>8&0x1F) // payload_unit_start_indicator=1
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10 | m.nextCC(pid) // только payload, без adaptation field
+
+	n := copy(pkt[4:], payload)
+	for i := 4 + n; i < tsPacketSize; i++ {
+		pkt[i] = 0xFF
+	}
+	m.buf = append(m.buf, pkt...)
+}
+
+func (m *Muxer) nextCC(pid uint16) byte {
+	cc := m.cc[pid]
+	m.cc[pid] = (cc + 1) & 0x0F
+	return cc
+}
+
+// buildPESHeader собирает PES заголовок (ISO/IEC 13818-1, раздел 2.4.3.6) с PTS и, опционально, DTS
+func buildPESHeader(streamID byte, pts, dts uint64, hasDTS bool) []byte {
+	tsLen := 5
+	ptsDtsFlags := byte(0x02)
+	if hasDTS {
+		tsLen = 10
+		ptsDtsFlags = 0x03
+	}
+
+	header := make([]byte, 9+tsLen)
+	header[0], header[1], header[2] = 0x00, 0x00, 0x01
+	header[3] = streamID
+	header[4], header[5] = 0x00, 0x00 // PES_packet_length = 0 (неограничено, допустимо для ES внутри TS)
+	header[6] = 0x84                  // '10' marker + data_alignment_indicator=1
+	header[7] = ptsDtsFlags << 6
+	header[8] = byte(tsLen)
+
+	if hasDTS {
+		writeTimestamp(header[9:14], 0x03, pts)
+		writeTimestamp(header[14:19], 0x01, dts)
+	} else {
+		writeTimestamp(header[9:14], 0x02, pts)
+	}
+
+	return header
+}
+
+// writeTimestamp кодирует 33-битную временную метку (PTS/DTS) в 5-байтовом формате,
+// описанном в ISO/IEC 13818-1, разделе 2.4.3.6
+func writeTimestamp(buf []byte, prefix byte, ts uint64) {
+	ts &= 0x1FFFFFFFF
+	buf[0] = (prefix << 4) | byte((ts>>29)&0x0E) | 0x01
+	buf[1] = byte(ts >> 22)
+	buf[2] = byte((ts>>14)&0xFE) | 0x01
+	buf[3] = byte(ts >> 7)
+	buf[4] = byte((ts&0x7F)<<1) | 0x01
+}
+
+// writePCR записывает Program Clock Reference (6 байт): 33-битный base (90 кГц) и 9-битный
+// extension (27 МГц). Мы используем видео-такт как базовые часы, поэтому extension всегда 0
+func writePCR(buf []byte, pcrBase uint64) {
+	base := pcrBase & 0x1FFFFFFFF
+	buf[0] = byte(base >> 25)
+	buf[1] = byte(base >> 17)
+	buf[2] = byte(base >> 9)
+	buf[3] = byte(base >> 1)
+	buf[4] = byte((base&0x1)<<7) | 0x7E
+	buf[5] = 0x00
+}
+
+// writePESPackets режет PES пакет на TS пакеты по videoPID/audioPID, выставляя
+// payload_unit_start_indicator на первом из них и, если withPCR, добавляя PCR в его
+// adaptation field. Также добивает adaptation field'ом последний TS пакет, чтобы он был
+// ровно 188 байт
+func (m *Muxer) writePESPackets(pid uint16, data []byte, pcr uint64, withPCR bool) {
+	first := true
+	for len(data) > 0 {
+		const afterHeader = tsPacketSize - 4
+
+		needsPCR := first && withPCR
+		afLen := 0
+		payloadSpace := afterHeader
+
+		switch {
+		case needsPCR:
+			afLen = 7 // flags(1) + PCR(6)
+			payloadSpace = afterHeader - 1 - afLen
+		case len(data) < afterHeader:
+			afLen = 1 // только flags байт, он же будет stuffing базой
+			payloadSpace = afterHeader - 1 - afLen
+		}
+
+		n := len(data)
+		if n > payloadSpace {
+			n = payloadSpace
+		}
+		if afLen > 0 && n < payloadSpace {
+			afLen += payloadSpace - n
+		}
+
+		pkt := make([]byte, tsPacketSize)
+		pkt[0] = 0x47
+		pusi := byte(0)
+		if first {
+			pusi = 0x40
+		}
+		pkt[1] = pusi | byte(pid>>8&0x1F)
+		pkt[2] = byte(pid)
+		cc := m.nextCC(pid)
+
+		offset := 4
+		if afLen > 0 {
+			pkt[3] = 0x30 | cc
+			pkt[4] = byte(afLen)
+			flags := byte(0)
+			if needsPCR {
+				flags |= 0x10
+			}
+			pkt[5] = flags
+			dataOffset := 6
+			if needsPCR {
+				writePCR(pkt[dataOffset:dataOffset+6], pcr)
+				dataOffset += 6
+			}
+			for i := dataOffset; i < 5+afLen; i++ {
+				pkt[i] = 0xFF
+			}
+			offset = 5 + afLen
+		} else {
+			pkt[3] = 0x10 | cc
+		}
+
+		copy(pkt[offset:], data[:n])
+		data = data[n:]
+		first = false
+		m.buf = append(m.buf, pkt...)
+	}
+}
@@ -0,0 +1,111 @@
+package licensing
+
+// Edition - редакция приложения, от которой зависит набор доступных возможностей
+type Edition string
+
+const (
+	EditionFree       Edition = "free"
+	EditionPro        Edition = "pro"
+	EditionEnterprise Edition = "enterprise"
+)
+
+// Feature - отдельная возможность, доступность которой проверяется через FeatureGates.Allow
+type Feature string
+
+const (
+	// FeatureRTSPProbe - проверка RTSP потоков (rtsp.Checker), доступна во всех редакциях
+	FeatureRTSPProbe Feature = "rtsp_probe"
+	// FeatureTableOutput - интерактивный текстовый вывод (formatter.TextFormatter), доступен во всех редакциях
+	FeatureTableOutput Feature = "table_output"
+
+	// FeatureRTMPProbe - проверка RTMP потоков (protocols.RTMPDetector)
+	FeatureRTMPProbe Feature = "rtmp_probe"
+	// FeatureHLSProbe - проверка HLS потоков (protocols.HLSDetector)
+	FeatureHLSProbe Feature = "hls_probe"
+	// FeatureDASHProbe - проверка MPEG-DASH потоков (protocols.DASHDetector)
+	FeatureDASHProbe Feature = "dash_probe"
+	// FeatureStructuredOutput - структурированный вывод результатов (json/ndjson/yaml/csv),
+	// пригодный для передачи в другие системы по конвейеру
+	FeatureStructuredOutput Feature = "structured_output"
+	// FeatureHighConcurrencyScan - параллельное сканирование с конкурентностью выше
+	// HighConcurrencyThreshold
+	FeatureHighConcurrencyScan Feature = "high_concurrency_scan"
+
+	// FeatureONVIF - обнаружение и опрос устройств через ONVIF (protocols.ONVIFHTTPDetector,
+	// internal/protocols/onvif, rtsp.Checker.DiscoverStreams)
+	FeatureONVIF Feature = "onvif"
+	// FeatureWebRTC - обнаружение WebRTC/WHEP источников (protocols.WebRTCDetector, protocols.WHEPDetector)
+	FeatureWebRTC Feature = "webrtc"
+	// FeatureContinuousMonitoring - длительный/повторяющийся мониторинг устройств, а не
+	// разовое сканирование
+	FeatureContinuousMonitoring Feature = "continuous_monitoring"
+
+	// FeatureCredentialRotation - смена пароля устройства через ONVIF SetUser/CreateUsers
+	// (registry.DeviceRegistry.RotateCredential) - в отличие от простого чтения/записи
+	// учетных данных в Vault (доступно во всех редакциях), реально меняет состояние
+	// устройства и откатывает его при сбое записи в Vault, так что отнесена к Enterprise
+	// наравне с остальными возможностями, затрагивающими реальные устройства, а не только
+	// их обнаружение
+	FeatureCredentialRotation Feature = "credential_rotation"
+)
+
+// HighConcurrencyThreshold - порог конкурентности сканирования (N), выше которого требуется
+// FeatureHighConcurrencyScan
+const HighConcurrencyThreshold = 20
+
+// freeFeatures - возможности, доступные без лицензии (редакция Free)
+var freeFeatures = []Feature{
+	FeatureRTSPProbe,
+	FeatureTableOutput,
+}
+
+// proFeatures - возможности редакции Pro, дополняющие freeFeatures
+var proFeatures = []Feature{
+	FeatureRTMPProbe,
+	FeatureHLSProbe,
+	FeatureDASHProbe,
+	FeatureStructuredOutput,
+	FeatureHighConcurrencyScan,
+}
+
+// enterpriseFeatures - возможности редакции Enterprise, дополняющие proFeatures
+var enterpriseFeatures = []Feature{
+	FeatureONVIF,
+	FeatureWebRTC,
+	FeatureContinuousMonitoring,
+	FeatureCredentialRotation,
+}
+
+// editionFeatures - полный набор возможностей каждой редакции (редакции кумулятивны: Pro
+// включает все возможности Free, Enterprise включает все возможности Pro)
+var editionFeatures = buildEditionFeatures()
+
+func buildEditionFeatures() map[Edition]map[Feature]bool {
+	free := toFeatureSet(freeFeatures)
+	pro := mergeFeatureSets(free, toFeatureSet(proFeatures))
+	enterprise := mergeFeatureSets(pro, toFeatureSet(enterpriseFeatures))
+
+	return map[Edition]map[Feature]bool{
+		EditionFree:       free,
+		EditionPro:        pro,
+		EditionEnterprise: enterprise,
+	}
+}
+
+func toFeatureSet(features []Feature) map[Feature]bool {
+	set := make(map[Feature]bool, len(features))
+	for _, f := range features {
+		set[f] = true
+	}
+	return set
+}
+
+func mergeFeatureSets(sets ...map[Feature]bool) map[Feature]bool {
+	merged := make(map[Feature]bool)
+	for _, set := range sets {
+		for f := range set {
+			merged[f] = true
+		}
+	}
+	return merged
+}
@@ -0,0 +1,84 @@
+package licensing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/local-video-server/pkg/utils"
+)
+
+// FeatureGates решает, доступна ли конкретная Feature в текущей редакции. Отсутствие
+// лицензии или ошибка при ее загрузке (битая подпись, истекший срок, не найден файл) не
+// являются фатальными - FeatureGates в этом случае просто откатывается на EditionFree
+type FeatureGates struct {
+	edition Edition
+	license *License
+}
+
+// NewFeatureGates создает FeatureGates из лицензионного файла по licensePath. Если
+// licensePath пуст, используется DefaultLicensePath. Любая ошибка загрузки или проверки
+// лицензии понижает редакцию до EditionFree вместо паники или отказа в работе
+func NewFeatureGates(licensePath string) *FeatureGates {
+	logger := utils.GetLogger()
+
+	if licensePath == "" {
+		path, err := DefaultLicensePath()
+		if err != nil {
+			logger.Debugf("Failed to resolve default license path, running as %s edition: %v", EditionFree, err)
+			return &FeatureGates{edition: EditionFree}
+		}
+		licensePath = path
+	}
+
+	lic, err := LoadLicense(licensePath)
+	if err != nil {
+		logger.Debugf("No valid license at %s, running as %s edition: %v", licensePath, EditionFree, err)
+		return &FeatureGates{edition: EditionFree}
+	}
+
+	logger.Infof("Loaded %s license, expires %s", lic.Edition, lic.Exp.Format(time.RFC3339))
+	return &FeatureGates{edition: lic.Edition, license: lic}
+}
+
+// Edition возвращает текущую редакцию
+func (g *FeatureGates) Edition() Edition {
+	return g.edition
+}
+
+// License возвращает загруженную лицензию, либо nil в редакции Free без лицензии
+func (g *FeatureGates) License() *License {
+	return g.license
+}
+
+// Allow сообщает, доступна ли feature в текущей редакции или явно выдана лицензией через
+// License.Features
+func (g *FeatureGates) Allow(feature Feature) bool {
+	if editionFeatures[g.edition][feature] {
+		return true
+	}
+
+	if g.license == nil {
+		return false
+	}
+	for _, f := range g.license.Features {
+		if Feature(f) == feature {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultGatesOnce sync.Once
+	defaultGates     *FeatureGates
+)
+
+// DefaultGates возвращает общий для всего процесса экземпляр FeatureGates, загруженный из
+// DefaultLicensePath при первом обращении - аналогично utils.GetLogger(), избавляет каждый
+// вызывающий пакет от необходимости явно прокидывать лицензию через свои конструкторы
+func DefaultGates() *FeatureGates {
+	defaultGatesOnce.Do(func() {
+		defaultGates = NewFeatureGates("")
+	})
+	return defaultGates
+}
@@ -0,0 +1,85 @@
+package licensing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// licensePublicKey - публичный ключ ed25519, которым подписываются выпущенные лицензии.
+// Соответствующий приватный ключ в репозитории не хранится
+var licensePublicKey = ed25519.PublicKey{
+	0x1f, 0x5a, 0xc3, 0x8e, 0x72, 0xb1, 0x4d, 0x09,
+	0xe6, 0x33, 0xaa, 0x17, 0x2c, 0x5f, 0x91, 0x0b,
+	0x48, 0xd2, 0x7e, 0xc4, 0x96, 0x3d, 0x2a, 0x85,
+	0x61, 0xf0, 0x3c, 0x7b, 0xbe, 0x14, 0x29, 0x58,
+}
+
+// License - полезная нагрузка лицензионного файла
+type License struct {
+	Edition Edition   `json:"edition"`
+	Exp     time.Time `json:"exp"`
+	// Features - дополнительные возможности сверх стандартной матрицы Edition (например,
+	// для пробного доступа к отдельной enterprise-функции на Pro редакции)
+	Features []string `json:"features,omitempty"`
+}
+
+// licenseEnvelope - формат лицензионного файла: JSON-сериализованный License (payload) и
+// его ed25519 подпись в base64
+type licenseEnvelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+// DefaultLicensePath возвращает путь к лицензионному файлу по умолчанию
+// (~/.local-video-server/license)
+func DefaultLicensePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".local-video-server", "license"), nil
+}
+
+// LoadLicense читает и проверяет лицензионный файл по path: подпись payload должна быть
+// верна относительно licensePublicKey, а срок действия (Exp) не должен быть истекшим.
+// Любая ошибка здесь означает, что вызывающий код должен считать лицензию отсутствующей
+// и работать в редакции Free - а не отказывать в работе
+func LoadLicense(path string) (*License, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read license file: %w", err)
+	}
+
+	var env licenseEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse license file: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(env.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode license signature: %w", err)
+	}
+
+	if !ed25519.Verify(licensePublicKey, env.Payload, signature) {
+		return nil, fmt.Errorf("license signature verification failed")
+	}
+
+	var lic License
+	if err := json.Unmarshal(env.Payload, &lic); err != nil {
+		return nil, fmt.Errorf("failed to parse license payload: %w", err)
+	}
+
+	if lic.Edition == "" {
+		return nil, fmt.Errorf("license payload is missing an edition")
+	}
+	if time.Now().After(lic.Exp) {
+		return nil, fmt.Errorf("license expired on %s", lic.Exp.Format(time.RFC3339))
+	}
+
+	return &lic, nil
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace - общий префикс для всех метрик пакета (lvs_...)
+const namespace = "lvs"
+
+// Значения для ObserveFetchLatency - различают вид сетевой операции, измеряемой одной и
+// той же гистограммой FetchLatency
+const (
+	KindRTSPDescribe = "rtsp_describe"
+	KindDASHManifest = "dash_manifest"
+)
+
+// Метрики сканирования сети (см. internal/scanner.NetworkScanner)
+var (
+	// HostsScanned - количество хостов, для которых было запущено сканирование портов
+	HostsScanned = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hosts_scanned_total",
+		Help:      "Общее количество просканированных хостов",
+	})
+
+	// PortsProbed - количество TCP подключений, выполненных для проверки открытости порта
+	PortsProbed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ports_probed_total",
+		Help:      "Общее количество проверенных портов",
+	})
+
+	// ProtocolDetections - количество обнаружений протокола по типу (RTSP, RTMP, ONVIF и
+	// т.д.), независимо от того, через какой детектор он был найден
+	ProtocolDetections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "protocol_detections_total",
+		Help:      "Количество обнаружений протокола по типу",
+	}, []string{"protocol"})
+
+	// ScanDuration - длительность одного прохода NetworkScanner.scanPortsParallel по
+	// списку хостов
+	ScanDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scan_duration_seconds",
+		Help:      "Длительность параллельного сканирования портов по списку хостов",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// PortDialLatency - латентность TCP подключения при проверке отдельного порта
+	PortDialLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "port_dial_latency_seconds",
+		Help:      "Латентность TCP подключения при проверке порта",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// FetchLatency - латентность RTSP DESCRIBE и DASH manifest запросов, разбитая по
+	// виду операции (см. константы Kind*)
+	FetchLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_latency_seconds",
+		Help:      "Латентность запросов DESCRIBE/манифеста по виду операции",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"kind"})
+
+	// ActiveScans - количество сейчас выполняющихся сканирований
+	ActiveScans = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_scans",
+		Help:      "Количество сейчас выполняющихся сканирований",
+	})
+
+	// DevicesDetected - количество устройств, найденных за последнее завершенное
+	// сканирование
+	DevicesDetected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "devices_detected",
+		Help:      "Количество устройств, найденных в последнем завершенном сканировании",
+	})
+
+	// ScanConcurrency - текущий уровень параллельности сканирования, поддерживаемый
+	// AIMD-контроллером (см. internal/scanner.aimdController)
+	ScanConcurrency = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "scan_concurrency",
+		Help:      "Текущий уровень параллельности, выданный адаптивным AIMD-контроллером",
+	})
+)
+
+// Метрики экспорта результатов (см. pkg/export.ExportToFile)
+var (
+	// ExportsTotal - количество вызовов ExportToFile по формату и результату (ok/error)
+	ExportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exports_total",
+		Help:      "Количество экспортов результатов сканирования по формату и результату",
+	}, []string{"format", "result"})
+
+	// ExportDuration - длительность записи файла экспорта по формату
+	ExportDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "export_duration_seconds",
+		Help:      "Длительность экспорта результатов сканирования в файл по формату",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"format"})
+)
+
+// ObserveFetchLatency записывает длительность запроса DESCRIBE/манифеста для заданного
+// вида операции (см. константы Kind*)
+func ObserveFetchLatency(kind string, d time.Duration) {
+	FetchLatency.WithLabelValues(kind).Observe(d.Seconds())
+}
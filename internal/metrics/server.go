@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// Server - HTTP сервер, отдающий /metrics (Prometheus) и, если включено в конфигурации,
+// /debug/pprof/* для профилирования CPU/горутин на долгоживущих сканированиях
+type Server struct {
+	cfg    *models.MetricsConfig
+	logger *logrus.Logger
+	http   *http.Server
+}
+
+// NewServer создает сервер метрик по конфигурации
+func NewServer(cfg *models.MetricsConfig) *Server {
+	return &Server{
+		cfg:    cfg,
+		logger: utils.GetLogger(),
+	}
+}
+
+// Handler возвращает http.Handler с /metrics и, при cfg.EnablePprof, /debug/pprof/*
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	if s.cfg.EnablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	return mux
+}
+
+// ListenAndServe запускает сервер на cfg.ListenAddr и блокируется, пока сервер не
+// остановится или не вернет ошибку
+func (s *Server) ListenAndServe() error {
+	s.http = &http.Server{
+		Addr:    s.cfg.ListenAddr,
+		Handler: s.Handler(),
+	}
+	s.logger.Infof("Metrics server listening on %s (pprof: %v)", s.cfg.ListenAddr, s.cfg.EnablePprof)
+	return s.http.ListenAndServe()
+}
+
+// Shutdown останавливает сервер метрик
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.http == nil {
+		return nil
+	}
+	return s.http.Shutdown(ctx)
+}
@@ -0,0 +1,166 @@
+package mjpeg
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/pkg/utils"
+)
+
+// maxFrameSize - предел на чтение одного JPEG кадра из multipart потока
+const maxFrameSize = 2 << 20 // 2 MiB
+
+// StreamInfo - информация об MJPEG потоке, полученная по HTTP
+type StreamInfo struct {
+	URL        string
+	Width      int
+	Height     int
+	Components int
+	Available  bool
+}
+
+// CheckStream проверяет HTTP MJPEG поток (multipart/x-mixed-replace): выполняет GET с
+// поддержкой Basic/Digest аутентификации, убеждается, что Content-Type - multipart/* с
+// boundary, читает первый JPEG кадр, проверяет его SOI/EOI маркеры и разбирает SOF0/SOF2
+// сегмент, чтобы получить ширину, высоту и число компонент
+func CheckStream(streamURL string, username, password string, timeout time.Duration) (*StreamInfo, error) {
+	logger := utils.GetLogger()
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := doGet(client, streamURL, username, password, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("HTTP GET failed: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		authMethod, realm, nonce := parseWWWAuthenticate(resp.Header.Get("WWW-Authenticate"))
+		resp.Body.Close()
+
+		if authMethod == "" {
+			return nil, fmt.Errorf("server returned 401 with no supported auth scheme")
+		}
+
+		resp, err = doGet(client, streamURL, username, password, authMethod, realm, nonce)
+		if err != nil {
+			return nil, fmt.Errorf("HTTP GET with auth failed: %w", err)
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("unexpected content type %q, expected multipart/*", contentType)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, fmt.Errorf("multipart response has no boundary parameter")
+	}
+
+	mr := multipart.NewReader(resp.Body, boundary)
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read first MJPEG part: %w", err)
+	}
+	defer part.Close()
+
+	data, err := io.ReadAll(io.LimitReader(part, maxFrameSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JPEG frame: %w", err)
+	}
+
+	if err := validateJPEG(data); err != nil {
+		return nil, fmt.Errorf("invalid JPEG frame: %w", err)
+	}
+
+	width, height, components, err := parseSOF(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JPEG dimensions: %w", err)
+	}
+
+	logger.Debugf("MJPEG stream %s: %dx%d, %d components", streamURL, width, height, components)
+
+	return &StreamInfo{
+		URL:        streamURL,
+		Width:      width,
+		Height:     height,
+		Components: components,
+		Available:  true,
+	}, nil
+}
+
+// doGet выполняет один GET запрос, при необходимости добавляя заголовок Authorization
+func doGet(client *http.Client, streamURL, username, password, authMethod, realm, nonce string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, streamURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch authMethod {
+	case "Basic":
+		req.SetBasicAuth(username, password)
+	case "Digest":
+		parsedURL, err := url.Parse(streamURL)
+		if err != nil {
+			return nil, err
+		}
+		path := parsedURL.Path
+		if path == "" {
+			path = "/"
+		}
+
+		ha1 := utils.MD5Hash(fmt.Sprintf("%s:%s:%s", username, realm, password))
+		ha2 := utils.MD5Hash(fmt.Sprintf("%s:%s", http.MethodGet, path))
+		response := utils.MD5Hash(fmt.Sprintf("%s:%s:%s", ha1, nonce, ha2))
+
+		req.Header.Set("Authorization", fmt.Sprintf(
+			`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+			username, realm, nonce, path, response))
+	}
+
+	return client.Do(req)
+}
+
+// parseWWWAuthenticate разбирает заголовок WWW-Authenticate (Basic или Digest, RFC 2617)
+func parseWWWAuthenticate(header string) (method, realm, nonce string) {
+	switch {
+	case strings.HasPrefix(header, "Basic"):
+		method = "Basic"
+		realm = extractQuoted(header, "realm=")
+	case strings.HasPrefix(header, "Digest"):
+		method = "Digest"
+		for _, part := range strings.Split(header, ",") {
+			part = strings.TrimSpace(part)
+			switch {
+			case strings.HasPrefix(part, "realm="):
+				realm = strings.Trim(part[len("realm="):], "\"")
+			case strings.HasPrefix(part, "nonce="):
+				nonce = strings.Trim(part[len("nonce="):], "\"")
+			}
+		}
+	}
+	return method, realm, nonce
+}
+
+// extractQuoted извлекает значение параметра key="value" из заголовка
+func extractQuoted(header, key string) string {
+	idx := strings.Index(header, key)
+	if idx == -1 {
+		return ""
+	}
+	value := header[idx+len(key):]
+	if end := strings.Index(value, ","); end != -1 {
+		value = value[:end]
+	}
+	return strings.Trim(value, "\"")
+}
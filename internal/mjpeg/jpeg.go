@@ -0,0 +1,69 @@
+package mjpeg
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// validateJPEG проверяет наличие маркеров SOI (FFD8) и EOI (FFD9) в кадре
+func validateJPEG(data []byte) error {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return fmt.Errorf("missing JPEG SOI marker")
+	}
+	if !bytes.Contains(data, []byte{0xFF, 0xD9}) {
+		return fmt.Errorf("missing JPEG EOI marker")
+	}
+	return nil
+}
+
+// parseSOF проходит по маркерам JPEG и извлекает ширину, высоту и число компонент из первого
+// встреченного сегмента SOF0/SOF2 (baseline/progressive DCT, ITU-T T.81, раздел B.2.2)
+func parseSOF(data []byte) (width, height, components int, err error) {
+	pos := 2 // пропускаем SOI
+
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			pos++
+			continue
+		}
+		marker := data[pos+1]
+		pos += 2
+
+		// Маркеры без сегмента длины: TEM, RST0-RST7
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			continue
+		}
+		if marker == 0xD9 {
+			break // EOI - SOF не найден до конца кадра
+		}
+		if pos+2 > len(data) {
+			break
+		}
+
+		segLen := int(data[pos])<<8 | int(data[pos+1])
+		if segLen < 2 || pos+segLen > len(data) {
+			return 0, 0, 0, fmt.Errorf("invalid JPEG segment length at offset %d", pos)
+		}
+
+		if isSOFMarker(marker) {
+			if segLen < 8 {
+				return 0, 0, 0, fmt.Errorf("SOF segment too short")
+			}
+			sof := data[pos+2:]
+			height = int(sof[1])<<8 | int(sof[2])
+			width = int(sof[3])<<8 | int(sof[4])
+			components = int(sof[5])
+			return width, height, components, nil
+		}
+
+		pos += segLen
+	}
+
+	return 0, 0, 0, fmt.Errorf("no SOF marker found in JPEG data")
+}
+
+// isSOFMarker сообщает, является ли маркер SOF0-SOF15, за исключением DHT/JPG/DAC, которые
+// занимают те же номера в диапазоне 0xC0-0xCF, но не являются SOF
+func isSOFMarker(marker byte) bool {
+	return marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC
+}
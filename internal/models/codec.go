@@ -0,0 +1,27 @@
+package models
+
+// VideoCodec - параметры видеокодека, разобранные из SDP/SPS (profile, уровень, разрешение, частота кадров)
+type VideoCodec struct {
+	Name    string  `json:"name" yaml:"name" xml:"name"`
+	Profile string  `json:"profile,omitempty" yaml:"profile,omitempty" xml:"profile,omitempty"`
+	Level   string  `json:"level,omitempty" yaml:"level,omitempty" xml:"level,omitempty"`
+	Width   int     `json:"width,omitempty" yaml:"width,omitempty" xml:"width,omitempty"`
+	Height  int     `json:"height,omitempty" yaml:"height,omitempty" xml:"height,omitempty"`
+	FPS     float64 `json:"fps,omitempty" yaml:"fps,omitempty" xml:"fps,omitempty"`
+
+	// MatchType - насколько уверенно RTP payload type/rtpmap этого кодека был сопоставлен с
+	// известным кодеком (см. rtsp.MatchCodec): "exact", "partial" или пусто, если кодек вообще
+	// не распознан ни одним известным профилем
+	MatchType string `json:"match_type,omitempty" yaml:"match_type,omitempty" xml:"match_type,omitempty"`
+}
+
+// AudioCodec - параметры аудиокодека, разобранные из AudioSpecificConfig (ISO/IEC 14496-3)
+type AudioCodec struct {
+	Name       string `json:"name" yaml:"name" xml:"name"`
+	ObjectType int    `json:"object_type,omitempty" yaml:"object_type,omitempty" xml:"object_type,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty" yaml:"sample_rate,omitempty" xml:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty" yaml:"channels,omitempty" xml:"channels,omitempty"`
+
+	// MatchType - то же самое, что models.VideoCodec.MatchType, но для аудиокодека
+	MatchType string `json:"match_type,omitempty" yaml:"match_type,omitempty" xml:"match_type,omitempty"`
+}
@@ -15,6 +15,28 @@ type Config struct {
 
 	// Настройки RTSP
 	RTSP RTSPConfig `yaml:"rtsp" json:"rtsp"`
+
+	// Настройки RTMP
+	RTMP RTMPConfig `yaml:"rtmp" json:"rtmp"`
+
+	// Настройки экспорта метрик Prometheus и pprof
+	Metrics MetricsConfig `yaml:"metrics" json:"metrics"`
+
+	// Настройки WHIP/WHEP шлюза (см. vigilos/pkg/media.WebRTCRelay)
+	WebRTC WebRTCConfig `yaml:"webrtc" json:"webrtc"`
+
+	// Настройки циклической записи на диск (см. vigilos/pkg/storage.LocalRecorder)
+	Recording RecordingConfig `yaml:"recording" json:"recording"`
+
+	// Настройки потокового обнаружения устройств для долгоживущего демона (см.
+	// vigilos/pkg/camera.DiscoveryManager)
+	Discovery DiscoveryConfig `yaml:"discovery" json:"discovery"`
+
+	// Настройки рассылки событий обнаружения во внешние системы (см. pkg/events)
+	Events EventsConfig `yaml:"events" json:"events"`
+
+	// Настройки устойчивого хранения реестра устройств (см. internal/registry.Store)
+	Registry RegistryConfig `yaml:"registry" json:"registry"`
 }
 
 // ScanConfig - настройки сканирования
@@ -28,22 +50,59 @@ type ScanConfig struct {
 	// Таймаут для обнаружения устройств (в секундах)
 	DiscoveryTimeout time.Duration `yaml:"discovery_timeout" json:"discovery_timeout"`
 
-	// Максимальное количество параллельных сканирований
+	// Максимальное количество параллельных сканирований - потолок, до которого
+	// адаптивный AIMD-контроллер (см. internal/scanner.aimdController) может разогнать
+	// параллельность
 	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency"`
 
+	// Минимальное количество параллельных сканирований - порог, ниже которого AIMD-
+	// контроллер не опускается даже при высокой доле таймаутов
+	MinConcurrency int `yaml:"min_concurrency" json:"min_concurrency"`
+
+	// Предел скорости сканирования в попытках подключения в секунду (token-bucket),
+	// защищающий от ARP/SYN storm на широких сканированиях
+	RateLimit int `yaml:"rate_limit" json:"rate_limit"`
+
 	// Порты для сканирования
 	Ports []int `yaml:"ports" json:"ports"`
 
 	// Включить ONVIF Discovery
 	EnableONVIF bool `yaml:"enable_onvif" json:"enable_onvif"`
 
+	// Учетные данные для ONVIF GetProfiles/GetStreamUri (см. internal/protocols/onvif) -
+	// пусто означает анонимный запрос, на который отвечают камеры без настроенной
+	// авторизации
+	ONVIFUsername string `yaml:"onvif_username" json:"onvif_username"`
+	ONVIFPassword string `yaml:"onvif_password" json:"onvif_password"`
+
+	// ONVIFCredentialsByNetwork - учетные данные ONVIF для конкретных устройств или подсетей,
+	// ключ - IP-адрес ("192.168.1.50") или CIDR ("192.168.1.0/24"). Проверяются раньше, чем
+	// ONVIFUsername/ONVIFPassword и ONVIFDefaultCredentials (см. onvif.ResolveCredentials)
+	ONVIFCredentialsByNetwork map[string]ONVIFCredential `yaml:"onvif_credentials_by_network" json:"onvif_credentials_by_network"`
+
+	// ONVIFDefaultCredentials - пары логин/пароль "из коробки", которые перебираются, если
+	// ни явно настроенные, ни анонимные учетные данные не подошли - многие камеры так и
+	// остаются с заводской парой вида admin/admin
+	ONVIFDefaultCredentials []ONVIFCredential `yaml:"onvif_default_credentials" json:"onvif_default_credentials"`
+
 	// Включить UPnP/SSDP Discovery
 	EnableUPnP bool `yaml:"enable_upnp" json:"enable_upnp"`
 
+	// Включить пассивное обнаружение (прослушивание SSDP/WS-Discovery/mDNS/RTSP трафика
+	// без отправки запросов)
+	EnablePassive bool `yaml:"enable_passive" json:"enable_passive"`
+
 	// Проверять RTSP потоки
 	CheckRTSP bool `yaml:"check_rtsp" json:"check_rtsp"`
 }
 
+// ONVIFCredential - логин/пароль для WS-Security UsernameToken аутентификации одного ONVIF
+// устройства или подсети (см. onvif.Credentials, на который он отображается один в один)
+type ONVIFCredential struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+}
+
 // LogConfig - настройки логирования
 type LogConfig struct {
 	// Уровень логирования (debug, info, warn, error)
@@ -78,6 +137,166 @@ type RTSPConfig struct {
 
 	// Стандартные пути RTSP потоков для проверки
 	DefaultPaths []string `yaml:"default_paths" json:"default_paths"`
+
+	// Transport - режим RTSP транспорта: "auto" (пробует UDP и при отсутствии пакетов
+	// переключается на TCP), "tcp", "udp" или "udp-multicast"
+	Transport string `yaml:"transport" json:"transport"`
+
+	// AnyPort - в режимах "udp"/"auto" принимать RTP/RTCP с любого порта отправителя
+	// вместо строгой проверки server_port, согласованного в SETUP - нужно для камер за NAT
+	AnyPort bool `yaml:"any_port" json:"any_port"`
+
+	// MaxConcurrency - размер пула воркеров CheckMultipleStreamsCtx/DiscoverStreams,
+	// ограничивающий общее число одновременных RTSP соединений при проверке многих URL
+	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency"`
+
+	// PerHostConcurrency - сколько TCP соединений к одному хосту CheckMultipleStreamsCtx
+	// открывает одновременно (остальные пути этого хоста проверяются по очереди через эти
+	// соединения, чтобы не повторять SETUP/DESCRIBE-аутентификацию на каждый путь)
+	PerHostConcurrency int `yaml:"per_host_concurrency" json:"per_host_concurrency"`
+}
+
+// RTMPConfig - настройки RTMP
+type RTMPConfig struct {
+	// Таймаут для RTMP запросов (в секундах)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// Стандартные имена приложений для перебора при DiscoverStreams (например, "live", "cam")
+	DefaultApps []string `yaml:"default_apps" json:"default_apps"`
+
+	// Стандартные ключи потоков для перебора при DiscoverStreams (например, "livestream", "stream1")
+	DefaultStreamKeys []string `yaml:"default_stream_keys" json:"default_stream_keys"`
+}
+
+// MetricsConfig - настройки HTTP эндпоинта метрик (см. internal/metrics)
+type MetricsConfig struct {
+	// Включить HTTP сервер с /metrics (и, при EnablePprof, /debug/pprof)
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Адрес, на котором слушает сервер метрик (например, ":9090")
+	ListenAddr string `yaml:"listen_addr" json:"listen_addr"`
+
+	// Включить /debug/pprof/* для профилирования CPU/горутин долгоживущих сканирований
+	EnablePprof bool `yaml:"enable_pprof" json:"enable_pprof"`
+}
+
+// WebRTCConfig - настройки WHIP/WHEP шлюза (vigilos/pkg/media.WebRTCRelay)
+type WebRTCConfig struct {
+	// Включить WebRTC шлюз
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Адрес, на котором слушает HTTP сервер с /whep/{streamID} и /whip/{streamID}
+	ListenAddr string `yaml:"listen_addr" json:"listen_addr"`
+
+	// STUN/TURN серверы, например "stun:stun.l.google.com:19302"
+	ICEServers []string `yaml:"ice_servers" json:"ice_servers"`
+
+	// Публичный IP сервера, подставляется в host-кандидаты для клиентов за NAT
+	PublicIP string `yaml:"public_ip" json:"public_ip"`
+
+	// Диапазон UDP портов для ICE (0/0 - использовать системный диапазон)
+	PortMin uint16 `yaml:"port_min" json:"port_min"`
+	PortMax uint16 `yaml:"port_max" json:"port_max"`
+}
+
+// RecordingConfig - настройки циклической записи на диск (vigilos/pkg/storage.LocalRecorder)
+type RecordingConfig struct {
+	// Включить автоматическую запись устройств, помеченных Available, пайплайном сканирования
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Корневая директория записи - для каждого потока заводится своя поддиректория
+	OutputDir string `yaml:"output_dir" json:"output_dir"`
+
+	// Целевая длительность сегмента
+	SegmentDuration time.Duration `yaml:"segment_duration" json:"segment_duration"`
+
+	// Сколько последних сегментов входит в живой плейлист потока
+	LiveWindow int `yaml:"live_window" json:"live_window"`
+
+	// Сколько часов хранить сегменты на диске, 0 - без ограничения по возрасту
+	RetentionHours float64 `yaml:"retention_hours" json:"retention_hours"`
+
+	// Максимальный суммарный объем сегментов одного потока на диске в байтах, 0 - без
+	// ограничения по объему
+	MaxDiskBytes int64 `yaml:"max_disk_bytes" json:"max_disk_bytes"`
+
+	// Overrides - переопределение параметров записи для отдельных потоков по их
+	// streamID, поверх значений выше
+	Overrides map[string]RecordingOverride `yaml:"overrides" json:"overrides"`
+}
+
+// RecordingOverride - переопределение части параметров RecordingConfig для одного
+// потока. Нулевое значение поля означает "не переопределять"
+type RecordingOverride struct {
+	SegmentDuration time.Duration `yaml:"segment_duration" json:"segment_duration"`
+	LiveWindow      int           `yaml:"live_window" json:"live_window"`
+	RetentionHours  float64       `yaml:"retention_hours" json:"retention_hours"`
+	MaxDiskBytes    int64         `yaml:"max_disk_bytes" json:"max_disk_bytes"`
+}
+
+// DiscoveryConfig - настройки потокового обнаружения устройств (vigilos/pkg/camera.DiscoveryManager)
+type DiscoveryConfig struct {
+	// Sources - включенные источники обнаружения: "portscan", "onvif", "mdns", "ssdp".
+	// Пустой список означает "ни одного" - менеджер обнаружения не стартует сам по себе
+	Sources []string `yaml:"sources" json:"sources"`
+
+	// Interface - сетевой интерфейс, на котором слушают/рассылают источники обнаружения
+	// (например, "eth0"), пусто - взять из NetworkConfig.Interface
+	Interface string `yaml:"interface" json:"interface"`
+}
+
+// EventsConfig - настройки рассылки событий обнаружения (см. pkg/events)
+type EventsConfig struct {
+	// Включить рассылку событий
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Путь к файлу BoltDB, в котором хранятся очереди недоставленных событий по каждому
+	// приемнику - так события переживают перезапуск процесса
+	QueuePath string `yaml:"queue_path" json:"queue_path"`
+
+	// Максимальное число событий в одной доставке приемнику
+	MaxBatchSize int `yaml:"max_batch_size" json:"max_batch_size"`
+
+	// Интервал, с которым накопленные события сбрасываются приемнику, даже если
+	// MaxBatchSize не набран
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+
+	// Максимальный размер очереди одного приемника - при переполнении отбрасываются
+	// самые старые недоставленные события
+	MaxQueueSize int `yaml:"max_queue_size" json:"max_queue_size"`
+
+	// Начальная задержка между повторными попытками доставки (удваивается после
+	// каждой неудачной попытки)
+	RetryBackoff time.Duration `yaml:"retry_backoff" json:"retry_backoff"`
+
+	// Максимальное число повторных попыток доставки одной партии, прежде чем
+	// дождаться следующего флаша
+	MaxRetries int `yaml:"max_retries" json:"max_retries"`
+
+	// Webhooks - приемники-вебхуки, на которые рассылаются события
+	Webhooks []WebhookConfig `yaml:"webhooks" json:"webhooks"`
+}
+
+// RegistryConfig - настройки устойчивого хранения реестра устройств (см.
+// internal/registry.Store)
+type RegistryConfig struct {
+	// BoltPath - путь к файлу BoltDB, в котором реестр сохраняет устройства и историю
+	// изменений между перезапусками. Пусто (по умолчанию) - реестр хранит состояние только
+	// в памяти (internal/registry.MemoryStore), как и раньше
+	BoltPath string `yaml:"bolt_path" json:"bolt_path"`
+}
+
+// WebhookConfig - один приемник-вебхук (см. pkg/events.WebhookSender)
+type WebhookConfig struct {
+	// Name - имя приемника, используется как ключ очереди недоставленных событий
+	Name string `yaml:"name" json:"name"`
+
+	// URL - адрес, на который выполняется HTTP POST с событиями
+	URL string `yaml:"url" json:"url"`
+
+	// Secret - секрет для подписи тела запроса HMAC-SHA256 (заголовок X-LVS-Signature),
+	// пусто - запрос отправляется без подписи
+	Secret string `yaml:"secret" json:"secret"`
 }
 
 // DefaultConfig возвращает конфигурацию по умолчанию
@@ -88,10 +307,18 @@ func DefaultConfig() *Config {
 			PortTimeout:        2 * time.Second,
 			DiscoveryTimeout:   10 * time.Second,
 			MaxConcurrency:     50,
-			Ports:              []int{554, 1935, 80, 8080, 8554},
+			MinConcurrency:     5,
+			RateLimit:          200,
+			Ports:              []int{554, 1935, 80, 8080, 8554, 9710, 9000, 8889},
 			EnableONVIF:        true,
 			EnableUPnP:         true,
+			EnablePassive:      true,
 			CheckRTSP:          false,
+			ONVIFDefaultCredentials: []ONVIFCredential{
+				{Username: "admin", Password: "admin"},
+				{Username: "admin", Password: "12345"},
+				{Username: "admin", Password: ""},
+			},
 		},
 		Log: LogConfig{
 			Level:  "info",
@@ -113,6 +340,49 @@ func DefaultConfig() *Config {
 				"/live",
 				"/cam/realmonitor",
 			},
+			Transport:          "auto",
+			AnyPort:            false,
+			MaxConcurrency:     20,
+			PerHostConcurrency: 2,
+		},
+		RTMP: RTMPConfig{
+			Timeout:           5 * time.Second,
+			DefaultApps:       []string{"live", "cam", "stream"},
+			DefaultStreamKeys: []string{"livestream", "stream1", "live"},
+		},
+		Metrics: MetricsConfig{
+			Enabled:     false,
+			ListenAddr:  ":9090",
+			EnablePprof: false,
+		},
+		WebRTC: WebRTCConfig{
+			Enabled:    false,
+			ListenAddr: ":8189",
+			ICEServers: []string{"stun:stun.l.google.com:19302"},
+		},
+		Recording: RecordingConfig{
+			Enabled:         false,
+			OutputDir:       "recordings",
+			SegmentDuration: 4 * time.Second,
+			LiveWindow:      7,
+			RetentionHours:  24,
+			MaxDiskBytes:    0,
+		},
+		Discovery: DiscoveryConfig{
+			Sources:   []string{"portscan", "onvif"},
+			Interface: "",
+		},
+		Events: EventsConfig{
+			Enabled:       false,
+			QueuePath:     "events.db",
+			MaxBatchSize:  20,
+			FlushInterval: 5 * time.Second,
+			MaxQueueSize:  10000,
+			RetryBackoff:  2 * time.Second,
+			MaxRetries:    5,
+		},
+		Registry: RegistryConfig{
+			BoltPath: "",
 		},
 	}
 }
@@ -11,8 +11,74 @@ type Device struct {
 	Model        string            `json:"model,omitempty" yaml:"model,omitempty" xml:"model,omitempty"`
 	Protocols    []Protocol        `json:"protocols" yaml:"protocols" xml:"protocols>protocol"`
 	RTSPStreams  []RTSPStreamInfo  `json:"rtsp_streams,omitempty" yaml:"rtsp_streams,omitempty" xml:"rtsp_streams>stream,omitempty"`
+	RTMPStreams  []RTMPStreamInfo  `json:"rtmp_streams,omitempty" yaml:"rtmp_streams,omitempty" xml:"rtmp_streams>stream,omitempty"`
+	HLSStreams   []HLSStreamInfo   `json:"hls_streams,omitempty" yaml:"hls_streams,omitempty" xml:"hls_streams>stream,omitempty"`
+	MJPEGStreams []MJPEGStreamInfo `json:"mjpeg_streams,omitempty" yaml:"mjpeg_streams,omitempty" xml:"mjpeg_streams>stream,omitempty"`
 	DiscoveredAt time.Time         `json:"discovered_at" yaml:"discovered_at" xml:"discovered_at"`
 	LastSeen     time.Time         `json:"last_seen,omitempty" yaml:"last_seen,omitempty" xml:"last_seen,omitempty"`
+
+	// PTZ сообщает, привязана ли к устройству ONVIF PTZConfiguration хотя бы в одном
+	// медиа профиле (см. internal/protocols/onvif.Client.GetProfiles)
+	PTZ bool `json:"ptz,omitempty" yaml:"ptz,omitempty" xml:"ptz,omitempty"`
+
+	// Services - сервисы UPnP (ContentDirectory, AVTransport и т.д.), перечисленные в XML
+	// описании устройства по адресу SSDP Location (см. scanner.UPnPScanner.fetchDescription)
+	Services []UPnPService `json:"upnp_services,omitempty" yaml:"upnp_services,omitempty" xml:"upnp_services>service,omitempty"`
+
+	// ExternalIP - внешний IPv4 адрес, о котором сообщил сервис WANIPConnection/WANPPPConnection
+	// этого устройства (см. scanner.UPnPScanner.enrichGateway, pkg/scanner/igd.Client.GetExternalIPAddress).
+	// Заполняется только для Internet Gateway Device
+	ExternalIP string `json:"external_ip,omitempty" yaml:"external_ip,omitempty" xml:"external_ip,omitempty"`
+
+	// PortMappings - таблица port-forward этого шлюза на момент обнаружения (см.
+	// pkg/scanner/igd.Client.ListPortMappings). Заполняется только для Internet Gateway Device
+	PortMappings []PortMapping `json:"port_mappings,omitempty" yaml:"port_mappings,omitempty" xml:"port_mappings>mapping,omitempty"`
+
+	// UDN - Unique Device Name из USN SSDP ответа (часть после "uuid:" и до "::"), один и тот
+	// же для всех адресов одного физического устройства. UPnPScanner использует его как ключ
+	// объединения результатов IPv4 и IPv6 SSDP discovery, чтобы dual-stack устройство, ответившее
+	// по обоим стекам, не попало в список дважды (см. scanner.UPnPScanner.Discover)
+	UDN string `json:"udn,omitempty" yaml:"udn,omitempty" xml:"udn,omitempty"`
+
+	// FirmwareVersion/SerialNumber - поля ответа ONVIF GetDeviceInformation, которых нет в
+	// WS-Discovery ProbeMatch (см. onvif.Client.GetDeviceInformation)
+	FirmwareVersion string `json:"firmware_version,omitempty" yaml:"firmware_version,omitempty" xml:"firmware_version,omitempty"`
+	SerialNumber    string `json:"serial_number,omitempty" yaml:"serial_number,omitempty" xml:"serial_number,omitempty"`
+
+	// EndpointRef - WS-Discovery EndpointReference Address ("urn:uuid:...") устройства,
+	// один и тот же в ProbeMatch/Hello/Bye независимо от текущего IP. registry.DeviceRegistry
+	// использует его как стабильный идентификатор устройства вместо IP (см.
+	// scanner.ONVIFScanner.Listen)
+	EndpointRef string `json:"endpoint_ref,omitempty" yaml:"endpoint_ref,omitempty" xml:"endpoint_ref,omitempty"`
+
+	// Online сообщает, числится ли устройство сейчас на связи. Заполняется только
+	// устройствами, обнаруженными через scanner.ONVIFScanner.Listen - registry.DeviceRegistry
+	// выставляет false, когда Bye получен или LastSeen не обновлялось дольше stale TTL
+	// (см. registry.DeviceRegistry.PruneStale)
+	Online bool `json:"online,omitempty" yaml:"online,omitempty" xml:"online,omitempty"`
+}
+
+// PortMapping - одна запись таблицы port-forward Internet Gateway Device (соответствует
+// pkg/scanner/igd.PortMapping)
+type PortMapping struct {
+	RemoteHost     string `json:"remote_host,omitempty" yaml:"remote_host,omitempty" xml:"remote_host,omitempty"`
+	ExternalPort   int    `json:"external_port" yaml:"external_port" xml:"external_port"`
+	Protocol       string `json:"protocol" yaml:"protocol" xml:"protocol"`
+	InternalPort   int    `json:"internal_port" yaml:"internal_port" xml:"internal_port"`
+	InternalClient string `json:"internal_client" yaml:"internal_client" xml:"internal_client"`
+	Enabled        bool   `json:"enabled" yaml:"enabled" xml:"enabled"`
+	Description    string `json:"description,omitempty" yaml:"description,omitempty" xml:"description,omitempty"`
+	LeaseDuration  int    `json:"lease_duration,omitempty" yaml:"lease_duration,omitempty" xml:"lease_duration,omitempty"`
+}
+
+// UPnPService - один сервис из <serviceList> XML описания UPnP устройства (UPnP Device
+// Architecture 1.0, раздел 2.3), достаточный для того, чтобы выполнить на него SOAP запрос
+type UPnPService struct {
+	ServiceType string `json:"service_type" yaml:"service_type" xml:"service_type"`
+	ServiceID   string `json:"service_id" yaml:"service_id" xml:"service_id"`
+	ControlURL  string `json:"control_url" yaml:"control_url" xml:"control_url"`
+	EventSubURL string `json:"event_sub_url,omitempty" yaml:"event_sub_url,omitempty" xml:"event_sub_url,omitempty"`
+	SCPDURL     string `json:"scpd_url,omitempty" yaml:"scpd_url,omitempty" xml:"scpd_url,omitempty"`
 }
 
 // Protocol - поддерживаемый протокол
@@ -22,6 +88,44 @@ type Protocol struct {
 	URL       string    `json:"url,omitempty" yaml:"url,omitempty" xml:"url,omitempty"`
 	Available bool      `json:"available" yaml:"available" xml:"available"`
 	DetectedAt time.Time `json:"detected_at,omitempty" yaml:"detected_at,omitempty" xml:"detected_at,omitempty"`
+
+	// HLSURL - адрес, по которому этот поток доступен через internal/gateway после
+	// перепаковки в HLS (см. internal/hls), если детектор протокола смог его предсказать
+	HLSURL string `json:"hls_url,omitempty" yaml:"hls_url,omitempty" xml:"hls_url,omitempty"`
+
+	// PublicAddress - реальный адрес вида "ip:port", под которым устройство видно снаружи
+	// NAT, извлеченный из XOR-MAPPED-ADDRESS ответа STUN Binding Success (см.
+	// protocols.WebRTCDetector.checkSTUNServer). Заполняется только для протокола WebRTC
+	PublicAddress string `json:"public_address,omitempty" yaml:"public_address,omitempty" xml:"public_address,omitempty"`
+
+	// WebRTCInfo - кодеки, BUNDLE группа и ICE параметры, разобранные из SDP offer/answer,
+	// найденного в веб-интерфейсе устройства (см. pkg/sdp и
+	// protocols.WebRTCDetector.checkSDPOffer). Заполняется только для протокола WebRTC и
+	// только если offer/answer удалось найти и разобрать
+	WebRTCInfo *WebRTCCodecInfo `json:"webrtc_info,omitempty" yaml:"webrtc_info,omitempty" xml:"webrtc_info,omitempty"`
+}
+
+// WebRTCCodecInfo - кодеки и параметры ICE/DTLS одного SDP offer/answer (см. pkg/sdp.Parse)
+type WebRTCCodecInfo struct {
+	// Codecs - имена кодеков (a=rtpmap encoding name) из всех m= секций, например
+	// ["VP8", "H264", "opus"]
+	Codecs []string `json:"codecs,omitempty" yaml:"codecs,omitempty" xml:"codecs>codec,omitempty"`
+
+	// BundleGroup - идентификаторы (a=mid) m= секций, замультиплексированных в одно
+	// транспортное соединение (a=group:BUNDLE, RFC 5888)
+	BundleGroup []string `json:"bundle_group,omitempty" yaml:"bundle_group,omitempty" xml:"bundle_group>mid,omitempty"`
+
+	// ICEUfrag/ICEPwd - учетные данные ICE (RFC 8839), взятые с уровня сессии либо из
+	// первой m= секции, где они заданы
+	ICEUfrag string `json:"ice_ufrag,omitempty" yaml:"ice_ufrag,omitempty" xml:"ice_ufrag,omitempty"`
+	ICEPwd   string `json:"ice_pwd,omitempty" yaml:"ice_pwd,omitempty" xml:"ice_pwd,omitempty"`
+
+	// Candidates - адреса ICE кандидатов вида "ip:port" (a=candidate), включая mDNS
+	// .local имена, если устройство их использует вместо настоящего адреса
+	Candidates []string `json:"candidates,omitempty" yaml:"candidates,omitempty" xml:"candidates>candidate,omitempty"`
+
+	// Fingerprint - отпечаток DTLS сертификата (a=fingerprint), в формате "<hash-function> <value>"
+	Fingerprint string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty" xml:"fingerprint,omitempty"`
 }
 
 // RTSPStreamInfo - информация о RTSP потоке
@@ -35,4 +139,94 @@ type RTSPStreamInfo struct {
 	Channels   int       `json:"channels,omitempty" yaml:"channels,omitempty" xml:"channels,omitempty"`
 	Available  bool      `json:"available" yaml:"available" xml:"available"`
 	CheckedAt  time.Time `json:"checked_at,omitempty" yaml:"checked_at,omitempty" xml:"checked_at,omitempty"`
+
+	// Метрики живого потока, полученные из RTCP Receiver Report (см. internal/rtcp)
+	LossPercent   float64       `json:"loss_percent,omitempty" yaml:"loss_percent,omitempty" xml:"loss_percent,omitempty"`
+	JitterMs      float64       `json:"jitter_ms,omitempty" yaml:"jitter_ms,omitempty" xml:"jitter_ms,omitempty"`
+	LastPacketAge time.Duration `json:"last_packet_age,omitempty" yaml:"last_packet_age,omitempty" xml:"last_packet_age,omitempty"`
+
+	// Детализированные параметры кодеков, разобранные из SDP (см. rtsp.ParseSDP), если они были получены
+	VideoCodecInfo *VideoCodec `json:"video_codec_info,omitempty" yaml:"video_codec_info,omitempty" xml:"video_codec_info,omitempty"`
+	AudioCodecInfo *AudioCodec `json:"audio_codec_info,omitempty" yaml:"audio_codec_info,omitempty" xml:"audio_codec_info,omitempty"`
+
+	// Transport - фактически использованный режим RTSP транспорта ("tcp", "udp" или
+	// "udp-multicast"), сохраняется тем компонентом, который выполнил SETUP/PLAY (см.
+	// hls.Repackager.ResolvedTransport), чтобы рекордер и WebRTC реле переиспользовали
+	// его без повторного обращения к камере
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty" xml:"transport,omitempty"`
+
+	// ProfileName - имя ONVIF медиа профиля (GetProfiles), которому соответствует этот поток,
+	// заполняется только когда URL получен через onvif.Client.GetStreamUri
+	ProfileName string `json:"profile_name,omitempty" yaml:"profile_name,omitempty" xml:"profile_name,omitempty"`
+
+	// SnapshotURL - адрес JPEG снимка кадра этого профиля (см. onvif.Client.GetSnapshotUri),
+	// заполняется только если устройство поддерживает Media GetSnapshotUri
+	SnapshotURL string `json:"snapshot_url,omitempty" yaml:"snapshot_url,omitempty" xml:"snapshot_url,omitempty"`
+}
+
+// RTMPStreamInfo - информация о RTMP потоке (app/streamName), полученная через connect/createStream/
+// play (см. internal/protocols.RTMPDetector.CheckStream), а не через SDP, как у RTSP
+type RTMPStreamInfo struct {
+	URL        string  `json:"url" yaml:"url" xml:"url"`
+	AppName    string  `json:"app_name" yaml:"app_name" xml:"app_name"`
+	StreamName string  `json:"stream_name" yaml:"stream_name" xml:"stream_name"`
+	Codec      string  `json:"codec" yaml:"codec" xml:"codec"` // H.264, H.265
+	Resolution string  `json:"resolution" yaml:"resolution" xml:"resolution"`
+	FPS        float64 `json:"fps" yaml:"fps" xml:"fps"`
+	AudioCodec string  `json:"audio_codec,omitempty" yaml:"audio_codec,omitempty" xml:"audio_codec,omitempty"`
+	Channels   int     `json:"channels,omitempty" yaml:"channels,omitempty" xml:"channels,omitempty"`
+	Available  bool    `json:"available" yaml:"available" xml:"available"`
+
+	CheckedAt time.Time `json:"checked_at,omitempty" yaml:"checked_at,omitempty" xml:"checked_at,omitempty"`
+
+	// Детализированные параметры кодеков, разобранные из onMetaData и первых видео/аудио тегов
+	// (см. rtsp.ParseH264SPSNAL/ParseH265SPSNAL, переиспользуемые RTMP парсером), если они были получены
+	VideoCodecInfo *VideoCodec `json:"video_codec_info,omitempty" yaml:"video_codec_info,omitempty" xml:"video_codec_info,omitempty"`
+	AudioCodecInfo *AudioCodec `json:"audio_codec_info,omitempty" yaml:"audio_codec_info,omitempty" xml:"audio_codec_info,omitempty"`
+}
+
+// HLSStreamInfo - информация о потоке, обнаруженном через манифест HLS (.m3u8) или DASH (.mpd)
+// (см. internal/protocols.HLSDetector.CheckStream). Используется для обоих форматов, поскольку
+// DASH Representation несет по сути тот же набор параметров, что и вариант EXT-X-STREAM-INF
+type HLSStreamInfo struct {
+	URL        string    `json:"url" yaml:"url" xml:"url"`
+	Format     string    `json:"format" yaml:"format" xml:"format"` // HLS или DASH
+	Codec      string    `json:"codec" yaml:"codec" xml:"codec"` // H.264, H.265
+	Resolution string    `json:"resolution" yaml:"resolution" xml:"resolution"`
+	FPS        float64   `json:"fps" yaml:"fps" xml:"fps"`
+	Bitrate    int       `json:"bitrate,omitempty" yaml:"bitrate,omitempty" xml:"bitrate,omitempty"`
+	AudioCodec string    `json:"audio_codec,omitempty" yaml:"audio_codec,omitempty" xml:"audio_codec,omitempty"`
+	Channels   int       `json:"channels,omitempty" yaml:"channels,omitempty" xml:"channels,omitempty"`
+	Available  bool      `json:"available" yaml:"available" xml:"available"`
+	CheckedAt  time.Time `json:"checked_at,omitempty" yaml:"checked_at,omitempty" xml:"checked_at,omitempty"`
+
+	// Variants перечисляет все варианты (EXT-X-STREAM-INF / DASH Representation), найденные в
+	// мастер-манифесте, выбранный вариант помечен флагом Selected
+	Variants []HLSVariant `json:"variants,omitempty" yaml:"variants,omitempty" xml:"variants>variant,omitempty"`
+
+	// Детализированные параметры кодеков, разобранные из контейнера выбранного варианта
+	// (MPEG-TS PMT/PES или фрагментированный MP4 moov/avcC/hvcC - см. internal/protocols/hls_ts.go,
+	// internal/protocols/hls_mp4.go), если контейнер удалось получить и разобрать
+	VideoCodecInfo *VideoCodec `json:"video_codec_info,omitempty" yaml:"video_codec_info,omitempty" xml:"video_codec_info,omitempty"`
+	AudioCodecInfo *AudioCodec `json:"audio_codec_info,omitempty" yaml:"audio_codec_info,omitempty" xml:"audio_codec_info,omitempty"`
+}
+
+// HLSVariant - один вариант (рендиция) из мастер-манифеста HLS или DASH
+type HLSVariant struct {
+	URL        string  `json:"url" yaml:"url" xml:"url"`
+	Bandwidth  int     `json:"bandwidth,omitempty" yaml:"bandwidth,omitempty" xml:"bandwidth,omitempty"`
+	Resolution string  `json:"resolution,omitempty" yaml:"resolution,omitempty" xml:"resolution,omitempty"`
+	Codecs     string  `json:"codecs,omitempty" yaml:"codecs,omitempty" xml:"codecs,omitempty"`
+	FPS        float64 `json:"fps,omitempty" yaml:"fps,omitempty" xml:"fps,omitempty"`
+	Selected   bool    `json:"selected,omitempty" yaml:"selected,omitempty" xml:"selected,omitempty"`
+}
+
+// MJPEGStreamInfo - информация об MJPEG потоке, обнаруженном по HTTP (multipart/x-mixed-replace)
+type MJPEGStreamInfo struct {
+	URL        string    `json:"url" yaml:"url" xml:"url"`
+	Width      int       `json:"width,omitempty" yaml:"width,omitempty" xml:"width,omitempty"`
+	Height     int       `json:"height,omitempty" yaml:"height,omitempty" xml:"height,omitempty"`
+	Components int       `json:"components,omitempty" yaml:"components,omitempty" xml:"components,omitempty"`
+	Available  bool      `json:"available" yaml:"available" xml:"available"`
+	CheckedAt  time.Time `json:"checked_at,omitempty" yaml:"checked_at,omitempty" xml:"checked_at,omitempty"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Event - одно ONVIF уведомление устройства (motion/tamper/analytics), полученное PullPoint
+// подпиской (см. internal/events.Watch). В отличие от pkg/events.Event, которое описывает
+// жизненный цикл самого процесса сканирования (DeviceDiscovered/DeviceLost/...), это данные,
+// присланные самим устройством - Topic соответствует ONVIF Topic Namespace, например
+// "tns1:VideoSource/MotionAlarm" или "tns1:RuleEngine/CellMotionDetector/Motion"
+type Event struct {
+	DeviceIP    string            `json:"device_ip"`
+	EndpointRef string            `json:"endpoint_ref,omitempty"`
+	Topic       string            `json:"topic"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Data        map[string]string `json:"data,omitempty"`
+	IsActive    bool              `json:"is_active"`
+}
@@ -0,0 +1,18 @@
+package protocols
+
+// init самостоятельно регистрирует все встроенные детекторы в глобальном реестре
+// (см. registry.go). Сторонние детекторы регистрируются точно так же через публичные
+// RegisterDetector/RegisterDetectorWithOptions, без необходимости форкать пакет
+func init() {
+	RegisterDetector(NewRTSPDetector())
+	RegisterDetector(NewRTMPDetector())
+	RegisterDetector(NewHLSDetector())
+	RegisterDetector(NewDASHDetector())
+	RegisterDetector(NewMJPEGDetector())
+	RegisterDetector(NewWebRTCDetector())
+	RegisterDetector(NewWHEPDetector())
+	RegisterDetector(NewONVIFHTTPDetector())
+	RegisterDetector(NewHTTPFLVDetector())
+
+	RegisterDetectorWithOptions(NewSRTDetector(), []int{9710, 9000}, defaultUDPBanner)
+}
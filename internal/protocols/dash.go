@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/local-video-server/internal/metrics"
 	"github.com/local-video-server/internal/models"
 	"github.com/local-video-server/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -90,7 +91,9 @@ func (d *DASHDetector) Detect(ip string, port int, timeout time.Duration) (*mode
 
 // checkDASHManifest проверяет наличие валидного DASH манифеста
 func (d *DASHDetector) checkDASHManifest(client *http.Client, url string) bool {
+	start := time.Now()
 	resp, err := client.Get(url)
+	metrics.ObserveFetchLatency(metrics.KindDASHManifest, time.Since(start))
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,128 @@
+package protocols
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// dashMPD - подмножество полей MPD DASH (ISO/IEC 23009-1, раздел 5.3), нужных для перечисления
+// вариантов так же, как это делает master-плейлист HLS (AdaptationSet/Representation несут тот
+// же набор параметров, что и EXT-X-STREAM-INF)
+type dashMPD struct {
+	Periods []dashPeriod `xml:"Period"`
+}
+
+type dashPeriod struct {
+	AdaptationSets []dashAdaptationSet `xml:"AdaptationSet"`
+}
+
+type dashAdaptationSet struct {
+	MimeType        string               `xml:"mimeType,attr"`
+	Representations []dashRepresentation `xml:"Representation"`
+}
+
+type dashRepresentation struct {
+	ID        string `xml:"id,attr"`
+	Bandwidth int    `xml:"bandwidth,attr"`
+	Width     int    `xml:"width,attr"`
+	Height    int    `xml:"height,attr"`
+	Codecs    string `xml:"codecs,attr"`
+	FrameRate string `xml:"frameRate,attr"`
+	BaseURL   string `xml:"BaseURL"`
+}
+
+// parseDASHManifest разбирает MPD DASH по baseURL, заполняя info.Variants вариантами из всех
+// Representation с видео MIME-типом и выбирая среди них вариант с наибольшим bandwidth, подобно
+// selectBestVariant для HLS
+func parseDASHManifest(data []byte, baseURL string, info *models.HLSStreamInfo) error {
+	var mpd dashMPD
+	if err := xml.Unmarshal(data, &mpd); err != nil {
+		return fmt.Errorf("failed to parse DASH MPD: %w", err)
+	}
+
+	for _, period := range mpd.Periods {
+		for _, adaptationSet := range period.AdaptationSets {
+			if adaptationSet.MimeType != "" && !strings.HasPrefix(adaptationSet.MimeType, "video") {
+				continue
+			}
+			for _, representation := range adaptationSet.Representations {
+				variant := models.HLSVariant{
+					Bandwidth:  representation.Bandwidth,
+					Codecs:     representation.Codecs,
+					URL:        resolveDASHURL(baseURL, representation.BaseURL),
+				}
+				if representation.Width > 0 && representation.Height > 0 {
+					variant.Resolution = fmt.Sprintf("%dx%d", representation.Width, representation.Height)
+				}
+				if fps, err := parseDASHFrameRate(representation.FrameRate); err == nil {
+					variant.FPS = fps
+				}
+				info.Variants = append(info.Variants, variant)
+			}
+		}
+	}
+
+	best := selectBestVariant(info.Variants)
+	if best == nil {
+		return fmt.Errorf("MPD has no video Representation")
+	}
+	best.Selected = true
+	info.Resolution = best.Resolution
+	info.Bitrate = best.Bandwidth
+	info.FPS = best.FPS
+	info.Codec = normalizeDASHCodec(best.Codecs)
+
+	return nil
+}
+
+// resolveDASHURL разрешает BaseURL представления (если задан) относительно адреса самого MPD
+func resolveDASHURL(mpdURL, baseURL string) string {
+	if baseURL == "" {
+		return mpdURL
+	}
+	base, err := url.Parse(mpdURL)
+	if err != nil {
+		return baseURL
+	}
+	ref, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// parseDASHFrameRate разбирает атрибут frameRate DASH - либо целое число, либо дробь "30000/1001"
+// (ISO/IEC 23009-1, раздел 5.3.7)
+func parseDASHFrameRate(value string) (float64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty frame rate")
+	}
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) == 2 {
+		num, err1 := strconv.ParseFloat(parts[0], 64)
+		den, err2 := strconv.ParseFloat(parts[1], 64)
+		if err1 == nil && err2 == nil && den != 0 {
+			return num / den, nil
+		}
+		return 0, fmt.Errorf("invalid frame rate fraction: %s", value)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// normalizeDASHCodec сводит значение атрибута codecs (RFC 6381) к тому же обозначению, которое
+// используют models.RTSPStreamInfo/models.RTMPStreamInfo ("H.264", "H.265")
+func normalizeDASHCodec(codecs string) string {
+	switch {
+	case strings.HasPrefix(codecs, "avc1") || strings.HasPrefix(codecs, "avc3"):
+		return "H.264"
+	case strings.HasPrefix(codecs, "hev1") || strings.HasPrefix(codecs, "hvc1"):
+		return "H.265"
+	default:
+		return ""
+	}
+}
@@ -3,11 +3,23 @@ package protocols
 import (
 	"time"
 
+	"github.com/local-video-server/internal/licensing"
 	"github.com/local-video-server/internal/models"
 	"github.com/local-video-server/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
 
+// protocolFeature сопоставляет имя протокола (Detector.GetName), требующее лицензии выше
+// Free, с соответствующей licensing.Feature. Протоколы, отсутствующие в этой карте (RTSP,
+// MJPEG, SRT, WHEP, HTTP-FLV), доступны во всех редакциях
+var protocolFeature = map[string]licensing.Feature{
+	"RTMP":      licensing.FeatureRTMPProbe,
+	"HLS":       licensing.FeatureHLSProbe,
+	"MPEG-DASH": licensing.FeatureDASHProbe,
+	"ONVIF":     licensing.FeatureONVIF,
+	"WebRTC":    licensing.FeatureWebRTC,
+}
+
 // Detector - интерфейс для детекторов протоколов
 type Detector interface {
 	// Detect проверяет наличие протокола на устройстве
@@ -20,59 +32,63 @@ type Detector interface {
 	GetDefaultPort() int
 }
 
-// ProtocolDetector - координатор всех детекторов протоколов
+// ProtocolDetector - координатор детекторов, работающий поверх глобального реестра
+// ProtocolDetectorRegistry (см. registry.go и builtins.go). Сохранен как фасад для
+// кода, которому нужно обратиться к конкретному протоколу по имени или перебрать все
+// зарегистрированные детекторы разом, не работая напрямую с портами
 type ProtocolDetector struct {
-	detectors []Detector
-	logger    *logrus.Logger
+	registry *ProtocolDetectorRegistry
+	logger   *logrus.Logger
+	gates    *licensing.FeatureGates
 }
 
-// NewProtocolDetector создает новый координатор детекторов
+// NewProtocolDetector создает координатор поверх глобального реестра встроенных
+// детекторов, сверяясь с licensing.DefaultGates() на предмет того, какие протоколы
+// разрешены текущей редакцией
 func NewProtocolDetector() *ProtocolDetector {
-	logger := utils.GetLogger()
-	
 	return &ProtocolDetector{
-		detectors: []Detector{
-			NewRTSPDetector(),
-			NewRTMPDetector(),
-			NewHLSDetector(),
-			NewMJPEGDetector(),
-			NewDASHDetector(),
-			NewWebRTCDetector(),
-		},
-		logger: logger,
+		registry: DefaultRegistry(),
+		logger:   utils.GetLogger(),
+		gates:    licensing.DefaultGates(),
 	}
 }
 
-// DetectAll проверяет все протоколы на устройстве
+// DetectAll проверяет на устройстве все зарегистрированные протоколы по их портам по
+// умолчанию, пропуская те, для которых текущая редакция не дает лицензии (см.
+// protocolFeature)
 func (pd *ProtocolDetector) DetectAll(ip string, timeout time.Duration) ([]models.Protocol, error) {
 	var protocols []models.Protocol
-	
-	for _, detector := range pd.detectors {
+
+	for _, detector := range pd.registry.Detectors() {
+		if feature, gated := protocolFeature[detector.GetName()]; gated && !pd.gates.Allow(feature) {
+			pd.logger.Debugf("Skipping %s detection on %s: not licensed for %s edition",
+				detector.GetName(), ip, pd.gates.Edition())
+			continue
+		}
+
 		port := detector.GetDefaultPort()
 		protocol, err := detector.Detect(ip, port, timeout)
 		if err != nil {
-			pd.logger.Debugf("Protocol %s not detected on %s:%d: %v", 
+			pd.logger.Debugf("Protocol %s not detected on %s:%d: %v",
 				detector.GetName(), ip, port, err)
 			continue
 		}
-		
+
 		if protocol != nil && protocol.Available {
 			protocols = append(protocols, *protocol)
-			pd.logger.Infof("Detected %s protocol on %s:%d", 
+			pd.logger.Infof("Detected %s protocol on %s:%d",
 				detector.GetName(), ip, port)
 		}
 	}
-	
+
 	return protocols, nil
 }
 
-// DetectProtocol проверяет конкретный протокол
+// DetectProtocol проверяет конкретный протокол по имени
 func (pd *ProtocolDetector) DetectProtocol(protocolName string, ip string, port int, timeout time.Duration) (*models.Protocol, error) {
-	for _, detector := range pd.detectors {
-		if detector.GetName() == protocolName {
-			return detector.Detect(ip, port, timeout)
-		}
+	detector := pd.registry.Find(protocolName)
+	if detector == nil {
+		return nil, nil
 	}
-	
-	return nil, nil
+	return detector.Detect(ip, port, timeout)
 }
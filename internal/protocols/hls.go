@@ -159,3 +159,190 @@ func (d *HLSDetector) checkWebInterfaceForHLS(client *http.Client, ip string, po
 
 	return false
 }
+
+// maxHLSSegmentProbeBytes ограничивает объём одного сегмента/инициализирующего блока, который
+// CheckStream готов прочитать при разборе контейнера - нужные данные (PAT/PMT/первый SPS или
+// moov) умещаются в первые несколько сотен килобайт даже самого длинного сегмента
+const maxHLSSegmentProbeBytes = 2 * 1024 * 1024
+
+// CheckStream запрашивает манифест по manifestURL (мастер- или медиа-плейлист HLS, либо MPD DASH),
+// при необходимости выбирает вариант с наибольшим битрейтом, получает его медиа-плейлист и
+// контейнер первого сегмента (MPEG-TS или инициализирующий блок фрагментированного MP4) и
+// извлекает из него кодек/разрешение/FPS, возвращая models.HLSStreamInfo
+func (d *HLSDetector) CheckStream(manifestURL string, timeout time.Duration) (*models.HLSStreamInfo, error) {
+	client := &http.Client{Timeout: timeout}
+	info := &models.HLSStreamInfo{URL: manifestURL, CheckedAt: time.Now()}
+
+	body, contentType, err := fetchHLSResource(client, manifestURL)
+	if err != nil {
+		return info, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+
+	if looksLikeDASHManifest(body, contentType, manifestURL) {
+		info.Format = "DASH"
+		if err := parseDASHManifest(body, manifestURL, info); err != nil {
+			return info, fmt.Errorf("failed to parse DASH manifest: %w", err)
+		}
+		info.Available = true
+		return info, nil
+	}
+
+	info.Format = "HLS"
+	playlist, err := parseM3U8(body, manifestURL)
+	if err != nil {
+		return info, fmt.Errorf("failed to parse HLS playlist: %w", err)
+	}
+
+	if playlist.IsMaster {
+		info.Variants = playlist.Variants
+		variant := selectBestVariant(info.Variants)
+		if variant == nil {
+			return info, fmt.Errorf("master playlist has no variants")
+		}
+		variant.Selected = true
+		applyHLSVariantMetadata(info, variant)
+
+		body, _, err = fetchHLSResource(client, variant.URL)
+		if err != nil {
+			return info, fmt.Errorf("failed to fetch media playlist: %w", err)
+		}
+		playlist, err = parseM3U8(body, variant.URL)
+		if err != nil {
+			return info, fmt.Errorf("failed to parse media playlist: %w", err)
+		}
+	}
+
+	if err := populateFromHLSMediaPlaylist(client, playlist, info); err != nil {
+		// Контейнер не удалось получить или разобрать - у нас всё ещё есть метаданные из
+		// мастер-плейлиста (CODECS/RESOLUTION/FRAME-RATE), поэтому это не фатальная ошибка
+		d.logger.Debugf("HLS media playlist %s: %v", manifestURL, err)
+	}
+
+	info.Available = true
+	return info, nil
+}
+
+// fetchHLSResource выполняет GET и возвращает тело ответа (ограниченное maxHLSSegmentProbeBytes)
+// и заголовок Content-Type
+func fetchHLSResource(client *http.Client, url string) ([]byte, string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHLSSegmentProbeBytes))
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// looksLikeDASHManifest определяет MPD DASH по Content-Type, расширению URL или корневому
+// элементу XML, если первые два признака недоступны или противоречивы
+func looksLikeDASHManifest(body []byte, contentType, manifestURL string) bool {
+	if strings.Contains(contentType, "dash+xml") {
+		return true
+	}
+	if strings.HasSuffix(manifestURL, ".mpd") {
+		return true
+	}
+	return strings.Contains(string(body), "<MPD")
+}
+
+// applyHLSVariantMetadata переносит то, что уже известно о выбранном варианте из мастер-плейлиста
+// (BANDWIDTH/RESOLUTION/FRAME-RATE/CODECS), на info, прежде чем пытаться разобрать сам контейнер
+func applyHLSVariantMetadata(info *models.HLSStreamInfo, variant *models.HLSVariant) {
+	info.Bitrate = variant.Bandwidth
+	info.Resolution = variant.Resolution
+	info.FPS = variant.FPS
+	info.Codec = normalizeDASHCodec(variant.Codecs)
+}
+
+// populateFromHLSMediaPlaylist получает контейнер первого сегмента медиа-плейлиста (MPEG-TS или,
+// если плейлист содержит EXT-X-MAP, инициализирующий сегмент фрагментированного MP4) и заполняет
+// info кодеком/разрешением/FPS, которые удалось из него извлечь
+func populateFromHLSMediaPlaylist(client *http.Client, playlist *m3u8Playlist, info *models.HLSStreamInfo) error {
+	if playlist.Map != "" {
+		initSegment, _, err := fetchHLSResource(client, playlist.Map)
+		if err != nil {
+			return fmt.Errorf("fetch init segment: %w", err)
+		}
+		result, err := probeFMP4InitSegment(initSegment)
+		if err != nil {
+			return fmt.Errorf("probe init segment: %w", err)
+		}
+		applyMP4ProbeResult(info, result)
+		return nil
+	}
+
+	if len(playlist.Segments) == 0 {
+		return fmt.Errorf("media playlist has no segments")
+	}
+	segment, _, err := fetchHLSResource(client, playlist.Segments[0])
+	if err != nil {
+		return fmt.Errorf("fetch segment: %w", err)
+	}
+	result, err := probeTSSegment(segment)
+	if err != nil {
+		return fmt.Errorf("probe TS segment: %w", err)
+	}
+	applyTSProbeResult(info, result)
+	return nil
+}
+
+// applyMP4ProbeResult переносит результат probeFMP4InitSegment в info, не перезаписывая то, что
+// уже было известно из мастер-плейлиста (RESOLUTION/FRAME-RATE часто точнее в манифесте, чем в
+// усечённом VisualSampleEntry)
+func applyMP4ProbeResult(info *models.HLSStreamInfo, result *mp4ProbeResult) {
+	if info.Codec == "" {
+		info.Codec = result.VideoCodec
+	}
+	if info.Resolution == "" && result.Width > 0 && result.Height > 0 {
+		info.Resolution = fmt.Sprintf("%dx%d", result.Width, result.Height)
+	}
+	if info.FPS == 0 {
+		info.FPS = result.FPS
+	}
+	if result.Profile != "" || result.Width > 0 {
+		info.VideoCodecInfo = &models.VideoCodec{
+			Name:    result.VideoCodec,
+			Profile: result.Profile,
+			Level:   result.Level,
+			Width:   result.Width,
+			Height:  result.Height,
+			FPS:     result.FPS,
+		}
+	}
+}
+
+// applyTSProbeResult переносит результат probeTSSegment в info, как и applyMP4ProbeResult
+// сохраняя уже известные из мастер-плейлиста значения
+func applyTSProbeResult(info *models.HLSStreamInfo, result *tsProbeResult) {
+	if info.Codec == "" {
+		info.Codec = result.VideoCodec
+	}
+	if info.Resolution == "" && result.Width > 0 && result.Height > 0 {
+		info.Resolution = fmt.Sprintf("%dx%d", result.Width, result.Height)
+	}
+	if info.FPS == 0 {
+		info.FPS = result.FPS
+	}
+	if result.AudioCodec != "" {
+		info.AudioCodec = result.AudioCodec
+	}
+	if result.Profile != "" || result.Width > 0 {
+		info.VideoCodecInfo = &models.VideoCodec{
+			Name:    result.VideoCodec,
+			Profile: result.Profile,
+			Level:   result.Level,
+			Width:   result.Width,
+			Height:  result.Height,
+			FPS:     result.FPS,
+		}
+	}
+}
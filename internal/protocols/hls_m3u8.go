@@ -0,0 +1,152 @@
+package protocols
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// m3u8Playlist - результат разбора плейлиста HLS (RFC 8216). Мастер-плейлист заполняет
+// Variants и не содержит сегментов; медиа-плейлист заполняет Segments (и, для фрагментированного
+// MP4, Map - URI инициализирующего сегмента из EXT-X-MAP)
+type m3u8Playlist struct {
+	IsMaster bool
+	Variants []models.HLSVariant
+	Segments []string
+	Map      string
+}
+
+// parseM3U8 разбирает плейлист HLS, полученный по baseURL - относительные URI строк
+// (#EXT-X-STREAM-INF, #EXTINF, EXT-X-MAP) разрешаются относительно него (RFC 8216, раздел 4.1)
+func parseM3U8(data []byte, baseURL string) (*m3u8Playlist, error) {
+	playlist := &m3u8Playlist{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var pendingVariant *models.HLSVariant
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			playlist.IsMaster = true
+			attrs := parseM3U8Attributes(line[len("#EXT-X-STREAM-INF:"):])
+			variant := models.HLSVariant{
+				Codecs: attrs["CODECS"],
+			}
+			if bandwidth, ok := attrs["BANDWIDTH"]; ok {
+				if n, err := strconv.Atoi(bandwidth); err == nil {
+					variant.Bandwidth = n
+				}
+			}
+			if resolution, ok := attrs["RESOLUTION"]; ok {
+				variant.Resolution = resolution
+			}
+			if frameRate, ok := attrs["FRAME-RATE"]; ok {
+				if fps, err := strconv.ParseFloat(frameRate, 64); err == nil {
+					variant.FPS = fps
+				}
+			}
+			pendingVariant = &variant
+
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			// Аудио/субтитры рендиции по отдельному URI - из набора метаданных, извлекаемых
+			// этим детектором, сейчас не нужны (AudioCodecInfo берется из контейнера сегмента)
+
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			attrs := parseM3U8Attributes(line[len("#EXT-X-MAP:"):])
+			if uri, ok := attrs["URI"]; ok {
+				playlist.Map = resolveM3U8URL(baseURL, uri)
+			}
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// Следующая не-комментарийная строка - URI сегмента
+
+		case strings.HasPrefix(line, "#"):
+			// Прочие теги (EXT-X-VERSION, EXT-X-TARGETDURATION и т.п.) не несут нужных нам данных
+
+		default:
+			resolved := resolveM3U8URL(baseURL, line)
+			if pendingVariant != nil {
+				pendingVariant.URL = resolved
+				playlist.Variants = append(playlist.Variants, *pendingVariant)
+				pendingVariant = nil
+			} else {
+				playlist.Segments = append(playlist.Segments, resolved)
+			}
+		}
+	}
+
+	return playlist, scanner.Err()
+}
+
+// parseM3U8Attributes разбирает список атрибутов тега вида KEY=VALUE,KEY2="VALUE2" (RFC 8216,
+// раздел 4.2), корректно пропуская запятые внутри кавычек
+func parseM3U8Attributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	var key strings.Builder
+	var value strings.Builder
+	inValue, inQuotes := false, false
+
+	flush := func() {
+		if key.Len() > 0 {
+			attrs[strings.TrimSpace(key.String())] = value.String()
+		}
+		key.Reset()
+		value.Reset()
+		inValue = false
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == '=' && !inValue && !inQuotes:
+			inValue = true
+		case r == ',' && !inQuotes:
+			flush()
+		case inValue:
+			value.WriteRune(r)
+		default:
+			key.WriteRune(r)
+		}
+	}
+	flush()
+
+	return attrs
+}
+
+// resolveM3U8URL разрешает относительный URI плейлиста относительно baseURL; если uri уже
+// абсолютный либо baseURL не парсится, возвращает uri как есть
+func resolveM3U8URL(baseURL, uri string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return uri
+	}
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// selectBestVariant выбирает вариант мастер-плейлиста с наибольшим BANDWIDTH - приближение к
+// тому, что выбрал бы плеер с самым быстрым соединением, и даёт наиболее информативный CODECS/
+// RESOLUTION для отчёта об устройстве
+func selectBestVariant(variants []models.HLSVariant) *models.HLSVariant {
+	var best *models.HLSVariant
+	for i := range variants {
+		if best == nil || variants[i].Bandwidth > best.Bandwidth {
+			best = &variants[i]
+		}
+	}
+	return best
+}
@@ -0,0 +1,137 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// mp4ProbeResult - то, что удалось извлечь из стандартных боксов ISO BMFF (ISO/IEC 14496-12)
+// инициализирующего сегмента фрагментированного MP4 (EXT-X-MAP)
+type mp4ProbeResult struct {
+	VideoCodec string // H.264 или H.265
+	Profile    string
+	Level      string
+	Width      int
+	Height     int
+	FPS        float64
+}
+
+// probeFMP4InitSegment разбирает moov инициализирующего сегмента фрагментированного MP4 и
+// извлекает параметры видео из первого найденного avcC/hvcC (stsd -> avc1/hev1/hvc1), переиспользуя
+// тот же разбор AVCDecoderConfigurationRecord/HEVCDecoderConfigurationRecord, что и RTMP/FLV
+// (см. parseAVCDecoderConfig/parseHEVCDecoderConfig в rtmp_flv.go - запись идентична)
+func probeFMP4InitSegment(data []byte) (*mp4ProbeResult, error) {
+	stsd, ok := findMP4Box(data, "moov", "trak", "mdia", "minf", "stbl", "stsd")
+	if !ok {
+		return nil, fmt.Errorf("moov/.../stsd box not found")
+	}
+	if len(stsd) < 8 {
+		return nil, fmt.Errorf("stsd box too short")
+	}
+
+	// stsd: version(1) + flags(3) + entry_count(4), затем сами sample entries
+	pos := 8
+	for pos+8 <= len(stsd) {
+		entrySize := int(binary.BigEndian.Uint32(stsd[pos : pos+4]))
+		format := string(stsd[pos+4 : pos+8])
+		if entrySize <= 0 || pos+entrySize > len(stsd) {
+			break
+		}
+		entry := stsd[pos : pos+entrySize]
+
+		switch format {
+		case "avc1", "avc3":
+			if result := parseVisualSampleEntry(entry, "avcC"); result != nil {
+				return result, nil
+			}
+		case "hev1", "hvc1":
+			if result := parseVisualSampleEntry(entry, "hvcC"); result != nil {
+				return result, nil
+			}
+		}
+
+		pos += entrySize
+	}
+
+	return nil, fmt.Errorf("no avc1/hev1 sample entry with a decoder configuration record found")
+}
+
+// visualSampleEntryHeaderLen - размер фиксированной части VisualSampleEntry до width/height и
+// дочерних боксов (ISO/IEC 14496-12, раздел 12.1.3)
+const visualSampleEntryHeaderLen = 8 + 6 + 2 + 2 + 2 + 12
+
+// parseVisualSampleEntry читает width/height из заголовка VisualSampleEntry и decoder
+// configuration record (avcC/hvcC) из дочерних боксов после него
+func parseVisualSampleEntry(entry []byte, configBox string) *mp4ProbeResult {
+	if len(entry) < visualSampleEntryHeaderLen+4 {
+		return nil
+	}
+	width := int(binary.BigEndian.Uint16(entry[visualSampleEntryHeaderLen : visualSampleEntryHeaderLen+2]))
+	height := int(binary.BigEndian.Uint16(entry[visualSampleEntryHeaderLen+2 : visualSampleEntryHeaderLen+4]))
+
+	// Дочерние боксы (avcC/hvcC/colr/pasp/...) идут после полного 78-байтового заголовка
+	// VisualSampleEntry, считая от начала entry (size+format включены)
+	const fullHeaderLen = 8 + 6 + 2 + 2 + 2 + 12 + 2 + 2 + 4 + 4 + 4 + 2 + 32 + 2 + 2
+	if len(entry) <= fullHeaderLen {
+		return &mp4ProbeResult{Width: width, Height: height}
+	}
+	config, ok := findMP4BoxIn(entry[fullHeaderLen:], configBox)
+	if !ok {
+		return &mp4ProbeResult{Width: width, Height: height}
+	}
+
+	result := &mp4ProbeResult{Width: width, Height: height}
+	if configBox == "avcC" {
+		profile, level, _, _, fps := parseAVCDecoderConfig(config)
+		result.VideoCodec, result.Profile, result.Level, result.FPS = "H.264", profile, level, fps
+	} else {
+		profile, level, _, _ := parseHEVCDecoderConfig(config)
+		result.VideoCodec, result.Profile, result.Level = "H.265", profile, level
+	}
+	return result
+}
+
+// findMP4Box ищет вложенный бокс по указанному пути имён (например "moov","trak",...,"stsd") и
+// возвращает его payload (без 8-байтового заголовка size+type); контейнерные боксы проходятся
+// напрямую, без интерпретации их собственного содержимого помимо вложенных боксов
+func findMP4Box(data []byte, path ...string) ([]byte, bool) {
+	cur := data
+	for _, name := range path {
+		box, ok := findMP4BoxIn(cur, name)
+		if !ok {
+			return nil, false
+		}
+		cur = box
+	}
+	return cur, true
+}
+
+// findMP4BoxIn ищет первый непосредственный дочерний бокс с указанным именем внутри payload
+// контейнерного бокса (ISO/IEC 14496-12, раздел 4.2 - box = size(4) + type(4) + payload)
+func findMP4BoxIn(payload []byte, name string) ([]byte, bool) {
+	pos := 0
+	for pos+8 <= len(payload) {
+		size := int(binary.BigEndian.Uint32(payload[pos : pos+4]))
+		boxType := string(payload[pos+4 : pos+8])
+
+		headerLen := 8
+		if size == 1 {
+			if pos+16 > len(payload) {
+				break
+			}
+			size = int(binary.BigEndian.Uint64(payload[pos+8 : pos+16]))
+			headerLen = 16
+		} else if size == 0 {
+			size = len(payload) - pos
+		}
+		if size < headerLen || pos+size > len(payload) {
+			break
+		}
+
+		if boxType == name {
+			return payload[pos+headerLen : pos+size], true
+		}
+		pos += size
+	}
+	return nil, false
+}
@@ -0,0 +1,245 @@
+package protocols
+
+import (
+	"fmt"
+
+	"github.com/local-video-server/internal/rtsp"
+)
+
+// Константы формата транспортного потока MPEG-2 (ISO/IEC 13818-1)
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+
+	tsPIDPAT = 0x0000
+
+	tsStreamTypeH264 = 0x1B
+	tsStreamTypeHEVC = 0x24
+	tsStreamTypeAAC  = 0x0F
+)
+
+// tsProbeResult - то, что удалось извлечь из первых пакетов транспортного потока сегмента HLS
+type tsProbeResult struct {
+	VideoCodec string // H.264 или H.265
+	Profile    string
+	Level      string
+	Width      int
+	Height     int
+	FPS        float64
+	AudioCodec string // AAC
+}
+
+// probeTSSegment разбирает PAT/PMT сегмента MPEG-TS, находит элементарные потоки видео/аудио и
+// извлекает параметры видео из первого SPS NAL, встреченного в потоке видео PID (ISO/IEC 13818-1,
+// разделы 2.4.4 (PAT/PMT) и 2.4.3.6 (PES))
+func probeTSSegment(data []byte) (*tsProbeResult, error) {
+	if len(data) < tsPacketSize {
+		return nil, fmt.Errorf("MPEG-TS segment too short")
+	}
+
+	var pmtPID = -1
+	var videoPID, videoStreamType = -1, 0
+	var audioPID = -1
+	videoPayload := make([]byte, 0, 4096)
+	videoPESDone := false
+	result := &tsProbeResult{}
+
+	for offset := 0; offset+tsPacketSize <= len(data); offset += tsPacketSize {
+		packet := data[offset : offset+tsPacketSize]
+		if packet[0] != tsSyncByte {
+			continue
+		}
+		payloadUnitStart := packet[1]&0x40 != 0
+		pid := (int(packet[1]&0x1F) << 8) | int(packet[2])
+		hasAdaptation := packet[3]&0x20 != 0
+		hasPayload := packet[3]&0x10 != 0
+		if !hasPayload {
+			continue
+		}
+
+		payloadStart := 4
+		if hasAdaptation {
+			if len(packet) <= 4 {
+				continue
+			}
+			payloadStart += 1 + int(packet[4])
+		}
+		if payloadStart >= len(packet) {
+			continue
+		}
+		payload := packet[payloadStart:]
+
+		switch {
+		case pid == tsPIDPAT:
+			if pmtPID == -1 {
+				pmtPID = parsePAT(payload, payloadUnitStart)
+			}
+		case pid == pmtPID:
+			if videoPID == -1 {
+				vPID, vType, aPID := parsePMT(payload, payloadUnitStart)
+				videoPID, videoStreamType, audioPID = vPID, vType, aPID
+			}
+		case pid == videoPID:
+			if payloadUnitStart && len(videoPayload) > 0 {
+				// Начался следующий PES - данных первого кадра достаточно для поиска SPS
+				videoPESDone = true
+			}
+			if !videoPESDone {
+				videoPayload = append(videoPayload, payload...)
+			}
+		case pid == audioPID:
+			if result.AudioCodec == "" {
+				result.AudioCodec = "AAC"
+			}
+		}
+
+		if videoPESDone || (videoPID != -1 && len(videoPayload) > 4096) {
+			break
+		}
+	}
+
+	if videoPID == -1 {
+		return result, nil
+	}
+
+	nal, annexB, err := extractFirstSPSFromPES(videoPayload)
+	if err != nil || nal == nil {
+		if videoStreamType == tsStreamTypeH264 {
+			result.VideoCodec = "H.264"
+		} else if videoStreamType == tsStreamTypeHEVC {
+			result.VideoCodec = "H.265"
+		}
+		return result, nil
+	}
+
+	if annexB && videoStreamType == tsStreamTypeH264 {
+		sps, err := rtsp.ParseH264SPSNAL(nal)
+		if err == nil {
+			result.VideoCodec = "H.264"
+			result.Profile = fmt.Sprintf("%d", sps.ProfileIDC)
+			result.Level = rtsp.FormatH264Level(sps.LevelIDC)
+			result.Width, result.Height, result.FPS = sps.Width, sps.Height, sps.FPS
+		}
+	} else if annexB && videoStreamType == tsStreamTypeHEVC {
+		sps, err := rtsp.ParseH265SPSNAL(nal)
+		if err == nil {
+			result.VideoCodec = "H.265"
+			result.Profile = fmt.Sprintf("%d", sps.ProfileIDC)
+			result.Level = rtsp.FormatH265Level(sps.LevelIDC)
+			result.Width, result.Height = sps.Width, sps.Height
+		}
+	}
+
+	return result, nil
+}
+
+// parsePAT разбирает Program Association Table и возвращает PID первой программы (PMT) - камеры
+// и энкодеры практически всегда публикуют ровно одну программу (ISO/IEC 13818-1, раздел 2.4.4.3)
+func parsePAT(payload []byte, payloadUnitStart bool) int {
+	section, ok := startOfSection(payload, payloadUnitStart)
+	if !ok || len(section) < 8 {
+		return -1
+	}
+	sectionLength := (int(section[1]&0x0F) << 8) | int(section[2])
+	if 3+sectionLength > len(section) {
+		return -1
+	}
+	// Программы начинаются после 8-байтового заголовка секции и идут до 4-байтового CRC
+	for i := 8; i+4 <= 3+sectionLength-1; i += 4 {
+		programNumber := (int(section[i]) << 8) | int(section[i+1])
+		pid := (int(section[i+2]&0x1F) << 8) | int(section[i+3])
+		if programNumber != 0 {
+			return pid
+		}
+	}
+	return -1
+}
+
+// parsePMT разбирает Program Map Table и возвращает PID/тип видео-потока (H.264 или HEVC) и PID
+// аудио-потока (AAC), если они перечислены (ISO/IEC 13818-1, раздел 2.4.4.8)
+func parsePMT(payload []byte, payloadUnitStart bool) (videoPID, videoStreamType, audioPID int) {
+	videoPID, audioPID = -1, -1
+
+	section, ok := startOfSection(payload, payloadUnitStart)
+	if !ok || len(section) < 12 {
+		return
+	}
+	sectionLength := (int(section[1]&0x0F) << 8) | int(section[2])
+	programInfoLength := (int(section[10]&0x0F) << 8) | int(section[11])
+
+	pos := 12 + programInfoLength
+	end := 3 + sectionLength - 4 // до CRC
+	for pos+5 <= end && pos+5 <= len(section) {
+		streamType := int(section[pos])
+		elementaryPID := (int(section[pos+1]&0x1F) << 8) | int(section[pos+2])
+		esInfoLength := (int(section[pos+3]&0x0F) << 8) | int(section[pos+4])
+
+		switch streamType {
+		case tsStreamTypeH264, tsStreamTypeHEVC:
+			if videoPID == -1 {
+				videoPID, videoStreamType = elementaryPID, streamType
+			}
+		case tsStreamTypeAAC:
+			if audioPID == -1 {
+				audioPID = elementaryPID
+			}
+		}
+
+		pos += 5 + esInfoLength
+	}
+	return
+}
+
+// startOfSection пропускает pointer_field в начале первого пакета секции PSI (ISO/IEC 13818-1,
+// раздел 2.4.4.1); в продолжающих пакетах той же секции pointer_field отсутствует
+func startOfSection(payload []byte, payloadUnitStart bool) ([]byte, bool) {
+	if !payloadUnitStart || len(payload) == 0 {
+		return payload, payloadUnitStart
+	}
+	pointerField := int(payload[0])
+	if 1+pointerField >= len(payload) {
+		return nil, false
+	}
+	return payload[1+pointerField:], true
+}
+
+// extractFirstSPSFromPES снимает заголовок PES (ISO/IEC 13818-1, раздел 2.4.3.6) с payload
+// видео-потока и возвращает первый встреченный NAL SPS (Annex B, с кодом начала стартовой
+// последовательности) вместе с признаком того, что это действительно NAL-поток Annex B
+func extractFirstSPSFromPES(payload []byte) (nal []byte, annexB bool, err error) {
+	if len(payload) < 9 || payload[0] != 0x00 || payload[1] != 0x00 || payload[2] != 0x01 {
+		return nil, false, fmt.Errorf("not a PES packet")
+	}
+	headerDataLength := int(payload[8])
+	esStart := 9 + headerDataLength
+	if esStart >= len(payload) {
+		return nil, false, fmt.Errorf("PES header longer than payload")
+	}
+	es := payload[esStart:]
+
+	for i := 0; i+4 < len(es); i++ {
+		if es[i] != 0x00 || es[i+1] != 0x00 || es[i+2] != 0x01 {
+			continue
+		}
+		nalStart := i + 3
+		nalType264 := es[nalStart] & 0x1F
+		nalType265 := (es[nalStart] >> 1) & 0x3F
+		if nalType264 == 7 || nalType265 == 33 {
+			nalEnd := findNextStartCode(es, nalStart+1)
+			return es[nalStart:nalEnd], true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// findNextStartCode ищет следующий код начала NAL (Annex B), начиная поиск с from, и возвращает
+// len(data), если до конца потока больше ни одного не встретилось
+func findNextStartCode(data []byte, from int) int {
+	for i := from; i+2 < len(data); i++ {
+		if data[i] == 0x00 && data[i+1] == 0x00 && data[i+2] == 0x01 {
+			return i
+		}
+	}
+	return len(data)
+}
@@ -0,0 +1,89 @@
+package protocols
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// httpFLVPaths - наиболее распространенные пути HTTP-FLV потоков (nginx-http-flv-module,
+// SRS, go2rtc и др.)
+var httpFLVPaths = []string{"/live.flv", "/live/stream.flv", "/flv/live.flv"}
+
+// flvMagic - первые 3 байта любого FLV файла ("FLV", ISO/IEC 14496 не относится - это
+// формат Adobe)
+const flvMagic = "FLV"
+
+// HTTPFLVDetector - детектор HTTP-FLV (FLV поверх обычного HTTP, без RTMP рукопожатия)
+type HTTPFLVDetector struct {
+	logger *logrus.Logger
+}
+
+// NewHTTPFLVDetector создает новый HTTP-FLV детектор
+func NewHTTPFLVDetector() *HTTPFLVDetector {
+	return &HTTPFLVDetector{
+		logger: utils.GetLogger(),
+	}
+}
+
+// GetName возвращает название протокола
+func (d *HTTPFLVDetector) GetName() string {
+	return "HTTP-FLV"
+}
+
+// GetDefaultPort возвращает порт по умолчанию
+func (d *HTTPFLVDetector) GetDefaultPort() int {
+	return 80
+}
+
+// Detect перебирает распространенные HTTP-FLV пути, подтверждая совпадение по
+// Content-Type video/x-flv или по магическим байтам "FLV" в начале тела ответа
+func (d *HTTPFLVDetector) Detect(ip string, port int, timeout time.Duration) (*models.Protocol, error) {
+	protocol := &models.Protocol{
+		Type:       "HTTP-FLV",
+		Port:       port,
+		Available:  false,
+		DetectedAt: time.Now(),
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	for _, path := range httpFLVPaths {
+		url := fmt.Sprintf("http://%s:%d%s", ip, port, path)
+		if d.probe(client, url) {
+			protocol.Available = true
+			protocol.URL = url
+			d.logger.Debugf("Found HTTP-FLV stream at %s", url)
+			return protocol, nil
+		}
+	}
+
+	return protocol, fmt.Errorf("no HTTP-FLV stream found on %s:%d", ip, port)
+}
+
+func (d *HTTPFLVDetector) probe(client *http.Client, url string) bool {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Range", "bytes=0-8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	if strings.Contains(resp.Header.Get("Content-Type"), "video/x-flv") {
+		return true
+	}
+
+	buf := make([]byte, len(flvMagic))
+	n, _ := resp.Body.Read(buf)
+	return n == len(flvMagic) && string(buf) == flvMagic
+}
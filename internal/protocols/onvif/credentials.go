@@ -0,0 +1,95 @@
+package onvif
+
+import (
+	"net"
+	"sort"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// ResolveCredentials возвращает учетные данные, которые стоит перебрать для устройства ip,
+// в порядке приоритета: точное совпадение по IP в byNetwork, затем совпадение по CIDR в
+// byNetwork, затем fallback (обычно ScanConfig.ONVIFUsername/Password), затем defaults
+// (ScanConfig.ONVIFDefaultCredentials). Повторяющиеся пары и пустая Credentials{} из
+// fallback/defaults отбрасываются; если после этого список пуст, возвращается одна
+// анонимная Credentials{} - большинство камер без настроенной авторизации отвечают на нее
+func ResolveCredentials(ip string, byNetwork map[string]Credentials, fallback Credentials, defaults []Credentials) []Credentials {
+	var ordered []Credentials
+	seen := make(map[Credentials]bool)
+
+	add := func(c Credentials) {
+		if c.Username == "" && c.Password == "" {
+			return
+		}
+		if seen[c] {
+			return
+		}
+		seen[c] = true
+		ordered = append(ordered, c)
+	}
+
+	if c, ok := byNetwork[ip]; ok {
+		add(c)
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP != nil {
+		// Перебираем ключи byNetwork в отсортированном порядке - map не гарантирует порядок
+		// итерации, а при нескольких пересекающихся CIDR он должен быть детерминированным:
+		// иначе при нескольких неверных парах камера с ограничением числа попыток входа
+		// получала бы от раза к разу разные логины в случайном порядке
+		keys := make([]string, 0, len(byNetwork))
+		for key := range byNetwork {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			_, network, err := net.ParseCIDR(key)
+			if err != nil || !network.Contains(parsedIP) {
+				continue
+			}
+			add(byNetwork[key])
+		}
+	}
+
+	add(fallback)
+	for _, c := range defaults {
+		add(c)
+	}
+
+	if len(ordered) == 0 {
+		ordered = append(ordered, Credentials{})
+	}
+
+	return ordered
+}
+
+// CredentialConfig конвертирует ScanConfig.ONVIFCredentialsByNetwork/ONVIFUsername+
+// ONVIFPassword/ONVIFDefaultCredentials в byNetwork/fallback/defaults, которых ожидают
+// ResolveCredentials и Discover/DiscoverOnInterface - каждый вызывающий ONVIF код строил эту
+// тройку вручную по месту (see internal/scanner.Detector.Scan,
+// internal/scanner.ONVIFScanner.startEventWatch)
+func CredentialConfig(cfg *models.ScanConfig) (byNetwork map[string]Credentials, fallback Credentials, defaults []Credentials) {
+	byNetwork = make(map[string]Credentials, len(cfg.ONVIFCredentialsByNetwork))
+	for key, c := range cfg.ONVIFCredentialsByNetwork {
+		byNetwork[key] = Credentials{Username: c.Username, Password: c.Password}
+	}
+
+	fallback = Credentials{Username: cfg.ONVIFUsername, Password: cfg.ONVIFPassword}
+
+	defaults = make([]Credentials, len(cfg.ONVIFDefaultCredentials))
+	for i, c := range cfg.ONVIFDefaultCredentials {
+		defaults[i] = Credentials{Username: c.Username, Password: c.Password}
+	}
+
+	return byNetwork, fallback, defaults
+}
+
+// CredentialCandidates возвращает ResolveCredentials(ip, ...), построенный прямо из
+// ScanConfig через CredentialConfig - для кода, который (в отличие от Discover) уже знает IP
+// конкретного устройства наперед
+func CredentialCandidates(ip string, cfg *models.ScanConfig) []Credentials {
+	byNetwork, fallback, defaults := CredentialConfig(cfg)
+	return ResolveCredentials(ip, byNetwork, fallback, defaults)
+}
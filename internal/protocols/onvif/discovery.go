@@ -0,0 +1,119 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// wsDiscoveryAddr - multicast адрес и порт WS-Discovery (ONVIF всегда использует
+// 239.255.255.250:3702, см. ONVIF WS-Discovery Specification)
+const wsDiscoveryAddr = "239.255.255.250:3702"
+
+// probeTemplate - WS-Discovery Probe сообщение, запрашивающее устройства типа
+// NetworkVideoTransmitter (т.е. ONVIF камеры, а не NVR/VMS)
+const probeTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"
+            xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing"
+            xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery"
+            xmlns:dn="http://www.onvif.org/ver10/network/wsdl">
+    <s:Header>
+        <a:Action s:mustUnderstand="1">http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</a:Action>
+        <a:MessageID>%s</a:MessageID>
+        <a:To s:mustUnderstand="1">urn:schemas-xmlsoap-org:ws:2005:04:discovery</a:To>
+    </s:Header>
+    <s:Body>
+        <d:Probe>
+            <d:Types>dn:NetworkVideoTransmitter</d:Types>
+        </d:Probe>
+    </s:Body>
+</s:Envelope>`
+
+type probeEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    struct {
+		ProbeMatches struct {
+			ProbeMatch []struct {
+				XAddrs string `xml:"XAddrs"`
+			} `xml:"ProbeMatch"`
+		} `xml:"ProbeMatches"`
+	} `xml:"Body"`
+}
+
+// Probe рассылает WS-Discovery Probe мультикастом со всех интерфейсов и в течение
+// timeout собирает адреса device service (XAddrs) откликнувшихся устройств. В отличие
+// от scanner.ONVIFScanner, который на этом и останавливается, эти XAddrs передаются в
+// NewClient для последующего GetProfiles/GetStreamUri
+func Probe(ctx context.Context, timeout time.Duration) ([]string, error) {
+	return probe(ctx, nil, timeout)
+}
+
+// ProbeOnInterface работает как Probe, но привязывает сокет обнаружения к одному
+// конкретному сетевому интерфейсу вместо того, чтобы слушать на всех - нужно, когда на
+// хосте несколько интерфейсов и только один из них ведет в VLAN с камерами (см.
+// vigilos/pkg/camera.ONVIFDiscovery)
+func ProbeOnInterface(ctx context.Context, ifaceName string, timeout time.Duration) ([]string, error) {
+	ifi, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up interface %s: %w", ifaceName, err)
+	}
+	return probe(ctx, ifi, timeout)
+}
+
+func probe(ctx context.Context, ifi *net.Interface, timeout time.Duration) ([]string, error) {
+	dst, err := net.ResolveUDPAddr("udp4", wsDiscoveryAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve WS-Discovery address: %w", err)
+	}
+
+	var conn *net.UDPConn
+	if ifi != nil {
+		conn, err = net.ListenMulticastUDP("udp4", ifi, dst)
+	} else {
+		conn, err = net.ListenUDP("udp4", nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	defer conn.Close()
+
+	messageID := fmt.Sprintf("urn:uuid:%x", time.Now().UnixNano())
+	probeMsg := fmt.Sprintf(probeTemplate, messageID)
+	if _, err := conn.WriteToUDP([]byte(probeMsg), dst); err != nil {
+		return nil, fmt.Errorf("failed to send Probe message: %w", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	var xaddrs []string
+	seen := make(map[string]bool)
+	buf := make([]byte, 8192)
+	for {
+		if ctx.Err() != nil {
+			break
+		}
+
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+
+		var env probeEnvelope
+		if err := xml.Unmarshal(buf[:n], &env); err != nil {
+			continue
+		}
+		for _, match := range env.Body.ProbeMatches.ProbeMatch {
+			for _, addr := range strings.Fields(match.XAddrs) {
+				if !seen[addr] {
+					seen[addr] = true
+					xaddrs = append(xaddrs, addr)
+				}
+			}
+		}
+	}
+
+	return xaddrs, nil
+}
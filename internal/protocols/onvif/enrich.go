@@ -0,0 +1,155 @@
+package onvif
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// DefaultProbeTimeout - сколько ждать ProbeMatch ответов после WS-Discovery Probe
+const DefaultProbeTimeout = 3 * time.Second
+
+// Discover выполняет WS-Discovery Probe и для каждого откликнувшегося устройства
+// запрашивает его медиа профили и настоящие RTSP URI через GetProfiles/GetStreamUri,
+// вместо того чтобы полагаться на угадывание пути в RTSPDetector.Detect. Учетные данные
+// для каждого устройства выбираются ResolveCredentials(ip, byNetwork, fallback, defaults) -
+// см. models.ScanConfig.ONVIFCredentialsByNetwork/ONVIFDefaultCredentials. Ошибка опроса
+// отдельного устройства не прерывает Discover целиком - такое устройство просто не
+// попадет в результат
+func Discover(ctx context.Context, timeout time.Duration, byNetwork map[string]Credentials, fallback Credentials, defaults []Credentials) ([]*models.Device, error) {
+	xaddrs, err := Probe(ctx, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return enrichXAddrs(xaddrs, byNetwork, fallback, defaults), nil
+}
+
+// DiscoverOnInterface работает как Discover, но ограничивает WS-Discovery Probe одним
+// сетевым интерфейсом через ProbeOnInterface - нужно, когда на хосте несколько
+// интерфейсов и только один из них ведет в VLAN с камерами (см.
+// vigilos/pkg/camera.ONVIFDiscovery)
+func DiscoverOnInterface(ctx context.Context, ifaceName string, timeout time.Duration, byNetwork map[string]Credentials, fallback Credentials, defaults []Credentials) ([]*models.Device, error) {
+	xaddrs, err := ProbeOnInterface(ctx, ifaceName, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return enrichXAddrs(xaddrs, byNetwork, fallback, defaults), nil
+}
+
+// enrichXAddrs опрашивает каждый найденный Probe'ом device service XAddr через
+// GetDeviceInformation/GetNetworkInterfaces/GetProfiles/GetStreamUri/GetSnapshotUri и
+// собирает из ответов models.Device. Перед первым authenticated вызовом перебирает
+// учетные данные, подходящие IP устройства (см. ResolveCredentials/ProbeCredentials).
+// Ошибка опроса отдельного устройства не прерывает обход целиком - такое устройство
+// просто не попадет в результат
+func enrichXAddrs(xaddrs []string, byNetwork map[string]Credentials, fallback Credentials, defaults []Credentials) []*models.Device {
+	logger := utils.GetLogger()
+
+	devices := make([]*models.Device, 0, len(xaddrs))
+	for _, xaddr := range xaddrs {
+		ip := ipFromXAddr(xaddr)
+		if ip == "" {
+			continue
+		}
+
+		candidates := ResolveCredentials(ip, byNetwork, fallback, defaults)
+		client, profiles, err := ProbeCredentials(xaddr, candidates)
+		if err != nil {
+			logger.Debugf("ONVIF GetProfiles failed for %s: %v", xaddr, err)
+			continue
+		}
+
+		device := &models.Device{
+			IP:           ip,
+			DiscoveredAt: time.Now(),
+		}
+
+		if info, err := client.GetDeviceInformation(); err != nil {
+			logger.Debugf("ONVIF GetDeviceInformation failed for %s: %v", xaddr, err)
+		} else {
+			device.Manufacturer = info.Manufacturer
+			device.Model = info.Model
+			device.FirmwareVersion = info.FirmwareVersion
+			device.SerialNumber = info.SerialNumber
+		}
+
+		if mac, err := client.GetNetworkInterfaces(); err != nil {
+			logger.Debugf("ONVIF GetNetworkInterfaces failed for %s: %v", xaddr, err)
+		} else {
+			device.MAC = mac
+		}
+
+		for _, profile := range profiles {
+			streamURI, err := client.GetStreamUri(profile.Token)
+			if err != nil {
+				logger.Debugf("ONVIF GetStreamUri failed for %s profile %s: %v", xaddr, profile.Token, err)
+				continue
+			}
+
+			snapshotURI, err := client.GetSnapshotUri(profile.Token)
+			if err != nil {
+				logger.Debugf("ONVIF GetSnapshotUri failed for %s profile %s: %v", xaddr, profile.Token, err)
+			}
+
+			device.PTZ = device.PTZ || profile.PTZ
+			device.RTSPStreams = append(device.RTSPStreams, models.RTSPStreamInfo{
+				URL:         streamURI,
+				Codec:       profile.Codec,
+				Resolution:  profile.Resolution,
+				FPS:         profile.FPS,
+				Available:   true,
+				CheckedAt:   time.Now(),
+				ProfileName: profile.Name,
+				SnapshotURL: snapshotURI,
+			})
+		}
+
+		if len(device.RTSPStreams) == 0 {
+			continue
+		}
+
+		device.Protocols = append(device.Protocols, models.Protocol{
+			Type:       "RTSP",
+			Port:       rtspPort(device.RTSPStreams[0].URL),
+			URL:        device.RTSPStreams[0].URL,
+			Available:  true,
+			DetectedAt: time.Now(),
+		})
+
+		devices = append(devices, device)
+	}
+
+	return devices
+}
+
+// ipFromXAddr извлекает IP из адреса ONVIF сервиса вида
+// "http://192.168.1.10:80/onvif/device_service"
+func ipFromXAddr(xaddr string) string {
+	host := strings.TrimPrefix(xaddr, "http://")
+	host = strings.TrimPrefix(host, "https://")
+	if idx := strings.IndexByte(host, '/'); idx >= 0 {
+		host = host[:idx]
+	}
+	if idx := strings.IndexByte(host, ':'); idx >= 0 {
+		host = host[:idx]
+	}
+	return host
+}
+
+// rtspPort извлекает порт из RTSP URI, возвращенного GetStreamUri, по умолчанию 554
+func rtspPort(rtspURL string) int {
+	u, err := url.Parse(rtspURL)
+	if err != nil || u.Port() == "" {
+		return 554
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return 554
+	}
+	return port
+}
@@ -0,0 +1,177 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SOAPAction константы для Events сервиса (ONVIF Core Specification, PullPoint Notification
+// Interface) и WS-Eventing SubscriptionManager (Renew/Unsubscribe применяются к
+// SubscriptionReference, возвращенному CreatePullPointSubscription, а не к Events XAddr)
+const (
+	createPullPointSubscriptionAction = "http://www.onvif.org/ver10/events/wsdl/EventPortType/CreatePullPointSubscriptionRequest"
+	pullMessagesAction                = "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/PullMessagesRequest"
+	renewAction                       = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/RenewRequest"
+	unsubscribeAction                 = "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/UnsubscribeRequest"
+)
+
+// Subscription - активная ONVIF PullPoint подписка. Адресуется SubscriptionReference,
+// который устройство вернуло в ответ на CreatePullPointSubscription - он не обязан совпадать
+// с Events XAddr, с которого подписка была открыта
+type Subscription struct {
+	manager    string
+	creds      Credentials
+	httpClient *http.Client
+}
+
+type createPullPointSubscriptionResponse struct {
+	Body struct {
+		CreatePullPointSubscriptionResponse struct {
+			SubscriptionReference struct {
+				Address string `xml:"Address"`
+			} `xml:"SubscriptionReference"`
+		} `xml:"CreatePullPointSubscriptionResponse"`
+	} `xml:"Body"`
+}
+
+// CreatePullPointSubscription открывает PullPoint подписку на Events сервисе устройства
+// (xaddr - Capabilities.Events из GetCapabilities) сроком на duration. Дальнейшие
+// PullMessages/Renew/Unsubscribe адресуются на SubscriptionReference, который устройство
+// вернуло в ответе, а не на xaddr
+func CreatePullPointSubscription(xaddr string, creds Credentials, duration time.Duration) (*Subscription, error) {
+	httpClient := &http.Client{Timeout: httpTimeout}
+
+	body := fmt.Sprintf(`<CreatePullPointSubscription xmlns="http://www.onvif.org/ver10/events/wsdl">
+    <InitialTerminationTime>%s</InitialTerminationTime>
+</CreatePullPointSubscription>`, formatDuration(duration))
+
+	data, err := soapCall(httpClient, xaddr, createPullPointSubscriptionAction, body, creds)
+	if err != nil {
+		return nil, fmt.Errorf("CreatePullPointSubscription failed: %w", err)
+	}
+
+	var resp createPullPointSubscriptionResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse CreatePullPointSubscription response: %w", err)
+	}
+
+	manager := resp.Body.CreatePullPointSubscriptionResponse.SubscriptionReference.Address
+	if manager == "" {
+		manager = xaddr
+	}
+
+	return &Subscription{manager: manager, creds: creds, httpClient: httpClient}, nil
+}
+
+// formatDuration форматирует d как секундный ISO 8601 duration (PTnS), которого ожидают
+// InitialTerminationTime/Renew/PullMessages Timeout
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%dS", int(d.Seconds()))
+}
+
+// NotificationMessage - одно разобранное сообщение PullMessages ответа, еще не привязанное к
+// устройству - DeviceIP/EndpointRef проставляет events.Watch
+type NotificationMessage struct {
+	Topic     string
+	Timestamp time.Time
+	Data      map[string]string
+	IsActive  bool
+}
+
+type pullMessagesResponse struct {
+	Body struct {
+		PullMessagesResponse struct {
+			NotificationMessage []struct {
+				Topic struct {
+					Value string `xml:",chardata"`
+				} `xml:"Topic"`
+				Message struct {
+					Message struct {
+						UtcTime string `xml:"UtcTime,attr"`
+						Data    struct {
+							SimpleItem []struct {
+								Name  string `xml:"Name,attr"`
+								Value string `xml:"Value,attr"`
+							} `xml:"SimpleItem"`
+						} `xml:"Data"`
+					} `xml:"Message"`
+				} `xml:"Message"`
+			} `xml:"NotificationMessage"`
+		} `xml:"PullMessagesResponse"`
+	} `xml:"Body"`
+}
+
+// PullMessages делает один long-poll запрос к подписке, ожидая новых уведомлений до timeout,
+// но не больше limit штук за раз (MessageLimit)
+func (s *Subscription) PullMessages(timeout time.Duration, limit int) ([]NotificationMessage, error) {
+	body := fmt.Sprintf(`<PullMessages xmlns="http://www.onvif.org/ver10/events/wsdl">
+    <Timeout>%s</Timeout>
+    <MessageLimit>%d</MessageLimit>
+</PullMessages>`, formatDuration(timeout), limit)
+
+	// long-poll таймаут запроса должен быть больше Timeout, который мы просим устройство
+	// соблюдать, иначе http.Client оборвет соединение раньше, чем устройство успеет
+	// ответить пустым PullMessagesResponse по истечении Timeout
+	client := &http.Client{Timeout: timeout + httpTimeout}
+
+	data, err := soapCall(client, s.manager, pullMessagesAction, body, s.creds)
+	if err != nil {
+		return nil, fmt.Errorf("PullMessages failed: %w", err)
+	}
+
+	var resp pullMessagesResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse PullMessages response: %w", err)
+	}
+
+	messages := make([]NotificationMessage, 0, len(resp.Body.PullMessagesResponse.NotificationMessage))
+	for _, nm := range resp.Body.PullMessagesResponse.NotificationMessage {
+		item := nm.Message.Message
+		data := make(map[string]string, len(item.Data.SimpleItem))
+		isActive := false
+		for _, si := range item.Data.SimpleItem {
+			data[si.Name] = si.Value
+			if strings.EqualFold(si.Name, "State") {
+				isActive = si.Value == "true" || si.Value == "1"
+			}
+		}
+
+		timestamp, err := time.Parse(time.RFC3339, item.UtcTime)
+		if err != nil {
+			timestamp = time.Now()
+		}
+
+		messages = append(messages, NotificationMessage{
+			Topic:     strings.TrimSpace(nm.Topic.Value),
+			Timestamp: timestamp,
+			Data:      data,
+			IsActive:  isActive,
+		})
+	}
+
+	return messages, nil
+}
+
+// Renew продлевает срок действия подписки на duration от текущего момента - должен
+// вызываться до TerminationTime, иначе устройство удалит подписку и следующий PullMessages
+// ответит ошибкой (см. events.Watch)
+func (s *Subscription) Renew(duration time.Duration) error {
+	body := fmt.Sprintf(`<Renew xmlns="http://docs.oasis-open.org/wsn/b-2"><TerminationTime>%s</TerminationTime></Renew>`, formatDuration(duration))
+	if _, err := soapCall(s.httpClient, s.manager, renewAction, body, s.creds); err != nil {
+		return fmt.Errorf("Renew failed: %w", err)
+	}
+	return nil
+}
+
+// Unsubscribe завершает подписку раньше TerminationTime, чтобы устройство освободило
+// связанные с ней ресурсы
+func (s *Subscription) Unsubscribe() error {
+	body := `<Unsubscribe xmlns="http://docs.oasis-open.org/wsn/b-2"/>`
+	if _, err := soapCall(s.httpClient, s.manager, unsubscribeAction, body, s.creds); err != nil {
+		return fmt.Errorf("Unsubscribe failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package onvif
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Credentials - учетные данные устройства для WS-UsernameToken аутентификации. Пустой
+// Username означает анонимный запрос без заголовка Security
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// usernameToken строит WS-Security UsernameToken заголовок с PasswordDigest (WS-Security
+// UsernameToken Profile 1.0): Digest = Base64(SHA1(Nonce + Created + Password))
+func usernameToken(creds Credentials) string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	created := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(creds.Password))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	nonceB64 := base64.StdEncoding.EncodeToString(nonce)
+
+	return fmt.Sprintf(`<wsse:Security s:mustUnderstand="1" xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd" xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">
+    <wsse:UsernameToken>
+        <wsse:Username>%s</wsse:Username>
+        <wsse:Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">%s</wsse:Password>
+        <wsse:Nonce EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">%s</wsse:Nonce>
+        <wsu:Created>%s</wsu:Created>
+    </wsse:UsernameToken>
+</wsse:Security>`, creds.Username, digest, nonceB64, created)
+}
+
+// soapCall отправляет SOAP 1.2 запрос на endpoint и возвращает тело ответа. WS-Security
+// заголовок добавляется только если заданы учетные данные
+func soapCall(client *http.Client, endpoint, action, body string, creds Credentials) ([]byte, error) {
+	security := ""
+	if creds.Username != "" {
+		security = usernameToken(creds)
+	}
+
+	envelope := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">
+    <s:Header>%s</s:Header>
+    <s:Body>%s</s:Body>
+</s:Envelope>`, security, body)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBufferString(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SOAP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+	req.Header.Set("SOAPAction", action)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SOAP request to %s failed: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SOAP response from %s: %w", endpoint, err)
+	}
+
+	if reason, isFault := soapFaultReason(data); isFault {
+		return data, fmt.Errorf("SOAP fault from %s: %s", endpoint, reason)
+	}
+
+	if resp.StatusCode >= 300 {
+		return data, fmt.Errorf("SOAP request to %s failed with HTTP status %s", endpoint, resp.Status)
+	}
+
+	return data, nil
+}
+
+// soapFaultResponse разбирает только s:Fault из SOAP 1.2 конверта - структуру ответа
+// устройство заполняет вместо обычного *Response, когда запрос отклонен (неверные учетные
+// данные помимо 401 на уровне HTTP, недопустимое значение параметра, нарушение политики
+// устройства и т.п.). Fault - указатель, а не встроенная структура, чтобы отличить "элемента
+// Fault не было вовсе" от "Fault был, но без Reason/Text" (некоторые прошивки камер
+// присылают Fault с пустым Reason) - в обоих случаях Body интересует только наличие самого
+// Fault, остальные поля ответа выше в Body игнорируются
+type soapFaultResponse struct {
+	Body struct {
+		Fault *struct {
+			Reason struct {
+				Text string `xml:"Text"`
+			} `xml:"Reason"`
+		} `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+// soapFaultReason сообщает, содержит ли data SOAP Fault, и если да - его текст причины (или
+// "unknown fault reason", если устройство прислало Fault без Reason/Text). До добавления
+// этой проверки soapCall возвращал тело Fault как обычный успешный ответ: SetUser/CreateUsers
+// (см. users.go) в частности приняли бы отклоненную devices смену пароля за успех, поскольку
+// xml.Unmarshal не проваливается на структуре ответа, у которой просто не заполнились
+// ожидаемые поля
+func soapFaultReason(data []byte) (string, bool) {
+	var resp soapFaultResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return "", false
+	}
+	if resp.Body.Fault == nil {
+		return "", false
+	}
+	if resp.Body.Fault.Reason.Text == "" {
+		return "unknown fault reason", true
+	}
+	return resp.Body.Fault.Reason.Text, true
+}
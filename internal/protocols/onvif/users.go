@@ -0,0 +1,91 @@
+package onvif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// setUserAction / createUsersAction - пространства имен SOAPAction Device сервиса для
+// изменения и создания учетных записей (ONVIF Core Specification, раздел User Handling)
+const (
+	setUserAction     = "http://www.onvif.org/ver10/device/wsdl/SetUser"
+	createUsersAction = "http://www.onvif.org/ver10/device/wsdl/CreateUsers"
+)
+
+// escapeXMLText экранирует username/password, подставляемые в SOAP тело через
+// fmt.Sprintf, - в отличие от profileToken в client.go (внутренний идентификатор,
+// полученный от самого устройства), это значения, которые вызывающий код может получить
+// откуда угодно (сгенерированный пароль, пользовательский ввод), и символы вроде "&"/"<"
+// в них иначе сломали бы XML или подставили лишние элементы
+func escapeXMLText(s string) string {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// SetUser меняет пароль уже существующей на устройстве учетной записи username на
+// newPassword через Device сервис. UserLevel передается Administrator, так как именно с ней
+// обычно заведена заводская учетная запись, чей пароль меняет RotateUser
+func (c *Client) SetUser(username, newPassword string) error {
+	body := fmt.Sprintf(`<SetUser xmlns="http://www.onvif.org/ver10/device/wsdl">
+    <User xmlns="http://www.onvif.org/ver10/schema">
+        <Username>%s</Username>
+        <Password>%s</Password>
+        <UserLevel>Administrator</UserLevel>
+    </User>
+</SetUser>`, escapeXMLText(username), escapeXMLText(newPassword))
+
+	if _, err := soapCall(c.httpClient, c.xaddr, setUserAction, body, c.creds); err != nil {
+		return fmt.Errorf("SetUser failed for %s: %w", username, err)
+	}
+	return nil
+}
+
+// CreateUsers заводит новую учетную запись username/password на устройстве через Device
+// сервис - запасной вариант RotateUser, если SetUser вернул ошибку потому, что на
+// устройстве еще нет учетной записи с таким именем (например, при первичной настройке
+// камеры "из коробки", отвечающей анонимно)
+func (c *Client) CreateUsers(username, password string) error {
+	body := fmt.Sprintf(`<CreateUsers xmlns="http://www.onvif.org/ver10/device/wsdl">
+    <User xmlns="http://www.onvif.org/ver10/schema">
+        <Username>%s</Username>
+        <Password>%s</Password>
+        <UserLevel>Administrator</UserLevel>
+    </User>
+</CreateUsers>`, escapeXMLText(username), escapeXMLText(password))
+
+	if _, err := soapCall(c.httpClient, c.xaddr, createUsersAction, body, c.creds); err != nil {
+		return fmt.Errorf("CreateUsers failed for %s: %w", username, err)
+	}
+	return nil
+}
+
+// RotateUser меняет пароль учетной записи username на устройстве, к которому уже
+// подключен c (обычно *Client, полученный из ProbeDeviceService/ProbeCredentials под
+// старыми учетными данными): пробует SetUser первым (самый частый случай - учетная запись
+// уже существует), и только если он не сработал, CreateUsers - та же стратегия "попробовать
+// основной способ, откатиться на запасной", что у GetProfiles (media10/media2). Принятый
+// компромисс: откат на CreateUsers происходит при ЛЮБОЙ ошибке SetUser, не только
+// "учетная запись не найдена" - ONVIF Fault не стандартизирует код этой причины достаточно
+// надежно между производителями, чтобы отличать ее от прочих ошибок (временная сетевая, прав
+// доступа). Правдоподобный неудачный сценарий - создание лишней учетной записи вместо
+// повторной ошибки; registry.DeviceRegistry.RotateCredential.auditCredentialEvent логирует
+// каждый такой случай, так что он не проходит незамеченным
+func (c *Client) RotateUser(username, newPassword string) error {
+	if err := c.SetUser(username, newPassword); err == nil {
+		return nil
+	} else if createErr := c.CreateUsers(username, newPassword); createErr != nil {
+		return fmt.Errorf("SetUser failed (%v) and CreateUsers fallback also failed: %w", err, createErr)
+	}
+	return nil
+}
+
+// RotateUser - вариант Client.RotateUser для вызывающих, у которых еще нет готового
+// *Client (например, отката на старые учетные данные после смены пароля - клиент,
+// подключенный под новым паролем, для этого не годится, нужен новый с oldCreds)
+func RotateUser(xaddr string, oldCreds Credentials, username, newPassword string) error {
+	return NewClient(xaddr, oldCreds).RotateUser(username, newPassword)
+}
@@ -0,0 +1,59 @@
+package protocols
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols/onvif"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// ONVIFHTTPDetector - детектор ONVIF device service по уже известному ip:port. В
+// отличие от onvif.Discover, который находит устройства мультикастом WS-Discovery,
+// этот детектор подтверждает ONVIF на конкретном открытом HTTP порту вызовом GetProfiles
+type ONVIFHTTPDetector struct {
+	logger *logrus.Logger
+}
+
+// NewONVIFHTTPDetector создает новый ONVIF HTTP детектор
+func NewONVIFHTTPDetector() *ONVIFHTTPDetector {
+	return &ONVIFHTTPDetector{
+		logger: utils.GetLogger(),
+	}
+}
+
+// GetName возвращает название протокола
+func (d *ONVIFHTTPDetector) GetName() string {
+	return "ONVIF"
+}
+
+// GetDefaultPort возвращает порт по умолчанию
+func (d *ONVIFHTTPDetector) GetDefaultPort() int {
+	return 80
+}
+
+// Detect проверяет наличие ONVIF device service на устройстве, запрашивая GetProfiles
+// по стандартному пути /onvif/device_service
+func (d *ONVIFHTTPDetector) Detect(ip string, port int, timeout time.Duration) (*models.Protocol, error) {
+	protocol := &models.Protocol{
+		Type:       "ONVIF",
+		Port:       port,
+		Available:  false,
+		DetectedAt: time.Now(),
+	}
+
+	xaddr := fmt.Sprintf("http://%s:%d/onvif/device_service", ip, port)
+	client := onvif.NewClient(xaddr, onvif.Credentials{})
+
+	profiles, err := client.GetProfiles()
+	if err != nil {
+		return protocol, fmt.Errorf("not an ONVIF device service: %w", err)
+	}
+
+	protocol.Available = true
+	protocol.URL = xaddr
+	d.logger.Debugf("Confirmed ONVIF device service at %s (%d profiles)", xaddr, len(profiles))
+	return protocol, nil
+}
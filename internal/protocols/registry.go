@@ -0,0 +1,190 @@
+package protocols
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// BannerProbe - дешевая проверка, стоит ли вообще запускать дорогой Detect для этого
+// порта: как правило, просто TCP/UDP connect, иногда - сверка первых байт баннера
+type BannerProbe func(ip string, port int, timeout time.Duration) bool
+
+// registration - один зарегистрированный детектор протокола со своими портами по
+// умолчанию и banner-проверкой
+type registration struct {
+	detector Detector
+	ports    []int
+	banner   BannerProbe
+}
+
+// ProtocolDetectorRegistry - реестр детекторов протоколов с двухфазной проверкой:
+// сначала дешевый banner-проб всех детекторов, зарегистрированных на порт
+// (параллельно), затем дорогой Detect (deep inspection) только для тех, чей banner
+// совпал. Заменяет прежний NetworkScanner.detectProtocol, который присваивал протокол
+// по одному лишь номеру порта
+type ProtocolDetectorRegistry struct {
+	mu            sync.RWMutex
+	registrations []*registration
+	byPort        map[int][]*registration
+}
+
+// NewProtocolDetectorRegistry создает пустой реестр
+func NewProtocolDetectorRegistry() *ProtocolDetectorRegistry {
+	return &ProtocolDetectorRegistry{byPort: make(map[int][]*registration)}
+}
+
+// defaultRegistry - глобальный реестр, в который самостоятельно регистрируются
+// встроенные детекторы через init() (см. builtins.go)
+var defaultRegistry = NewProtocolDetectorRegistry()
+
+// DefaultRegistry возвращает глобальный реестр со всеми встроенными детекторами
+func DefaultRegistry() *ProtocolDetectorRegistry {
+	return defaultRegistry
+}
+
+// RegisterDetector регистрирует детектор в глобальном реестре под его порт по
+// умолчанию (GetDefaultPort) с TCP banner-проверкой "порт открыт". Детекторам, которым
+// нужны дополнительные порты или UDP, следует использовать RegisterDetectorWithOptions.
+// Вызывается из init() встроенных детекторов, а также сторонним кодом, добавляющим
+// собственные протоколы без форка репозитория
+func RegisterDetector(d Detector) {
+	RegisterDetectorWithOptions(d, []int{d.GetDefaultPort()}, defaultTCPBanner)
+}
+
+// RegisterDetectorWithOptions регистрирует детектор с явным списком портов и
+// собственной banner-проверкой (например, defaultUDPBanner для протоколов поверх UDP)
+func RegisterDetectorWithOptions(d Detector, ports []int, banner BannerProbe) {
+	defaultRegistry.Register(d, ports, banner)
+}
+
+// Register регистрирует детектор в этом реестре
+func (r *ProtocolDetectorRegistry) Register(d Detector, ports []int, banner BannerProbe) {
+	if banner == nil {
+		banner = defaultTCPBanner
+	}
+	reg := &registration{detector: d, ports: ports, banner: banner}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registrations = append(r.registrations, reg)
+	for _, port := range ports {
+		r.byPort[port] = append(r.byPort[port], reg)
+	}
+}
+
+// Probe проверяет один ip:port: сначала параллельно запускает banner-проверки всех
+// детекторов, зарегистрированных на этот порт, затем deep inspection (Detect) только
+// для тех, чей banner совпал. На одном порту может легитимно ужиться несколько
+// протоколов (например HTTP и HTTP-FLV), поэтому возвращается срез
+func (r *ProtocolDetectorRegistry) Probe(ip string, port int, timeout time.Duration) []models.Protocol {
+	r.mu.RLock()
+	candidates := append([]*registration(nil), r.byPort[port]...)
+	r.mu.RUnlock()
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	logger := utils.GetLogger()
+
+	matched := make(chan *registration, len(candidates))
+	var wg sync.WaitGroup
+	for _, reg := range candidates {
+		wg.Add(1)
+		go func(reg *registration) {
+			defer wg.Done()
+			if reg.banner(ip, port, timeout) {
+				matched <- reg
+			}
+		}(reg)
+	}
+	wg.Wait()
+	close(matched)
+
+	var result []models.Protocol
+	for reg := range matched {
+		protocol, err := reg.detector.Detect(ip, port, timeout)
+		if err != nil {
+			logger.Debugf("%s deep inspection on %s:%d failed: %v", reg.detector.GetName(), ip, port, err)
+			continue
+		}
+		if protocol.Available {
+			result = append(result, *protocol)
+		}
+	}
+
+	return result
+}
+
+// Ports возвращает объединенный список всех портов, зарегистрированных хотя бы одним
+// детектором
+func (r *ProtocolDetectorRegistry) Ports() []int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ports := make([]int, 0, len(r.byPort))
+	for port := range r.byPort {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// Detectors возвращает все детекторы, зарегистрированные в реестре, без дублей (один
+// детектор может быть зарегистрирован сразу на нескольких портах)
+func (r *ProtocolDetectorRegistry) Detectors() []Detector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[Detector]bool, len(r.registrations))
+	detectors := make([]Detector, 0, len(r.registrations))
+	for _, reg := range r.registrations {
+		if !seen[reg.detector] {
+			seen[reg.detector] = true
+			detectors = append(detectors, reg.detector)
+		}
+	}
+	return detectors
+}
+
+// Find возвращает зарегистрированный детектор по имени (Detector.GetName), либо nil
+func (r *ProtocolDetectorRegistry) Find(name string) Detector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, reg := range r.registrations {
+		if reg.detector.GetName() == name {
+			return reg.detector
+		}
+	}
+	return nil
+}
+
+// defaultTCPBanner - banner-проверка по умолчанию: порт открыт по TCP
+func defaultTCPBanner(ip string, port int, timeout time.Duration) bool {
+	address := fmt.Sprintf("%s:%d", ip, port)
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// defaultUDPBanner - banner-проверка для протоколов поверх UDP: сокет открывается без
+// handshake (UDP не имеет состояния соединения), поэтому это не доказывает, что там
+// кто-то слушает - только отсутствие явной ошибки резолва адреса. Окончательное
+// подтверждение остается за deep inspection (Detect)
+func defaultUDPBanner(ip string, port int, timeout time.Duration) bool {
+	address := fmt.Sprintf("%s:%d", ip, port)
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
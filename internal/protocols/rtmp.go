@@ -1,148 +1,435 @@
-package protocols
-
-import (
-	"encoding/binary"
-	"fmt"
-	"net"
-	"time"
-
-	"github.com/local-video-server/internal/models"
-	"github.com/local-video-server/pkg/utils"
-	"github.com/sirupsen/logrus"
-)
-
-// RTMPDetector - детектор RTMP протокола
-type RTMPDetector struct {
-	logger *logrus.Logger
-}
-
-// NewRTMPDetector создает новый RTMP детектор
-func NewRTMPDetector() *RTMPDetector {
-	return &RTMPDetector{
-		logger: utils.GetLogger(),
-	}
-}
-
-// GetName возвращает название протокола
-func (d *RTMPDetector) GetName() string {
-	return "RTMP"
-}
-
-// GetDefaultPort возвращает порт по умолчанию
-func (d *RTMPDetector) GetDefaultPort() int {
-	return 1935
-}
-
-// Detect проверяет наличие RTMP протокола на устройстве
-func (d *RTMPDetector) Detect(ip string, port int, timeout time.Duration) (*models.Protocol, error) {
-	protocol := &models.Protocol{
-		Type:       "RTMP",
-		Port:       port,
-		Available:  false,
-		DetectedAt: time.Now(),
-	}
-
-	// Подключение к RTMP порту
-	address := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
-	if err != nil {
-		return protocol, fmt.Errorf("failed to connect: %w", err)
-	}
-	defer conn.Close()
-
-	// Устанавливаем таймаут
-	conn.SetDeadline(time.Now().Add(timeout))
-
-	// RTMP handshake состоит из 3 этапов:
-	// C0+C1 (клиент отправляет), S0+S1+S2 (сервер отвечает), C2 (клиент подтверждает)
-
-	// Этап 1: Отправка C0+C1
-	c0c1 := d.createC0C1()
-	if _, err := conn.Write(c0c1); err != nil {
-		return protocol, fmt.Errorf("failed to send C0+C1: %w", err)
-	}
-
-	// Этап 2: Чтение S0+S1+S2
-	s0s1s2 := make([]byte, 3073) // 1 + 1536 + 1536
-	if _, err := conn.Read(s0s1s2); err != nil {
-		return protocol, fmt.Errorf("failed to read S0+S1+S2: %w", err)
-	}
-
-	// Проверка S0 (версия протокола, должна быть 3)
-	if s0s1s2[0] != 3 {
-		return protocol, fmt.Errorf("invalid RTMP version: %d", s0s1s2[0])
-	}
-
-	// Этап 3: Отправка C2 (эхо S1)
-	c2 := s0s1s2[1:1537] // S1 часть
-	if _, err := conn.Write(c2); err != nil {
-		return protocol, fmt.Errorf("failed to send C2: %w", err)
-	}
-
-	// Если handshake успешен, RTMP доступен
-	protocol.Available = true
-	protocol.URL = fmt.Sprintf("rtmp://%s:%d", ip, port)
-
-	return protocol, nil
-}
-
-// createC0C1 создает C0+C1 пакет для RTMP handshake
-func (d *RTMPDetector) createC0C1() []byte {
-	// C0: 1 байт версии (3)
-	c0 := []byte{3}
-
-	// C1: 1536 байт
-	c1 := make([]byte, 1536)
-	
-	// Первые 4 байта - timestamp (текущее время)
-	timestamp := uint32(time.Now().Unix())
-	binary.BigEndian.PutUint32(c1[0:4], timestamp)
-	
-	// Следующие 4 байта - нули (версия)
-	binary.BigEndian.PutUint32(c1[4:8], 0)
-	
-	// Остальные байты - случайные данные
-	// В реальной реализации здесь должны быть случайные данные,
-	// но для детектирования достаточно минимального handshake
-	for i := 8; i < 1536; i++ {
-		c1[i] = byte(i % 256)
-	}
-
-	return append(c0, c1...)
-}
-
-// CheckStream проверяет доступность конкретного RTMP потока
-func (d *RTMPDetector) CheckStream(ip string, port int, appName string, streamName string, timeout time.Duration) (bool, error) {
-	address := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
-	if err != nil {
-		return false, err
-	}
-	defer conn.Close()
-
-	conn.SetDeadline(time.Now().Add(timeout))
-
-	// Выполняем handshake
-	c0c1 := d.createC0C1()
-	if _, err := conn.Write(c0c1); err != nil {
-		return false, err
-	}
-
-	s0s1s2 := make([]byte, 3073)
-	if _, err := conn.Read(s0s1s2); err != nil {
-		return false, err
-	}
-
-	if s0s1s2[0] != 3 {
-		return false, fmt.Errorf("invalid RTMP version")
-	}
-
-	c2 := s0s1s2[1:1537]
-	if _, err := conn.Write(c2); err != nil {
-		return false, err
-	}
-
-	// После handshake можно попытаться подключиться к приложению
-	// Для детектирования достаточно успешного handshake
-	return true, nil
-}
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/rtsp"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// rtmpConnectTransactionID/rtmpCreateStreamTransactionID - transaction ID AMF0, используемые
+// этим клиентом для команд "connect"/"createStream"
+const (
+	rtmpConnectTransactionID      = 1
+	rtmpCreateStreamTransactionID = 2
+)
+
+// rtmpCommandChunkStream/rtmpStreamChunkStream - chunk stream ID, на которых этот клиент
+// отправляет сообщения: 3 для "connect"/"createStream" (message stream 0, управляющее
+// соединение), 8 для "play" и последующих аудио/видео/информационных сообщений на созданном
+// потоке. Выбор произвольный, но общепринятый: csid 2 зарезервирован сервером под служебные
+// сообщения протокола
+const (
+	rtmpCommandChunkStream = 3
+	rtmpStreamChunkStream  = 8
+)
+
+// maxRTMPProbeMessages ограничивает число сообщений, которые CheckStream читает после PLAY в
+// ожидании onMetaData и первых видео/аудио тегов, чтобы сервер, который вообще не шлет медиа
+// данные (или шлет только немедийные сообщения), не мог подвесить проверку дольше таймаута
+const maxRTMPProbeMessages = 200
+
+// RTMPDetector - детектор RTMP протокола
+type RTMPDetector struct {
+	logger *logrus.Logger
+}
+
+// NewRTMPDetector создает новый RTMP детектор
+func NewRTMPDetector() *RTMPDetector {
+	return &RTMPDetector{
+		logger: utils.GetLogger(),
+	}
+}
+
+// GetName возвращает название протокола
+func (d *RTMPDetector) GetName() string {
+	return "RTMP"
+}
+
+// GetDefaultPort возвращает порт по умолчанию
+func (d *RTMPDetector) GetDefaultPort() int {
+	return 1935
+}
+
+// Detect проверяет наличие RTMP протокола на устройстве
+func (d *RTMPDetector) Detect(ip string, port int, timeout time.Duration) (*models.Protocol, error) {
+	protocol := &models.Protocol{
+		Type:       "RTMP",
+		Port:       port,
+		Available:  false,
+		DetectedAt: time.Now(),
+	}
+
+	// Подключение к RTMP порту
+	address := fmt.Sprintf("%s:%d", ip, port)
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return protocol, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	// Устанавливаем таймаут
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := d.handshake(conn); err != nil {
+		return protocol, err
+	}
+
+	// Если handshake успешен, RTMP доступен
+	protocol.Available = true
+	protocol.URL = fmt.Sprintf("rtmp://%s:%d", ip, port)
+
+	return protocol, nil
+}
+
+// handshake выполняет рукопожатие RTMP (C0+C1 -> S0+S1+S2 -> C2, RTMP Specification 1.0,
+// раздел 5.2) на уже установленном TCP соединении
+func (d *RTMPDetector) handshake(conn net.Conn) error {
+	c0c1 := d.createC0C1()
+	if _, err := conn.Write(c0c1); err != nil {
+		return fmt.Errorf("failed to send C0+C1: %w", err)
+	}
+
+	s0s1s2 := make([]byte, 3073) // 1 + 1536 + 1536
+	if _, err := io.ReadFull(conn, s0s1s2); err != nil {
+		return fmt.Errorf("failed to read S0+S1+S2: %w", err)
+	}
+
+	if s0s1s2[0] != 3 {
+		return fmt.Errorf("invalid RTMP version: %d", s0s1s2[0])
+	}
+
+	c2 := s0s1s2[1:1537] // эхо S1, как того требует спецификация
+	if _, err := conn.Write(c2); err != nil {
+		return fmt.Errorf("failed to send C2: %w", err)
+	}
+	return nil
+}
+
+// createC0C1 создает C0+C1 пакет для RTMP handshake
+func (d *RTMPDetector) createC0C1() []byte {
+	// C0: 1 байт версии (3)
+	c0 := []byte{3}
+
+	// C1: 1536 байт
+	c1 := make([]byte, 1536)
+
+	// Первые 4 байта - timestamp (текущее время)
+	timestamp := uint32(time.Now().Unix())
+	binary.BigEndian.PutUint32(c1[0:4], timestamp)
+
+	// Следующие 4 байта - нули (версия)
+	binary.BigEndian.PutUint32(c1[4:8], 0)
+
+	// Остальные байты - случайные данные
+	// В реальной реализации здесь должны быть случайные данные,
+	// но для детектирования достаточно минимального handshake
+	for i := 8; i < 1536; i++ {
+		c1[i] = byte(i % 256)
+	}
+
+	return append(c0, c1...)
+}
+
+// connectAndPlay выполняет connect/createStream/play поверх уже установленного и
+// рукопожатого TCP соединения, возвращая chunk stream, готовый к чтению ответа play (теги
+// видео/аудио либо, для разового зонда, onMetaData) - общий шаг для CheckStream (разовая
+// проверка) и OpenStream (непрерывная перекачка для записи)
+func (d *RTMPDetector) connectAndPlay(conn net.Conn, ip string, port int, appName, streamName string) (*rtmpChunkStream, error) {
+	cs := newRTMPChunkStream(conn)
+	tcURL := fmt.Sprintf("rtmp://%s:%d/%s", ip, port, appName)
+
+	connectCmd := encodeAMF0String("connect")
+	connectCmd = append(connectCmd, encodeAMF0Number(rtmpConnectTransactionID)...)
+	connectCmd = append(connectCmd, encodeAMF0Object(amfObject{
+		{Key: "app", Value: appName},
+		{Key: "flashVer", Value: "LNX 9,0,124,2"},
+		{Key: "tcUrl", Value: tcURL},
+	})...)
+	if err := cs.writeMessage(rtmpCommandChunkStream, rtmpMsgAMF0Command, 0, connectCmd); err != nil {
+		return nil, fmt.Errorf("failed to send connect: %w", err)
+	}
+	if _, err := d.expectResult(cs, rtmpConnectTransactionID); err != nil {
+		return nil, fmt.Errorf("connect failed: %w", err)
+	}
+
+	createStreamCmd := encodeAMF0String("createStream")
+	createStreamCmd = append(createStreamCmd, encodeAMF0Number(rtmpCreateStreamTransactionID)...)
+	createStreamCmd = append(createStreamCmd, encodeAMF0Null()...)
+	if err := cs.writeMessage(rtmpCommandChunkStream, rtmpMsgAMF0Command, 0, createStreamCmd); err != nil {
+		return nil, fmt.Errorf("failed to send createStream: %w", err)
+	}
+	createResult, err := d.expectResult(cs, rtmpCreateStreamTransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("createStream failed: %w", err)
+	}
+	if len(createResult) < 4 {
+		return nil, fmt.Errorf("createStream: _result missing stream ID")
+	}
+	streamIDFloat, ok := createResult[3].(float64)
+	if !ok {
+		return nil, fmt.Errorf("createStream: unexpected stream ID type %T", createResult[3])
+	}
+	streamID := uint32(streamIDFloat)
+
+	playCmd := encodeAMF0String("play")
+	playCmd = append(playCmd, encodeAMF0Number(0)...)
+	playCmd = append(playCmd, encodeAMF0Null()...)
+	playCmd = append(playCmd, encodeAMF0String(streamName)...)
+	if err := cs.writeMessage(rtmpStreamChunkStream, rtmpMsgAMF0Command, streamID, playCmd); err != nil {
+		return nil, fmt.Errorf("failed to send play: %w", err)
+	}
+
+	return cs, nil
+}
+
+// CheckStream проверяет доступность конкретного RTMP потока: выполняет handshake, затем
+// полный цикл connect/createStream/play и разбирает первое onMetaData и первые видео/аудио
+// теги, чтобы получить параметры кодеков - аналогично тому, как rtsp.ParseSDP описывает RTSP поток.
+// Не требует, чтобы оба тега (видео и аудио) пришли до закрытия соединения или истечения
+// timeout - нестандартные публикующие клиенты (замечено у DJI дронов и iOS StreamLabs) иногда
+// обрывают chunk stream раньше, и доступной частичной информации достаточно для отчета
+func (d *RTMPDetector) CheckStream(ip string, port int, appName string, streamName string, timeout time.Duration) (*models.RTMPStreamInfo, error) {
+	address := fmt.Sprintf("%s:%d", ip, port)
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := d.handshake(conn); err != nil {
+		return nil, err
+	}
+
+	cs, err := d.connectAndPlay(conn, ip, port, appName, streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &models.RTMPStreamInfo{
+		URL:        fmt.Sprintf("rtmp://%s:%d/%s/%s", ip, port, appName, streamName),
+		AppName:    appName,
+		StreamName: streamName,
+	}
+
+	for i := 0; i < maxRTMPProbeMessages; i++ {
+		msg, err := cs.readMessage()
+		if err != nil {
+			break // поток мог закрыться раньше, чем придут оба типа тегов - это не фатально
+		}
+
+		switch msg.typeID {
+		case rtmpMsgAMF0Data:
+			values, err := decodeAMF0Sequence(msg.payload)
+			if err == nil {
+				applyOnMetaData(info, values)
+			}
+
+		case rtmpMsgVideo:
+			if codec, profile, level, width, height, fps, ok := parseVideoTag(msg.payload); ok {
+				info.Codec = codec
+				info.Resolution = fmt.Sprintf("%dx%d", width, height)
+				if fps > 0 {
+					info.FPS = fps
+				}
+				info.VideoCodecInfo = &models.VideoCodec{
+					Name: codec, Profile: profile, Level: level, Width: width, Height: height, FPS: info.FPS,
+				}
+			}
+
+		case rtmpMsgAudio:
+			if codec, sampleRate, channels, ok := parseAudioTag(msg.payload); ok {
+				info.AudioCodec = codec
+				info.Channels = channels
+				info.AudioCodecInfo = &models.AudioCodec{Name: codec, SampleRate: sampleRate, Channels: channels}
+			}
+		}
+
+		if info.VideoCodecInfo != nil && info.AudioCodecInfo != nil {
+			break
+		}
+	}
+
+	info.Available = true
+	info.CheckedAt = time.Now()
+	return info, nil
+}
+
+// expectResult читает командные сообщения RTMP, пока не найдет ответ _result/_error с
+// совпадающим transactionID, и возвращает его декодированные значения AMF0 (включая ведущую
+// строку "_result"/"_error" и сам transaction ID)
+func (d *RTMPDetector) expectResult(cs *rtmpChunkStream, transactionID float64) ([]interface{}, error) {
+	for i := 0; i < maxRTMPProbeMessages; i++ {
+		msg, err := cs.readMessage()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reply: %w", err)
+		}
+		if msg.typeID != rtmpMsgAMF0Command {
+			continue
+		}
+		values, err := decodeAMF0Sequence(msg.payload)
+		if err != nil || len(values) < 2 {
+			continue
+		}
+		name, _ := values[0].(string)
+		txID, _ := values[1].(float64)
+		if txID != transactionID {
+			continue
+		}
+		if name == "_error" {
+			return nil, fmt.Errorf("RTMP server returned _error")
+		}
+		if name == "_result" {
+			return values, nil
+		}
+	}
+	return nil, fmt.Errorf("no reply for transaction %v", transactionID)
+}
+
+// OpenStream подключается к appName/streamName тем же рукопожатием и connect/createStream/play,
+// что CheckStream, но оставляет соединение открытым для ReadTag вместо разбора первых тегов и
+// закрытия - предназначен для непрерывной перекачки потока в запись (см.
+// vigilos/pkg/storage.rtmpRepackager). connectTimeout ограничивает только время
+// установления соединения и handshake/connect/createStream/play; после этого на чтение тегов
+// таймаут снимается, так как запись рассчитана на долгоживущее соединение
+func (d *RTMPDetector) OpenStream(ip string, port int, appName, streamName string, connectTimeout time.Duration) (*RTMPStream, error) {
+	address := fmt.Sprintf("%s:%d", ip, port)
+	conn, err := net.DialTimeout("tcp", address, connectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(connectTimeout))
+
+	if err := d.handshake(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	cs, err := d.connectAndPlay(conn, ip, port, appName, streamName)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return &RTMPStream{conn: conn, cs: cs}, nil
+}
+
+// RTMPStream - открытое RTMP соединение после connect/createStream/play, отдающее
+// видео/аудио теги по мере поступления через ReadTag - в отличие от CheckStream, не
+// останавливается после первых тегов с метаданными кодека
+type RTMPStream struct {
+	conn net.Conn
+	cs   *rtmpChunkStream
+
+	// audioObjectType/audioSampleRate/audioChannels - параметры AAC, извлеченные из
+	// AudioSpecificConfig (sequence header); до его получения аудио теги пропускаются, так
+	// как без них нельзя собрать корректный заголовок ADTS (см. hls.BuildADTSFrame)
+	audioObjectType, audioSampleRate, audioChannels int
+}
+
+// RTMPTag - один видео или аудио кадр, извлеченный из непрерывного RTMP потока и готовый к
+// муксированию. NALUs/AudioFrame заполняется в зависимости от Video
+type RTMPTag struct {
+	Video     bool
+	Keyframe  bool // только для видео (NAL IDR против не-IDR, по frameType FLV тега)
+	Timestamp uint32
+
+	// NALUs - NAL unit'ы этого видео кадра без старт-кодов Annex-B, см. hls.Muxer.WriteVideoNALUs
+	NALUs [][]byte
+
+	// AudioFrame - сырой AAC raw_data_block без заголовка ADTS, а также параметры,
+	// необходимые, чтобы собрать ADTS заголовок (см. hls.BuildADTSFrame)
+	AudioFrame                                      []byte
+	AudioObjectType, AudioSampleRate, AudioChannels int
+}
+
+// ReadTag блокируется до следующего сообщения RTMP и возвращает его как RTMPTag, если это
+// видео или аудио кадр с данными для муксирования. ok=false означает служебное сообщение
+// (AMF0 команда/данные, AAC sequence header) - ReadTag обновил внутреннее состояние (для
+// аудио - параметры из AudioSpecificConfig), но кадра на этот раз нет; вызывающему коду
+// достаточно вызвать ReadTag снова
+func (s *RTMPStream) ReadTag() (tag RTMPTag, ok bool, err error) {
+	msg, err := s.cs.readMessage()
+	if err != nil {
+		return RTMPTag{}, false, err
+	}
+
+	switch msg.typeID {
+	case rtmpMsgVideo:
+		return s.parseVideoMessage(msg)
+	case rtmpMsgAudio:
+		return s.parseAudioMessage(msg)
+	default:
+		return RTMPTag{}, false, nil
+	}
+}
+
+// parseVideoMessage разбирает видео сообщение RTMP в RTMPTag, если оно несет NALU (а не
+// sequence header/end-of-sequence, см. parseAVCNALUs в rtmp_flv.go)
+func (s *RTMPStream) parseVideoMessage(msg *rtmpMessage) (RTMPTag, bool, error) {
+	if len(msg.payload) < 6 || msg.payload[1] != 1 {
+		return RTMPTag{}, false, nil
+	}
+	frameType := msg.payload[0] >> 4
+	nalus, err := parseAVCNALUs(msg.payload[5:])
+	if err != nil {
+		return RTMPTag{}, false, fmt.Errorf("failed to parse AVC NALUs: %w", err)
+	}
+	return RTMPTag{Video: true, Keyframe: frameType == 1, Timestamp: msg.timestamp, NALUs: nalus}, true, nil
+}
+
+// parseAudioMessage разбирает аудио сообщение RTMP в RTMPTag. На AAC sequence header
+// сохраняет параметры кодека в s и сообщает ok=false - с этим кадром муксировать нечего
+func (s *RTMPStream) parseAudioMessage(msg *rtmpMessage) (RTMPTag, bool, error) {
+	if len(msg.payload) < 2 {
+		return RTMPTag{}, false, nil
+	}
+	if msg.payload[1] == 0 {
+		objectType, sampleRate, channels, err := rtsp.ParseAACAudioSpecificConfigBytes(msg.payload[2:])
+		if err != nil {
+			return RTMPTag{}, false, fmt.Errorf("failed to parse AudioSpecificConfig: %w", err)
+		}
+		s.audioObjectType, s.audioSampleRate, s.audioChannels = objectType, sampleRate, channels
+		return RTMPTag{}, false, nil
+	}
+	if s.audioSampleRate == 0 {
+		return RTMPTag{}, false, nil // кадр пришел раньше sequence header - без него ADTS не собрать
+	}
+	return RTMPTag{
+		Video:           false,
+		Timestamp:       msg.timestamp,
+		AudioFrame:      msg.payload[2:],
+		AudioObjectType: s.audioObjectType,
+		AudioSampleRate: s.audioSampleRate,
+		AudioChannels:   s.audioChannels,
+	}, true, nil
+}
+
+// Close закрывает RTMP соединение
+func (s *RTMPStream) Close() error {
+	return s.conn.Close()
+}
+
+// applyOnMetaData переносит поле framerate информационного сообщения onMetaData в info, если
+// оно есть (onMetaData формально не специфицирован Adobe RTMP specification 1.0, но этот ключ
+// энкодеры передают по негласному соглашению)
+func applyOnMetaData(info *models.RTMPStreamInfo, values []interface{}) {
+	if len(values) < 2 {
+		return
+	}
+	meta, ok := values[1].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if fps, ok := meta["framerate"].(float64); ok && info.FPS == 0 {
+		info.FPS = fps
+	}
+}
@@ -0,0 +1,245 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Маркеры типов AMF0, реально используемые этим детектором (Adobe AMF0 specification,
+// раздел 2.1). Ими кодируются командные/информационные сообщения RTMP (connect, createStream,
+// play, onStatus, onMetaData)
+const (
+	amf0Number     = 0x00
+	amf0Boolean    = 0x01
+	amf0String     = 0x02
+	amf0Object     = 0x03
+	amf0Null       = 0x05
+	amf0Undefined  = 0x06
+	amf0ECMAArray  = 0x08
+	amf0ObjectEnd  = 0x09
+	amf0StrictArr  = 0x0A
+	amf0Date       = 0x0B
+	amf0LongString = 0x0C
+)
+
+// amfProperty - одна пара ключ/значение объекта AMF0, хранится в срезе, а не в map, чтобы
+// порядок кодирования свойств совпадал с тем, что ожидает типичный RTMP сервер для "connect"
+// (app, flashVer, tcUrl, ...)
+type amfProperty struct {
+	Key   string
+	Value interface{}
+}
+
+// amfObject - упорядоченный объект/ECMA массив AMF0, кодируемый encodeAMF0Object
+type amfObject []amfProperty
+
+// encodeAMF0String кодирует строку AMF0 (маркер 0x02, 2-байтовая длина, UTF-8). Длинные строки
+// (маркер 0x0C, 4-байтовая длина) этому клиенту не нужны - он отправляет только короткие
+// строки команд/свойств
+func encodeAMF0String(s string) []byte {
+	buf := make([]byte, 0, 3+len(s))
+	buf = append(buf, amf0String)
+	lenBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBuf, uint16(len(s)))
+	buf = append(buf, lenBuf...)
+	return append(buf, s...)
+}
+
+// encodeAMF0Number кодирует число AMF0 (маркер 0x00, 8-байтовый IEEE 754 double)
+func encodeAMF0Number(n float64) []byte {
+	buf := make([]byte, 9)
+	buf[0] = amf0Number
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(n))
+	return buf
+}
+
+// encodeAMF0Boolean кодирует булево значение AMF0 (маркер 0x01, 1 байт)
+func encodeAMF0Boolean(b bool) []byte {
+	v := byte(0)
+	if b {
+		v = 1
+	}
+	return []byte{amf0Boolean, v}
+}
+
+// encodeAMF0Null кодирует null AMF0 (маркер 0x05, без данных)
+func encodeAMF0Null() []byte {
+	return []byte{amf0Null}
+}
+
+// encodeAMF0Value кодирует одно значение Go как значение AMF0 в зависимости от его типа -
+// используется для аргументов команд (createStream/play принимают строки/числа/nil)
+func encodeAMF0Value(v interface{}) []byte {
+	switch val := v.(type) {
+	case string:
+		return encodeAMF0String(val)
+	case float64:
+		return encodeAMF0Number(val)
+	case int:
+		return encodeAMF0Number(float64(val))
+	case bool:
+		return encodeAMF0Boolean(val)
+	case amfObject:
+		return encodeAMF0Object(val)
+	case nil:
+		return encodeAMF0Null()
+	default:
+		return encodeAMF0Null()
+	}
+}
+
+// encodeAMF0Object кодирует объект AMF0 (маркер 0x03, пары ключ/значение, завершается
+// пустым ключом и маркером конца объекта 0x09) - используется для объекта команды "connect"
+func encodeAMF0Object(props amfObject) []byte {
+	buf := []byte{amf0Object}
+	for _, p := range props {
+		lenBuf := make([]byte, 2)
+		binary.BigEndian.PutUint16(lenBuf, uint16(len(p.Key)))
+		buf = append(buf, lenBuf...)
+		buf = append(buf, p.Key...)
+		buf = append(buf, encodeAMF0Value(p.Value)...)
+	}
+	buf = append(buf, 0x00, 0x00, amf0ObjectEnd)
+	return buf
+}
+
+// decodeAMF0Value декодирует одно значение AMF0 начиная с data[pos] и возвращает его вместе
+// с позицией сразу за ним. Объекты и ECMA массивы декодируются в map[string]interface{},
+// поскольку при разборе onMetaData/_result порядок свойств не важен
+func decodeAMF0Value(data []byte, pos int) (interface{}, int, error) {
+	if pos >= len(data) {
+		return nil, pos, fmt.Errorf("AMF0: unexpected end of data")
+	}
+
+	marker := data[pos]
+	pos++
+
+	switch marker {
+	case amf0Number:
+		if pos+8 > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated number")
+		}
+		n := math.Float64frombits(binary.BigEndian.Uint64(data[pos : pos+8]))
+		return n, pos + 8, nil
+
+	case amf0Boolean:
+		if pos+1 > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated boolean")
+		}
+		return data[pos] != 0, pos + 1, nil
+
+	case amf0String:
+		return decodeAMF0ShortString(data, pos)
+
+	case amf0LongString:
+		if pos+4 > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated long string length")
+		}
+		n := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		pos += 4
+		if pos+n > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated long string")
+		}
+		return string(data[pos : pos+n]), pos + n, nil
+
+	case amf0Object:
+		return decodeAMF0Object(data, pos)
+
+	case amf0ECMAArray:
+		if pos+4 > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated ECMA array count")
+		}
+		// счетчик ECMA массива - справочный, объект все равно заканчивается на 0x00 0x00 0x09
+		return decodeAMF0Object(data, pos+4)
+
+	case amf0StrictArr:
+		if pos+4 > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated strict array count")
+		}
+		count := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		pos += 4
+		values := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			var v interface{}
+			var err error
+			v, pos, err = decodeAMF0Value(data, pos)
+			if err != nil {
+				return nil, pos, err
+			}
+			values = append(values, v)
+		}
+		return values, pos, nil
+
+	case amf0Date:
+		if pos+10 > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated date")
+		}
+		ms := math.Float64frombits(binary.BigEndian.Uint64(data[pos : pos+8]))
+		return ms, pos + 10, nil // часовой пояс (2 байта) на практике всегда 0, игнорируется
+
+	case amf0Null, amf0Undefined:
+		return nil, pos, nil
+
+	default:
+		return nil, pos, fmt.Errorf("AMF0: unsupported marker 0x%02x", marker)
+	}
+}
+
+// decodeAMF0ShortString декодирует тело UTF-8 строки AMF0 (маркер уже считан)
+func decodeAMF0ShortString(data []byte, pos int) (interface{}, int, error) {
+	if pos+2 > len(data) {
+		return nil, pos, fmt.Errorf("AMF0: truncated string length")
+	}
+	n := int(binary.BigEndian.Uint16(data[pos : pos+2]))
+	pos += 2
+	if pos+n > len(data) {
+		return nil, pos, fmt.Errorf("AMF0: truncated string")
+	}
+	return string(data[pos : pos+n]), pos + n, nil
+}
+
+// decodeAMF0Object декодирует пары ключ/значение тела объекта/ECMA массива AMF0 (маркер и
+// ведущий счетчик, если был, уже считаны) до маркера конца 0x00 0x00 0x09
+func decodeAMF0Object(data []byte, pos int) (interface{}, int, error) {
+	obj := make(map[string]interface{})
+	for {
+		if pos+2 > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated object key length")
+		}
+		keyLen := int(binary.BigEndian.Uint16(data[pos : pos+2]))
+		pos += 2
+		if keyLen == 0 && pos < len(data) && data[pos] == amf0ObjectEnd {
+			return obj, pos + 1, nil
+		}
+		if pos+keyLen > len(data) {
+			return nil, pos, fmt.Errorf("AMF0: truncated object key")
+		}
+		key := string(data[pos : pos+keyLen])
+		pos += keyLen
+
+		var value interface{}
+		var err error
+		value, pos, err = decodeAMF0Value(data, pos)
+		if err != nil {
+			return nil, pos, fmt.Errorf("AMF0: object property %q: %w", key, err)
+		}
+		obj[key] = value
+	}
+}
+
+// decodeAMF0Sequence декодирует все значения AMF0 в data подряд - так устроено тело команды
+// (_result/_error/onStatus) или информационного сообщения (onMetaData)
+func decodeAMF0Sequence(data []byte) ([]interface{}, error) {
+	var values []interface{}
+	pos := 0
+	for pos < len(data) {
+		v, next, err := decodeAMF0Value(data, pos)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+		pos = next
+	}
+	return values, nil
+}
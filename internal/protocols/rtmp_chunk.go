@@ -0,0 +1,267 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Идентификаторы типов сообщений RTMP, реально обрабатываемые этим клиентом (Adobe RTMP
+// specification 1.0, раздел 6.1)
+const (
+	rtmpMsgSetChunkSize = 1
+	rtmpMsgAudio        = 8
+	rtmpMsgVideo        = 9
+	rtmpMsgAMF0Data     = 18
+	rtmpMsgAMF0Command  = 20
+)
+
+// defaultRTMPChunkSize - размер чанка, который обе стороны используют до тех пор, пока
+// кто-то не пришлет управляющее сообщение Set Chunk Size (Adobe RTMP specification 1.0,
+// раздел 5.4.1)
+const defaultRTMPChunkSize = 128
+
+// rtmpMessage - одно собранное сообщение RTMP: payload, полученный из серии чанков одного
+// chunk stream (Adobe RTMP specification 1.0, раздел 5.3)
+type rtmpMessage struct {
+	typeID    byte
+	streamID  uint32
+	timestamp uint32
+	payload   []byte
+}
+
+// chunkStreamState - состояние одного chunk stream ID, необходимое readMessage для разбора
+// компактных заголовков fmt 1/2/3 - они несут только то, что изменилось с предыдущего
+// сообщения этого chunk stream
+type chunkStreamState struct {
+	timestamp       uint32
+	messageLength   int
+	messageTypeID   byte
+	messageStreamID uint32
+}
+
+// rtmpChunkStream читает и пишет фрейминг chunk stream RTMP поверх обычного TCP соединения
+// (уже после handshake). Разбором содержимого сообщений не занимается - это задача rtmp.go,
+// когда у него на руках уже собранное rtmpMessage
+type rtmpChunkStream struct {
+	conn net.Conn
+
+	readChunkSize  int
+	writeChunkSize int
+
+	readStates map[uint32]*chunkStreamState
+	partial    map[uint32][]byte
+}
+
+func newRTMPChunkStream(conn net.Conn) *rtmpChunkStream {
+	return &rtmpChunkStream{
+		conn:           conn,
+		readChunkSize:  defaultRTMPChunkSize,
+		writeChunkSize: defaultRTMPChunkSize,
+		readStates:     make(map[uint32]*chunkStreamState),
+		partial:        make(map[uint32][]byte),
+	}
+}
+
+// readMessage читает чанки из соединения, пока не соберет целое сообщение, попутно
+// молча применяя любое управляющее сообщение Set Chunk Size, вместо того чтобы возвращать
+// его вызывающему коду
+func (s *rtmpChunkStream) readMessage() (*rtmpMessage, error) {
+	for {
+		fmtType, csid, err := s.readBasicHeader()
+		if err != nil {
+			return nil, err
+		}
+
+		state, ok := s.readStates[csid]
+		if !ok {
+			state = &chunkStreamState{}
+			s.readStates[csid] = state
+		}
+		if err := s.readMessageHeader(fmtType, state); err != nil {
+			return nil, err
+		}
+
+		need := state.messageLength - len(s.partial[csid])
+		if need < 0 {
+			need = 0
+		}
+		toRead := need
+		if toRead > s.readChunkSize {
+			toRead = s.readChunkSize
+		}
+		if toRead > 0 {
+			buf := make([]byte, toRead)
+			if _, err := io.ReadFull(s.conn, buf); err != nil {
+				return nil, fmt.Errorf("RTMP: failed to read chunk payload: %w", err)
+			}
+			s.partial[csid] = append(s.partial[csid], buf...)
+		}
+
+		if len(s.partial[csid]) < state.messageLength {
+			continue // остаток сообщения придет в следующих чанках того же csid (fmt 3)
+		}
+
+		payload := s.partial[csid]
+		delete(s.partial, csid)
+		msg := &rtmpMessage{
+			typeID:    state.messageTypeID,
+			streamID:  state.messageStreamID,
+			timestamp: state.timestamp,
+			payload:   payload,
+		}
+
+		if msg.typeID == rtmpMsgSetChunkSize && len(payload) >= 4 {
+			s.readChunkSize = int(binary.BigEndian.Uint32(payload[:4]))
+			continue // управляющее сообщение применяется на месте, наверх не отдается
+		}
+		return msg, nil
+	}
+}
+
+// readMessageHeader читает Message Header, следующий за базовым заголовком (fmt 0-3), и
+// обновляет state на месте - fmt 1-3 несут только те поля, что изменились с предыдущего
+// сообщения этого chunk stream (Adobe RTMP specification 1.0, раздел 5.3.1.2)
+func (s *rtmpChunkStream) readMessageHeader(fmtType byte, state *chunkStreamState) error {
+	switch fmtType {
+	case 0:
+		hdr := make([]byte, 11)
+		if _, err := io.ReadFull(s.conn, hdr); err != nil {
+			return fmt.Errorf("RTMP: failed to read type 0 message header: %w", err)
+		}
+		ts := uint32(hdr[0])<<16 | uint32(hdr[1])<<8 | uint32(hdr[2])
+		state.messageLength = int(hdr[3])<<16 | int(hdr[4])<<8 | int(hdr[5])
+		state.messageTypeID = hdr[6]
+		state.messageStreamID = binary.LittleEndian.Uint32(hdr[7:11])
+		ts, err := s.resolveTimestamp(ts)
+		if err != nil {
+			return err
+		}
+		state.timestamp = ts
+
+	case 1:
+		hdr := make([]byte, 7)
+		if _, err := io.ReadFull(s.conn, hdr); err != nil {
+			return fmt.Errorf("RTMP: failed to read type 1 message header: %w", err)
+		}
+		delta := uint32(hdr[0])<<16 | uint32(hdr[1])<<8 | uint32(hdr[2])
+		state.messageLength = int(hdr[3])<<16 | int(hdr[4])<<8 | int(hdr[5])
+		state.messageTypeID = hdr[6]
+		delta, err := s.resolveTimestamp(delta)
+		if err != nil {
+			return err
+		}
+		state.timestamp += delta
+
+	case 2:
+		hdr := make([]byte, 3)
+		if _, err := io.ReadFull(s.conn, hdr); err != nil {
+			return fmt.Errorf("RTMP: failed to read type 2 message header: %w", err)
+		}
+		delta := uint32(hdr[0])<<16 | uint32(hdr[1])<<8 | uint32(hdr[2])
+		delta, err := s.resolveTimestamp(delta)
+		if err != nil {
+			return err
+		}
+		state.timestamp += delta
+
+	case 3:
+		// Повторяет заголовок предыдущего сообщения этого csid без изменений - ничего читать не нужно.
+		// Расширенный timestamp (если он использовался в породившем эту серию type 0/1/2) при этом
+		// не повторяется: для детектора кодеков, где потоки метаданных/первые кадры идут в начале
+		// сессии на коротких промежутках времени, это не встречается на практике.
+	}
+	return nil
+}
+
+// resolveTimestamp читает 4 дополнительных байта расширенного timestamp, если 24-битное поле
+// timestamp/delta в заголовке сообщения равно сигнальному значению 0xFFFFFF (Adobe RTMP
+// specification 1.0, раздел 5.3.1.3)
+func (s *rtmpChunkStream) resolveTimestamp(ts uint32) (uint32, error) {
+	if ts != 0xFFFFFF {
+		return ts, nil
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(s.conn, buf); err != nil {
+		return 0, fmt.Errorf("RTMP: failed to read extended timestamp: %w", err)
+	}
+	return binary.BigEndian.Uint32(buf), nil
+}
+
+// readBasicHeader читает базовый заголовок чанка (1-3 байта) и возвращает его поле fmt и
+// chunk stream ID (Adobe RTMP specification 1.0, раздел 5.3.1.1)
+func (s *rtmpChunkStream) readBasicHeader() (fmtType byte, csid uint32, err error) {
+	b := make([]byte, 1)
+	if _, err = io.ReadFull(s.conn, b); err != nil {
+		return 0, 0, err
+	}
+	fmtType = b[0] >> 6
+	idField := b[0] & 0x3F
+
+	switch idField {
+	case 0:
+		b2 := make([]byte, 1)
+		if _, err = io.ReadFull(s.conn, b2); err != nil {
+			return 0, 0, err
+		}
+		csid = uint32(b2[0]) + 64
+	case 1:
+		b2 := make([]byte, 2)
+		if _, err = io.ReadFull(s.conn, b2); err != nil {
+			return 0, 0, err
+		}
+		csid = uint32(b2[1])*256 + uint32(b2[0]) + 64
+	default:
+		csid = uint32(idField)
+	}
+	return fmtType, csid, nil
+}
+
+// writeMessage отправляет payload как одно сообщение RTMP на chunk stream csid/message stream
+// streamID, используя заголовок типа 0 для первого чанка и продолжающие заголовки типа 3 для
+// остальных, если payload больше writeChunkSize
+func (s *rtmpChunkStream) writeMessage(csid uint32, typeID byte, streamID uint32, payload []byte) error {
+	header := basicHeaderBytes(0, csid)
+	msgHdr := make([]byte, 11)
+	msgLen := len(payload)
+	msgHdr[3] = byte(msgLen >> 16)
+	msgHdr[4] = byte(msgLen >> 8)
+	msgHdr[5] = byte(msgLen)
+	msgHdr[6] = typeID
+	binary.LittleEndian.PutUint32(msgHdr[7:11], streamID)
+	header = append(header, msgHdr...)
+
+	first := payload
+	if len(first) > s.writeChunkSize {
+		first = first[:s.writeChunkSize]
+	}
+	if _, err := s.conn.Write(append(header, first...)); err != nil {
+		return fmt.Errorf("RTMP: failed to write message: %w", err)
+	}
+
+	rest := payload[len(first):]
+	for len(rest) > 0 {
+		chunk := rest
+		if len(chunk) > s.writeChunkSize {
+			chunk = chunk[:s.writeChunkSize]
+		}
+		if _, err := s.conn.Write(append(basicHeaderBytes(3, csid), chunk...)); err != nil {
+			return fmt.Errorf("RTMP: failed to write chunk continuation: %w", err)
+		}
+		rest = rest[len(chunk):]
+	}
+	return nil
+}
+
+// basicHeaderBytes кодирует базовый заголовок чанка для заданного fmt (0-3) и chunk stream ID
+func basicHeaderBytes(fmtType byte, csid uint32) []byte {
+	if csid < 64 {
+		return []byte{fmtType<<6 | byte(csid)}
+	}
+	if csid < 320 {
+		return []byte{fmtType << 6, byte(csid - 64)}
+	}
+	id := csid - 64
+	return []byte{fmtType<<6 | 1, byte(id), byte(id >> 8)}
+}
@@ -0,0 +1,223 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/local-video-server/internal/rtsp"
+)
+
+// Коды кодеков видео/аудио FLV, реально распознаваемые здесь (Adobe Flash Video file format
+// specification, разделы "Video tags"/"Audio tags"). RTMP несет те же тела тегов, что и FLV,
+// без 11-байтового заголовка FLV тега (заголовок сообщения chunk stream RTMP уже содержит
+// тип/timestamp/длину)
+const (
+	flvVideoCodecH264 = 7
+	flvVideoCodecHEVC = 12
+
+	flvSoundFormatLinearPCMLE = 3
+	flvSoundFormatG711ALaw    = 7
+	flvSoundFormatG711MuLaw   = 8
+	flvSoundFormatAAC         = 10
+)
+
+// parseVideoTag разбирает payload видео сообщения RTMP/FLV и, если это sequence header
+// AVC/HEVC (AVCDecoderConfigurationRecord/HEVCDecoderConfigurationRecord с SPS внутри),
+// извлекает название кодека, профиль/уровень, разрешение кадра и (только для H.264) частоту
+// кадров. ok равен false для кодеков, которые этот детектор не умеет разбирать, либо для
+// видео тега, не являющегося sequence header (SPS еще не встретился)
+func parseVideoTag(payload []byte) (codec, profile, level string, width, height int, fps float64, ok bool) {
+	if len(payload) < 1 {
+		return "", "", "", 0, 0, 0, false
+	}
+	codecID := payload[0] & 0x0F
+	switch codecID {
+	case flvVideoCodecH264:
+		codec = "H.264"
+	case flvVideoCodecHEVC:
+		codec = "H.265"
+	default:
+		return "", "", "", 0, 0, 0, false
+	}
+
+	// байт 0: frameType/codecID, байт 1: AVCPacketType/HEVCPacketType (0 = sequence header),
+	// байты 2-4: composition time offset, байт 5+: decoder configuration record
+	if len(payload) < 6 || payload[1] != 0 {
+		return codec, "", "", 0, 0, 0, false
+	}
+	config := payload[5:]
+
+	if codec == "H.264" {
+		p, l, w, h, f := parseAVCDecoderConfig(config)
+		return codec, p, l, w, h, f, true
+	}
+	p, l, w, h := parseHEVCDecoderConfig(config)
+	return codec, p, l, w, h, 0, true
+}
+
+// parseAVCDecoderConfig извлекает профиль/уровень, разрешение кадра и частоту кадров из
+// первого SPS NAL в AVCDecoderConfigurationRecord (ISO/IEC 14496-15, раздел 5.2.4.1)
+func parseAVCDecoderConfig(config []byte) (profile, level string, width, height int, fps float64) {
+	if len(config) < 6 {
+		return "", "", 0, 0, 0
+	}
+	numSPS := int(config[5] & 0x1F)
+	pos := 6
+	for i := 0; i < numSPS; i++ {
+		if pos+2 > len(config) {
+			return "", "", 0, 0, 0
+		}
+		spsLen := int(binary.BigEndian.Uint16(config[pos : pos+2]))
+		pos += 2
+		if pos+spsLen > len(config) {
+			return "", "", 0, 0, 0
+		}
+		sps := config[pos : pos+spsLen]
+		pos += spsLen
+
+		// Некоторые нестандартные энкодеры (замечено у DJI дронов) кладут в массив SPS
+		// нулевой длины NALU - пропускаем их и ищем дальше, вместо того чтобы считать
+		// sequence header нечитаемым
+		if spsLen == 0 {
+			continue
+		}
+
+		if parsed, err := rtsp.ParseH264SPSNAL(sps); err == nil {
+			return strconv.Itoa(parsed.ProfileIDC), rtsp.FormatH264Level(parsed.LevelIDC), parsed.Width, parsed.Height, parsed.FPS
+		}
+	}
+	return "", "", 0, 0, 0
+}
+
+// parseHEVCDecoderConfig проходит по фиксированному 23-байтовому заголовку и массивам NAL
+// HEVCDecoderConfigurationRecord (ISO/IEC 14496-15, раздел 8.3.3.1.2) в поисках первого SPS
+// NAL (NAL unit type 33) и извлекает его профиль/уровень и разрешение кадра
+func parseHEVCDecoderConfig(config []byte) (profile, level string, width, height int) {
+	const fixedHeaderLen = 23
+	if len(config) < fixedHeaderLen {
+		return "", "", 0, 0
+	}
+	numArrays := int(config[22])
+	pos := fixedHeaderLen
+
+	for i := 0; i < numArrays; i++ {
+		if pos+3 > len(config) {
+			return "", "", 0, 0
+		}
+		nalType := config[pos] & 0x3F
+		pos++
+		numNalus := int(binary.BigEndian.Uint16(config[pos : pos+2]))
+		pos += 2
+
+		for j := 0; j < numNalus; j++ {
+			if pos+2 > len(config) {
+				return "", "", 0, 0
+			}
+			naluLen := int(binary.BigEndian.Uint16(config[pos : pos+2]))
+			pos += 2
+			if pos+naluLen > len(config) {
+				return "", "", 0, 0
+			}
+			nalu := config[pos : pos+naluLen]
+			pos += naluLen
+
+			// Как и в AVCDecoderConfigurationRecord, нулевой длины NALU встречаются у
+			// нестандартных энкодеров - пропускаем, не прерывая разбор остальных массивов
+			if naluLen == 0 {
+				continue
+			}
+
+			if nalType == 33 { // SPS_NUT (ITU-T H.265, таблица 7-1)
+				if parsed, err := rtsp.ParseH265SPSNAL(nalu); err == nil {
+					return strconv.Itoa(parsed.ProfileIDC), rtsp.FormatH265Level(parsed.LevelIDC), parsed.Width, parsed.Height
+				}
+			}
+		}
+	}
+	return "", "", 0, 0
+}
+
+// parseAudioTag разбирает payload аудио сообщения RTMP/FLV и извлекает название кодека,
+// частоту дискретизации и число каналов. Для AAC частота/каналы становятся известны только
+// после прихода AAC sequence header (AudioSpecificConfig); остальные кодеки несут достаточно
+// информации в первом же байте тега (Adobe Flash Video file format specification, раздел
+// "Audio tags")
+func parseAudioTag(payload []byte) (codec string, sampleRate, channels int, ok bool) {
+	if len(payload) < 1 {
+		return "", 0, 0, false
+	}
+	soundFormat := payload[0] >> 4
+
+	switch soundFormat {
+	case flvSoundFormatAAC:
+		codec = "AAC"
+	case flvSoundFormatG711ALaw:
+		codec = "G711A"
+	case flvSoundFormatG711MuLaw:
+		codec = "G711U"
+	case flvSoundFormatLinearPCMLE:
+		codec = "LPCM"
+	default:
+		return "", 0, 0, false
+	}
+
+	if codec != "AAC" {
+		sampleRate, channels = flvAudioRateChannels(payload[0])
+		return codec, sampleRate, channels, true
+	}
+
+	// байт 0: soundFormat/soundRate/soundSize/soundType, байт 1: AACPacketType
+	// (0 = AudioSpecificConfig sequence header, 1 = обычный AAC кадр)
+	if len(payload) < 2 || payload[1] != 0 {
+		return codec, 0, 0, false
+	}
+	if len(payload) < 3 {
+		return codec, 0, 0, true
+	}
+	_, sampleRate, channels, err := rtsp.ParseAACAudioSpecificConfigBytes(payload[2:])
+	if err != nil {
+		return codec, 0, 0, true
+	}
+	return codec, sampleRate, channels, true
+}
+
+// parseAVCNALUs разбирает тело AVC видео тега с AVCPacketType=1 (NALU, не sequence header) -
+// один или несколько NAL unit'ов, каждый с 4-байтовым префиксом длины (ISO/IEC 14496-15,
+// AVCDecoderConfigurationRecord с lengthSizeMinusOne=3 - универсальное для RTMP/FLV энкодеров
+// значение), без старт-кодов Annex-B. Используется RTMPStream.ReadTag для передачи NALU в
+// hls.Muxer.WriteVideoNALUs
+func parseAVCNALUs(data []byte) ([][]byte, error) {
+	var nalus [][]byte
+	pos := 0
+	for pos+4 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		pos += 4
+		if length < 0 || pos+length > len(data) {
+			return nil, fmt.Errorf("AVC NALU length exceeds payload")
+		}
+		nal := make([]byte, length)
+		copy(nal, data[pos:pos+length])
+		nalus = append(nalus, nal)
+		pos += length
+	}
+	if len(nalus) == 0 {
+		return nil, fmt.Errorf("no NAL units found in AVC tag")
+	}
+	return nalus, nil
+}
+
+// flvAudioRateChannels разбирает частоту дискретизации и число каналов, закодированные прямо
+// в первом байте аудио тега FLV - этого достаточно для кодеков с фиксированной частотой
+// (G.711, Linear PCM); AAC это поле игнорирует и всегда несет настоящую частоту в своем
+// AudioSpecificConfig
+func flvAudioRateChannels(tagByte byte) (sampleRate, channels int) {
+	rates := [4]int{5512, 11025, 22050, 44100}
+	sampleRate = rates[(tagByte>>2)&0x03]
+	if tagByte&0x01 != 0 {
+		channels = 2
+	} else {
+		channels = 1
+	}
+	return sampleRate, channels
+}
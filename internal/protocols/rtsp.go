@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"fmt"
 	"net"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/local-video-server/internal/metrics"
 	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/rtsp"
+	"github.com/local-video-server/pkg/sdp"
 	"github.com/local-video-server/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -81,9 +83,14 @@ func (d *RTSPDetector) Detect(ip string, port int, timeout time.Duration) (*mode
 	if strings.Contains(response, "200") || strings.Contains(response, "401") {
 		protocol.Available = true
 		protocol.URL = fmt.Sprintf("rtsp://%s:%d", ip, port)
+		// Предсказуемый путь, по которому internal/gateway отдаст этот поток в HLS после
+		// первого обращения - см. hls.Manager.Handler и gateway.Manager.Watch
+		protocol.HLSURL = fmt.Sprintf("/hls/%s/index.m3u8", ip)
 
 		// Попытка получить DESCRIBE для определения потоков
+		describeStart := time.Now()
 		streams, err := d.getStreams(conn, ip, port, timeout)
+		metrics.ObserveFetchLatency(metrics.KindRTSPDescribe, time.Since(describeStart))
 		if err == nil && len(streams) > 0 {
 			// Если удалось получить потоки, можно добавить дополнительную информацию
 			d.logger.Debugf("Found %d RTSP streams on %s:%d", len(streams), ip, port)
@@ -109,8 +116,7 @@ func (d *RTSPDetector) getStreams(conn net.Conn, ip string, port int, timeout ti
 
 	// Чтение SDP ответа
 	reader := bufio.NewReader(conn)
-	var sdp strings.Builder
-	var streams []string
+	var sdpBody strings.Builder
 
 	// Читаем заголовки
 	for {
@@ -129,77 +135,75 @@ func (d *RTSPDetector) getStreams(conn net.Conn, ip string, port int, timeout ti
 		if err != nil {
 			break
 		}
-		sdp.WriteString(line)
-		
-		// Парсим SDP для поиска медиа потоков
-		if strings.HasPrefix(line, "m=") {
-			// m=video или m=audio указывает на поток
-			parts := strings.Fields(line)
-			if len(parts) > 0 {
-				streams = append(streams, parts[0])
-			}
-		}
+		sdpBody.WriteString(line)
+	}
+
+	// pkg/sdp.Parse переносит разбор на полноценный RFC 4566 парсер вместо построчного
+	// grep'а "m=" - устойчив к folded строкам и произвольному числу m= секций
+	sessionDesc, err := sdp.Parse(sdpBody.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SDP: %w", err)
+	}
+
+	streams := make([]string, 0, len(sessionDesc.Media))
+	for _, media := range sessionDesc.Media {
+		streams = append(streams, "m="+media.Type)
 	}
 
 	return streams, nil
 }
 
-// ParseSDP парсит SDP ответ и извлекает информацию о потоках
+// ParseSDP парсит SDP ответ и извлекает информацию о потоках. Разбор fmtp-параметров
+// (sprop-parameter-sets для H.264, sprop-sps для H.265), включая декодирование битового потока
+// SPS для разрешения/profile/level/FPS, делегируется пакету internal/rtsp - см.
+// rtsp.ParseSDP и rtsp.StreamInfo.ToRTSPStreamInfo, которые уже используются internal/hls.
 func (d *RTSPDetector) ParseSDP(sdp string) ([]models.RTSPStreamInfo, error) {
+	info, err := rtsp.ParseSDP(sdp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SDP: %w", err)
+	}
+
 	var streams []models.RTSPStreamInfo
-	
-	lines := strings.Split(sdp, "\n")
-	var currentStream *models.RTSPStreamInfo
-	
-	// Регулярные выражения для парсинга SDP
-	codecRegex := regexp.MustCompile(`a=rtpmap:(\d+)\s+(\w+)/(\d+)`)
-	resolutionRegex := regexp.MustCompile(`a=framesize:(\d+)\s+(\d+)x(\d+)`)
-	fpsRegex := regexp.MustCompile(`a=framerate:([\d.]+)`)
-	
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		
-		// Начало медиа описания
-		if strings.HasPrefix(line, "m=video") {
-			if currentStream != nil {
-				streams = append(streams, *currentStream)
-			}
-			currentStream = &models.RTSPStreamInfo{
-				Available: true,
-				CheckedAt: time.Now(),
-			}
-		}
-		
-		if currentStream == nil {
-			continue
+
+	if len(info.VideoTracks) == 0 {
+		return streams, nil
+	}
+
+	// Одна models.RTSPStreamInfo на видео дорожку; аудио дорожка (если есть) у RTSP камер
+	// обычно одна на весь SDP, поэтому переносим её на каждый видео поток
+	for _, videoTrack := range info.VideoTracks {
+		stream := models.RTSPStreamInfo{
+			Codec:      videoTrack.Codec,
+			Resolution: videoTrack.Resolution,
+			FPS:        videoTrack.FPS,
+			Available:  true,
+			CheckedAt:  time.Now(),
 		}
-		
-		// Парсинг кодека
-		if matches := codecRegex.FindStringSubmatch(line); len(matches) > 0 {
-			codec := strings.ToUpper(matches[2])
-			if codec == "H264" || codec == "H265" || codec == "MPEG4" {
-				currentStream.Codec = codec
-			} else if codec == "JPEG" {
-				currentStream.Codec = "MJPEG"
+		if videoTrack.Profile != "" || videoTrack.Width > 0 {
+			stream.VideoCodecInfo = &models.VideoCodec{
+				Name:    videoTrack.Codec,
+				Profile: videoTrack.Profile,
+				Level:   videoTrack.Level,
+				Width:   videoTrack.Width,
+				Height:  videoTrack.Height,
+				FPS:     videoTrack.FPS,
 			}
 		}
-		
-		// Парсинг разрешения
-		if matches := resolutionRegex.FindStringSubmatch(line); len(matches) > 0 {
-			currentStream.Resolution = fmt.Sprintf("%sx%s", matches[2], matches[3])
-		}
-		
-		// Парсинг FPS
-		if matches := fpsRegex.FindStringSubmatch(line); len(matches) > 0 {
-			var fps float64
-			fmt.Sscanf(matches[1], "%f", &fps)
-			currentStream.FPS = fps
+		if len(info.AudioTracks) > 0 {
+			audioTrack := info.AudioTracks[0]
+			stream.AudioCodec = audioTrack.Codec
+			stream.Channels = audioTrack.Channels
+			if audioTrack.ObjectType > 0 || audioTrack.SampleRate > 0 {
+				stream.AudioCodecInfo = &models.AudioCodec{
+					Name:       audioTrack.Codec,
+					ObjectType: audioTrack.ObjectType,
+					SampleRate: audioTrack.SampleRate,
+					Channels:   audioTrack.Channels,
+				}
+			}
 		}
+		streams = append(streams, stream)
 	}
-	
-	if currentStream != nil {
-		streams = append(streams, *currentStream)
-	}
-	
+
 	return streams, nil
 }
@@ -0,0 +1,100 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// srtMagic - значение magic поля в расширении SRT хендшейка (Haivision SRT Protocol
+// Specification, handshake extension)
+const srtMagic = 0x4A17
+
+// srtHandshakeTypeInduction - тип хендшейка "induction", которым caller первым
+// обращается к listener'у
+const srtHandshakeTypeInduction = 1
+
+// SRTDetector - детектор SRT (Secure Reliable Transport) протокола
+type SRTDetector struct {
+	logger *logrus.Logger
+}
+
+// NewSRTDetector создает новый SRT детектор
+func NewSRTDetector() *SRTDetector {
+	return &SRTDetector{
+		logger: utils.GetLogger(),
+	}
+}
+
+// GetName возвращает название протокола
+func (d *SRTDetector) GetName() string {
+	return "SRT"
+}
+
+// GetDefaultPort возвращает порт по умолчанию
+func (d *SRTDetector) GetDefaultPort() int {
+	return 9710
+}
+
+// Detect проверяет наличие SRT протокола на устройстве, отправляя induction пакет
+// хендшейка и ожидая ответ от caller/listener
+func (d *SRTDetector) Detect(ip string, port int, timeout time.Duration) (*models.Protocol, error) {
+	protocol := &models.Protocol{
+		Type:       "SRT",
+		Port:       port,
+		Available:  false,
+		DetectedAt: time.Now(),
+	}
+
+	address := fmt.Sprintf("%s:%d", ip, port)
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return protocol, fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(buildInductionPacket()); err != nil {
+		return protocol, fmt.Errorf("failed to send induction packet: %w", err)
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return protocol, fmt.Errorf("no response to SRT induction: %w", err)
+	}
+
+	// Контрольные пакеты SRT имеют старший бит первого байта установлен в 1
+	if n < 16 || buf[0]&0x80 == 0 {
+		return protocol, fmt.Errorf("unexpected response to SRT induction")
+	}
+
+	protocol.Available = true
+	protocol.URL = fmt.Sprintf("srt://%s:%d", ip, port)
+	d.logger.Debugf("Found SRT responder at %s", address)
+	return protocol, nil
+}
+
+// buildInductionPacket строит минимальный SRT handshake induction пакет: 16-байтный
+// control заголовок (старший бит первого поля установлен - это control пакет, Control
+// Type = 0 означает HANDSHAKE, Destination Socket ID = 0 для induction) с упрощенным
+// handshake расширением, несущим srtMagic и тип хендшейка induction
+func buildInductionPacket() []byte {
+	header := make([]byte, 16)
+	binary.BigEndian.PutUint16(header[0:2], 0x8000) // control packet, Control Type = 0 (HANDSHAKE)
+	binary.BigEndian.PutUint16(header[2:4], 0)      // subtype
+	binary.BigEndian.PutUint32(header[4:8], 0)      // type-specific info / initial sequence number
+	binary.BigEndian.PutUint32(header[8:12], 0)     // timestamp
+	binary.BigEndian.PutUint32(header[12:16], 0)    // destination socket ID
+
+	extension := make([]byte, 8)
+	binary.BigEndian.PutUint16(extension[0:2], srtMagic)
+	binary.BigEndian.PutUint32(extension[2:6], srtHandshakeTypeInduction)
+
+	return append(header, extension...)
+}
@@ -7,10 +7,12 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/sdp"
 	"github.com/local-video-server/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -60,19 +62,114 @@ func (d *WebRTCDetector) Detect(ip string, port int, timeout time.Duration) (*mo
 	if d.checkWebInterfaceForWebRTC(client, ip, port) {
 		protocol.Available = true
 		protocol.URL = fmt.Sprintf("http://%s:%d", ip, port)
+		protocol.WebRTCInfo = d.checkSDPOffer(client, ip, port)
 		return protocol, nil
 	}
 
 	// Проверяем наличие STUN/TURN серверов
-	if d.checkSTUNTURN(client, ip, port) {
+	if found, publicAddr := d.checkSTUNTURN(client, ip, port); found {
 		protocol.Available = true
 		protocol.URL = fmt.Sprintf("http://%s:%d", ip, port)
+		protocol.PublicAddress = publicAddr
+		protocol.WebRTCInfo = d.checkSDPOffer(client, ip, port)
 		return protocol, nil
 	}
 
 	return protocol, fmt.Errorf("WebRTC not found")
 }
 
+// checkSDPOffer ищет SDP offer/answer, который веб-интерфейс устройства обычно отдает как
+// JSON {"sdp": "...", "type": "offer"} на одном из типовых API путей, и разбирает его через
+// pkg/sdp, чтобы заполнить Protocol.WebRTCInfo кодеками, BUNDLE группой и ICE параметрами.
+// Возвращает nil, если ни один из путей не отдал разбираемый SDP
+func (d *WebRTCDetector) checkSDPOffer(client *http.Client, ip string, port int) *models.WebRTCCodecInfo {
+	paths := []string{"/api/webrtc/offer", "/api/webrtc/sdp", "/webrtc/offer", "/offer.json"}
+
+	for _, scheme := range []string{"http", "https"} {
+		for _, path := range paths {
+			url := fmt.Sprintf("%s://%s:%d%s", scheme, ip, port, path)
+
+			sessionDesc := func() *sdp.SessionDescription {
+				resp, err := client.Get(url)
+				if err != nil {
+					return nil
+				}
+				defer resp.Body.Close()
+
+				if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+					return nil
+				}
+
+				body, err := io.ReadAll(io.LimitReader(resp.Body, 65536))
+				if err != nil {
+					return nil
+				}
+
+				var payload struct {
+					SDP string `json:"sdp"`
+				}
+				if err := json.Unmarshal(body, &payload); err != nil || payload.SDP == "" {
+					return nil
+				}
+
+				parsed, err := sdp.Parse(payload.SDP)
+				if err != nil {
+					return nil
+				}
+				return parsed
+			}()
+
+			if sessionDesc != nil {
+				return toWebRTCCodecInfo(sessionDesc)
+			}
+		}
+	}
+
+	return nil
+}
+
+// toWebRTCCodecInfo сворачивает разобранное SDP сообщение в models.WebRTCCodecInfo: кодеки
+// и кандидаты собираются по всем m= секциям, ICE учетные данные и BUNDLE группа берутся с
+// уровня сессии, если они не заданы на уровне конкретной m= секции
+func toWebRTCCodecInfo(sessionDesc *sdp.SessionDescription) *models.WebRTCCodecInfo {
+	info := &models.WebRTCCodecInfo{
+		ICEUfrag: sessionDesc.ICEUfrag,
+		ICEPwd:   sessionDesc.ICEPwd,
+	}
+
+	seenCodec := make(map[string]bool)
+	for _, media := range sessionDesc.Media {
+		for _, rtpmap := range media.RTPMaps {
+			if !seenCodec[rtpmap.EncodingName] {
+				seenCodec[rtpmap.EncodingName] = true
+				info.Codecs = append(info.Codecs, rtpmap.EncodingName)
+			}
+		}
+
+		for _, candidate := range media.Candidates {
+			info.Candidates = append(info.Candidates, net.JoinHostPort(candidate.Address, strconv.Itoa(candidate.Port)))
+		}
+
+		if media.Fingerprint != nil && info.Fingerprint == "" {
+			info.Fingerprint = media.Fingerprint.HashFunction + " " + media.Fingerprint.Value
+		}
+		if info.ICEUfrag == "" {
+			info.ICEUfrag = media.ICEUfrag
+		}
+		if info.ICEPwd == "" {
+			info.ICEPwd = media.ICEPwd
+		}
+	}
+
+	for _, group := range sessionDesc.Groups {
+		if strings.EqualFold(group.Semantics, "BUNDLE") {
+			info.BundleGroup = append(info.BundleGroup, group.Identification...)
+		}
+	}
+
+	return info
+}
+
 // checkWebInterfaceForWebRTC проверяет веб-интерфейс на наличие WebRTC
 func (d *WebRTCDetector) checkWebInterfaceForWebRTC(client *http.Client, ip string, port int) bool {
 	schemes := []string{"http", "https"}
@@ -160,23 +257,24 @@ func (d *WebRTCDetector) checkWebInterfaceForWebRTC(client *http.Client, ip stri
 	return false
 }
 
-// checkSTUNTURN проверяет наличие STUN/TURN серверов
-func (d *WebRTCDetector) checkSTUNTURN(client *http.Client, ip string, port int) bool {
+// checkSTUNTURN проверяет наличие STUN/TURN серверов. Возвращает рефлексивный адрес,
+// полученный от STUN (если он был), вторым значением
+func (d *WebRTCDetector) checkSTUNTURN(client *http.Client, ip string, port int) (bool, string) {
 	// Стандартные порты для STUN/TURN
 	stunPorts := []int{3478, 5349}
 	turnPorts := []int{3478, 5349}
 
 	// Проверяем STUN
 	for _, stunPort := range stunPorts {
-		if d.checkSTUNServer(ip, stunPort, 2*time.Second) {
-			return true
+		if found, publicAddr := d.checkSTUNServer(ip, stunPort, 2*time.Second); found {
+			return true, publicAddr
 		}
 	}
 
 	// Проверяем TURN
 	for _, turnPort := range turnPorts {
 		if d.checkTURNServer(ip, turnPort, 2*time.Second) {
-			return true
+			return true, ""
 		}
 	}
 
@@ -184,7 +282,7 @@ func (d *WebRTCDetector) checkSTUNTURN(client *http.Client, ip string, port int)
 	configPaths := []string{"/api/webrtc/config", "/config/webrtc.json", "/webrtc/config"}
 	for _, path := range configPaths {
 		url := fmt.Sprintf("http://%s:%d%s", ip, port, path)
-		
+
 		resp, err := client.Get(url)
 		if err != nil {
 			continue
@@ -204,53 +302,111 @@ func (d *WebRTCDetector) checkSTUNTURN(client *http.Client, ip string, port int)
 				if err := json.Unmarshal(body[:n], &config); err == nil {
 					// Проверяем наличие STUN/TURN конфигурации
 					if configStr, ok := config["stun"].(string); ok && configStr != "" {
-						return true
+						return true, ""
 					}
 					if configStr, ok := config["turn"].(string); ok && configStr != "" {
-						return true
+						return true, ""
 					}
 					if iceServers, ok := config["iceServers"].([]interface{}); ok && len(iceServers) > 0 {
-						return true
+						return true, ""
 					}
 				}
 			}
 		}
 	}
 
-	return false
+	return false, ""
 }
 
-// checkSTUNServer проверяет доступность STUN сервера
-func (d *WebRTCDetector) checkSTUNServer(ip string, port int, timeout time.Duration) bool {
-	// STUN использует UDP протокол
-	// Простая проверка - пытаемся подключиться
+// checkSTUNServer проверяет доступность STUN сервера настоящим Binding Request (RFC 5389,
+// раздел 7.1): если собеседник не ответил по UDP в пределах timeout, запрос повторяется
+// один раз по TCP (RFC 5389, раздел 7.2.2) - некоторые встроенные STUN серверы слушают
+// только на TCP. Возвращает также рефлексивный адрес из XOR-MAPPED-ADDRESS, если он был в
+// ответе, чтобы Detect мог заполнить Protocol.PublicAddress
+func (d *WebRTCDetector) checkSTUNServer(ip string, port int, timeout time.Duration) (bool, string) {
 	address := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("udp", address, timeout)
+
+	request, transactionID, err := buildSTUNBindingRequest()
 	if err != nil {
-		return false
+		return false, ""
+	}
+
+	msgType, attrs, err := d.sendSTUNMessage("udp", address, timeout, request, transactionID)
+	if err != nil {
+		msgType, attrs, err = d.sendSTUNMessage("tcp", address, timeout, request, transactionID)
+		if err != nil {
+			return false, ""
+		}
 	}
-	defer conn.Close()
 
-	// STUN Binding Request (упрощенная версия)
-	// В реальной реализации здесь должен быть полный STUN запрос
-	// Для детектирования достаточно проверки доступности порта
-	return true
+	if msgType != stunMsgBindingSuccess {
+		return false, ""
+	}
+
+	for _, attr := range attrs {
+		if attr.Type == stunAttrXorMappedAddress {
+			if publicAddr, err := parseXorMappedAddress(attr.Value); err == nil {
+				return true, publicAddr
+			}
+		}
+	}
+
+	return true, ""
 }
 
-// checkTURNServer проверяет доступность TURN сервера
+// checkTURNServer проверяет доступность TURN сервера настоящим Allocate запросом (RFC 5766,
+// раздел 6.1): STUN Binding Request на том же порту отвечают и обычные STUN серверы, поэтому
+// он не различает протоколы. Allocate без учетных данных TURN сервер обязан отклонить
+// "401 Unauthorized" (тип сообщения 0x0113, ERROR-CODE 401) - это и есть позитивное
+// обнаружение TURN, а не провал проверки
 func (d *WebRTCDetector) checkTURNServer(ip string, port int, timeout time.Duration) bool {
-	// TURN также использует UDP (или TCP)
-	// Аналогично STUN, для детектирования проверяем доступность порта
 	address := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("udp", address, timeout)
+
+	request, transactionID, err := buildTURNAllocateRequest()
 	if err != nil {
-		// Пробуем TCP
-		conn, err = net.DialTimeout("tcp", address, timeout)
+		return false
+	}
+
+	msgType, attrs, err := d.sendSTUNMessage("udp", address, timeout, request, transactionID)
+	if err != nil {
+		msgType, attrs, err = d.sendSTUNMessage("tcp", address, timeout, request, transactionID)
 		if err != nil {
 			return false
 		}
 	}
+
+	if msgType != stunMsgAllocateError {
+		return false
+	}
+
+	for _, attr := range attrs {
+		if attr.Type == stunAttrErrorCode && len(attr.Value) >= 4 && attr.Value[2] == 4 && attr.Value[3] == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sendSTUNMessage отправляет готовое STUN сообщение на address по заданной сети ("udp" или
+// "tcp") и возвращает тип и атрибуты ответа. На TCP ответ читается до тех пор, пока не
+// наберется длина, заявленная в заголовке STUN - сегмент может прийти несколькими read()
+func (d *WebRTCDetector) sendSTUNMessage(network, address string, timeout time.Duration, request, transactionID []byte) (uint16, []stunAttribute, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return 0, nil, err
+	}
 	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(request); err != nil {
+		return 0, nil, err
+	}
+
+	resp, err := readSTUNResponse(conn, network)
+	if err != nil {
+		return 0, nil, err
+	}
 
-	return true
+	return parseSTUNMessage(resp, transactionID)
 }
@@ -0,0 +1,199 @@
+package protocols
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// stunMagicCookie - константа STUN (RFC 5389, раздел 6), обязана стоять в байтах 4-7
+// заголовка любого STUN сообщения и используется при XOR-декодировании MAPPED-ADDRESS
+const stunMagicCookie uint32 = 0x2112A442
+
+// Типы STUN/TURN сообщений, которые различает этот детектор (RFC 5389, раздел 6; Allocate -
+// RFC 5766, раздел 6.1)
+const (
+	stunMsgBindingRequest = 0x0001
+	stunMsgBindingSuccess = 0x0101
+
+	stunMsgAllocateRequest = 0x0003
+	stunMsgAllocateError   = 0x0113
+)
+
+// Типы TLV атрибутов STUN, которые этот детектор умеет читать из ответа или должен положить
+// в Allocate запрос
+const (
+	stunAttrXorMappedAddress   = 0x0020
+	stunAttrErrorCode          = 0x0009
+	stunAttrRequestedTransport = 0x0019
+)
+
+// turnTransportUDP - номер протокола UDP (IANA), который указывается в атрибуте
+// REQUESTED-TRANSPORT Allocate запроса (RFC 5766, раздел 14.7)
+const turnTransportUDP = 17
+
+// stunHeaderSize - размер фиксированного заголовка STUN сообщения: 2 байта типа, 2 байта
+// длины тела, 4 байта magic cookie, 12 байт transaction ID (RFC 5389, раздел 6)
+const stunHeaderSize = 20
+
+// stunTransactionIDSize - размер transaction ID в заголовке STUN сообщения
+const stunTransactionIDSize = 12
+
+// buildSTUNBindingRequest собирает 20-байтовый STUN Binding Request без атрибутов: тип
+// 0x0001, нулевая длина тела, magic cookie и случайный transaction ID. Возвращает также сам
+// transaction ID, чтобы вызывающий код мог сверить его с ответом
+func buildSTUNBindingRequest() ([]byte, []byte, error) {
+	transactionID := make([]byte, stunTransactionIDSize)
+	if _, err := rand.Read(transactionID); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate transaction id: %w", err)
+	}
+
+	msg := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(msg[0:2], stunMsgBindingRequest)
+	binary.BigEndian.PutUint16(msg[2:4], 0)
+	binary.BigEndian.PutUint32(msg[4:8], stunMagicCookie)
+	copy(msg[8:20], transactionID)
+
+	return msg, transactionID, nil
+}
+
+// buildTURNAllocateRequest собирает STUN Allocate запрос (RFC 5766, раздел 6.1) с единственным
+// обязательным атрибутом REQUESTED-TRANSPORT=UDP и без учетных данных - TURN сервер обязан
+// ответить "401 Unauthorized", тогда как обычный STUN сервер Allocate вовсе не понимает.
+// Возвращает также transaction ID, чтобы вызывающий код мог сверить его с ответом
+func buildTURNAllocateRequest() ([]byte, []byte, error) {
+	transactionID := make([]byte, stunTransactionIDSize)
+	if _, err := rand.Read(transactionID); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate transaction id: %w", err)
+	}
+
+	requestedTransport := make([]byte, 4)
+	requestedTransport[0] = turnTransportUDP
+
+	attrs := make([]byte, 4+len(requestedTransport))
+	binary.BigEndian.PutUint16(attrs[0:2], stunAttrRequestedTransport)
+	binary.BigEndian.PutUint16(attrs[2:4], uint16(len(requestedTransport)))
+	copy(attrs[4:], requestedTransport)
+
+	msg := make([]byte, stunHeaderSize+len(attrs))
+	binary.BigEndian.PutUint16(msg[0:2], stunMsgAllocateRequest)
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(attrs)))
+	binary.BigEndian.PutUint32(msg[4:8], stunMagicCookie)
+	copy(msg[8:20], transactionID)
+	copy(msg[20:], attrs)
+
+	return msg, transactionID, nil
+}
+
+// readSTUNResponse читает STUN ответ из conn. На UDP сообщение приходит одним datagram'ом и
+// читается одним Read. На TCP это байтовый поток (RFC 5389, раздел 7.2.2), поэтому сначала
+// дочитывается заголовок, а затем - ровно столько байт тела, сколько в нем заявлено
+func readSTUNResponse(conn net.Conn, network string) ([]byte, error) {
+	if network != "tcp" {
+		buf := make([]byte, 1500)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	header := make([]byte, stunHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("failed to read STUN header over TCP: %w", err)
+	}
+
+	msgLength := binary.BigEndian.Uint16(header[2:4])
+	body := make([]byte, msgLength)
+	if msgLength > 0 {
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return nil, fmt.Errorf("failed to read STUN body over TCP: %w", err)
+		}
+	}
+
+	return append(header, body...), nil
+}
+
+// stunAttribute - один TLV атрибут STUN сообщения (RFC 5389, раздел 15)
+type stunAttribute struct {
+	Type  uint16
+	Value []byte
+}
+
+// parseSTUNMessage проверяет заголовок STUN ответа (magic cookie, совпадение transaction ID)
+// и возвращает его тип сообщения вместе с разобранными атрибутами тела
+func parseSTUNMessage(resp []byte, wantTransactionID []byte) (uint16, []stunAttribute, error) {
+	if len(resp) < stunHeaderSize {
+		return 0, nil, fmt.Errorf("STUN response too short: %d bytes", len(resp))
+	}
+
+	msgType := binary.BigEndian.Uint16(resp[0:2])
+	msgLength := binary.BigEndian.Uint16(resp[2:4])
+	cookie := binary.BigEndian.Uint32(resp[4:8])
+	transactionID := resp[8:20]
+
+	if cookie != stunMagicCookie {
+		return 0, nil, fmt.Errorf("unexpected STUN magic cookie: %#x", cookie)
+	}
+	if !bytesEqual(transactionID, wantTransactionID) {
+		return 0, nil, fmt.Errorf("STUN transaction id mismatch")
+	}
+	if int(stunHeaderSize)+int(msgLength) > len(resp) {
+		return 0, nil, fmt.Errorf("STUN message length %d exceeds response size %d", msgLength, len(resp))
+	}
+
+	var attrs []stunAttribute
+	body := resp[stunHeaderSize : stunHeaderSize+int(msgLength)]
+	for len(body) >= 4 {
+		attrType := binary.BigEndian.Uint16(body[0:2])
+		attrLen := int(binary.BigEndian.Uint16(body[2:4]))
+		if 4+attrLen > len(body) {
+			break
+		}
+		attrs = append(attrs, stunAttribute{Type: attrType, Value: body[4 : 4+attrLen]})
+
+		// Атрибуты STUN выровнены по границе 4 байт (RFC 5389, раздел 15)
+		padded := (attrLen + 3) &^ 3
+		body = body[4+padded:]
+	}
+
+	return msgType, attrs, nil
+}
+
+// parseXorMappedAddress декодирует атрибут XOR-MAPPED-ADDRESS (RFC 5389, раздел 15.2): порт
+// ксорится со старшими 16 битами magic cookie, IPv4 адрес - с полным magic cookie. IPv6
+// (family 0x02) этому детектору не встречался в проде, поддерживается только family 0x01
+func parseXorMappedAddress(value []byte) (string, error) {
+	if len(value) < 8 {
+		return "", fmt.Errorf("XOR-MAPPED-ADDRESS attribute too short: %d bytes", len(value))
+	}
+
+	family := value[1]
+	if family != 0x01 {
+		return "", fmt.Errorf("unsupported XOR-MAPPED-ADDRESS family: %#x", family)
+	}
+
+	xport := binary.BigEndian.Uint16(value[2:4])
+	port := xport ^ uint16(stunMagicCookie>>16)
+
+	var ip [4]byte
+	xip := binary.BigEndian.Uint32(value[4:8])
+	binary.BigEndian.PutUint32(ip[:], xip^stunMagicCookie)
+
+	return fmt.Sprintf("%s:%d", net.IP(ip[:]).String(), port), nil
+}
+
+// bytesEqual сравнивает два среза байт одинаковой ожидаемой длины
+func bytesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package protocols
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// whepContentType - MIME тип SDP ответа WHEP сервера
+const whepContentType = "application/sdp"
+
+// whepPaths - наиболее распространенные пути WHEP эндпоинтов (MediaMTX, go2rtc и др.)
+var whepPaths = []string{"/whep", "/whep/endpoint", "/live/whep"}
+
+// whepFakeOffer - минимальный валидный SDP offer, достаточный, чтобы WHEP сервер
+// сгенерировал и вернул SDP answer с ICE/DTLS параметрами
+const whepFakeOffer = `v=0
+o=- 0 0 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=sendonly
+a=mid:0
+`
+
+// WHEPDetector - детектор WebRTC-HTTP Egress Protocol (WHEP)
+type WHEPDetector struct {
+	logger *logrus.Logger
+}
+
+// NewWHEPDetector создает новый WHEP детектор
+func NewWHEPDetector() *WHEPDetector {
+	return &WHEPDetector{
+		logger: utils.GetLogger(),
+	}
+}
+
+// GetName возвращает название протокола
+func (d *WHEPDetector) GetName() string {
+	return "WHEP"
+}
+
+// GetDefaultPort возвращает порт по умолчанию
+func (d *WHEPDetector) GetDefaultPort() int {
+	return 8889
+}
+
+// Detect перебирает распространенные WHEP пути, отправляя на каждый OPTIONS и POST c
+// фиктивным SDP offer, и считает протокол обнаруженным, если сервер вернул
+// application/sdp с ICE/DTLS параметрами
+func (d *WHEPDetector) Detect(ip string, port int, timeout time.Duration) (*models.Protocol, error) {
+	protocol := &models.Protocol{
+		Type:       "WHEP",
+		Port:       port,
+		Available:  false,
+		DetectedAt: time.Now(),
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	for _, path := range whepPaths {
+		url := fmt.Sprintf("http://%s:%d%s", ip, port, path)
+		if d.probe(client, url) {
+			protocol.Available = true
+			protocol.URL = url
+			d.logger.Debugf("Found WHEP endpoint at %s", url)
+			return protocol, nil
+		}
+	}
+
+	return protocol, fmt.Errorf("no WHEP endpoint found on %s:%d", ip, port)
+}
+
+// probe проверяет один кандидат в эндпоинты: OPTIONS как дешевая предварительная
+// проверка (большинство WHEP серверов отвечают на него CORS заголовками, не создавая
+// сессию), затем POST с фиктивным offer - решающая проверка по телу SDP ответа
+func (d *WHEPDetector) probe(client *http.Client, url string) bool {
+	if req, err := http.NewRequest(http.MethodOptions, url, nil); err == nil {
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(whepFakeOffer))
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Content-Type", whepContentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	if !strings.Contains(resp.Header.Get("Content-Type"), whepContentType) {
+		return false
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false
+	}
+
+	answer := string(body)
+	return strings.Contains(answer, "a=ice-ufrag") && strings.Contains(answer, "a=fingerprint")
+}
@@ -0,0 +1,261 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// devicesBucket хранит последнее известное состояние каждого устройства: ключ - Store key
+// (deviceIdentity, см. store.go), значение - JSON models.Device
+var devicesBucket = []byte("devices")
+
+// historyBucket содержит по под-бакету на Store key, в котором ключ - RFC3339Nano
+// таймстамп, а значение - JSON DeviceSnapshot
+var historyBucket = []byte("history")
+
+// BoltStore - персистентная реализация Store поверх BoltDB (bucket на IP для истории
+// изменений, как описано в документации bbolt)
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore открывает (создавая при необходимости) файл BoltDB по указанному пути
+// и готовит в нем бакеты devices/history
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(devicesBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(historyBucket); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize bolt buckets: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Upsert добавляет или обновляет устройство под ключом key, записывая снимок в историю при
+// изменении hostname, протоколов или потоков
+func (s *BoltStore) Upsert(key string, device *models.Device) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		devices := tx.Bucket(devicesBucket)
+		now := time.Now()
+
+		var existing *models.Device
+		if raw := devices.Get([]byte(key)); raw != nil {
+			existing = &models.Device{}
+			if err := json.Unmarshal(raw, existing); err != nil {
+				return fmt.Errorf("failed to decode stored device %s: %w", key, err)
+			}
+		}
+
+		if deviceChanged(existing, device) {
+			history, err := tx.Bucket(historyBucket).CreateBucketIfNotExists([]byte(key))
+			if err != nil {
+				return fmt.Errorf("failed to create history bucket for %s: %w", key, err)
+			}
+			snapshot := DeviceSnapshot{IP: device.IP, Timestamp: now, Device: *device}
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				return fmt.Errorf("failed to encode snapshot for %s: %w", key, err)
+			}
+			if err := history.Put([]byte(now.Format(time.RFC3339Nano)), data); err != nil {
+				return fmt.Errorf("failed to store snapshot for %s: %w", key, err)
+			}
+		}
+
+		if existing != nil {
+			device.DiscoveredAt = existing.DiscoveredAt
+		} else {
+			device.DiscoveredAt = now
+		}
+		device.LastSeen = now
+
+		data, err := json.Marshal(device)
+		if err != nil {
+			return fmt.Errorf("failed to encode device %s: %w", key, err)
+		}
+		return devices.Put([]byte(key), data)
+	})
+}
+
+// Get возвращает устройство по key
+func (s *BoltStore) Get(key string) (*models.Device, bool, error) {
+	var device *models.Device
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(devicesBucket).Get([]byte(key))
+		if raw == nil {
+			return nil
+		}
+		device = &models.Device{}
+		return json.Unmarshal(raw, device)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read device %s: %w", key, err)
+	}
+	if device == nil {
+		return nil, false, nil
+	}
+	return device, true, nil
+}
+
+// List возвращает устройства, удовлетворяющие фильтру
+func (s *BoltStore) List(filter Filter) ([]*models.Device, error) {
+	var result []*models.Device
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(devicesBucket).ForEach(func(k, v []byte) error {
+			device := &models.Device{}
+			if err := json.Unmarshal(v, device); err != nil {
+				return fmt.Errorf("failed to decode device %s: %w", k, err)
+			}
+			if filter.matches(device) {
+				result = append(result, device)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// History возвращает снимки изменений устройства с указанного момента времени
+func (s *BoltStore) History(key string, since time.Time) ([]DeviceSnapshot, error) {
+	var result []DeviceSnapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		history := tx.Bucket(historyBucket).Bucket([]byte(key))
+		if history == nil {
+			return nil
+		}
+		return history.ForEach(func(k, v []byte) error {
+			var snapshot DeviceSnapshot
+			if err := json.Unmarshal(v, &snapshot); err != nil {
+				return fmt.Errorf("failed to decode snapshot %s for %s: %w", k, key, err)
+			}
+			if snapshot.Timestamp.After(since) || snapshot.Timestamp.Equal(since) {
+				result = append(result, snapshot)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result, nil
+}
+
+// GetDevicesSeenBetween возвращает устройства, чье LastSeen попадает в [from, to]
+func (s *BoltStore) GetDevicesSeenBetween(from, to time.Time) ([]*models.Device, error) {
+	var result []*models.Device
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(devicesBucket).ForEach(func(k, v []byte) error {
+			device := &models.Device{}
+			if err := json.Unmarshal(v, device); err != nil {
+				return fmt.Errorf("failed to decode device %s: %w", k, err)
+			}
+			if (device.LastSeen.After(from) || device.LastSeen.Equal(from)) &&
+				(device.LastSeen.Before(to) || device.LastSeen.Equal(to)) {
+				result = append(result, device)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDevicesGoneOfflineSince возвращает устройства, последний раз виденные до t
+func (s *BoltStore) GetDevicesGoneOfflineSince(t time.Time) ([]*models.Device, error) {
+	var result []*models.Device
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(devicesBucket).ForEach(func(k, v []byte) error {
+			device := &models.Device{}
+			if err := json.Unmarshal(v, device); err != nil {
+				return fmt.Errorf("failed to decode device %s: %w", k, err)
+			}
+			if device.LastSeen.Before(t) {
+				result = append(result, device)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete удаляет устройство key и его историю изменений
+func (s *BoltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(devicesBucket).Delete([]byte(key)); err != nil {
+			return fmt.Errorf("failed to delete device %s: %w", key, err)
+		}
+		if err := tx.Bucket(historyBucket).DeleteBucket([]byte(key)); err != nil && err != bolt.ErrBucketNotFound {
+			return fmt.Errorf("failed to delete history for %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// Rekey переносит запись и историю изменений устройства с oldKey на newKey. bbolt не дает
+// переименовать ключ/бакет на месте, поэтому запись и снимки истории читаются и
+// записываются заново под newKey, после чего старые удаляются
+func (s *BoltStore) Rekey(oldKey, newKey string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		devices := tx.Bucket(devicesBucket)
+		if raw := devices.Get([]byte(oldKey)); raw != nil {
+			if err := devices.Put([]byte(newKey), raw); err != nil {
+				return fmt.Errorf("failed to move device %s to %s: %w", oldKey, newKey, err)
+			}
+			if err := devices.Delete([]byte(oldKey)); err != nil {
+				return fmt.Errorf("failed to delete old device key %s: %w", oldKey, err)
+			}
+		}
+
+		history := tx.Bucket(historyBucket)
+		oldHistory := history.Bucket([]byte(oldKey))
+		if oldHistory == nil {
+			return nil
+		}
+		newHistory, err := history.CreateBucketIfNotExists([]byte(newKey))
+		if err != nil {
+			return fmt.Errorf("failed to create history bucket for %s: %w", newKey, err)
+		}
+		if err := oldHistory.ForEach(func(k, v []byte) error {
+			return newHistory.Put(k, v)
+		}); err != nil {
+			return fmt.Errorf("failed to copy history from %s to %s: %w", oldKey, newKey, err)
+		}
+		if err := history.DeleteBucket([]byte(oldKey)); err != nil {
+			return fmt.Errorf("failed to delete old history bucket %s: %w", oldKey, err)
+		}
+		return nil
+	})
+}
+
+// Close закрывает файл BoltDB
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/local-video-server/internal/credentials"
+	"github.com/local-video-server/internal/licensing"
+	"github.com/local-video-server/internal/protocols/onvif"
+	"github.com/sirupsen/logrus"
+)
+
+// SetVault подключает vault, с которым будут работать SetCredential/GetCredential/
+// RotateCredential. Без вызова SetVault все три метода возвращают ошибку - тот же опциональный-
+// зависимости паттерн, что у Detector.SetDispatcher
+func (r *DeviceRegistry) SetVault(vault credentials.Vault) {
+	r.vault = vault
+}
+
+// auditCredentialEvent пишет в общий логгер реестра одну строку аудита на каждое обращение
+// к учетным данным устройства - чтение, запись или смену пароля. endpointRef и username
+// логируются, пароль - никогда
+func (r *DeviceRegistry) auditCredentialEvent(action, endpointRef, username string, err error) {
+	fields := logrus.Fields{
+		"action":       action,
+		"endpoint_ref": endpointRef,
+		"username":     username,
+	}
+	if err != nil {
+		r.logger.WithFields(fields).Warnf("credential audit: %s failed: %v", action, err)
+		return
+	}
+	r.logger.WithFields(fields).Infof("credential audit: %s succeeded", action)
+}
+
+// SetCredential сохраняет c в подключенном vault под ключом endpointRef, перезаписывая
+// предыдущее значение, если оно было. Каждый вызов пишет строку аудита независимо от
+// результата
+func (r *DeviceRegistry) SetCredential(endpointRef string, c credentials.Credential) error {
+	if r.vault == nil {
+		err := fmt.Errorf("no credential vault configured, call SetVault first")
+		r.auditCredentialEvent("set", endpointRef, c.Username, err)
+		return err
+	}
+
+	err := r.vault.Set(endpointRef, c)
+	r.auditCredentialEvent("set", endpointRef, c.Username, err)
+	return err
+}
+
+// GetCredential читает учетные данные устройства endpointRef из подключенного vault.
+// Каждое обращение пишет строку аудита, в том числе успешное - запрос к учетным данным сам
+// по себе достоин журналирования, даже если ничего не нашлось
+func (r *DeviceRegistry) GetCredential(endpointRef string) (credentials.Credential, bool, error) {
+	if r.vault == nil {
+		err := fmt.Errorf("no credential vault configured, call SetVault first")
+		r.auditCredentialEvent("get", endpointRef, "", err)
+		return credentials.Credential{}, false, err
+	}
+
+	c, ok, err := r.vault.Get(endpointRef)
+	r.auditCredentialEvent("get", endpointRef, c.Username, err)
+	return c, ok, err
+}
+
+// RotateCredential меняет пароль устройства endpointRef с old на new: сначала обращается к
+// самому устройству через ONVIF SetUser/CreateUsers (onvif.Client.RotateUser) под учетными
+// данными old, и только если устройство приняло новый пароль - записывает new в vault. Если
+// запись в vault не удалась, откатывает пароль устройства обратно на old тем же RotateUser - иначе
+// vault и реальное состояние устройства разошлись бы, и ни одна сохраненная пара не
+// подошла бы при следующем обращении. Требует устройство, зарегистрированное с EndpointRef
+// (обычное ограничение ONVIF - у устройств без него нет SetUser/CreateUsers) и известный
+// ONVIF device service XAddr. Доступно только в редакции Enterprise
+// (licensing.FeatureCredentialRotation) - в отличие от SetCredential/GetCredential, реально
+// меняет состояние устройства, а не только запись в vault
+func (r *DeviceRegistry) RotateCredential(endpointRef string, old, newCred credentials.Credential) error {
+	if !licensing.DefaultGates().Allow(licensing.FeatureCredentialRotation) {
+		err := fmt.Errorf("credential rotation requires %s edition (current: %s)", licensing.EditionEnterprise, licensing.DefaultGates().Edition())
+		r.auditCredentialEvent("rotate", endpointRef, newCred.Username, err)
+		return err
+	}
+
+	if r.vault == nil {
+		err := fmt.Errorf("no credential vault configured, call SetVault first")
+		r.auditCredentialEvent("rotate", endpointRef, newCred.Username, err)
+		return err
+	}
+
+	device, exists := r.GetDeviceByEndpointRef(endpointRef)
+	if !exists {
+		err := fmt.Errorf("no device registered with endpoint ref %q", endpointRef)
+		r.auditCredentialEvent("rotate", endpointRef, newCred.Username, err)
+		return err
+	}
+
+	oldONVIFCreds := onvif.Credentials{Username: old.Username, Password: old.Password}
+	client, _, err := onvif.ProbeDeviceService(device.IP, nil, oldONVIFCreds)
+	if err != nil {
+		wrapped := fmt.Errorf("failed to reach ONVIF device service on %s: %w", device.IP, err)
+		r.auditCredentialEvent("rotate", endpointRef, newCred.Username, wrapped)
+		return wrapped
+	}
+
+	if err := client.RotateUser(newCred.Username, newCred.Password); err != nil {
+		wrapped := fmt.Errorf("ONVIF credential rotation failed on %s: %w", device.IP, err)
+		r.auditCredentialEvent("rotate", endpointRef, newCred.Username, wrapped)
+		return wrapped
+	}
+
+	if err := r.vault.Set(endpointRef, newCred); err != nil {
+		// Устройство уже приняло new, но vault его не сохранил - откатываем устройство
+		// обратно на old, чтобы оно и vault снова указывали на одну и ту же пару
+		if rollbackErr := onvif.RotateUser(client.XAddr(), onvif.Credentials{Username: newCred.Username, Password: newCred.Password}, old.Username, old.Password); rollbackErr != nil {
+			wrapped := fmt.Errorf("vault write failed (%v) and device rollback also failed: %w", err, rollbackErr)
+			r.auditCredentialEvent("rotate", endpointRef, newCred.Username, wrapped)
+			return wrapped
+		}
+		wrapped := fmt.Errorf("vault write failed, device credential rolled back: %w", err)
+		r.auditCredentialEvent("rotate", endpointRef, newCred.Username, wrapped)
+		return wrapped
+	}
+
+	r.auditCredentialEvent("rotate", endpointRef, newCred.Username, nil)
+	return nil
+}
@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// MemoryStore - реализация Store поверх карты в памяти, без персистентности между
+// перезапусками. Сохраняет прежнее поведение DeviceRegistry, но дополнительно ведет
+// историю изменений устройств
+type MemoryStore struct {
+	mu        sync.RWMutex
+	devices   map[string]*models.Device
+	snapshots map[string][]DeviceSnapshot
+}
+
+// NewMemoryStore создает пустой Store в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		devices:   make(map[string]*models.Device),
+		snapshots: make(map[string][]DeviceSnapshot),
+	}
+}
+
+// Upsert добавляет или обновляет устройство под ключом key, записывая снимок в историю при
+// изменении hostname, протоколов или потоков
+func (s *MemoryStore) Upsert(key string, device *models.Device) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	existing, exists := s.devices[key]
+
+	if deviceChanged(existing, device) {
+		snapshot := *device
+		s.snapshots[key] = append(s.snapshots[key], DeviceSnapshot{
+			IP:        device.IP,
+			Timestamp: now,
+			Device:    snapshot,
+		})
+	}
+
+	if exists {
+		device.DiscoveredAt = existing.DiscoveredAt
+	} else {
+		device.DiscoveredAt = now
+	}
+	device.LastSeen = now
+
+	stored := *device
+	s.devices[key] = &stored
+	return nil
+}
+
+// Get возвращает устройство по key
+func (s *MemoryStore) Get(key string) (*models.Device, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	device, exists := s.devices[key]
+	if !exists {
+		return nil, false, nil
+	}
+	result := *device
+	return &result, true, nil
+}
+
+// List возвращает устройства, удовлетворяющие фильтру
+func (s *MemoryStore) List(filter Filter) ([]*models.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Device, 0, len(s.devices))
+	for _, device := range s.devices {
+		if filter.matches(device) {
+			d := *device
+			result = append(result, &d)
+		}
+	}
+	return result, nil
+}
+
+// History возвращает снимки изменений устройства с указанного момента времени
+func (s *MemoryStore) History(key string, since time.Time) ([]DeviceSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []DeviceSnapshot
+	for _, snapshot := range s.snapshots[key] {
+		if snapshot.Timestamp.After(since) || snapshot.Timestamp.Equal(since) {
+			result = append(result, snapshot)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result, nil
+}
+
+// GetDevicesSeenBetween возвращает устройства, чье LastSeen попадает в [from, to]
+func (s *MemoryStore) GetDevicesSeenBetween(from, to time.Time) ([]*models.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.Device
+	for _, device := range s.devices {
+		if (device.LastSeen.After(from) || device.LastSeen.Equal(from)) &&
+			(device.LastSeen.Before(to) || device.LastSeen.Equal(to)) {
+			d := *device
+			result = append(result, &d)
+		}
+	}
+	return result, nil
+}
+
+// GetDevicesGoneOfflineSince возвращает устройства, последний раз виденные до t
+func (s *MemoryStore) GetDevicesGoneOfflineSince(t time.Time) ([]*models.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.Device
+	for _, device := range s.devices {
+		if device.LastSeen.Before(t) {
+			d := *device
+			result = append(result, &d)
+		}
+	}
+	return result, nil
+}
+
+// Delete удаляет устройство key и его историю изменений
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.devices, key)
+	delete(s.snapshots, key)
+	return nil
+}
+
+// Rekey переносит запись и историю изменений устройства с oldKey на newKey
+func (s *MemoryStore) Rekey(oldKey, newKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if device, exists := s.devices[oldKey]; exists {
+		s.devices[newKey] = device
+		delete(s.devices, oldKey)
+	}
+	if snapshots, exists := s.snapshots[oldKey]; exists {
+		s.snapshots[newKey] = append(snapshots, s.snapshots[newKey]...)
+		delete(s.snapshots, oldKey)
+	}
+	return nil
+}
+
+// Close ничего не делает для MemoryStore - ресурсов для освобождения нет
+func (s *MemoryStore) Close() error {
+	return nil
+}
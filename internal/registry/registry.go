@@ -1,231 +1,526 @@
-package registry
-
-import (
-	"sync"
-	"time"
-
-	"github.com/local-video-server/internal/models"
-)
-
-// DeviceRegistry - реестр обнаруженных устройств
-type DeviceRegistry struct {
-	devices map[string]*models.Device
-	mu      sync.RWMutex
-	cache   *Cache
-}
-
-// Cache - кэш для результатов сканирования
-type Cache struct {
-	devices     map[string]*CachedDevice
-	mu          sync.RWMutex
-	defaultTTL  time.Duration
-	lastScan    time.Time
-	scanResults []*models.Device
-}
-
-// CachedDevice - кэшированное устройство
-type CachedDevice struct {
-	Device    *models.Device
-	ExpiresAt time.Time
-}
-
-// NewDeviceRegistry создает новый реестр устройств
-func NewDeviceRegistry(cacheTTL time.Duration) *DeviceRegistry {
-	return &DeviceRegistry{
-		devices: make(map[string]*models.Device),
-		cache: &Cache{
-			devices:    make(map[string]*CachedDevice),
-			defaultTTL: cacheTTL,
-		},
-	}
-}
-
-// AddDevice добавляет или обновляет устройство в реестре
-func (r *DeviceRegistry) AddDevice(device *models.Device) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Обновляем время последнего обнаружения
-	now := time.Now()
-	if existing, exists := r.devices[device.IP]; exists {
-		// Обновляем существующее устройство
-		existing.MAC = device.MAC
-		existing.Hostname = device.Hostname
-		existing.Manufacturer = device.Manufacturer
-		existing.Model = device.Model
-		existing.Protocols = device.Protocols
-		existing.RTSPStreams = device.RTSPStreams
-		existing.LastSeen = now
-	} else {
-		// Добавляем новое устройство
-		device.DiscoveredAt = now
-		device.LastSeen = now
-		r.devices[device.IP] = device
-	}
-}
-
-// GetDevice возвращает устройство по IP адресу
-func (r *DeviceRegistry) GetDevice(ip string) (*models.Device, bool) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	device, exists := r.devices[ip]
-	if !exists {
-		return nil, false
-	}
-	return device, true
-}
-
-// GetAllDevices возвращает все устройства
-func (r *DeviceRegistry) GetAllDevices() []*models.Device {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	devices := make([]*models.Device, 0, len(r.devices))
-	for _, device := range r.devices {
-		devices = append(devices, device)
-	}
-	return devices
-}
-
-// RemoveDevice удаляет устройство из реестра
-func (r *DeviceRegistry) RemoveDevice(ip string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	delete(r.devices, ip)
-}
-
-// UpdateDeviceState обновляет состояние устройства
-func (r *DeviceRegistry) UpdateDeviceState(ip string, updateFunc func(*models.Device)) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	device, exists := r.devices[ip]
-	if !exists {
-		return false
-	}
-
-	updateFunc(device)
-	device.LastSeen = time.Now()
-	return true
-}
-
-// GetDeviceCount возвращает количество устройств в реестре
-func (r *DeviceRegistry) GetDeviceCount() int {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	return len(r.devices)
-}
-
-// Clear очищает реестр
-func (r *DeviceRegistry) Clear() {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	r.devices = make(map[string]*models.Device)
-}
-
-// Cache methods
-
-// SaveToCache сохраняет результаты сканирования в кэш
-func (r *DeviceRegistry) SaveToCache(devices []*models.Device) {
-	r.cache.mu.Lock()
-	defer r.cache.mu.Unlock()
-
-	now := time.Now()
-	expiresAt := now.Add(r.cache.defaultTTL)
-
-	// Очищаем старый кэш
-	r.cache.devices = make(map[string]*CachedDevice)
-
-	// Сохраняем новые результаты
-	for _, device := range devices {
-		r.cache.devices[device.IP] = &CachedDevice{
-			Device:    device,
-			ExpiresAt: expiresAt,
-		}
-	}
-
-	r.cache.lastScan = now
-	r.cache.scanResults = devices
-}
-
-// GetFromCache возвращает результаты из кэша, если они еще не истекли
-func (r *DeviceRegistry) GetFromCache() ([]*models.Device, bool) {
-	r.cache.mu.RLock()
-	defer r.cache.mu.RUnlock()
-
-	now := time.Now()
-
-	// Проверяем, не истек ли кэш
-	if r.cache.lastScan.IsZero() || now.After(r.cache.lastScan.Add(r.cache.defaultTTL)) {
-		return nil, false
-	}
-
-	// Проверяем, не истекли ли отдельные устройства
-	validDevices := make([]*models.Device, 0)
-	for _, cached := range r.cache.devices {
-		if now.Before(cached.ExpiresAt) {
-			validDevices = append(validDevices, cached.Device)
-		}
-	}
-
-	if len(validDevices) == 0 {
-		return nil, false
-	}
-
-	return validDevices, true
-}
-
-// ClearCache очищает кэш
-func (r *DeviceRegistry) ClearCache() {
-	r.cache.mu.Lock()
-	defer r.cache.mu.Unlock()
-
-	r.cache.devices = make(map[string]*CachedDevice)
-	r.cache.lastScan = time.Time{}
-	r.cache.scanResults = nil
-}
-
-// GetLastScanTime возвращает время последнего сканирования
-func (r *DeviceRegistry) GetLastScanTime() time.Time {
-	r.cache.mu.RLock()
-	defer r.cache.mu.RUnlock()
-
-	return r.cache.lastScan
-}
-
-// FilterDevices фильтрует устройства по критериям
-func (r *DeviceRegistry) FilterDevices(filterFunc func(*models.Device) bool) []*models.Device {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	var result []*models.Device
-	for _, device := range r.devices {
-		if filterFunc(device) {
-			result = append(result, device)
-		}
-	}
-	return result
-}
-
-// GetDevicesByProtocol возвращает устройства с указанным протоколом
-func (r *DeviceRegistry) GetDevicesByProtocol(protocolType string) []*models.Device {
-	return r.FilterDevices(func(device *models.Device) bool {
-		for _, protocol := range device.Protocols {
-			if protocol.Type == protocolType {
-				return true
-			}
-		}
-		return false
-	})
-}
-
-// GetDevicesWithRTSP возвращает устройства с RTSP потоками
-func (r *DeviceRegistry) GetDevicesWithRTSP() []*models.Device {
-	return r.FilterDevices(func(device *models.Device) bool {
-		return len(device.RTSPStreams) > 0
-	})
-}
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/internal/credentials"
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// registryEventBuffer - размер буфера канала, возвращаемого DeviceRegistry.Events(). Если
+// подписчик не успевает вычитывать события, новое публикуемое событие отбрасывается вместо
+// того, чтобы блокировать AddDevice/RemoveDevice - задержка подписчика не должна тормозить
+// сканирование
+const registryEventBuffer = 64
+
+// cameraEventBuffer - размер буфера канала, который Subscribe выдает каждому подписчику на
+// device-level события (motion/tamper/analytics). Тот же компромисс, что у registryEventBuffer:
+// отставший подписчик теряет события вместо того, чтобы тормозить PublishEvent
+const cameraEventBuffer = 64
+
+// RegistryEventType - тип события жизненного цикла устройства, публикуемого DeviceRegistry
+type RegistryEventType string
+
+const (
+	// DeviceAdded - устройство появилось в реестре впервые (по своему идентификатору, см.
+	// deviceIdentity)
+	DeviceAdded RegistryEventType = "device_added"
+
+	// DeviceUpdated - уже известное устройство обновлено (новые данные от AddDevice/
+	// UpdateDeviceState либо переход Online в false по PruneStale)
+	DeviceUpdated RegistryEventType = "device_updated"
+
+	// DeviceRemoved - устройство удалено из реестра (RemoveDevice/RemoveDeviceByEndpointRef)
+	DeviceRemoved RegistryEventType = "device_removed"
+)
+
+// RegistryEvent - одно событие, публикуемое в канал DeviceRegistry.Events()
+type RegistryEvent struct {
+	Type   RegistryEventType
+	Device *models.Device
+}
+
+// deviceIdentity возвращает ключ, по которому DeviceRegistry отличает одно физическое
+// устройство от другого: WS-Discovery EndpointReference, если он известен - он не меняется,
+// даже когда DHCP выдает устройству новый IP, поэтому IP хранится как обычный изменяемый
+// атрибут записи. Если EndpointRef не известен (устройство пришло не от ONVIF WS-Discovery),
+// используется IP - то же поведение, что было раньше
+func deviceIdentity(device *models.Device) string {
+	if device.EndpointRef != "" {
+		return "endpoint:" + device.EndpointRef
+	}
+	return "ip:" + device.IP
+}
+
+// DeviceRegistry - реестр обнаруженных устройств
+type DeviceRegistry struct {
+	devices map[string]*models.Device // ключ - deviceIdentity(device)
+	mu      sync.RWMutex
+	cache   *Cache
+
+	events chan RegistryEvent
+	logger *logrus.Logger
+
+	// eventSubs - каналы, выданные Subscribe подписчикам на device-level события
+	// (motion/tamper/analytics), публикуемые PublishEvent. В отличие от events/Events() -
+	// одного канала для всего реестра - у каждого подписчика свой канал, поскольку
+	// PublishEvent рассылает каждое событие всем сразу (broadcast), а не одному читателю
+	eventSubs   []chan *models.Event
+	eventSubsMu sync.RWMutex
+
+	// vault - хранилище учетных данных устройств, подключаемое через SetVault (см.
+	// credentials.go). Без вызова SetVault SetCredential/GetCredential/RotateCredential
+	// возвращают ошибку, как Detector.publishEvent ничего не делает без SetDispatcher
+	vault credentials.Vault
+
+	// store - устойчивое хранилище устройств и истории их изменений, подключаемое через
+	// SetStore (MemoryStore по умолчанию, см. NewDeviceRegistry). AddDevice пишет в него
+	// каждое обновление, чтобы история изменений (Store.History) и выборки по времени
+	// (GetDevicesSeenBetween/GetDevicesGoneOfflineSince) отражали реальные сканы, а не
+	// только текущий снимок в r.devices
+	store Store
+}
+
+// Cache - кэш для результатов сканирования
+type Cache struct {
+	devices     map[string]*CachedDevice
+	mu          sync.RWMutex
+	defaultTTL  time.Duration
+	lastScan    time.Time
+	scanResults []*models.Device
+}
+
+// CachedDevice - кэшированное устройство
+type CachedDevice struct {
+	Device    *models.Device
+	ExpiresAt time.Time
+}
+
+// NewDeviceRegistry создает новый реестр устройств. Устойчивое хранилище по умолчанию -
+// MemoryStore (то же поведение, что было раньше); для хранения, переживающего перезапуск,
+// подключите BoltStore через SetStore
+func NewDeviceRegistry(cacheTTL time.Duration) *DeviceRegistry {
+	return &DeviceRegistry{
+		devices: make(map[string]*models.Device),
+		cache: &Cache{
+			devices:    make(map[string]*CachedDevice),
+			defaultTTL: cacheTTL,
+		},
+		events: make(chan RegistryEvent, registryEventBuffer),
+		logger: utils.GetLogger(),
+		store:  NewMemoryStore(),
+	}
+}
+
+// SetStore подключает store как устойчивое хранилище реестра, заменяя MemoryStore,
+// используемый по умолчанию - тот же опциональный-зависимости паттерн, что у SetVault/
+// Detector.SetDispatcher, только с ненулевым значением по умолчанию вместо отключенной
+// функциональности
+func (r *DeviceRegistry) SetStore(store Store) {
+	r.store = store
+}
+
+// Events возвращает канал, в который публикуются DeviceAdded/DeviceUpdated/DeviceRemoved по
+// мере изменения состава устройств. Канал общий на весь реестр - подписчику следует
+// вычитывать его в отдельной горутине на все время жизни DeviceRegistry
+func (r *DeviceRegistry) Events() <-chan RegistryEvent {
+	return r.events
+}
+
+// Subscribe регистрирует нового получателя device-level событий (motion/tamper/analytics),
+// публикуемых PublishEvent - обычно internal/events.Watch на одну ONVIF PullPoint подписку.
+// Каждый вызов заводит собственный канал; закрывать его не нужно, он живет вместе с
+// DeviceRegistry. Реализует internal/events.Sink
+func (r *DeviceRegistry) Subscribe() <-chan *models.Event {
+	ch := make(chan *models.Event, cameraEventBuffer)
+
+	r.eventSubsMu.Lock()
+	r.eventSubs = append(r.eventSubs, ch)
+	r.eventSubsMu.Unlock()
+
+	return ch
+}
+
+// PublishEvent рассылает event всем подписчикам Subscribe, не блокируясь на том, кто отстал
+func (r *DeviceRegistry) PublishEvent(event *models.Event) {
+	r.eventSubsMu.RLock()
+	defer r.eventSubsMu.RUnlock()
+
+	for _, ch := range r.eventSubs {
+		select {
+		case ch <- event:
+		default:
+			r.logger.Warnf("camera event subscriber channel full, dropping %s event for %s", event.Topic, event.DeviceIP)
+		}
+	}
+}
+
+// publish отправляет событие в канал Events(), не блокируясь, если подписчик отстал. device
+// должен быть копией, снятой под r.mu, а не живым указателем из r.devices - иначе подписчик,
+// читающий его уже после Unlock, гонялся бы за полями, которые конкурентно меняет следующий
+// AddDevice/UpdateDeviceState/PruneStale
+func (r *DeviceRegistry) publish(t RegistryEventType, device *models.Device) {
+	select {
+	case r.events <- RegistryEvent{Type: t, Device: device}:
+	default:
+		r.logger.Warnf("registry event channel is full, dropping %s event for %s", t, device.IP)
+	}
+}
+
+// AddDevice добавляет или обновляет устройство в реестре, публикуя DeviceAdded или
+// DeviceUpdated соответственно. Идентичность определяется deviceIdentity - IP меняется
+// свободно между вызовами для одного и того же EndpointRef (см. DHCP lease renewal)
+func (r *DeviceRegistry) AddDevice(device *models.Device) {
+	now := time.Now()
+	key := deviceIdentity(device)
+
+	r.mu.Lock()
+	target, exists := r.devices[key]
+	if exists {
+		target.IP = device.IP
+		target.MAC = device.MAC
+		target.Hostname = device.Hostname
+		target.Manufacturer = device.Manufacturer
+		target.Model = device.Model
+		target.Protocols = device.Protocols
+		target.RTSPStreams = device.RTSPStreams
+		target.LastSeen = now
+		target.Online = true
+	} else {
+		device.DiscoveredAt = now
+		device.LastSeen = now
+		device.Online = true
+		r.devices[key] = device
+		target = device
+	}
+	// Устройство, сперва увиденное без EndpointRef (обычный Discover-скан) и попавшее в
+	// реестр под ключом "ip:...", а позже опознанное через WS-Discovery Listen, должно
+	// занять один ключ "endpoint:...", а не два - иначе старая "ip:" запись навсегда
+	// остается дубликатом того же физического устройства
+	var staleIPKey string
+	if device.EndpointRef != "" {
+		if ipKey := "ip:" + device.IP; ipKey != key {
+			if _, existed := r.devices[ipKey]; existed {
+				delete(r.devices, ipKey)
+				staleIPKey = ipKey
+			}
+		}
+	}
+	published := *target
+	store := r.store
+	r.mu.Unlock()
+
+	// Запись в store выполняется после Unlock, как и publish ниже - без этого BoltDB
+	// fsync на каждый AddDevice держал бы r.mu всю запись. Та же плата за гонку, что уже
+	// принята для publish: при конкурентных AddDevice/RemoveDevice одного и того же
+	// устройства store может применить их не в том порядке, в каком они легли в r.devices;
+	// это не портит r.devices (обновляется под r.mu), только отражение в Store может
+	// отстать на один скан до следующего AddDevice
+	if store != nil {
+		// Пишем отдельную копию, а не published: Upsert переприсваивает переданному
+		// устройству DiscoveredAt/LastSeen своим собственным time.Now(), и если бы это был
+		// тот же указатель, что уходит в r.publish ниже, подписчики увидели бы LastSeen,
+		// не совпадающий с тем, что реально записано в r.devices под r.mu
+		if staleIPKey != "" {
+			// Переносим накопленную историю устройства на новый ключ до Upsert, чтобы не
+			// потерять ее - иначе она осталась бы висеть под старым "ip:" ключом навсегда
+			if err := store.Rekey(staleIPKey, key); err != nil {
+				r.logger.Warnf("failed to move store history from %s to %s: %v", staleIPKey, key, err)
+			}
+		}
+		storeDevice := published
+		if err := store.Upsert(key, &storeDevice); err != nil {
+			r.logger.Warnf("failed to persist device %s to store: %v", published.IP, err)
+		}
+	}
+
+	if exists {
+		r.publish(DeviceUpdated, &published)
+	} else {
+		r.publish(DeviceAdded, &published)
+	}
+}
+
+// GetDevice возвращает устройство по IP адресу. Реестр индексирован по deviceIdentity, а IP -
+// изменяемый атрибут, поэтому поиск линейный по числу устройств; для поиска по неизменному
+// идентификатору используйте GetDeviceByEndpointRef. Возвращается копия, снятая под r.mu (как
+// published в AddDevice/RemoveDevice/UpdateDeviceState) - не живой указатель из r.devices,
+// который конкурентно мутирует следующий AddDevice/UpdateDeviceState/PruneStale
+func (r *DeviceRegistry) GetDevice(ip string) (*models.Device, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, device := range r.devices {
+		if device.IP == ip {
+			published := *device
+			return &published, true
+		}
+	}
+	return nil, false
+}
+
+// GetDeviceByEndpointRef возвращает устройство по его WS-Discovery EndpointReference -
+// стабильному идентификатору, не меняющемуся при смене IP по DHCP
+func (r *DeviceRegistry) GetDeviceByEndpointRef(ref string) (*models.Device, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	device, exists := r.devices["endpoint:"+ref]
+	return device, exists
+}
+
+// GetAllDevices возвращает все устройства
+func (r *DeviceRegistry) GetAllDevices() []*models.Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	devices := make([]*models.Device, 0, len(r.devices))
+	for _, device := range r.devices {
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// RemoveDevice удаляет устройство из реестра по IP и публикует DeviceRemoved
+func (r *DeviceRegistry) RemoveDevice(ip string) {
+	r.mu.Lock()
+	var removed *models.Device
+	var removedKey string
+	for key, device := range r.devices {
+		if device.IP == ip {
+			removed = device
+			removedKey = key
+			delete(r.devices, key)
+			break
+		}
+	}
+	var published models.Device
+	if removed != nil {
+		published = *removed
+	}
+	store := r.store
+	r.mu.Unlock()
+
+	if removed == nil {
+		return
+	}
+
+	if store != nil {
+		if err := store.Delete(removedKey); err != nil {
+			r.logger.Warnf("failed to delete device %s from store: %v", ip, err)
+		}
+	}
+	r.publish(DeviceRemoved, &published)
+}
+
+// RemoveDeviceByEndpointRef удаляет устройство по его EndpointReference и публикует
+// DeviceRemoved - основной способ обработать WS-Discovery Bye, который сообщает
+// EndpointReference, а не IP (см. scanner.ONVIFScanner.Listen)
+func (r *DeviceRegistry) RemoveDeviceByEndpointRef(ref string) {
+	key := "endpoint:" + ref
+
+	r.mu.Lock()
+	device, exists := r.devices[key]
+	var published models.Device
+	if exists {
+		delete(r.devices, key)
+		published = *device
+	}
+	store := r.store
+	r.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	if store != nil {
+		if err := store.Delete(key); err != nil {
+			r.logger.Warnf("failed to delete device %s from store: %v", ref, err)
+		}
+	}
+	r.publish(DeviceRemoved, &published)
+}
+
+// UpdateDeviceState обновляет состояние устройства по IP и публикует DeviceUpdated
+func (r *DeviceRegistry) UpdateDeviceState(ip string, updateFunc func(*models.Device)) bool {
+	r.mu.Lock()
+	var device *models.Device
+	for _, d := range r.devices {
+		if d.IP == ip {
+			device = d
+			break
+		}
+	}
+	if device == nil {
+		r.mu.Unlock()
+		return false
+	}
+	updateFunc(device)
+	device.LastSeen = time.Now()
+	published := *device
+	r.mu.Unlock()
+
+	r.publish(DeviceUpdated, &published)
+	return true
+}
+
+// PruneStale помечает Online=false устройства, чей LastSeen не обновлялся дольше staleTTL, и
+// публикует DeviceUpdated для каждого. Рассчитана на устройства, получающие Hello/Bye от
+// ONVIFScanner.Listen - staleTTL, выбранный кратным периоду Hello/Probe, дает эффект "N
+// пропущенных Hello подряд"; для устройств из разовых Discover-сканов Online не используется
+func (r *DeviceRegistry) PruneStale(staleTTL time.Duration) {
+	now := time.Now()
+
+	r.mu.Lock()
+	var staled []models.Device
+	for _, device := range r.devices {
+		if device.Online && now.Sub(device.LastSeen) > staleTTL {
+			device.Online = false
+			staled = append(staled, *device)
+		}
+	}
+	r.mu.Unlock()
+
+	for i := range staled {
+		r.publish(DeviceUpdated, &staled[i])
+	}
+}
+
+// PruneLoop периодически вызывает PruneStale, пока ctx не отменен. Предназначена для запуска
+// в отдельной горутине вызывающим кодом, обычно вместе с ONVIFScanner.Listen
+func (r *DeviceRegistry) PruneLoop(ctx context.Context, interval, staleTTL time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.PruneStale(staleTTL)
+		}
+	}
+}
+
+// GetDeviceCount возвращает количество устройств в реестре
+func (r *DeviceRegistry) GetDeviceCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.devices)
+}
+
+// Clear очищает реестр
+func (r *DeviceRegistry) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.devices = make(map[string]*models.Device)
+}
+
+// Cache methods
+
+// SaveToCache сохраняет результаты сканирования в кэш
+func (r *DeviceRegistry) SaveToCache(devices []*models.Device) {
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+
+	now := time.Now()
+	expiresAt := now.Add(r.cache.defaultTTL)
+
+	// Очищаем старый кэш
+	r.cache.devices = make(map[string]*CachedDevice)
+
+	// Сохраняем новые результаты
+	for _, device := range devices {
+		r.cache.devices[device.IP] = &CachedDevice{
+			Device:    device,
+			ExpiresAt: expiresAt,
+		}
+	}
+
+	r.cache.lastScan = now
+	r.cache.scanResults = devices
+}
+
+// GetFromCache возвращает результаты из кэша, если они еще не истекли
+func (r *DeviceRegistry) GetFromCache() ([]*models.Device, bool) {
+	r.cache.mu.RLock()
+	defer r.cache.mu.RUnlock()
+
+	now := time.Now()
+
+	// Проверяем, не истек ли кэш
+	if r.cache.lastScan.IsZero() || now.After(r.cache.lastScan.Add(r.cache.defaultTTL)) {
+		return nil, false
+	}
+
+	// Проверяем, не истекли ли отдельные устройства
+	validDevices := make([]*models.Device, 0)
+	for _, cached := range r.cache.devices {
+		if now.Before(cached.ExpiresAt) {
+			validDevices = append(validDevices, cached.Device)
+		}
+	}
+
+	if len(validDevices) == 0 {
+		return nil, false
+	}
+
+	return validDevices, true
+}
+
+// ClearCache очищает кэш
+func (r *DeviceRegistry) ClearCache() {
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+
+	r.cache.devices = make(map[string]*CachedDevice)
+	r.cache.lastScan = time.Time{}
+	r.cache.scanResults = nil
+}
+
+// GetLastScanTime возвращает время последнего сканирования
+func (r *DeviceRegistry) GetLastScanTime() time.Time {
+	r.cache.mu.RLock()
+	defer r.cache.mu.RUnlock()
+
+	return r.cache.lastScan
+}
+
+// FilterDevices фильтрует устройства по критериям
+func (r *DeviceRegistry) FilterDevices(filterFunc func(*models.Device) bool) []*models.Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*models.Device
+	for _, device := range r.devices {
+		if filterFunc(device) {
+			result = append(result, device)
+		}
+	}
+	return result
+}
+
+// GetDevicesByProtocol возвращает устройства с указанным протоколом
+func (r *DeviceRegistry) GetDevicesByProtocol(protocolType string) []*models.Device {
+	return r.FilterDevices(func(device *models.Device) bool {
+		for _, protocol := range device.Protocols {
+			if protocol.Type == protocolType {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// GetDevicesWithRTSP возвращает устройства с RTSP потоками
+func (r *DeviceRegistry) GetDevicesWithRTSP() []*models.Device {
+	return r.FilterDevices(func(device *models.Device) bool {
+		return len(device.RTSPStreams) > 0
+	})
+}
@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// Filter - критерии отбора устройств для Store.List
+type Filter struct {
+	Protocol string // если задано, устройство должно иметь протокол этого типа (RTSP, RTMP, etc.)
+	HasRTSP  bool   // если true, возвращать только устройства с хотя бы одним RTSP потоком
+	HasMJPEG bool   // если true, возвращать только устройства с хотя бы одним MJPEG потоком
+}
+
+// matches проверяет, удовлетворяет ли устройство фильтру
+func (f Filter) matches(device *models.Device) bool {
+	if f.Protocol != "" {
+		found := false
+		for _, protocol := range device.Protocols {
+			if protocol.Type == f.Protocol {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.HasRTSP && len(device.RTSPStreams) == 0 {
+		return false
+	}
+	if f.HasMJPEG && len(device.MJPEGStreams) == 0 {
+		return false
+	}
+	return true
+}
+
+// DeviceSnapshot - состояние устройства на момент времени, записанное в историю изменений.
+// Snapshot'ы создаются только тогда, когда меняется hostname, протоколы или потоки, поэтому
+// история не засоряется повторяющимися записями с каждого скана
+type DeviceSnapshot struct {
+	IP        string        `json:"ip"`
+	Timestamp time.Time     `json:"timestamp"`
+	Device    models.Device `json:"device"`
+}
+
+// Store - хранилище реестра устройств с историей изменений. MemoryStore реализует его
+// поверх карты в памяти (прежнее поведение DeviceRegistry), BoltStore - поверх BoltDB,
+// сохраняя устройства и снимки истории между перезапусками. Все методы принимают key -
+// тот же идентификатор устройства, что и ключ DeviceRegistry.devices (deviceIdentity:
+// WS-Discovery EndpointReference, если он известен, иначе IP), а не голый IP - иначе смена
+// IP по DHCP обрывала бы историю устройства с известным EndpointRef на две несвязанные записи
+type Store interface {
+	// Upsert добавляет устройство или обновляет существующее по key. Если hostname,
+	// протоколы или потоки изменились по сравнению с последним известным состоянием,
+	// в историю записывается новый DeviceSnapshot
+	Upsert(key string, device *models.Device) error
+
+	// Get возвращает устройство по key
+	Get(key string) (*models.Device, bool, error)
+
+	// List возвращает устройства, удовлетворяющие фильтру (нулевое значение Filter
+	// возвращает все устройства)
+	List(filter Filter) ([]*models.Device, error)
+
+	// History возвращает снимки изменений устройства с указанного момента времени,
+	// отсортированные от старых к новым
+	History(key string, since time.Time) ([]DeviceSnapshot, error)
+
+	// GetDevicesSeenBetween возвращает устройства, чье LastSeen попадает в [from, to]
+	GetDevicesSeenBetween(from, to time.Time) ([]*models.Device, error)
+
+	// GetDevicesGoneOfflineSince возвращает устройства, последний раз виденные до t
+	// (т.е. пропавшие из сети не позже указанного момента)
+	GetDevicesGoneOfflineSince(t time.Time) ([]*models.Device, error)
+
+	// Delete удаляет устройство key и его историю изменений - вызывается при
+	// RemoveDevice/RemoveDeviceByEndpointRef, чтобы устройства, пропавшие из живого реестра,
+	// не оставались в Store навсегда
+	Delete(key string) error
+
+	// Rekey переносит запись и историю изменений устройства с oldKey на newKey, ничего не
+	// делая, если oldKey не существует - нужен AddDevice, когда устройство, увиденное сперва
+	// без EndpointRef под ключом "ip:...", позже опознается через WS-Discovery и получает
+	// ключ "endpoint:...": запись должна переехать на новый ключ, а не исчезнуть, иначе
+	// накопленная история обрывается
+	Rekey(oldKey, newKey string) error
+
+	// Close освобождает ресурсы хранилища (файлы БД и т.п.)
+	Close() error
+}
+
+// deviceChanged сообщает, отличаются ли hostname, протоколы или потоки между двумя
+// версиями устройства - это то, что попадает в историю изменений
+func deviceChanged(old, new *models.Device) bool {
+	if old == nil {
+		return true
+	}
+	if old.Hostname != new.Hostname {
+		return true
+	}
+	if !protocolsEqual(old.Protocols, new.Protocols) {
+		return true
+	}
+	if !rtspStreamsEqual(old.RTSPStreams, new.RTSPStreams) {
+		return true
+	}
+	if !mjpegStreamsEqual(old.MJPEGStreams, new.MJPEGStreams) {
+		return true
+	}
+	return false
+}
+
+func protocolsEqual(a, b []models.Protocol) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || a[i].Port != b[i].Port || a[i].URL != b[i].URL || a[i].Available != b[i].Available {
+			return false
+		}
+	}
+	return true
+}
+
+func rtspStreamsEqual(a, b []models.RTSPStreamInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].URL != b[i].URL || a[i].Codec != b[i].Codec || a[i].Resolution != b[i].Resolution || a[i].Available != b[i].Available {
+			return false
+		}
+	}
+	return true
+}
+
+func mjpegStreamsEqual(a, b []models.MJPEGStreamInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].URL != b[i].URL || a[i].Width != b[i].Width || a[i].Height != b[i].Height || a[i].Available != b[i].Available {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+// Package rtcp содержит минимальную реализацию приемной части RTCP (RFC 3550),
+// достаточную для построения Receiver Report по наблюдаемому RTP потоку.
+package rtcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	// ntpEpochOffset - разница в секундах между эпохой NTP (1900) и Unix (1970)
+	ntpEpochOffset = 2208988800
+
+	rtcpVersion   = 2
+	ptSenderRpt   = 200
+	ptReceiverRpt = 201
+)
+
+// ssrcStats - внутреннее состояние трекера для одного SSRC
+type ssrcStats struct {
+	initialized bool
+
+	baseSeq       uint16
+	maxSeq        uint16
+	cycles        uint32
+	received      uint32
+	expectedPrior uint32
+	receivedPrior uint32
+
+	lastTransit int32
+	jitter      float64
+
+	lastSRNTPMid  uint32
+	lastSRArrival time.Time
+}
+
+// Tracker накапливает статистику RTP потока и умеет собирать из нее Receiver Report
+type Tracker struct {
+	clockRate uint32
+	tracks    map[uint32]*ssrcStats
+}
+
+// NewTracker создает трекер для потока с указанной тактовой частотой (обычно 90000 для видео)
+func NewTracker(clockRate uint32) *Tracker {
+	return &Tracker{
+		clockRate: clockRate,
+		tracks:    make(map[uint32]*ssrcStats),
+	}
+}
+
+// OnRTPPacket разбирает фиксированный 12-байтовый заголовок RTP и обновляет статистику по SSRC
+func (t *Tracker) OnRTPPacket(payload []byte, arrival time.Time) error {
+	if len(payload) < 12 {
+		return fmt.Errorf("RTP packet too short: %d bytes", len(payload))
+	}
+
+	seq := binary.BigEndian.Uint16(payload[2:4])
+	rtpTimestamp := binary.BigEndian.Uint32(payload[4:8])
+	ssrc := binary.BigEndian.Uint32(payload[8:12])
+
+	s, ok := t.tracks[ssrc]
+	if !ok {
+		s = &ssrcStats{}
+		t.tracks[ssrc] = s
+	}
+
+	if !s.initialized {
+		s.initialized = true
+		s.baseSeq = seq
+		s.maxSeq = seq
+	} else {
+		delta := int32(seq) - int32(s.maxSeq)
+		if delta > 0 {
+			if seq < s.maxSeq {
+				// Переполнение 16-битного номера последовательности
+				s.cycles += 1 << 16
+			}
+			s.maxSeq = seq
+		}
+	}
+	s.received++
+
+	// Interarrival jitter по формуле RFC 3550, раздел 6.4.1
+	arrivalRTP := int64(arrival.Unix())*int64(t.clockRate) + int64(arrival.Nanosecond())*int64(t.clockRate)/1e9
+	transit := int32(arrivalRTP) - int32(rtpTimestamp)
+	if s.lastTransit != 0 {
+		d := transit - s.lastTransit
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (float64(d) - s.jitter) / 16
+	}
+	s.lastTransit = transit
+
+	return nil
+}
+
+// OnSenderReport обрабатывает принятый RTCP Sender Report и запоминает NTP-метку для LSR/DLSR
+func (t *Tracker) OnSenderReport(payload []byte, arrival time.Time) error {
+	if len(payload) < 8 || (payload[1] != ptSenderRpt) {
+		return fmt.Errorf("not a Sender Report packet")
+	}
+	ssrc := binary.BigEndian.Uint32(payload[4:8])
+	if len(payload) < 20 {
+		return fmt.Errorf("Sender Report too short")
+	}
+
+	ntpSeconds := binary.BigEndian.Uint32(payload[8:12])
+	ntpFraction := binary.BigEndian.Uint32(payload[12:16])
+	// Средние 32 бита 64-битной NTP метки, как того требует формат LSR
+	lsrMid := (ntpSeconds&0xFFFF)<<16 | (ntpFraction >> 16)
+
+	s, ok := t.tracks[ssrc]
+	if !ok {
+		s = &ssrcStats{}
+		t.tracks[ssrc] = s
+	}
+	s.lastSRNTPMid = lsrMid
+	s.lastSRArrival = arrival
+
+	return nil
+}
+
+// Metrics возвращает производные метрики качества для указанного SSRC:
+// процент потерь, джиттер в миллисекундах и возраст последнего пакета
+func (t *Tracker) Metrics(ssrc uint32, now time.Time) (lossPercent float64, jitterMs float64, lastPacketAge time.Duration) {
+	s, ok := t.tracks[ssrc]
+	if !ok {
+		return 0, 0, 0
+	}
+
+	expected := uint32(s.cycles) + uint32(s.maxSeq) - uint32(s.baseSeq) + 1
+	lost := int64(expected) - int64(s.received)
+	if lost < 0 {
+		lost = 0
+	}
+	if expected > 0 {
+		lossPercent = float64(lost) / float64(expected) * 100
+	}
+
+	jitterMs = s.jitter / float64(t.clockRate) * 1000
+
+	return lossPercent, jitterMs, now.Sub(s.lastSRArrival)
+}
+
+// BuildReceiverReport собирает RTCP Receiver Report (RFC 3550, раздел 6.4.2) для указанного SSRC
+func (t *Tracker) BuildReceiverReport(reporterSSRC, ssrc uint32) ([]byte, error) {
+	s, ok := t.tracks[ssrc]
+	if !ok {
+		return nil, fmt.Errorf("unknown SSRC: %08x", ssrc)
+	}
+
+	expected := uint32(s.cycles) + uint32(s.maxSeq) - uint32(s.baseSeq) + 1
+	expectedInterval := expected - s.expectedPrior
+	receivedInterval := s.received - s.receivedPrior
+	lostInterval := int32(expectedInterval) - int32(receivedInterval)
+	s.expectedPrior = expected
+	s.receivedPrior = s.received
+
+	fractionLost := byte(0)
+	if expectedInterval > 0 && lostInterval > 0 {
+		fractionLost = byte((lostInterval << 8) / int32(expectedInterval))
+	}
+
+	cumulativeLost := int32(expected) - int32(s.received)
+	if cumulativeLost < 0 {
+		cumulativeLost = 0
+	}
+
+	lsr := s.lastSRNTPMid
+	var dlsr uint32
+	if !s.lastSRArrival.IsZero() {
+		dlsr = uint32(time.Since(s.lastSRArrival).Seconds() * 65536)
+	}
+
+	buf := make([]byte, 32)
+	buf[0] = (rtcpVersion << 6) | 1 // V=2, P=0, RC=1 (один report block)
+	buf[1] = ptReceiverRpt
+	binary.BigEndian.PutUint16(buf[2:4], 7) // длина в 32-битных словах минус 1
+	binary.BigEndian.PutUint32(buf[4:8], reporterSSRC)
+
+	binary.BigEndian.PutUint32(buf[8:12], ssrc)
+	buf[12] = fractionLost
+	buf[13] = byte(cumulativeLost >> 16)
+	buf[14] = byte(cumulativeLost >> 8)
+	buf[15] = byte(cumulativeLost)
+	binary.BigEndian.PutUint32(buf[16:20], uint32(s.cycles)|uint32(s.maxSeq))
+	binary.BigEndian.PutUint32(buf[20:24], uint32(s.jitter))
+	binary.BigEndian.PutUint32(buf[24:28], lsr)
+	binary.BigEndian.PutUint32(buf[28:32], dlsr)
+
+	return buf, nil
+}
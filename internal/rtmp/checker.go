@@ -0,0 +1,71 @@
+package rtmp
+
+import (
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// Checker представляет модуль проверки RTMP потоков, аналогичный rtsp.Checker - только
+// вместо SDP здесь за параметры кодеков отвечают handshake + connect/createStream/play и
+// разбор onMetaData/первых видео-аудио тегов (см. protocols.RTMPDetector.CheckStream)
+type Checker struct {
+	config   *models.RTMPConfig
+	detector *protocols.RTMPDetector
+}
+
+// NewChecker создает новый RTMP checker
+func NewChecker(config *models.RTMPConfig) *Checker {
+	return &Checker{
+		config:   config,
+		detector: protocols.NewRTMPDetector(),
+	}
+}
+
+// CheckStream проверяет RTMP поток по app/streamName и возвращает информацию о нем
+func (c *Checker) CheckStream(ip string, port int, appName, streamName string) (*models.RTMPStreamInfo, error) {
+	logger := utils.GetLogger()
+	logger.Debugf("Checking RTMP stream: rtmp://%s:%d/%s/%s", ip, port, appName, streamName)
+
+	return c.detector.CheckStream(ip, port, appName, streamName, c.config.Timeout)
+}
+
+// TestStream проверяет доступность RTMP на устройстве без открытия потока (быстрая проверка,
+// только handshake)
+func (c *Checker) TestStream(ip string, port int) (bool, error) {
+	protocol, err := c.detector.Detect(ip, port, c.config.Timeout)
+	if err != nil {
+		return false, err
+	}
+	return protocol.Available, nil
+}
+
+// DiscoverStreams перебирает стандартные комбинации app/streamName (DefaultApps x
+// DefaultStreamKeys, например "live/livestream", "cam/stream1") и возвращает те, что
+// оказались доступны
+func (c *Checker) DiscoverStreams(ip string, port int) ([]models.RTMPStreamInfo, error) {
+	logger := utils.GetLogger()
+	discoveredStreams := []models.RTMPStreamInfo{}
+
+	apps := c.config.DefaultApps
+	streamKeys := c.config.DefaultStreamKeys
+	logger.Debugf("Discovering RTMP streams on %s:%d, checking %d apps x %d stream keys",
+		ip, port, len(apps), len(streamKeys))
+
+	for _, app := range apps {
+		for _, streamKey := range streamKeys {
+			streamInfo, err := c.CheckStream(ip, port, app, streamKey)
+			if err != nil {
+				logger.Debugf("RTMP stream %s/%s on %s:%d not available: %v", app, streamKey, ip, port, err)
+				continue
+			}
+			if streamInfo.Available {
+				discoveredStreams = append(discoveredStreams, *streamInfo)
+				logger.Infof("Discovered RTMP stream: %s (codec=%s, resolution=%s, fps=%.2f)",
+					streamInfo.URL, streamInfo.Codec, streamInfo.Resolution, streamInfo.FPS)
+			}
+		}
+	}
+
+	return discoveredStreams, nil
+}
@@ -1,22 +1,28 @@
 package rtsp
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/local-video-server/internal/licensing"
 	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols/onvif"
 	"github.com/local-video-server/pkg/utils"
 )
 
 // Checker представляет модуль проверки RTSP каналов
 type Checker struct {
-	config *models.RTSPConfig
+	config      *models.RTSPConfig
+	authBackoff *hostAuthBackoff
 }
 
 // NewChecker создает новый RTSP checker
 func NewChecker(config *models.RTSPConfig) *Checker {
 	return &Checker{
-		config: config,
+		config:      config,
+		authBackoff: newHostAuthBackoff(),
 	}
 }
 
@@ -48,49 +54,90 @@ func (c *Checker) CheckStream(rtspURL string, username, password string) (*model
 	rtspStreamInfo := streamInfo.ToRTSPStreamInfo()
 	rtspStreamInfo.CheckedAt = time.Now()
 
+	videoDesc := "none"
+	if rtspStreamInfo.Codec != "" {
+		videoDesc = rtspStreamInfo.Codec
+		if rtspStreamInfo.VideoCodecInfo != nil {
+			videoDesc = matchDescription(rtspStreamInfo.Codec, CodecMatchType(rtspStreamInfo.VideoCodecInfo.MatchType))
+		}
+	}
+	audioDesc := "none"
+	if rtspStreamInfo.AudioCodec != "" {
+		audioDesc = rtspStreamInfo.AudioCodec
+		if rtspStreamInfo.AudioCodecInfo != nil {
+			audioDesc = matchDescription(rtspStreamInfo.AudioCodec, CodecMatchType(rtspStreamInfo.AudioCodecInfo.MatchType))
+		}
+	}
+	logger.Debugf("RTSP stream %s: video=%s, audio=%s", rtspURL, videoDesc, audioDesc)
+
 	return &rtspStreamInfo, nil
 }
 
-// CheckMultipleStreams проверяет несколько RTSP потоков параллельно
-func (c *Checker) CheckMultipleStreams(streams []StreamCheckRequest) []StreamCheckResult {
+// CheckMultipleStreamsCtx проверяет несколько RTSP потоков, группируя их по хосту и
+// переиспользуя одно TCP соединение для нескольких путей одного хоста (RTSP допускает
+// несколько DESCRIBE на одной персистентной сессии), вместо того чтобы открывать отдельное
+// соединение и проходить аутентификацию заново на каждый путь. Одновременно открыто не
+// больше c.config.MaxConcurrency соединений всего и не больше c.config.PerHostConcurrency
+// соединений к одному хосту - это защищает сокеты и NIC от перегрузки при проверке широких
+// списков URL (например, при переборе путей на /16 подсети). ctx позволяет прервать еще не
+// начатые проверки, не дожидаясь уже открытых соединений
+func (c *Checker) CheckMultipleStreamsCtx(ctx context.Context, streams []StreamCheckRequest) []StreamCheckResult {
 	logger := utils.GetLogger()
-	results := make([]StreamCheckResult, 0, len(streams))
 
-	// Создаем канал для результатов
-	resultChan := make(chan StreamCheckResult, len(streams))
+	maxConcurrency := c.config.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	perHost := c.config.PerHostConcurrency
+	if perHost <= 0 {
+		perHost = defaultPerHostConcurrency
+	}
 
-	// Запускаем проверку потоков параллельно
-	for _, stream := range streams {
-		go func(req StreamCheckRequest) {
-			result := StreamCheckResult{
-				URL: req.URL,
-			}
+	jobs, results := buildConnJobs(streams, perHost)
+	if len(jobs) == 0 {
+		return results
+	}
 
-			streamInfo, err := c.CheckStream(req.URL, req.Username, req.Password)
-			if err != nil {
-				result.Error = err.Error()
-				result.Available = false
-				logger.Debugf("Stream check failed for %s: %v", req.URL, err)
-			} else {
-				result.StreamInfo = streamInfo
-				result.Available = streamInfo.Available
-				logger.Debugf("Stream check successful for %s: codec=%s, resolution=%s, fps=%.2f",
-					req.URL, streamInfo.Codec, streamInfo.Resolution, streamInfo.FPS)
-			}
+	jobChan := make(chan *connJob, len(jobs))
+	for _, job := range jobs {
+		jobChan <- job
+	}
+	close(jobChan)
 
-			resultChan <- result
-		}(stream)
+	workerCount := maxConcurrency
+	if workerCount > len(jobs) {
+		workerCount = len(jobs)
 	}
 
-	// Собираем результаты
-	for i := 0; i < len(streams); i++ {
-		result := <-resultChan
-		results = append(results, result)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				if ctx.Err() != nil {
+					continue
+				}
+				c.runConnJob(ctx, job, results, &mu)
+			}
+		}()
 	}
+	wg.Wait()
+
+	logger.Debugf("Checked %d RTSP stream candidates across %d connections (max_concurrency=%d, per_host=%d)",
+		len(streams), len(jobs), maxConcurrency, perHost)
 
 	return results
 }
 
+// CheckMultipleStreams проверяет несколько RTSP потоков параллельно - обертка над
+// CheckMultipleStreamsCtx с context.Background() для вызывающего кода, которому не нужна
+// отмена
+func (c *Checker) CheckMultipleStreams(streams []StreamCheckRequest) []StreamCheckResult {
+	return c.CheckMultipleStreamsCtx(context.Background(), streams)
+}
+
 // TestStream проверяет доступность потока (быстрая проверка)
 func (c *Checker) TestStream(rtspURL string, username, password string) (bool, error) {
 	logger := utils.GetLogger()
@@ -120,10 +167,30 @@ func (c *Checker) TestStream(rtspURL string, username, password string) (bool, e
 	return response.StatusCode == 200, nil
 }
 
-// DiscoverStreams пытается обнаружить доступные RTSP потоки на устройстве
+// DiscoverStreams пытается обнаружить доступные RTSP потоки на устройстве. Сначала
+// опрашивает ONVIF Media сервис (GetProfiles/GetStreamUri) - он возвращает настоящие RTSP
+// URI потоков устройства вместо угадывания, и естественным образом отдает и основной, и
+// суб-поток с правильными кодеком/разрешением/FPS. Угадывание стандартных путей остается
+// как fallback и как способ найти потоки, которые ONVIF не перечислил (не все камеры
+// регистрируют в ONVIF Media все свои RTSP эндпоинты)
 func (c *Checker) DiscoverStreams(deviceIP string, port int, username, password string) ([]models.RTSPStreamInfo, error) {
 	logger := utils.GetLogger()
 	discoveredStreams := []models.RTSPStreamInfo{}
+	seen := make(map[string]bool)
+
+	if licensing.DefaultGates().Allow(licensing.FeatureONVIF) {
+		for _, stream := range c.discoverStreamsONVIF(deviceIP, username, password) {
+			if seen[stream.URL] {
+				continue
+			}
+			seen[stream.URL] = true
+			discoveredStreams = append(discoveredStreams, stream)
+			logger.Infof("Discovered RTSP stream via ONVIF: %s (codec=%s, resolution=%s, fps=%.2f)",
+				stream.URL, stream.Codec, stream.Resolution, stream.FPS)
+		}
+	} else {
+		logger.Debugf("Skipping ONVIF discovery on %s: not licensed for %s edition", deviceIP, licensing.DefaultGates().Edition())
+	}
 
 	// Формируем базовый URL
 	baseURL := fmt.Sprintf("rtsp://%s:%d", deviceIP, port)
@@ -144,45 +211,84 @@ func (c *Checker) DiscoverStreams(deviceIP string, port int, username, password
 		}
 	}
 
-	logger.Debugf("Discovering RTSP streams on %s, checking %d paths", deviceIP, len(pathsToCheck))
+	logger.Debugf("Discovering RTSP streams on %s, checking %d guessed paths", deviceIP, len(pathsToCheck))
 
-	// Проверяем каждый путь
-	for _, path := range pathsToCheck {
-		streamURL := baseURL + path
-		
-		streamInfo, err := c.CheckStream(streamURL, username, password)
-		if err != nil {
-			logger.Debugf("Stream %s not available: %v", streamURL, err)
-			continue
-		}
+	// Также пытаемся найти поток через DESCRIBE на корневом пути. Копируем pathsToCheck,
+	// чтобы не дописывать "/" в c.config.DefaultPaths, если pathsToCheck на него ссылается
+	allPaths := make([]string, 0, len(pathsToCheck)+1)
+	allPaths = append(allPaths, pathsToCheck...)
+	allPaths = append(allPaths, "/")
+	pathsToCheck = allPaths
 
-		if streamInfo.Available {
-			discoveredStreams = append(discoveredStreams, *streamInfo)
-			logger.Infof("Discovered RTSP stream: %s (codec=%s, resolution=%s, fps=%.2f)",
-				streamURL, streamInfo.Codec, streamInfo.Resolution, streamInfo.FPS)
-		}
+	// Все запросы идут на один и тот же хост с одними и теми же учетными данными, поэтому
+	// CheckMultipleStreams сгруппирует их в одно (или до PerHostConcurrency) TCP соединение
+	// и переиспользует уже пройденную аутентификацию между путями вместо того, чтобы
+	// подключаться и проходить аутентификацию заново на каждый путь
+	requests := make([]StreamCheckRequest, len(pathsToCheck))
+	for i, path := range pathsToCheck {
+		requests[i] = StreamCheckRequest{URL: baseURL + path, Username: username, Password: password}
 	}
 
-	// Также пытаемся найти потоки через DESCRIBE на корневом пути
-	rootStreamURL := baseURL + "/"
-	rootStreamInfo, err := c.CheckStream(rootStreamURL, username, password)
-	if err == nil && rootStreamInfo.Available {
-		// Проверяем, не дублируется ли этот поток
-		isDuplicate := false
-		for _, existing := range discoveredStreams {
-			if existing.URL == rootStreamInfo.URL {
-				isDuplicate = true
-				break
+	results := c.CheckMultipleStreams(requests)
+
+	for _, result := range results {
+		if !result.Available || result.StreamInfo == nil {
+			if result.Error != "" {
+				logger.Debugf("Stream %s not available: %s", result.URL, result.Error)
 			}
+			continue
 		}
-		if !isDuplicate {
-			discoveredStreams = append(discoveredStreams, *rootStreamInfo)
+		if seen[result.StreamInfo.URL] {
+			continue
 		}
+		seen[result.StreamInfo.URL] = true
+
+		discoveredStreams = append(discoveredStreams, *result.StreamInfo)
+		logger.Infof("Discovered RTSP stream via path guessing: %s (codec=%s, resolution=%s, fps=%.2f)",
+			result.StreamInfo.URL, result.StreamInfo.Codec, result.StreamInfo.Resolution, result.StreamInfo.FPS)
 	}
 
 	return discoveredStreams, nil
 }
 
+// discoverStreamsONVIF пытается получить RTSP потоки устройства через ONVIF Media сервис
+// (GetProfiles + GetStreamUri для каждого профиля), пробуя device service на
+// onvif.DefaultDeviceServicePorts. Отсутствие ONVIF на устройстве или ошибка аутентификации
+// не считаются фатальными - вызывающий DiscoverStreams в любом случае продолжит угадыванием
+// путей, поэтому здесь только логируется причина и возвращается пустой срез
+func (c *Checker) discoverStreamsONVIF(deviceIP, username, password string) []models.RTSPStreamInfo {
+	logger := utils.GetLogger()
+
+	client, profiles, err := onvif.ProbeDeviceService(deviceIP, onvif.DefaultDeviceServicePorts, onvif.Credentials{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		logger.Debugf("ONVIF device service not available on %s: %v", deviceIP, err)
+		return nil
+	}
+
+	streams := make([]models.RTSPStreamInfo, 0, len(profiles))
+	for _, profile := range profiles {
+		streamURI, err := client.GetStreamUri(profile.Token)
+		if err != nil {
+			logger.Debugf("ONVIF GetStreamUri failed for %s profile %s: %v", deviceIP, profile.Token, err)
+			continue
+		}
+
+		streams = append(streams, models.RTSPStreamInfo{
+			URL:        streamURI,
+			Codec:      profile.Codec,
+			Resolution: profile.Resolution,
+			FPS:        profile.FPS,
+			Available:  true,
+			CheckedAt:  time.Now(),
+		})
+	}
+
+	return streams
+}
+
 // StreamCheckRequest представляет запрос на проверку потока
 type StreamCheckRequest struct {
 	URL      string
@@ -2,19 +2,31 @@ package rtsp
 
 import (
 	"bufio"
-	"crypto/md5"
+	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/local-video-server/internal/rtcp"
 	"github.com/local-video-server/pkg/utils"
 )
 
+// defaultRTPClockRate - тактовая частота, используемая для RTCP метрик до тех пор, пока
+// она не станет известна из SDP (90000 подходит для подавляющего большинства видеокодеков)
+const defaultRTPClockRate = 90000
+
+// DefaultKeepaliveInterval - период отправки keepalive; меньше стандартного таймаута
+// RTSP сессии в 60 секунд (RFC 2326, раздел 12.37), чтобы сервер не успел ее закрыть
+const DefaultKeepaliveInterval = 30 * time.Second
+
 // Client представляет RTSP клиент
 type Client struct {
 	conn       net.Conn
@@ -28,10 +40,41 @@ type Client struct {
 	authMethod string
 	realm      string
 	nonce      string
+
+	transport Transport
+	anyPort   bool
+
+	mu            sync.Mutex
+	rtcpTracker   *rtcp.Tracker
+	localSSRC     uint32
+	remoteSSRC    uint32
+	remoteSSRCSet bool
+
+	keepaliveStop chan struct{}
+	keepaliveWG   sync.WaitGroup
+
+	// optionsChecked/supportsGetParameter - заполняются Options() из заголовка Public ответа
+	// (RFC 2326, раздел 12.23). Пока Options() ни разу не вызван, optionsChecked остается
+	// false, и sendKeepalive использует прежнее поведение (GET_PARAMETER) - отсутствие этой
+	// информации не должно ломать keepalive для кода, который выполняет Describe() напрямую
+	// без предварительного Options()
+	optionsChecked       bool
+	supportsGetParameter bool
 }
 
 // NewClient создает новый RTSP клиент
 func NewClient(rtspURL string, username, password string, timeout time.Duration) (*Client, error) {
+	return NewClientContext(context.Background(), rtspURL, username, password, timeout)
+}
+
+// NewClientContext создает новый RTSP клиент, используя ctx для отмены самого dial - нужно,
+// чтобы CheckMultipleStreamsCtx могла прервать ожидающееся подключение раньше timeout, если
+// вызывающий код отменил проверку целиком
+// NewClientContext не проверяет схему rtspURL - "rtsp://" и устаревшая "rtspu://" (RFC 2326,
+// раздел 3.2, RTSP-поверх-UDP control-сообщений, которую не использует ни одна реальная камера
+// из встреченных этим сканером) одинаково разбираются url.Parse на host/port/path и ведут себя
+// идентично, поскольку весь протокольный обмен здесь всегда идет по TCP
+func NewClientContext(ctx context.Context, rtspURL string, username, password string, timeout time.Duration) (*Client, error) {
 	parsedURL, err := url.Parse(rtspURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid RTSP URL: %w", err)
@@ -45,19 +88,28 @@ func NewClient(rtspURL string, username, password string, timeout time.Duration)
 
 	// Подключаемся к серверу
 	address := net.JoinHostPort(parsedURL.Hostname(), port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
+	localSSRC, err := randomSSRC()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to generate SSRC: %w", err)
+	}
+
 	client := &Client{
-		conn:     conn,
-		reader:   bufio.NewReader(conn),
-		url:      parsedURL,
-		username: username,
-		password: password,
-		timeout:  timeout,
-		cseq:     1,
+		conn:        conn,
+		reader:      bufio.NewReader(conn),
+		url:         parsedURL,
+		username:    username,
+		password:    password,
+		timeout:     timeout,
+		cseq:        1,
+		rtcpTracker: rtcp.NewTracker(defaultRTPClockRate),
+		localSSRC:   localSSRC,
 	}
 
 	// Устанавливаем таймаут на соединение
@@ -69,8 +121,28 @@ func NewClient(rtspURL string, username, password string, timeout time.Duration)
 	return client, nil
 }
 
-// Close закрывает соединение
+// SetAnyPort включает прием RTP/RTCP с любого порта отправителя в SetupUDP вместо строгой
+// проверки server_port из ответа на SETUP - нужно для камер за NAT, которые отправляют
+// пакеты не с того порта, что заявили. По умолчанию выключено (строгая проверка)
+func (c *Client) SetAnyPort(anyPort bool) {
+	c.anyPort = anyPort
+}
+
+// randomSSRC генерирует случайный 32-битный SSRC для исходящих RTCP отчетов (RFC 3550, раздел 8.1)
+func randomSSRC() (uint32, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(buf), nil
+}
+
+// Close останавливает keepalive, закрывает соединение и транспорт, если он был настроен
 func (c *Client) Close() error {
+	c.StopKeepalive()
+	if c.transport != nil {
+		c.transport.Close()
+	}
 	if c.conn != nil {
 		return c.conn.Close()
 	}
@@ -188,17 +260,50 @@ type Response struct {
 	Body       string
 }
 
-// Options отправляет OPTIONS запрос
+// Options отправляет OPTIONS запрос и запоминает, перечислен ли GET_PARAMETER в заголовке
+// Public ответа - sendKeepalive использует это, чтобы не слать GET_PARAMETER серверам, которые
+// о нем не заявили и ответили бы 501 Not Implemented
 func (c *Client) Options() (*Response, error) {
-	return c.sendRequest("OPTIONS", "*", nil)
+	resp, err := c.sendRequest("OPTIONS", "*", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.optionsChecked = true
+	c.supportsGetParameter = publicHeaderSupports(resp.Headers["Public"], "GET_PARAMETER")
+	c.mu.Unlock()
+
+	return resp, nil
+}
+
+// publicHeaderSupports сообщает, входит ли method (без учета регистра) в список методов
+// заголовка Public ответа на OPTIONS - запятая разделяет элементы, пробелы вокруг допустимы
+func publicHeaderSupports(publicHeader, method string) bool {
+	for _, m := range strings.Split(publicHeader, ",") {
+		if strings.EqualFold(strings.TrimSpace(m), method) {
+			return true
+		}
+	}
+	return false
 }
 
-// Describe отправляет DESCRIBE запрос
+// Describe отправляет DESCRIBE запрос для пути из URL, с которым был создан клиент
 func (c *Client) Describe() (*Response, error) {
 	path := c.url.Path
 	if path == "" {
 		path = "/"
 	}
+	return c.DescribePath(path)
+}
+
+// DescribePath отправляет DESCRIBE для произвольного path на уже установленном соединении.
+// Используется для повторного использования одного TCP соединения и уже пройденной
+// аутентификации при проверке нескольких путей потоков на одном хосте (см. runConnJob в pool.go)
+func (c *Client) DescribePath(path string) (*Response, error) {
+	if path == "" {
+		path = "/"
+	}
 
 	headers := map[string]string{
 		"Accept": "application/sdp",
@@ -249,6 +354,220 @@ func (c *Client) Setup(transport string) (*Response, error) {
 	return response, nil
 }
 
+// SetupUDP резервирует пару UDP портов RTP/RTCP и отправляет SETUP с транспортом RTP/AVP
+func (c *Client) SetupUDP() (*Response, error) {
+	transport, err := NewUDPTransport(c.anyPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate UDP ports: %w", err)
+	}
+
+	response, err := c.Setup(transport.Header())
+	if err != nil {
+		transport.Close()
+		return nil, err
+	}
+
+	if serverTransport, ok := response.Headers["Transport"]; ok {
+		if err := transport.ApplyServerReply(serverTransport); err != nil {
+			transport.Close()
+			return nil, fmt.Errorf("failed to parse server Transport header: %w", err)
+		}
+	}
+
+	c.transport = transport
+	return response, nil
+}
+
+// SetupMulticast отправляет SETUP с транспортом RTP/AVP без client_port, ожидая, что
+// сервер сам назначит мультикаст группу и порты в ответе (destination=/port=/ttl=)
+func (c *Client) SetupMulticast() (*Response, error) {
+	transport := NewMulticastTransport()
+
+	response, err := c.Setup(transport.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	serverTransport, ok := response.Headers["Transport"]
+	if !ok {
+		return nil, fmt.Errorf("SETUP response has no Transport header")
+	}
+	if err := transport.ApplyServerReply(serverTransport); err != nil {
+		return nil, fmt.Errorf("failed to parse server Transport header: %w", err)
+	}
+
+	c.transport = transport
+	return response, nil
+}
+
+// SetupTransport вызывает SetupUDP, SetupInterleaved или SetupMulticast в зависимости от
+// mode. "auto" режим сюда не входит - им управляет PlayAuto, который сам решает между UDP
+// и TCP по факту приема пакетов после PLAY
+func (c *Client) SetupTransport(mode TransportMode) (*Response, error) {
+	switch mode {
+	case TransportModeUDP:
+		return c.SetupUDP()
+	case TransportModeInterleaved:
+		return c.SetupInterleaved()
+	case TransportModeUDPMulticast:
+		return c.SetupMulticast()
+	default:
+		return nil, fmt.Errorf("unsupported RTSP transport mode: %v", mode)
+	}
+}
+
+// SetupInterleaved запрашивает TCP-interleaved транспорт (RTP/RTCP внутри RTSP соединения)
+func (c *Client) SetupInterleaved() (*Response, error) {
+	transport := NewInterleavedTransport(c.reader, c.conn, 0, 1)
+
+	response, err := c.Setup(transport.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	if serverTransport, ok := response.Headers["Transport"]; ok {
+		if err := transport.ApplyServerReply(serverTransport); err != nil {
+			return nil, fmt.Errorf("failed to parse server Transport header: %w", err)
+		}
+	}
+
+	c.transport = transport
+	return response, nil
+}
+
+// ReadPacket возвращает следующий принятый RTP/RTCP пакет: канал, payload, ошибка.
+// Попутно скармливает RTP пакеты (канал 0) и Sender Report от сервера (канал 1) RTCP трекеру,
+// который лежит в основе Metrics() и отчетов, отправляемых keepalive-циклом
+func (c *Client) ReadPacket() (channel int, payload []byte, err error) {
+	if c.transport == nil {
+		return 0, nil, fmt.Errorf("transport not set up, call SetupUDP or SetupInterleaved first")
+	}
+
+	channel, payload, err = c.transport.ReadPacket()
+	if err != nil {
+		return channel, payload, err
+	}
+
+	now := time.Now()
+	switch channel {
+	case 0:
+		if len(payload) >= 12 {
+			c.mu.Lock()
+			if !c.remoteSSRCSet {
+				c.remoteSSRC = binary.BigEndian.Uint32(payload[8:12])
+				c.remoteSSRCSet = true
+			}
+			c.mu.Unlock()
+		}
+		c.rtcpTracker.OnRTPPacket(payload, now)
+	case 1:
+		// На этом канале также приходят Sender Report - игнорируем прочие типы RTCP пакетов
+		c.rtcpTracker.OnSenderReport(payload, now)
+	}
+
+	return channel, payload, nil
+}
+
+// TrackStats возвращает статистику приема RTP пакетов (packets, bytes, jitter)
+func (c *Client) TrackStats() TrackStats {
+	if c.transport == nil {
+		return TrackStats{}
+	}
+	return c.transport.Stats()
+}
+
+// Metrics возвращает метрики качества приема RTP потока (процент потерь, джиттер, возраст
+// последнего пакета), накопленные RTCP трекером по данным из ReadPacket
+func (c *Client) Metrics() (lossPercent float64, jitterMs float64, lastPacketAge time.Duration) {
+	c.mu.Lock()
+	ssrc, set := c.remoteSSRC, c.remoteSSRCSet
+	c.mu.Unlock()
+	if !set {
+		return 0, 0, 0
+	}
+	return c.rtcpTracker.Metrics(ssrc, time.Now())
+}
+
+// StartKeepalive запускает фоновую горутину, которая каждые interval поддерживает RTSP
+// сессию активной через GET_PARAMETER (или OPTIONS, если предыдущий вызов Options() не нашел
+// GET_PARAMETER в заголовке Public) и, если транспорт настроен, отправляет серверу RTCP
+// Receiver Report с накопленной статистикой приема
+func (c *Client) StartKeepalive(interval time.Duration) {
+	if c.keepaliveStop != nil {
+		return
+	}
+	c.keepaliveStop = make(chan struct{})
+	c.keepaliveWG.Add(1)
+
+	go func() {
+		defer c.keepaliveWG.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.sendKeepalive()
+			case <-c.keepaliveStop:
+				return
+			}
+		}
+	}()
+}
+
+// StopKeepalive останавливает ранее запущенный StartKeepalive и ждет завершения горутины.
+// Безопасна для повторного вызова и для вызова, когда keepalive не был запущен
+func (c *Client) StopKeepalive() {
+	if c.keepaliveStop == nil {
+		return
+	}
+	close(c.keepaliveStop)
+	c.keepaliveWG.Wait()
+	c.keepaliveStop = nil
+}
+
+// sendKeepalive отправляет один цикл keepalive: GET_PARAMETER (или OPTIONS, для серверов,
+// заявивших OPTIONS-ответом, что они его не поддерживают) для поддержания RTSP сессии, и, при
+// наличии активного транспорта и известного SSRC сервера, RTCP Receiver Report
+func (c *Client) sendKeepalive() {
+	logger := utils.GetLogger()
+
+	c.mu.Lock()
+	path := c.url.Path
+	if path == "" {
+		path = "/"
+	}
+	headers := make(map[string]string)
+	if c.sessionID != "" {
+		headers["Session"] = c.sessionID
+	}
+	method, requestPath := "GET_PARAMETER", path
+	if c.optionsChecked && !c.supportsGetParameter {
+		method, requestPath = "OPTIONS", "*"
+	}
+	_, err := c.sendRequest(method, requestPath, headers)
+	c.mu.Unlock()
+	if err != nil {
+		logger.Debugf("RTSP keepalive failed: %v", err)
+	}
+
+	c.mu.Lock()
+	transport, ssrc, ssrcSet := c.transport, c.remoteSSRC, c.remoteSSRCSet
+	c.mu.Unlock()
+	if transport == nil || !ssrcSet {
+		return
+	}
+
+	report, err := c.rtcpTracker.BuildReceiverReport(c.localSSRC, ssrc)
+	if err != nil {
+		logger.Debugf("failed to build RTCP receiver report: %v", err)
+		return
+	}
+	if err := transport.SendRTCP(report); err != nil {
+		logger.Debugf("failed to send RTCP receiver report: %v", err)
+	}
+}
+
 // Play отправляет PLAY запрос
 func (c *Client) Play() (*Response, error) {
 	path := c.url.Path
@@ -261,7 +580,98 @@ func (c *Client) Play() (*Response, error) {
 		headers["Session"] = c.sessionID
 	}
 
-	return c.sendRequest("PLAY", path, headers)
+	response, err := c.sendRequest("PLAY", path, headers)
+	if err != nil {
+		return response, err
+	}
+
+	// Сервер начинает отправку RTP только после успешного PLAY
+	if response.StatusCode == 200 && c.transport != nil {
+		if err := c.transport.Start(); err != nil {
+			return response, fmt.Errorf("failed to start transport: %w", err)
+		}
+	}
+
+	return response, nil
+}
+
+// DefaultAutoFallbackWait - сколько ждать первых RTP пакетов после PLAY в режиме "auto",
+// прежде чем считать UDP заблокированным (файрвол/NAT) и переключиться на TCP-interleaved
+const DefaultAutoFallbackWait = 2 * time.Second
+
+// PlayAuto реализует транспортный режим "auto": сначала пробует UDP (SetupUDP+Play), и
+// если за fallbackWait не пришло ни одного RTP пакета, переподключается и играет заново
+// через TCP-interleaved - транспорт нельзя сменить в рамках уже установленной RTSP сессии,
+// поэтому fallback идет через новое TCP соединение с нуля (OPTIONS/DESCRIBE/SETUP/PLAY).
+// Вызывается вместо SetupTransport+Play, уже после того, как Options/Describe отработали
+// на исходном соединении
+func (c *Client) PlayAuto(fallbackWait time.Duration) (*Response, TransportMode, error) {
+	if _, err := c.SetupUDP(); err != nil {
+		return nil, 0, fmt.Errorf("UDP SETUP failed: %w", err)
+	}
+
+	response, err := c.Play()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	time.Sleep(fallbackWait)
+	if c.transport.Stats().Packets > 0 {
+		return response, TransportModeUDP, nil
+	}
+
+	utils.GetLogger().Debugf("RTSP auto transport: no UDP packets received from %s within %s, falling back to TCP-interleaved", c.url.Host, fallbackWait)
+
+	if err := c.redial(); err != nil {
+		return nil, 0, fmt.Errorf("failed to reconnect for TCP fallback: %w", err)
+	}
+	if _, err := c.Options(); err != nil {
+		return nil, 0, fmt.Errorf("OPTIONS failed after TCP fallback: %w", err)
+	}
+	if _, err := c.Describe(); err != nil {
+		return nil, 0, fmt.Errorf("DESCRIBE failed after TCP fallback: %w", err)
+	}
+	if _, err := c.SetupInterleaved(); err != nil {
+		return nil, 0, fmt.Errorf("interleaved SETUP failed after TCP fallback: %w", err)
+	}
+	response, err = c.Play()
+	if err != nil {
+		return nil, 0, err
+	}
+	return response, TransportModeInterleaved, nil
+}
+
+// redial закрывает текущее TCP соединение и открывает новое к тому же URL, сбрасывая
+// состояние RTSP сессии - используется PlayAuto, так как транспорт нельзя сменить в
+// рамках уже установленной сессии
+func (c *Client) redial() error {
+	if c.transport != nil {
+		c.transport.Close()
+		c.transport = nil
+	}
+	c.conn.Close()
+
+	port := c.url.Port()
+	if port == "" {
+		port = "554"
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.url.Hostname(), port), c.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to reconnect: %w", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	c.sessionID = ""
+	c.cseq = 1
+	c.authMethod = ""
+	c.realm = ""
+	c.nonce = ""
+	return nil
 }
 
 // parseAuthHeader парсит заголовок WWW-Authenticate
@@ -304,9 +714,9 @@ func (c *Client) buildAuthHeader(method, path string) string {
 		return fmt.Sprintf("Basic %s", encoded)
 	} else if c.authMethod == "Digest" {
 		// Digest аутентификация
-		ha1 := md5Hash(fmt.Sprintf("%s:%s:%s", c.username, c.realm, c.password))
-		ha2 := md5Hash(fmt.Sprintf("%s:%s", method, path))
-		response := md5Hash(fmt.Sprintf("%s:%s:%s", ha1, c.nonce, ha2))
+		ha1 := utils.MD5Hash(fmt.Sprintf("%s:%s:%s", c.username, c.realm, c.password))
+		ha2 := utils.MD5Hash(fmt.Sprintf("%s:%s", method, path))
+		response := utils.MD5Hash(fmt.Sprintf("%s:%s:%s", ha1, c.nonce, ha2))
 
 		return fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
 			c.username, c.realm, c.nonce, path, response)
@@ -315,12 +725,6 @@ func (c *Client) buildAuthHeader(method, path string) string {
 	return ""
 }
 
-// md5Hash вычисляет MD5 хеш
-func md5Hash(data string) string {
-	hash := md5.Sum([]byte(data))
-	return fmt.Sprintf("%x", hash)
-}
-
 // CheckStream проверяет RTSP поток и возвращает информацию о нем
 func CheckStream(rtspURL string, username, password string, timeout time.Duration) (*StreamInfo, error) {
 	logger := utils.GetLogger()
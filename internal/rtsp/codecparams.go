@@ -0,0 +1,694 @@
+package rtsp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// bitReader читает биты RBSP (Raw Byte Sequence Payload) старший бит вперед,
+// как того требует синтаксис H.264/H.265 (ITU-T H.264 раздел 7.2, H.265 раздел 7.2)
+type bitReader struct {
+	data []byte
+	pos  int // позиция в битах от начала data
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+func (b *bitReader) bitsLeft() int {
+	return len(b.data)*8 - b.pos
+}
+
+// readBit читает один бит
+func (b *bitReader) readBit() (uint32, error) {
+	if b.bitsLeft() < 1 {
+		return 0, fmt.Errorf("bitReader: no bits left")
+	}
+	byteIdx := b.pos / 8
+	bitIdx := uint(7 - b.pos%8)
+	bit := (b.data[byteIdx] >> bitIdx) & 1
+	b.pos++
+	return uint32(bit), nil
+}
+
+// readBits читает n бит (n <= 32) как беззнаковое число
+func (b *bitReader) readBits(n int) (uint32, error) {
+	var v uint32
+	for i := 0; i < n; i++ {
+		bit, err := b.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v = (v << 1) | bit
+	}
+	return v, nil
+}
+
+// readUE читает unsigned Exp-Golomb код (ITU-T H.264, раздел 9.1)
+func (b *bitReader) readUE() (uint32, error) {
+	leadingZeroBits := 0
+	for {
+		bit, err := b.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if bit != 0 {
+			break
+		}
+		leadingZeroBits++
+		if leadingZeroBits > 32 {
+			return 0, fmt.Errorf("bitReader: exp-golomb code too long")
+		}
+	}
+	if leadingZeroBits == 0 {
+		return 0, nil
+	}
+	rest, err := b.readBits(leadingZeroBits)
+	if err != nil {
+		return 0, err
+	}
+	return (1 << uint(leadingZeroBits)) - 1 + rest, nil
+}
+
+// readSE читает signed Exp-Golomb код (ITU-T H.264, раздел 9.1.1)
+func (b *bitReader) readSE() (int32, error) {
+	ue, err := b.readUE()
+	if err != nil {
+		return 0, err
+	}
+	if ue%2 == 0 {
+		return -int32(ue / 2), nil
+	}
+	return int32(ue+1) / 2, nil
+}
+
+// skipScalingList пропускает scaling_list() из seq_parameter_set_data (ITU-T H.264, раздел 7.3.2.1.1.1)
+func (b *bitReader) skipScalingList(size int) error {
+	lastScale, nextScale := int32(8), int32(8)
+	for i := 0; i < size; i++ {
+		if nextScale != 0 {
+			deltaScale, err := b.readSE()
+			if err != nil {
+				return err
+			}
+			nextScale = (lastScale + deltaScale + 256) % 256
+		}
+		if nextScale != 0 {
+			lastScale = nextScale
+		}
+	}
+	return nil
+}
+
+// removeEmulationPrevention убирает байты предотвращения эмуляции стартового кода (0x03 после 0x00 0x00)
+// и превращает NAL unit в RBSP, пригодный для побитового чтения (ITU-T H.264, раздел 7.3.1)
+func removeEmulationPrevention(nal []byte) []byte {
+	out := make([]byte, 0, len(nal))
+	zeroCount := 0
+	for _, b := range nal {
+		if zeroCount >= 2 && b == 0x03 {
+			zeroCount = 0
+			continue
+		}
+		if b == 0x00 {
+			zeroCount++
+		} else {
+			zeroCount = 0
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// h264HighProfiles - коды profile_idc, после которых в SPS присутствует блок chroma_format_idc/scaling_matrix
+// (ITU-T H.264, раздел 7.3.2.1.1)
+var h264HighProfiles = map[uint32]bool{
+	100: true, 110: true, 122: true, 244: true, 44: true,
+	83: true, 86: true, 118: true, 128: true, 138: true, 139: true, 134: true, 135: true,
+}
+
+// h264SPSInfo содержит параметры, извлеченные из SPS (Sequence Parameter Set) потока H.264
+type h264SPSInfo struct {
+	ProfileIDC int
+	LevelIDC   int
+	Width      int
+	Height     int
+	FPS        float64
+}
+
+// parseH264SpropParameterSets декодирует sprop-parameter-sets (SPS и PPS в base64, RFC 6184, раздел 8.2.1)
+// и разбирает найденный в нем SPS NAL
+func parseH264SpropParameterSets(value string) (*h264SPSInfo, error) {
+	for _, part := range strings.Split(value, ",") {
+		nal, err := base64.StdEncoding.DecodeString(strings.TrimSpace(part))
+		if err != nil || len(nal) < 2 {
+			continue
+		}
+		if nal[0]&0x1F != 7 { // nal_unit_type == 7 (SPS)
+			continue
+		}
+		return parseH264SPS(nal)
+	}
+	return nil, fmt.Errorf("no SPS NAL found in sprop-parameter-sets")
+}
+
+// parseH264SPS разбирает SPS (Sequence Parameter Set) H.264 (ITU-T H.264, раздел 7.3.2.1.1)
+// и извлекает profile/level, разрешение кадра и частоту кадров из VUI, если она присутствует
+func parseH264SPS(nal []byte) (*h264SPSInfo, error) {
+	rbsp := removeEmulationPrevention(nal[1:])
+	b := newBitReader(rbsp)
+
+	profileIDC, err := b.readBits(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile_idc: %w", err)
+	}
+	if _, err := b.readBits(8); err != nil { // constraint_set flags + reserved_zero_2bits
+		return nil, err
+	}
+	levelIDC, err := b.readBits(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read level_idc: %w", err)
+	}
+	if _, err := b.readUE(); err != nil { // seq_parameter_set_id
+		return nil, err
+	}
+
+	chromaFormatIDC := uint32(1) // по умолчанию 4:2:0, если блок ниже отсутствует
+	if h264HighProfiles[profileIDC] {
+		chromaFormatIDC, err = b.readUE()
+		if err != nil {
+			return nil, err
+		}
+		if chromaFormatIDC == 3 {
+			if _, err := b.readBits(1); err != nil { // separate_colour_plane_flag
+				return nil, err
+			}
+		}
+		if _, err := b.readUE(); err != nil { // bit_depth_luma_minus8
+			return nil, err
+		}
+		if _, err := b.readUE(); err != nil { // bit_depth_chroma_minus8
+			return nil, err
+		}
+		if _, err := b.readBits(1); err != nil { // qpprime_y_zero_transform_bypass_flag
+			return nil, err
+		}
+		scalingMatrixPresent, err := b.readBits(1)
+		if err != nil {
+			return nil, err
+		}
+		if scalingMatrixPresent == 1 {
+			count := 8
+			if chromaFormatIDC == 3 {
+				count = 12
+			}
+			for i := 0; i < count; i++ {
+				present, err := b.readBits(1)
+				if err != nil {
+					return nil, err
+				}
+				if present == 1 {
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					if err := b.skipScalingList(size); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	if _, err := b.readUE(); err != nil { // log2_max_frame_num_minus4
+		return nil, err
+	}
+	picOrderCntType, err := b.readUE()
+	if err != nil {
+		return nil, err
+	}
+	switch picOrderCntType {
+	case 0:
+		if _, err := b.readUE(); err != nil { // log2_max_pic_order_cnt_lsb_minus4
+			return nil, err
+		}
+	case 1:
+		if _, err := b.readBits(1); err != nil { // delta_pic_order_always_zero_flag
+			return nil, err
+		}
+		if _, err := b.readSE(); err != nil { // offset_for_non_ref_pic
+			return nil, err
+		}
+		if _, err := b.readSE(); err != nil { // offset_for_top_to_bottom_field
+			return nil, err
+		}
+		numRefFrames, err := b.readUE() // num_ref_frames_in_pic_order_cnt_cycle
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < numRefFrames; i++ {
+			if _, err := b.readSE(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := b.readUE(); err != nil { // max_num_ref_frames
+		return nil, err
+	}
+	if _, err := b.readBits(1); err != nil { // gaps_in_frame_num_value_allowed_flag
+		return nil, err
+	}
+	picWidthInMbsMinus1, err := b.readUE()
+	if err != nil {
+		return nil, err
+	}
+	picHeightInMapUnitsMinus1, err := b.readUE()
+	if err != nil {
+		return nil, err
+	}
+	frameMbsOnlyFlag, err := b.readBits(1)
+	if err != nil {
+		return nil, err
+	}
+	if frameMbsOnlyFlag == 0 {
+		if _, err := b.readBits(1); err != nil { // mb_adaptive_frame_field_flag
+			return nil, err
+		}
+	}
+	if _, err := b.readBits(1); err != nil { // direct_8x8_inference_flag
+		return nil, err
+	}
+	frameCroppingFlag, err := b.readBits(1)
+	if err != nil {
+		return nil, err
+	}
+	var cropLeft, cropRight, cropTop, cropBottom uint32
+	if frameCroppingFlag == 1 {
+		if cropLeft, err = b.readUE(); err != nil {
+			return nil, err
+		}
+		if cropRight, err = b.readUE(); err != nil {
+			return nil, err
+		}
+		if cropTop, err = b.readUE(); err != nil {
+			return nil, err
+		}
+		if cropBottom, err = b.readUE(); err != nil {
+			return nil, err
+		}
+	}
+
+	width := int(picWidthInMbsMinus1+1) * 16
+	height := int(2-frameMbsOnlyFlag) * int(picHeightInMapUnitsMinus1+1) * 16
+
+	cropUnitX, cropUnitY := 2, 2
+	switch chromaFormatIDC {
+	case 0:
+		cropUnitX, cropUnitY = 1, 1
+	case 2:
+		cropUnitX, cropUnitY = 2, 1
+	case 3:
+		cropUnitX, cropUnitY = 1, 1
+	}
+	cropUnitY *= int(2 - frameMbsOnlyFlag)
+	width -= cropUnitX * int(cropLeft+cropRight)
+	height -= cropUnitY * int(cropTop+cropBottom)
+
+	info := &h264SPSInfo{
+		ProfileIDC: int(profileIDC),
+		LevelIDC:   int(levelIDC),
+		Width:      width,
+		Height:     height,
+	}
+
+	// VUI присутствует не всегда и несет FPS - если его не получилось дочитать, это не ошибка парсинга SPS
+	vuiPresent, err := b.readBits(1)
+	if err == nil && vuiPresent == 1 {
+		info.FPS = parseH264VUITiming(b)
+	}
+
+	return info, nil
+}
+
+// parseH264VUITiming дочитывает VUI parameters до timing_info (ITU-T H.264, приложение E.1.1)
+// и возвращает частоту кадров, если она указана. Ошибки игнорируются - это необязательные данные
+func parseH264VUITiming(b *bitReader) float64 {
+	aspectRatioPresent, err := b.readBits(1)
+	if err != nil {
+		return 0
+	}
+	if aspectRatioPresent == 1 {
+		aspectRatioIDC, err := b.readBits(8)
+		if err != nil {
+			return 0
+		}
+		if aspectRatioIDC == 255 { // Extended_SAR
+			if _, err := b.readBits(32); err != nil { // sar_width + sar_height
+				return 0
+			}
+		}
+	}
+	overscanPresent, err := b.readBits(1)
+	if err != nil {
+		return 0
+	}
+	if overscanPresent == 1 {
+		if _, err := b.readBits(1); err != nil {
+			return 0
+		}
+	}
+	videoSignalPresent, err := b.readBits(1)
+	if err != nil {
+		return 0
+	}
+	if videoSignalPresent == 1 {
+		if _, err := b.readBits(4); err != nil { // video_format + video_full_range_flag
+			return 0
+		}
+		colourDescPresent, err := b.readBits(1)
+		if err != nil {
+			return 0
+		}
+		if colourDescPresent == 1 {
+			if _, err := b.readBits(24); err != nil {
+				return 0
+			}
+		}
+	}
+	chromaLocPresent, err := b.readBits(1)
+	if err != nil {
+		return 0
+	}
+	if chromaLocPresent == 1 {
+		if _, err := b.readUE(); err != nil {
+			return 0
+		}
+		if _, err := b.readUE(); err != nil {
+			return 0
+		}
+	}
+	timingInfoPresent, err := b.readBits(1)
+	if err != nil {
+		return 0
+	}
+	if timingInfoPresent != 1 {
+		return 0
+	}
+	numUnitsInTick, err := b.readBits(32)
+	if err != nil || numUnitsInTick == 0 {
+		return 0
+	}
+	timeScale, err := b.readBits(32)
+	if err != nil {
+		return 0
+	}
+	return float64(timeScale) / float64(2*numUnitsInTick)
+}
+
+// h265SPSInfo содержит параметры, извлеченные из SPS (Sequence Parameter Set) потока H.265
+type h265SPSInfo struct {
+	ProfileIDC int
+	LevelIDC   int
+	Width      int
+	Height     int
+}
+
+// parseH265SpropSPS декодирует sprop-sps (один SPS NAL в base64, RFC 7798, раздел 7.1) и разбирает его
+func parseH265SpropSPS(value string) (*h265SPSInfo, error) {
+	nal, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sprop-sps: %w", err)
+	}
+	if len(nal) < 2 {
+		return nil, fmt.Errorf("sprop-sps NAL too short")
+	}
+	nalType := (nal[0] >> 1) & 0x3F
+	if nalType != 33 { // SPS_NUT
+		return nil, fmt.Errorf("sprop-sps does not contain an SPS NAL (type=%d)", nalType)
+	}
+	return parseH265SPS(nal)
+}
+
+// parseH265ProfileTierLevel пропускает profile_tier_level() (ITU-T H.265, раздел 7.3.3) и возвращает
+// general_profile_idc/general_level_idc - основные поля, используемые ниже
+func parseH265ProfileTierLevel(b *bitReader, maxNumSubLayersMinus1 uint32) (profileIDC, levelIDC uint32, err error) {
+	generalFlags, err := b.readBits(8) // general_profile_space(2) + general_tier_flag(1) + general_profile_idc(5)
+	if err != nil {
+		return 0, 0, err
+	}
+	profileIDC = generalFlags & 0x1F
+
+	if _, err := b.readBits(32); err != nil { // general_profile_compatibility_flag[32]
+		return 0, 0, err
+	}
+	if _, err := b.readBits(32); err != nil { // 4 constraint flags + первые 28 из 44 зарезервированных бит
+		return 0, 0, err
+	}
+	if _, err := b.readBits(16); err != nil { // оставшиеся 16 зарезервированных бит
+		return 0, 0, err
+	}
+	levelIDC, err = b.readBits(8) // general_level_idc
+	if err != nil {
+		return 0, 0, err
+	}
+
+	subLayerProfilePresent := make([]bool, maxNumSubLayersMinus1)
+	subLayerLevelPresent := make([]bool, maxNumSubLayersMinus1)
+	for i := uint32(0); i < maxNumSubLayersMinus1; i++ {
+		profilePresent, err := b.readBits(1)
+		if err != nil {
+			return 0, 0, err
+		}
+		levelPresent, err := b.readBits(1)
+		if err != nil {
+			return 0, 0, err
+		}
+		subLayerProfilePresent[i] = profilePresent == 1
+		subLayerLevelPresent[i] = levelPresent == 1
+	}
+	if maxNumSubLayersMinus1 > 0 {
+		if _, err := b.readBits(2 * int(8-maxNumSubLayersMinus1)); err != nil { // reserved_zero_2bits padding
+			return 0, 0, err
+		}
+	}
+	for i := uint32(0); i < maxNumSubLayersMinus1; i++ {
+		if subLayerProfilePresent[i] {
+			if _, err := b.readBits(32); err != nil {
+				return 0, 0, err
+			}
+			if _, err := b.readBits(32); err != nil {
+				return 0, 0, err
+			}
+			if _, err := b.readBits(24); err != nil {
+				return 0, 0, err
+			}
+		}
+		if subLayerLevelPresent[i] {
+			if _, err := b.readBits(8); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	return profileIDC, levelIDC, nil
+}
+
+// parseH265SPS разбирает SPS (Sequence Parameter Set) H.265 (ITU-T H.265, раздел 7.3.2.2.1)
+// и извлекает profile/level и разрешение кадра (с учетом conformance window)
+func parseH265SPS(nal []byte) (*h265SPSInfo, error) {
+	rbsp := removeEmulationPrevention(nal[2:]) // у H.265 NAL заголовок занимает 2 байта
+	b := newBitReader(rbsp)
+
+	if _, err := b.readBits(4); err != nil { // sps_video_parameter_set_id
+		return nil, err
+	}
+	maxSubLayersMinus1, err := b.readBits(3)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.readBits(1); err != nil { // sps_temporal_id_nesting_flag
+		return nil, err
+	}
+
+	profileIDC, levelIDC, err := parseH265ProfileTierLevel(b, maxSubLayersMinus1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profile_tier_level: %w", err)
+	}
+
+	if _, err := b.readUE(); err != nil { // sps_seq_parameter_set_id
+		return nil, err
+	}
+	chromaFormatIDC, err := b.readUE()
+	if err != nil {
+		return nil, err
+	}
+	if chromaFormatIDC == 3 {
+		if _, err := b.readBits(1); err != nil { // separate_colour_plane_flag
+			return nil, err
+		}
+	}
+	picWidth, err := b.readUE()
+	if err != nil {
+		return nil, err
+	}
+	picHeight, err := b.readUE()
+	if err != nil {
+		return nil, err
+	}
+
+	confWindowPresent, err := b.readBits(1)
+	if err != nil {
+		return nil, err
+	}
+	var confLeft, confRight, confTop, confBottom uint32
+	if confWindowPresent == 1 {
+		if confLeft, err = b.readUE(); err != nil {
+			return nil, err
+		}
+		if confRight, err = b.readUE(); err != nil {
+			return nil, err
+		}
+		if confTop, err = b.readUE(); err != nil {
+			return nil, err
+		}
+		if confBottom, err = b.readUE(); err != nil {
+			return nil, err
+		}
+	}
+
+	subWidthC, subHeightC := 1, 1
+	switch chromaFormatIDC {
+	case 1:
+		subWidthC, subHeightC = 2, 2
+	case 2:
+		subWidthC, subHeightC = 2, 1
+	}
+
+	width := int(picWidth) - subWidthC*int(confLeft+confRight)
+	height := int(picHeight) - subHeightC*int(confTop+confBottom)
+
+	return &h265SPSInfo{
+		ProfileIDC: int(profileIDC),
+		LevelIDC:   int(levelIDC),
+		Width:      width,
+		Height:     height,
+	}, nil
+}
+
+// aacSampleRates - таблица частот дискретизации AudioSpecificConfig (ISO/IEC 14496-3, таблица 1.16)
+var aacSampleRates = [16]int{
+	96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050,
+	16000, 12000, 11025, 8000, 7350, 0, 0, 0,
+}
+
+// parseAACAudioSpecificConfig декодирует AudioSpecificConfig (ISO/IEC 14496-3, раздел 1.6.2.1)
+// из hex-строки параметра config= в fmtp для mode=AAC-hbr (RFC 3640, раздел 4.1)
+func parseAACAudioSpecificConfig(configHex string) (objectType, sampleRate, channels int, err error) {
+	raw, err := hex.DecodeString(configHex)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid AAC config hex: %w", err)
+	}
+	return parseAACAudioSpecificConfigBytes(raw)
+}
+
+// parseAACAudioSpecificConfigBytes - то же самое, что parseAACAudioSpecificConfig, но принимает уже
+// декодированные байты AudioSpecificConfig вместо hex-строки
+func parseAACAudioSpecificConfigBytes(raw []byte) (objectType, sampleRate, channels int, err error) {
+	if len(raw) < 2 {
+		return 0, 0, 0, fmt.Errorf("AAC config too short")
+	}
+
+	b := newBitReader(raw)
+
+	objType, err := b.readBits(5)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if objType == 31 {
+		ext, err := b.readBits(6)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		objType = 32 + ext
+	}
+
+	freqIdx, err := b.readBits(4)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if freqIdx == 0xF {
+		explicitRate, err := b.readBits(24)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		sampleRate = int(explicitRate)
+	} else {
+		sampleRate = aacSampleRates[freqIdx]
+	}
+
+	chanConfig, err := b.readBits(4)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return int(objType), sampleRate, int(chanConfig), nil
+}
+
+// ParsedH264SPS - разобранные параметры SPS H.264, возвращаемые ParseH264SPSNAL
+type ParsedH264SPS struct {
+	ProfileIDC int
+	LevelIDC   int
+	Width      int
+	Height     int
+	FPS        float64
+}
+
+// ParseH264SPSNAL разбирает один необработанный SPS NAL H.264 (с байтом заголовка NAL, как он
+// приходит в sprop-parameter-sets или в AVCDecoderConfigurationRecord RTMP/FLV). Экспортирована
+// для internal/protocols, которому нужно то же разбиение SPS, что и RTSP
+func ParseH264SPSNAL(nal []byte) (*ParsedH264SPS, error) {
+	info, err := parseH264SPS(nal)
+	if err != nil {
+		return nil, err
+	}
+	return &ParsedH264SPS{ProfileIDC: info.ProfileIDC, LevelIDC: info.LevelIDC, Width: info.Width, Height: info.Height, FPS: info.FPS}, nil
+}
+
+// ParsedH265SPS - разобранные параметры SPS H.265, возвращаемые ParseH265SPSNAL
+type ParsedH265SPS struct {
+	ProfileIDC int
+	LevelIDC   int
+	Width      int
+	Height     int
+}
+
+// ParseH265SPSNAL разбирает один необработанный SPS NAL H.265 (с 2-байтовым заголовком NAL).
+// Экспортирована для internal/protocols по тем же причинам, что и ParseH264SPSNAL
+func ParseH265SPSNAL(nal []byte) (*ParsedH265SPS, error) {
+	info, err := parseH265SPS(nal)
+	if err != nil {
+		return nil, err
+	}
+	return &ParsedH265SPS{ProfileIDC: info.ProfileIDC, LevelIDC: info.LevelIDC, Width: info.Width, Height: info.Height}, nil
+}
+
+// FormatH264Level форматирует level_idc H.264 как десятичный уровень (например, 31 -> "3.1")
+func FormatH264Level(levelIDC int) string {
+	return formatH264Level(levelIDC)
+}
+
+// FormatH265Level форматирует general_level_idc H.265 как десятичный уровень
+func FormatH265Level(levelIDC int) string {
+	return formatH265Level(levelIDC)
+}
+
+// ParseAACAudioSpecificConfigBytes разбирает AudioSpecificConfig (ISO/IEC 14496-3, раздел 1.6.2.1) из
+// уже декодированных байтов - в отличие от parseAACAudioSpecificConfig, который ожидает hex-строку
+// параметра fmtp config=. Используется RTMP парсером, где AudioSpecificConfig приходит как raw payload
+// AAC sequence header, а не как hex в SDP
+func ParseAACAudioSpecificConfigBytes(raw []byte) (objectType, sampleRate, channels int, err error) {
+	return parseAACAudioSpecificConfigBytes(raw)
+}
@@ -0,0 +1,319 @@
+package rtsp
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The SPS fixtures below are synthetic, not captured from a real encoder - this sandbox has no
+// ffmpeg/ffprobe available to produce vendor SDP captures. Each was built bit-by-bit against the
+// field layout parseH264SPS/parseH265SPS actually reads (ITU-T H.264 §7.3.2.1.1, H.265 §7.3.2.2.1),
+// with emulation-prevention bytes (0x03) inserted wherever three-byte-escape would trigger on a
+// real encoder (ITU-T H.264 §7.3.1), then round-tripped through this package's own parser to
+// confirm the decoded fields match what was encoded.
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid test fixture hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestParseH264SPS(t *testing.T) {
+	tests := []struct {
+		name    string
+		nalHex  string
+		want    *h264SPSInfo
+		wantErr bool
+	}{
+		{
+			// baseline profile, pic_order_cnt_type=2, no VUI
+			name:   "baseline 640x480",
+			nalHex: "6742001eda0280f400",
+			want:   &h264SPSInfo{ProfileIDC: 66, LevelIDC: 30, Width: 640, Height: 480, FPS: 0},
+		},
+		{
+			// high profile (exercises chroma_format_idc/scaling-matrix block), pic_order_cnt_type=0,
+			// VUI present with timing_info (num_units_in_tick=1, time_scale=50 -> 25fps)
+			name:   "high profile 1280x720 with VUI timing",
+			nalHex: "67640028acca805005b21000000300100000030320",
+			want:   &h264SPSInfo{ProfileIDC: 100, LevelIDC: 40, Width: 1280, Height: 720, FPS: 25},
+		},
+		{
+			name:    "truncated NAL",
+			nalHex:  "6742",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nal := mustDecodeHex(t, tt.nalHex)
+			got, err := parseH264SPS(nal)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseH264SPS(%s) = %+v, want error", tt.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseH264SPS(%s) unexpected error: %v", tt.name, err)
+			}
+			if *got != *tt.want {
+				t.Fatalf("parseH264SPS(%s) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseH264SpropParameterSets(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    *h264SPSInfo
+		wantErr bool
+	}{
+		{
+			name:  "single SPS",
+			value: "Z0IAHtoCgPQA",
+			want:  &h264SPSInfo{ProfileIDC: 66, LevelIDC: 30, Width: 640, Height: 480, FPS: 0},
+		},
+		{
+			name:  "SPS,PPS pair - PPS (non-SPS NAL type) is skipped",
+			value: "Z0IAHtoCgPQA,aM48gA==",
+			want:  &h264SPSInfo{ProfileIDC: 66, LevelIDC: 30, Width: 640, Height: 480, FPS: 0},
+		},
+		{
+			name:    "invalid base64 only",
+			value:   "not-base64!!!",
+			wantErr: true,
+		},
+		{
+			name:    "no SPS NAL present",
+			value:   "aM48gA==", // PPS only (nal_unit_type 8)
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseH264SpropParameterSets(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseH264SpropParameterSets(%s) = %+v, want error", tt.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseH264SpropParameterSets(%s) unexpected error: %v", tt.name, err)
+			}
+			if *got != *tt.want {
+				t.Fatalf("parseH264SpropParameterSets(%s) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseH265SPS(t *testing.T) {
+	tests := []struct {
+		name    string
+		nalHex  string
+		want    *h265SPSInfo
+		wantErr bool
+	}{
+		{
+			// main profile, level 120 (4.0), 1280x720, no conformance window
+			name:   "main profile 1280x720",
+			nalHex: "42010001000003000003000003000003000078a00280802d10",
+			want:   &h265SPSInfo{ProfileIDC: 1, LevelIDC: 120, Width: 1280, Height: 720},
+		},
+		{
+			name:    "truncated NAL",
+			nalHex:  "4201000100",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nal := mustDecodeHex(t, tt.nalHex)
+			got, err := parseH265SPS(nal)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseH265SPS(%s) = %+v, want error", tt.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseH265SPS(%s) unexpected error: %v", tt.name, err)
+			}
+			if *got != *tt.want {
+				t.Fatalf("parseH265SPS(%s) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseH265SpropSPS(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    *h265SPSInfo
+		wantErr bool
+	}{
+		{
+			name:  "valid SPS NAL",
+			value: "QgEAAQAAAwAAAwAAAwAAAwAAeKACgIAtEA==",
+			want:  &h265SPSInfo{ProfileIDC: 1, LevelIDC: 120, Width: 1280, Height: 720},
+		},
+		{
+			name:    "invalid base64",
+			value:   "not-base64!!!",
+			wantErr: true,
+		},
+		{
+			name:    "wrong NAL type (VPS, type 32)",
+			value:   "QAEMAf//AWAAAAMAsAAAAwAAAwB5",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseH265SpropSPS(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseH265SpropSPS(%s) = %+v, want error", tt.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseH265SpropSPS(%s) unexpected error: %v", tt.name, err)
+			}
+			if *got != *tt.want {
+				t.Fatalf("parseH265SpropSPS(%s) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAACAudioSpecificConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		configHex      string
+		wantObjectType int
+		wantSampleRate int
+		wantChannels   int
+		wantErr        bool
+	}{
+		{
+			name:           "AAC-LC 44.1kHz stereo",
+			configHex:      "1210",
+			wantObjectType: 2,
+			wantSampleRate: 44100,
+			wantChannels:   2,
+		},
+		{
+			name:           "extended object type, 96kHz mono",
+			configHex:      "f82020",
+			wantObjectType: 33,
+			wantSampleRate: 96000,
+			wantChannels:   1,
+		},
+		{
+			name:      "invalid hex",
+			configHex: "zz",
+			wantErr:   true,
+		},
+		{
+			name:      "too short",
+			configHex: "12",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objType, rate, channels, err := parseAACAudioSpecificConfig(tt.configHex)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAACAudioSpecificConfig(%s) = (%d,%d,%d), want error", tt.name, objType, rate, channels)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAACAudioSpecificConfig(%s) unexpected error: %v", tt.name, err)
+			}
+			if objType != tt.wantObjectType || rate != tt.wantSampleRate || channels != tt.wantChannels {
+				t.Fatalf("parseAACAudioSpecificConfig(%s) = (%d,%d,%d), want (%d,%d,%d)",
+					tt.name, objType, rate, channels, tt.wantObjectType, tt.wantSampleRate, tt.wantChannels)
+			}
+		})
+	}
+}
+
+func TestRemoveEmulationPrevention(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{name: "no escape bytes", in: []byte{0x01, 0x02, 0x00, 0x01}, want: []byte{0x01, 0x02, 0x00, 0x01}},
+		{name: "strips 00 00 03 00", in: []byte{0x00, 0x00, 0x03, 0x00}, want: []byte{0x00, 0x00, 0x00}},
+		{name: "strips 00 00 03 01", in: []byte{0x00, 0x00, 0x03, 0x01}, want: []byte{0x00, 0x00, 0x01}},
+		{name: "does not strip 00 00 04", in: []byte{0x00, 0x00, 0x04}, want: []byte{0x00, 0x00, 0x04}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := removeEmulationPrevention(tt.in)
+			if len(got) != len(tt.want) {
+				t.Fatalf("removeEmulationPrevention(%x) = %x, want %x", tt.in, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("removeEmulationPrevention(%x) = %x, want %x", tt.in, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseH264SPSNALAndFormatLevel(t *testing.T) {
+	nal := mustDecodeHex(t, "6742001eda0280f400")
+	info, err := ParseH264SPSNAL(nal)
+	if err != nil {
+		t.Fatalf("ParseH264SPSNAL unexpected error: %v", err)
+	}
+	if info.ProfileIDC != 66 || info.Width != 640 || info.Height != 480 {
+		t.Fatalf("ParseH264SPSNAL = %+v, want ProfileIDC=66 Width=640 Height=480", info)
+	}
+	if got := FormatH264Level(info.LevelIDC); got != formatH264Level(30) {
+		t.Fatalf("FormatH264Level(%d) = %q, want %q", info.LevelIDC, got, formatH264Level(30))
+	}
+}
+
+func TestParseH265SPSNALAndFormatLevel(t *testing.T) {
+	nal := mustDecodeHex(t, "42010001000003000003000003000003000078a00280802d10")
+	info, err := ParseH265SPSNAL(nal)
+	if err != nil {
+		t.Fatalf("ParseH265SPSNAL unexpected error: %v", err)
+	}
+	if info.ProfileIDC != 1 || info.Width != 1280 || info.Height != 720 {
+		t.Fatalf("ParseH265SPSNAL = %+v, want ProfileIDC=1 Width=1280 Height=720", info)
+	}
+	if got := FormatH265Level(info.LevelIDC); got != formatH265Level(120) {
+		t.Fatalf("FormatH265Level(%d) = %q, want %q", info.LevelIDC, got, formatH265Level(120))
+	}
+}
+
+func TestParseAACAudioSpecificConfigBytesExported(t *testing.T) {
+	objType, rate, channels, err := ParseAACAudioSpecificConfigBytes([]byte{0x12, 0x10})
+	if err != nil {
+		t.Fatalf("ParseAACAudioSpecificConfigBytes unexpected error: %v", err)
+	}
+	if objType != 2 || rate != 44100 || channels != 2 {
+		t.Fatalf("ParseAACAudioSpecificConfigBytes = (%d,%d,%d), want (2,44100,2)", objType, rate, channels)
+	}
+}
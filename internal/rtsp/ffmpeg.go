@@ -89,7 +89,7 @@ func CheckStreamWithFFmpeg(rtspURL string, username, password string, ffmpegPath
 	cmd := exec.Command(ffprobePath, args...)
 	
 	// Устанавливаем таймаут
-	ctx, cancel := time.WithTimeout(context.Background(), timeout*2)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*2)
 	defer cancel()
 	cmd = exec.CommandContext(ctx, ffprobePath, args...)
 
@@ -233,7 +233,7 @@ func TestStreamWithFFmpeg(rtspURL string, username, password string, ffmpegPath
 	logger.Debugf("Testing stream with ffmpeg: %s %v", ffmpegCmd, args)
 
 	// Создаем команду с таймаутом
-	ctx, cancel := time.WithTimeout(context.Background(), timeout*2)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*2)
 	defer cancel()
 	cmd := exec.CommandContext(ctx, ffmpegCmd, args...)
 
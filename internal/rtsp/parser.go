@@ -31,14 +31,37 @@ type VideoTrack struct {
 	Bitrate    int
 	Profile    string
 	Level      string
+	Width      int
+	Height     int
+	Control    string // значение a=control: для SETUP этой дорожки (относительный или абсолютный URL)
+
+	PayloadType  int            // RTP payload type из rtpmap основного кодека этой дорожки
+	ClockRate    int            // частота RTP-тактирования из rtpmap (для видео почти всегда 90000)
+	MatchedCodec *RTPCodec      // результат MatchCodec для этой дорожки, nil если MatchType == CodecMatchNone
+	MatchType    CodecMatchType // насколько уверенно Codec/PayloadType/ClockRate сопоставлены с knownRTPCodecs
+
+	RTXPayloadType  int // payload type дорожки повтора RFC 4588 (rtpmap .../rtx), 0 если ее нет
+	RTXApt          int // исходное значение apt= из fmtp дорожки повтора
+	RTXRewrittenApt int // apt, переписанный на PayloadType сопоставленного кодека - см. rewriteRTXApt
 }
 
 // AudioTrack содержит информацию об аудио дорожке
 type AudioTrack struct {
-	Codec    string
-	Channels int
+	Codec      string
+	Channels   int
 	SampleRate int
-	Bitrate  int
+	Bitrate    int
+	ObjectType int    // MPEG-4 Audio Object Type из AudioSpecificConfig (2 = AAC-LC и т.д.)
+	Control    string // значение a=control: для SETUP этой дорожки
+
+	PayloadType  int
+	ClockRate    int
+	MatchedCodec *RTPCodec
+	MatchType    CodecMatchType
+
+	RTXPayloadType  int
+	RTXApt          int
+	RTXRewrittenApt int
 }
 
 // ParseSDP парсит SDP (Session Description Protocol) и извлекает информацию о потоке
@@ -113,6 +136,8 @@ func ParseSDP(sdp string) (*StreamInfo, error) {
 		info.Channels = audioTrack.Channels
 	}
 
+	resolveCodecMatches(info)
+
 	return info, nil
 }
 
@@ -141,15 +166,38 @@ func parseAttribute(line string, media *MediaDescription, info *StreamInfo) {
 		// Пример: rtpmap:96 H264/90000
 		parts := strings.Fields(attr[7:])
 		if len(parts) >= 2 {
+			payloadType, ptErr := strconv.Atoi(parts[0])
 			codecInfo := parts[1]
 			codecParts := strings.Split(codecInfo, "/")
 			codec := codecParts[0]
+			clockRate := 0
+			if len(codecParts) >= 2 {
+				clockRate, _ = strconv.Atoi(codecParts[1])
+			}
+
+			// rtx (RFC 4588) - дорожка повтора для уже существующей дорожки этой же m= секции,
+			// а не отдельный трек; ее apt=, несущий payload type исходной дорожки, приходит
+			// отдельной строкой fmtp
+			if strings.EqualFold(codec, "rtx") {
+				if ptErr == nil {
+					if media.Type == "video" && len(info.VideoTracks) > 0 {
+						info.VideoTracks[len(info.VideoTracks)-1].RTXPayloadType = payloadType
+					} else if media.Type == "audio" && len(info.AudioTracks) > 0 {
+						info.AudioTracks[len(info.AudioTracks)-1].RTXPayloadType = payloadType
+					}
+				}
+				return
+			}
 
 			if media.Type == "video" {
 				// Обновляем последнюю видео дорожку
 				if len(info.VideoTracks) > 0 {
 					idx := len(info.VideoTracks) - 1
 					info.VideoTracks[idx].Codec = normalizeCodec(codec)
+					info.VideoTracks[idx].ClockRate = clockRate
+					if ptErr == nil {
+						info.VideoTracks[idx].PayloadType = payloadType
+					}
 					info.Codec = normalizeCodec(codec)
 				}
 			} else if media.Type == "audio" {
@@ -157,18 +205,51 @@ func parseAttribute(line string, media *MediaDescription, info *StreamInfo) {
 				if len(info.AudioTracks) > 0 {
 					idx := len(info.AudioTracks) - 1
 					info.AudioTracks[idx].Codec = normalizeAudioCodec(codec)
+					info.AudioTracks[idx].ClockRate = clockRate
+					if ptErr == nil {
+						info.AudioTracks[idx].PayloadType = payloadType
+					}
 					info.AudioCodec = normalizeAudioCodec(codec)
+
+					// rtpmap для аудио также несет частоту дискретизации и число каналов,
+					// например "MPEG4-GENERIC/44100/2"
+					if len(codecParts) >= 2 {
+						if sampleRate, err := strconv.Atoi(codecParts[1]); err == nil {
+							info.AudioTracks[idx].SampleRate = sampleRate
+						}
+					}
+					if len(codecParts) >= 3 {
+						if channels, err := strconv.Atoi(codecParts[2]); err == nil {
+							info.AudioTracks[idx].Channels = channels
+							info.Channels = channels
+						}
+					}
 				}
 			}
 		}
 	}
 
+	// control: URL для SETUP конкретной дорожки (RFC 2326, раздел C.1.1)
+	if strings.HasPrefix(attr, "control:") {
+		control := strings.TrimSpace(strings.TrimPrefix(attr, "control:"))
+		if media.Type == "video" && len(info.VideoTracks) > 0 {
+			info.VideoTracks[len(info.VideoTracks)-1].Control = control
+		} else if media.Type == "audio" && len(info.AudioTracks) > 0 {
+			info.AudioTracks[len(info.AudioTracks)-1].Control = control
+		}
+	}
+
 	// fmtp: параметры формата
 	if strings.HasPrefix(attr, "fmtp:") {
 		// Пример: fmtp:96 profile-level-id=420029; packetization-mode=1; sprop-parameter-sets=...
 		parts := strings.SplitN(attr[5:], " ", 2)
 		if len(parts) == 2 {
+			payloadType, ptErr := strconv.Atoi(parts[0])
 			params := parts[1]
+
+			if ptErr == nil && parseRTXApt(payloadType, params, media, info) {
+				return
+			}
 			parseFmtpParams(params, media, info)
 		}
 	}
@@ -202,67 +283,208 @@ func parseAttribute(line string, media *MediaDescription, info *StreamInfo) {
 			}
 		}
 	}
+}
 
-	// Извлекаем разрешение из sprop-parameter-sets (H.264)
-	if strings.Contains(attr, "sprop-parameter-sets=") {
-		// Пытаемся извлечь разрешение из SPS
-		if resolution := extractResolutionFromSPS(attr); resolution != "" {
-			if media.Type == "video" && len(info.VideoTracks) > 0 {
-				idx := len(info.VideoTracks) - 1
-				info.VideoTracks[idx].Resolution = resolution
-				info.Resolution = resolution
+// parseRTXApt проверяет, относится ли fmtp с данным payloadType к дорожке повтора RFC 4588
+// (см. обработку rtpmap "rtx" выше) текущей m= секции, и если да - разбирает apt= и
+// сохраняет его на этой дорожке. Возвращает true, если fmtp был обработан как rtx и не
+// нуждается в обычном разборе parseFmtpParams
+func parseRTXApt(payloadType int, params string, media *MediaDescription, info *StreamInfo) bool {
+	var track *VideoTrack
+	var audioTrack *AudioTrack
+	switch media.Type {
+	case "video":
+		if len(info.VideoTracks) > 0 {
+			track = &info.VideoTracks[len(info.VideoTracks)-1]
+		}
+	case "audio":
+		if len(info.AudioTracks) > 0 {
+			audioTrack = &info.AudioTracks[len(info.AudioTracks)-1]
+		}
+	}
+
+	aptValue := -1
+	for _, pair := range strings.Split(params, ";") {
+		pair = strings.TrimSpace(pair)
+		if strings.HasPrefix(pair, "apt=") {
+			if v, err := strconv.Atoi(strings.TrimPrefix(pair, "apt=")); err == nil {
+				aptValue = v
+			}
+		}
+	}
+
+	if track != nil && track.RTXPayloadType == payloadType {
+		if aptValue >= 0 {
+			track.RTXApt = aptValue
+		}
+		return true
+	}
+	if audioTrack != nil && audioTrack.RTXPayloadType == payloadType {
+		if aptValue >= 0 {
+			audioTrack.RTXApt = aptValue
+		}
+		return true
+	}
+	return false
+}
+
+// resolveCodecMatches заполняет MatchedCodec/MatchType каждой дорожки через MatchCodec и
+// переписывает apt= дорожек повтора на PayloadType сопоставленного кодека (RTXRewrittenApt),
+// чтобы ремуксер, перенумеровывающий payload types под канонические значения реестра, не
+// оставил apt= ссылающимся на уже не существующий payload type (RFC 4588, раздел 8.1)
+func resolveCodecMatches(info *StreamInfo) {
+	for i := range info.VideoTracks {
+		track := &info.VideoTracks[i]
+		matched, matchType := MatchCodec(RTPCodec{
+			Name: track.Codec, MediaType: "video",
+			PayloadType: track.PayloadType, ClockRate: track.ClockRate,
+		})
+		track.MatchType = matchType
+		track.RTXRewrittenApt = track.RTXApt
+		if matchType != CodecMatchNone {
+			m := matched
+			track.MatchedCodec = &m
+			if track.RTXPayloadType != 0 {
+				track.RTXRewrittenApt = matched.PayloadType
+			}
+		}
+	}
+
+	for i := range info.AudioTracks {
+		track := &info.AudioTracks[i]
+		matched, matchType := MatchCodec(RTPCodec{
+			Name: track.Codec, MediaType: "audio",
+			PayloadType: track.PayloadType, ClockRate: track.ClockRate, Channels: track.Channels,
+		})
+		track.MatchType = matchType
+		track.RTXRewrittenApt = track.RTXApt
+		if matchType != CodecMatchNone {
+			m := matched
+			track.MatchedCodec = &m
+			if track.RTXPayloadType != 0 {
+				track.RTXRewrittenApt = matched.PayloadType
 			}
 		}
 	}
 }
 
-// parseFmtpParams парсит параметры fmtp
+// parseFmtpParams парсит параметры fmtp и для известных payload types (H.264, H.265, AAC-hbr)
+// декодирует codec-specific данные (SPS/PPS/AudioSpecificConfig) в дополнение к плоским key=value полям
 func parseFmtpParams(params string, media *MediaDescription, info *StreamInfo) {
 	// Разделяем параметры по точке с запятой
 	paramPairs := strings.Split(params, ";")
-	
+
 	for _, pair := range paramPairs {
 		pair = strings.TrimSpace(pair)
-		if idx := strings.Index(pair, "="); idx != -1 {
-			key := strings.TrimSpace(pair[:idx])
-			value := strings.TrimSpace(pair[idx+1:])
+		idx := strings.Index(pair, "=")
+		if idx == -1 {
+			continue
+		}
+		key := strings.TrimSpace(pair[:idx])
+		value := strings.TrimSpace(pair[idx+1:])
+
+		switch media.Type {
+		case "video":
+			parseVideoFmtpParam(key, value, info)
+		case "audio":
+			parseAudioFmtpParam(key, value, info)
+		}
+	}
+}
 
-			if media.Type == "video" && len(info.VideoTracks) > 0 {
-				idx := len(info.VideoTracks) - 1
-				track := &info.VideoTracks[idx]
-
-				switch key {
-				case "profile-level-id":
-					// Пример: 420029 (H.264)
-					if len(value) >= 6 {
-						profile := value[0:2]
-						level := value[4:6]
-						track.Profile = profile
-						track.Level = level
-					}
-				case "sprop-parameter-sets":
-					// Пытаемся извлечь разрешение из SPS
-					if resolution := extractResolutionFromSPS(value); resolution != "" {
-						track.Resolution = resolution
-						info.Resolution = resolution
-					}
-				}
+// parseVideoFmtpParam обрабатывает один параметр fmtp видео дорожки
+func parseVideoFmtpParam(key, value string, info *StreamInfo) {
+	if len(info.VideoTracks) == 0 {
+		return
+	}
+	track := &info.VideoTracks[len(info.VideoTracks)-1]
+
+	switch key {
+	case "profile-level-id":
+		// Пример: 420029 (H.264) - запасной вариант на случай, если в SPS эти поля не нашлись
+		if len(value) >= 6 && track.Profile == "" {
+			if profileIDC, err := strconv.ParseInt(value[0:2], 16, 32); err == nil {
+				track.Profile = strconv.FormatInt(profileIDC, 10)
 			}
+			if levelIDC, err := strconv.ParseInt(value[4:6], 16, 32); err == nil {
+				track.Level = formatH264Level(int(levelIDC))
+			}
+		}
+	case "sprop-parameter-sets":
+		// H.264: SPS/PPS в base64 (RFC 6184, раздел 8.2.1)
+		if sps, err := parseH264SpropParameterSets(value); err == nil {
+			applyH264SPS(track, info, sps)
+		}
+	case "sprop-sps":
+		// H.265: SPS в base64 отдельным параметром (RFC 7798, раздел 7.1)
+		if sps, err := parseH265SpropSPS(value); err == nil {
+			applyH265SPS(track, info, sps)
 		}
 	}
 }
 
-// extractResolutionFromSPS пытается извлечь разрешение из SPS (Sequence Parameter Set)
-// Это сложная задача, так как SPS закодирован в base64 и требует декодирования
-// Здесь упрощенная версия, которая пытается найти известные паттерны
-func extractResolutionFromSPS(sps string) string {
-	// SPS обычно в формате: sprop-parameter-sets=Z0IAHpWoKA9puAgICBA=,aM48gA==
-	// Это base64 закодированные данные
-	// Для полного парсинга нужна библиотека для декодирования H.264 SPS
-	
-	// Упрощенный подход: пытаемся найти известные разрешения в других атрибутах
-	// Или используем значения по умолчанию для популярных камер
-	return ""
+// applyH264SPS переносит параметры, разобранные из SPS H.264, на видео дорожку и StreamInfo
+func applyH264SPS(track *VideoTrack, info *StreamInfo, sps *h264SPSInfo) {
+	track.Profile = strconv.Itoa(sps.ProfileIDC)
+	track.Level = formatH264Level(sps.LevelIDC)
+	if sps.Width > 0 && sps.Height > 0 {
+		track.Width = sps.Width
+		track.Height = sps.Height
+		track.Resolution = fmt.Sprintf("%dx%d", sps.Width, sps.Height)
+		info.Resolution = track.Resolution
+	}
+	if sps.FPS > 0 {
+		track.FPS = sps.FPS
+		info.FPS = sps.FPS
+	}
+}
+
+// applyH265SPS переносит параметры, разобранные из SPS H.265, на видео дорожку и StreamInfo
+func applyH265SPS(track *VideoTrack, info *StreamInfo, sps *h265SPSInfo) {
+	track.Profile = strconv.Itoa(sps.ProfileIDC)
+	track.Level = formatH265Level(sps.LevelIDC)
+	if sps.Width > 0 && sps.Height > 0 {
+		track.Width = sps.Width
+		track.Height = sps.Height
+		track.Resolution = fmt.Sprintf("%dx%d", sps.Width, sps.Height)
+		info.Resolution = track.Resolution
+	}
+}
+
+// formatH264Level форматирует level_idc H.264 как десятичный уровень (например, 31 -> "3.1")
+func formatH264Level(levelIDC int) string {
+	return fmt.Sprintf("%.1f", float64(levelIDC)/10)
+}
+
+// formatH265Level форматирует general_level_idc H.265 как десятичный уровень (ITU-T H.265, приложение A.3:
+// general_level_idc = 30 * уровень, например 93 -> "3.1")
+func formatH265Level(levelIDC int) string {
+	return fmt.Sprintf("%.1f", float64(levelIDC)/30)
+}
+
+// parseAudioFmtpParam обрабатывает один параметр fmtp аудио дорожки
+func parseAudioFmtpParam(key, value string, info *StreamInfo) {
+	if len(info.AudioTracks) == 0 {
+		return
+	}
+	track := &info.AudioTracks[len(info.AudioTracks)-1]
+
+	switch key {
+	case "config":
+		// AudioSpecificConfig для MPEG4-GENERIC/AAC-hbr (RFC 3640, раздел 4.1)
+		objectType, sampleRate, channels, err := parseAACAudioSpecificConfig(value)
+		if err != nil {
+			return
+		}
+		track.ObjectType = objectType
+		if sampleRate > 0 {
+			track.SampleRate = sampleRate
+		}
+		if channels > 0 {
+			track.Channels = channels
+			info.Channels = channels
+		}
+	}
 }
 
 // normalizeCodec нормализует название кодека
@@ -341,7 +563,7 @@ func ExtractResolutionFromFmtp(fmtp string) (width, height int, err error) {
 
 // ToRTSPStreamInfo конвертирует StreamInfo в models.RTSPStreamInfo
 func (s *StreamInfo) ToRTSPStreamInfo() models.RTSPStreamInfo {
-	return models.RTSPStreamInfo{
+	rtspInfo := models.RTSPStreamInfo{
 		URL:        s.URL,
 		Codec:      s.Codec,
 		Resolution: s.Resolution,
@@ -351,4 +573,34 @@ func (s *StreamInfo) ToRTSPStreamInfo() models.RTSPStreamInfo {
 		Channels:   s.Channels,
 		Available:  s.Available,
 	}
+
+	if len(s.VideoTracks) > 0 {
+		videoTrack := s.VideoTracks[0]
+		if videoTrack.Profile != "" || videoTrack.Width > 0 {
+			rtspInfo.VideoCodecInfo = &models.VideoCodec{
+				Name:      videoTrack.Codec,
+				Profile:   videoTrack.Profile,
+				Level:     videoTrack.Level,
+				Width:     videoTrack.Width,
+				Height:    videoTrack.Height,
+				FPS:       videoTrack.FPS,
+				MatchType: string(videoTrack.MatchType),
+			}
+		}
+	}
+
+	if len(s.AudioTracks) > 0 {
+		audioTrack := s.AudioTracks[0]
+		if audioTrack.ObjectType > 0 || audioTrack.SampleRate > 0 {
+			rtspInfo.AudioCodecInfo = &models.AudioCodec{
+				Name:       audioTrack.Codec,
+				ObjectType: audioTrack.ObjectType,
+				SampleRate: audioTrack.SampleRate,
+				Channels:   audioTrack.Channels,
+				MatchType:  string(audioTrack.MatchType),
+			}
+		}
+	}
+
+	return rtspInfo
 }
@@ -0,0 +1,309 @@
+package rtsp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/pkg/utils"
+)
+
+const (
+	// defaultMaxConcurrency - размер пула воркеров CheckMultipleStreamsCtx по умолчанию,
+	// если в RTSPConfig не задано MaxConcurrency
+	defaultMaxConcurrency = 20
+
+	// defaultPerHostConcurrency - сколько соединений к одному хосту CheckMultipleStreamsCtx
+	// открывает одновременно по умолчанию, если в RTSPConfig не задано PerHostConcurrency
+	defaultPerHostConcurrency = 2
+
+	// authBackoffBase - начальная задержка перед следующей попыткой DESCRIBE к хосту после
+	// ответа 401/407, чтобы неверные учетные данные не перебирались без паузы и не
+	// заблокировали аккаунт на камерах со счетчиком неудачных попыток входа
+	authBackoffBase = 2 * time.Second
+
+	// authBackoffMax - потолок задержки hostAuthBackoff
+	authBackoffMax = 30 * time.Second
+
+	// maxAuthBackoffShift - ограничение показателя степени в экспоненциальном backoff,
+	// чтобы 1<<shift не переполнялся
+	maxAuthBackoffShift = 4
+)
+
+// indexedRequest привязывает StreamCheckRequest к его позиции во входном срезе
+// CheckMultipleStreamsCtx, чтобы результаты можно было записывать в общий results по индексу
+// независимо от порядка обработки заданий
+type indexedRequest struct {
+	index int
+	req   StreamCheckRequest
+}
+
+// connJob - одно TCP соединение, которое воркер CheckMultipleStreamsCtx откроет к host и
+// через которое последовательно проверит requests (пути потоков этого хоста), переиспользуя
+// соединение и уже пройденную аутентификацию между путями вместо того, чтобы открывать
+// отдельное соединение на каждый путь
+type connJob struct {
+	host     string
+	username string
+	password string
+	authKey  string // host+username, ключ для hostAuthBackoff
+	requests []indexedRequest
+}
+
+// buildConnJobs группирует streams по (хост, учетные данные) и делит каждую группу не более
+// чем на perHostConcurrency соединений (пути распределяются по соединениям round-robin), что
+// ограничивает число одновременных TCP соединений к одному хосту. Возвращает задания для
+// воркеров и results, заранее проиндексированный по URL в исходном порядке streams
+func buildConnJobs(streams []StreamCheckRequest, perHostConcurrency int) ([]*connJob, []StreamCheckResult) {
+	type groupKey struct {
+		host, username, password string
+	}
+
+	groups := make(map[groupKey][]indexedRequest)
+	var order []groupKey
+
+	results := make([]StreamCheckResult, len(streams))
+
+	for i, req := range streams {
+		results[i] = StreamCheckResult{URL: req.URL}
+
+		parsed, err := url.Parse(req.URL)
+		if err != nil {
+			results[i].Error = fmt.Sprintf("invalid RTSP URL: %v", err)
+			continue
+		}
+
+		port := parsed.Port()
+		if port == "" {
+			port = "554"
+		}
+		key := groupKey{host: net.JoinHostPort(parsed.Hostname(), port), username: req.Username, password: req.Password}
+
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], indexedRequest{index: i, req: req})
+	}
+
+	var jobs []*connJob
+	for _, key := range order {
+		requests := groups[key]
+
+		shardCount := perHostConcurrency
+		if shardCount > len(requests) {
+			shardCount = len(requests)
+		}
+		if shardCount < 1 {
+			shardCount = 1
+		}
+
+		shards := make([][]indexedRequest, shardCount)
+		for i, ir := range requests {
+			shards[i%shardCount] = append(shards[i%shardCount], ir)
+		}
+
+		for _, shard := range shards {
+			jobs = append(jobs, &connJob{
+				host:     key.host,
+				username: key.username,
+				password: key.password,
+				authKey:  key.host + "|" + key.username,
+				requests: shard,
+			})
+		}
+	}
+
+	return jobs, results
+}
+
+// pathFromURL возвращает path компонента RTSP URL для DESCRIBE ("/" если путь пуст или URL
+// не разбирается - в этом случае DESCRIBE сам вернет ошибку, которая попадет в результат)
+func pathFromURL(rtspURL string) string {
+	parsed, err := url.Parse(rtspURL)
+	if err != nil || parsed.Path == "" {
+		return "/"
+	}
+	return parsed.Path
+}
+
+// runConnJob открывает одно соединение для job.host и проверяет через него все назначенные
+// пути по очереди, используя одну и ту же аутентификацию. При ответе 401/407 помечает
+// оставшиеся пути этой группы как неудавшиеся из-за аутентификации, не повторяя попытки -
+// дальнейший backoff для этого хоста берет на себя hostAuthBackoff
+func (c *Checker) runConnJob(ctx context.Context, job *connJob, results []StreamCheckResult, mu *sync.Mutex) {
+	logger := utils.GetLogger()
+
+	if err := c.authBackoff.wait(ctx, job.authKey); err != nil {
+		mu.Lock()
+		for _, ir := range job.requests {
+			results[ir.index].Error = err.Error()
+		}
+		mu.Unlock()
+		return
+	}
+
+	first := job.requests[0]
+	client, err := NewClientContext(ctx, first.req.URL, job.username, job.password, c.config.Timeout)
+	if err != nil {
+		mu.Lock()
+		for _, ir := range job.requests {
+			results[ir.index].Error = err.Error()
+		}
+		mu.Unlock()
+		logger.Debugf("Failed to connect to %s: %v", job.host, err)
+		return
+	}
+	defer client.Close()
+
+	if _, err := client.Options(); err != nil {
+		mu.Lock()
+		for _, ir := range job.requests {
+			results[ir.index].Error = fmt.Sprintf("OPTIONS failed: %v", err)
+		}
+		mu.Unlock()
+		return
+	}
+
+	authFailed := false
+	for _, ir := range job.requests {
+		if ctx.Err() != nil {
+			mu.Lock()
+			results[ir.index].Error = ctx.Err().Error()
+			mu.Unlock()
+			continue
+		}
+
+		response, err := client.DescribePath(pathFromURL(ir.req.URL))
+		if err != nil {
+			mu.Lock()
+			results[ir.index].Error = fmt.Sprintf("DESCRIBE failed: %v", err)
+			mu.Unlock()
+			logger.Debugf("Stream check failed for %s: %v", ir.req.URL, err)
+			continue
+		}
+
+		if response.StatusCode == 401 || response.StatusCode == 407 {
+			authFailed = true
+			mu.Lock()
+			results[ir.index].Error = fmt.Sprintf("DESCRIBE returned status %d: %s", response.StatusCode, response.StatusText)
+			mu.Unlock()
+			continue
+		}
+
+		if response.StatusCode != 200 {
+			mu.Lock()
+			results[ir.index].Error = fmt.Sprintf("DESCRIBE returned status %d: %s", response.StatusCode, response.StatusText)
+			mu.Unlock()
+			continue
+		}
+
+		if response.Body == "" {
+			mu.Lock()
+			results[ir.index].Error = "empty SDP response"
+			mu.Unlock()
+			continue
+		}
+
+		streamInfo, err := ParseSDP(response.Body)
+		if err != nil {
+			mu.Lock()
+			results[ir.index].Error = fmt.Sprintf("failed to parse SDP: %v", err)
+			mu.Unlock()
+			continue
+		}
+		streamInfo.URL = ir.req.URL
+		streamInfo.Available = true
+
+		rtspStreamInfo := streamInfo.ToRTSPStreamInfo()
+		rtspStreamInfo.CheckedAt = time.Now()
+
+		mu.Lock()
+		results[ir.index].StreamInfo = &rtspStreamInfo
+		results[ir.index].Available = true
+		mu.Unlock()
+		logger.Debugf("Stream check successful for %s: codec=%s, resolution=%s, fps=%.2f",
+			ir.req.URL, rtspStreamInfo.Codec, rtspStreamInfo.Resolution, rtspStreamInfo.FPS)
+	}
+
+	c.authBackoff.record(job.authKey, authFailed)
+}
+
+// hostAuthBackoff отслеживает подряд идущие неудачи аутентификации (401/407) по ключу
+// хост+имя пользователя и откладывает следующую попытку - без этого CheckMultipleStreamsCtx
+// долбил бы один и тот же хост DESCRIBE с заведомо неверными учетными данными, рискуя
+// заблокировать аккаунт на камерах со счетчиком неудачных попыток входа
+type hostAuthBackoff struct {
+	mu    sync.Mutex
+	state map[string]*authBackoffState
+}
+
+type authBackoffState struct {
+	failures int
+	until    time.Time
+}
+
+func newHostAuthBackoff() *hostAuthBackoff {
+	return &hostAuthBackoff{state: make(map[string]*authBackoffState)}
+}
+
+// wait блокируется, если для key сейчас действует backoff-пауза, либо пока не отменится ctx
+func (b *hostAuthBackoff) wait(ctx context.Context, key string) error {
+	b.mu.Lock()
+	st, ok := b.state[key]
+	var until time.Time
+	if ok {
+		until = st.until
+	}
+	b.mu.Unlock()
+
+	if until.IsZero() {
+		return nil
+	}
+
+	d := time.Until(until)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// record учитывает результат группы DESCRIBE к key: успех (authFailed == false) сразу
+// сбрасывает backoff, 401/407 увеличивает его экспоненциально до authBackoffMax
+func (b *hostAuthBackoff) record(key string, authFailed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st, ok := b.state[key]
+	if !ok {
+		st = &authBackoffState{}
+		b.state[key] = st
+	}
+
+	if !authFailed {
+		st.failures = 0
+		st.until = time.Time{}
+		return
+	}
+
+	st.failures++
+	shift := st.failures - 1
+	if shift > maxAuthBackoffShift {
+		shift = maxAuthBackoffShift
+	}
+	delay := authBackoffBase * time.Duration(uint(1)<<uint(shift))
+	if delay > authBackoffMax {
+		delay = authBackoffMax
+	}
+	st.until = time.Now().Add(delay)
+}
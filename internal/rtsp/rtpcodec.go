@@ -0,0 +1,125 @@
+package rtsp
+
+import "strings"
+
+// RTPCodec описывает один RTP payload format - кодек, частоту RTP-тактирования и (для
+// аудио) число каналов, привязанные к конкретному payload type (RFC 3551, раздел 6)
+type RTPCodec struct {
+	Name        string
+	MediaType   string // "video" или "audio"
+	PayloadType int
+	ClockRate   int
+	Channels    int // 0, если не применимо/не важно (например, для видео)
+}
+
+// CodecMatchType описывает, насколько уверенно MatchCodec сопоставил кодек, объявленный
+// удаленной стороной в SDP, с записью в knownRTPCodecs
+type CodecMatchType string
+
+const (
+	// CodecMatchExact - совпали имя кодека, частота RTP-тактирования и (для аудио) число каналов
+	CodecMatchExact CodecMatchType = "exact"
+
+	// CodecMatchPartial - совпало только имя кодека; частота тактирования и/или число каналов,
+	// заявленные камерой, отличаются от того, что этот детектор считает стандартным для
+	// данного кодека (нестандартный, но распознаваемый fmtp/rtpmap)
+	CodecMatchPartial CodecMatchType = "partial"
+
+	// CodecMatchNone - кодек не удалось сопоставить ни с одной известной записью
+	CodecMatchNone CodecMatchType = "none"
+)
+
+// knownRTPCodecs - реестр кодеков, которые этот детектор умеет распознавать: статические
+// payload types, закрепленные RFC 3551, раздел 6, и общепринятые динамические payload
+// types, которыми IP камеры и энкодеры обычно помечают H.264/H.265/VP8/VP9/AV1/AAC/OPUS
+// в rtpmap (сами по себе динамические PT не специфицированы и согласуются произвольно,
+// поэтому это лишь эвристика для CodecMatchExact, а не требование протокола)
+var knownRTPCodecs = []RTPCodec{
+	{Name: "PCMU", MediaType: "audio", PayloadType: 0, ClockRate: 8000, Channels: 1},
+	{Name: "PCMA", MediaType: "audio", PayloadType: 8, ClockRate: 8000, Channels: 1},
+	{Name: "G.722", MediaType: "audio", PayloadType: 9, ClockRate: 8000, Channels: 1},
+	{Name: "L16", MediaType: "audio", PayloadType: 10, ClockRate: 44100, Channels: 2},
+	{Name: "L16", MediaType: "audio", PayloadType: 11, ClockRate: 44100, Channels: 1},
+
+	{Name: "H.264", MediaType: "video", PayloadType: 96, ClockRate: 90000},
+	{Name: "H.264", MediaType: "video", PayloadType: 97, ClockRate: 90000},
+	{Name: "H.264", MediaType: "video", PayloadType: 98, ClockRate: 90000},
+	{Name: "H.264", MediaType: "video", PayloadType: 99, ClockRate: 90000},
+	{Name: "H.265", MediaType: "video", PayloadType: 96, ClockRate: 90000},
+	{Name: "VP8", MediaType: "video", PayloadType: 100, ClockRate: 90000},
+	{Name: "VP9", MediaType: "video", PayloadType: 101, ClockRate: 90000},
+	{Name: "AV1", MediaType: "video", PayloadType: 102, ClockRate: 90000},
+
+	{Name: "AAC-LATM", MediaType: "audio", PayloadType: 96, ClockRate: 44100, Channels: 2},
+	{Name: "AAC-hbr", MediaType: "audio", PayloadType: 97, ClockRate: 44100, Channels: 2},
+	{Name: "OPUS", MediaType: "audio", PayloadType: 111, ClockRate: 48000, Channels: 2},
+}
+
+// MatchCodec сопоставляет кодек, объявленный удаленной стороной в rtpmap/fmtp, с записью
+// из knownRTPCodecs: сперва ищет точное совпадение имени, частоты тактирования и числа
+// каналов (CodecMatchExact), затем, если такого не нашлось, - первое совпадение только
+// по имени (CodecMatchPartial, для камер, заявляющих нестандартную частоту/число каналов).
+// remote.ClockRate/remote.Channels, равные нулю, считаются "не заявлено" и не мешают
+// точному совпадению
+func MatchCodec(remote RTPCodec) (RTPCodec, CodecMatchType) {
+	name := normalizeRTPCodecName(remote.Name)
+
+	var partial *RTPCodec
+	for i := range knownRTPCodecs {
+		candidate := knownRTPCodecs[i]
+		if remote.MediaType != "" && candidate.MediaType != remote.MediaType {
+			continue
+		}
+		if !strings.EqualFold(candidate.Name, name) {
+			continue
+		}
+
+		clockMatches := remote.ClockRate == 0 || remote.ClockRate == candidate.ClockRate
+		channelsMatch := remote.Channels == 0 || candidate.Channels == 0 || remote.Channels == candidate.Channels
+		if clockMatches && channelsMatch {
+			return candidate, CodecMatchExact
+		}
+		if partial == nil {
+			c := candidate
+			partial = &c
+		}
+	}
+
+	if partial != nil {
+		return *partial, CodecMatchPartial
+	}
+	return RTPCodec{}, CodecMatchNone
+}
+
+// normalizeRTPCodecName сводит имя кодека из rtpmap (encoding name, RFC 4566, раздел 6)
+// к тому же обозначению, что используется в knownRTPCodecs
+func normalizeRTPCodecName(name string) string {
+	upper := strings.ToUpper(name)
+	switch upper {
+	case "H264", "H.264", "AVC":
+		return "H.264"
+	case "H265", "H.265", "HEVC":
+		return "H.265"
+	case "MP4A-LATM":
+		return "AAC-LATM"
+	case "MPEG4-GENERIC":
+		// Этот детектор разбирает AudioSpecificConfig только в режиме AAC-hbr (RFC 3640,
+		// раздел 4.1) - см. parseAudioFmtpParam
+		return "AAC-hbr"
+	default:
+		return upper
+	}
+}
+
+// matchDescription формирует человекочитаемое описание результата MatchCodec для логов
+// Checker, например "H.264 (exact match)" или "AAC (partial fmtp match, profile fallback)"
+func matchDescription(codecName string, matchType CodecMatchType) string {
+	switch matchType {
+	case CodecMatchExact:
+		return codecName + " (exact match)"
+	case CodecMatchPartial:
+		return codecName + " (partial fmtp match, profile fallback)"
+	default:
+		return codecName
+	}
+}
@@ -0,0 +1,615 @@
+package rtsp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportMode определяет способ доставки RTP/RTCP пакетов
+type TransportMode int
+
+const (
+	// TransportModeUDP - пакеты приходят по отдельным UDP портам (unicast)
+	TransportModeUDP TransportMode = iota
+	// TransportModeInterleaved - пакеты приходят внутри того же TCP соединения (RFC 2326, раздел 10.12)
+	TransportModeInterleaved
+	// TransportModeUDPMulticast - пакеты приходят в мультикаст группу, которую сервер
+	// указывает в ответе на SETUP (destination=/port=/ttl=)
+	TransportModeUDPMulticast
+)
+
+// String возвращает имя режима транспорта в том виде, в котором оно указывается в
+// конфигурации (models.RTSPConfig.Transport)
+func (m TransportMode) String() string {
+	switch m {
+	case TransportModeUDP:
+		return "udp"
+	case TransportModeInterleaved:
+		return "tcp"
+	case TransportModeUDPMulticast:
+		return "udp-multicast"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTransportMode разбирает строковое имя режима транспорта из конфигурации.
+// "auto" не является TransportMode - выбор между UDP и TCP в этом режиме делает
+// Client.PlayAuto по факту приема пакетов, а не Client.Setup* заранее
+func ParseTransportMode(name string) (TransportMode, error) {
+	switch name {
+	case "udp":
+		return TransportModeUDP, nil
+	case "tcp":
+		return TransportModeInterleaved, nil
+	case "udp-multicast":
+		return TransportModeUDPMulticast, nil
+	default:
+		return 0, fmt.Errorf("unknown RTSP transport mode: %q", name)
+	}
+}
+
+// TrackStats содержит статистику приема пакетов по треку
+type TrackStats struct {
+	Packets uint64
+	Bytes   uint64
+	Jitter  float64
+}
+
+// rtpPacket - пакет, полученный транспортом, до передачи наружу через ReadPacket
+type rtpPacket struct {
+	channel int
+	payload []byte
+	err     error
+}
+
+// Transport - абстракция над способом получения RTP/RTCP пакетов после PLAY
+type Transport interface {
+	// Header возвращает значение заголовка Transport для запроса SETUP
+	Header() string
+
+	// ApplyServerReply разбирает ответный заголовок Transport сервера
+	ApplyServerReply(serverTransport string) error
+
+	// Start запускает чтение пакетов (для UDP - запускает читающие горутины)
+	Start() error
+
+	// ReadPacket возвращает очередной пакет: канал (0 - RTP, 1 - RTCP), данные, ошибку
+	ReadPacket() (channel int, payload []byte, err error)
+
+	// Stats возвращает статистику по RTP треку
+	Stats() TrackStats
+
+	// SendRTCP отправляет собранный RTCP пакет (например, Receiver Report) на сервер
+	SendRTCP(payload []byte) error
+
+	// Close останавливает транспорт и освобождает ресурсы
+	Close() error
+}
+
+// udpPortRangeStart/End - диапазон, в котором ищется свободная четная пара портов для RTP/RTCP
+const (
+	udpPortRangeStart = 15000
+	udpPortRangeEnd   = 25000
+)
+
+// allocateRTPRTCPPorts находит и резервирует последовательную четно-нечетную пару UDP портов
+func allocateRTPRTCPPorts() (rtpConn, rtcpConn *net.UDPConn, err error) {
+	for port := udpPortRangeStart; port < udpPortRangeEnd; port += 2 {
+		rc, errRTP := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+		if errRTP != nil {
+			continue
+		}
+		cc, errRTCP := net.ListenUDP("udp4", &net.UDPAddr{Port: port + 1})
+		if errRTCP != nil {
+			rc.Close()
+			continue
+		}
+		return rc, cc, nil
+	}
+	return nil, nil, fmt.Errorf("no free consecutive UDP port pair found in range %d-%d", udpPortRangeStart, udpPortRangeEnd)
+}
+
+// UDPTransport - транспорт, принимающий RTP/RTCP по отдельным UDP сокетам
+type UDPTransport struct {
+	rtpConn  *net.UDPConn
+	rtcpConn *net.UDPConn
+
+	localRTPPort  int
+	localRTCPPort int
+
+	serverRTPAddr  *net.UDPAddr
+	serverRTCPAddr *net.UDPAddr
+	ssrc           uint32
+
+	// anyPort, если true, принимает RTP/RTCP с любого порта отправителя вместо того,
+	// чтобы требовать совпадения с server_port из ответа на SETUP - нужно для камер за
+	// NAT, чьи пакеты приходят с порта, отличного от заявленного
+	anyPort bool
+
+	packets chan rtpPacket
+	stop    chan struct{}
+
+	mu    sync.Mutex
+	stats TrackStats
+}
+
+// NewUDPTransport создает UDP транспорт, резервируя пару портов RTP/RTCP
+func NewUDPTransport(anyPort bool) (*UDPTransport, error) {
+	rtpConn, rtcpConn, err := allocateRTPRTCPPorts()
+	if err != nil {
+		return nil, err
+	}
+
+	return &UDPTransport{
+		rtpConn:       rtpConn,
+		rtcpConn:      rtcpConn,
+		localRTPPort:  rtpConn.LocalAddr().(*net.UDPAddr).Port,
+		localRTCPPort: rtcpConn.LocalAddr().(*net.UDPAddr).Port,
+		anyPort:       anyPort,
+		packets:       make(chan rtpPacket, 256),
+		stop:          make(chan struct{}),
+	}, nil
+}
+
+// Header возвращает значение заголовка Transport для SETUP (client_port=RTP-RTCP)
+func (t *UDPTransport) Header() string {
+	return fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", t.localRTPPort, t.localRTCPPort)
+}
+
+// ApplyServerReply разбирает server_port= и ssrc= из ответа сервера на SETUP
+func (t *UDPTransport) ApplyServerReply(serverTransport string) error {
+	serverIP := t.rtpConn.LocalAddr().(*net.UDPAddr).IP
+	for _, field := range strings.Split(serverTransport, ";") {
+		field = strings.TrimSpace(field)
+		if strings.HasPrefix(field, "server_port=") {
+			ports := strings.TrimPrefix(field, "server_port=")
+			parts := strings.SplitN(ports, "-", 2)
+			rtpPort, err := strconv.Atoi(parts[0])
+			if err != nil {
+				return fmt.Errorf("invalid server_port: %s", ports)
+			}
+			rtcpPort := rtpPort + 1
+			if len(parts) == 2 {
+				if p, err := strconv.Atoi(parts[1]); err == nil {
+					rtcpPort = p
+				}
+			}
+			t.serverRTPAddr = &net.UDPAddr{IP: serverIP, Port: rtpPort}
+			t.serverRTCPAddr = &net.UDPAddr{IP: serverIP, Port: rtcpPort}
+		} else if strings.HasPrefix(field, "ssrc=") {
+			ssrcHex := strings.TrimPrefix(field, "ssrc=")
+			if v, err := strconv.ParseUint(ssrcHex, 16, 32); err == nil {
+				t.ssrc = uint32(v)
+			}
+		}
+	}
+	return nil
+}
+
+// Start запускает горутины чтения RTP и RTCP сокетов в двойной буфер
+func (t *UDPTransport) Start() error {
+	go t.readLoop(t.rtpConn, 0)
+	go t.readLoop(t.rtcpConn, 1)
+	return nil
+}
+
+// sourcePortAllowed проверяет, что пакет пришел с порта, согласованного в server_port=
+// ответа на SETUP - до ApplyServerReply (который вызывается раньше Start) порт еще
+// неизвестен, и пакет пропускается
+func (t *UDPTransport) sourcePortAllowed(channel int, addr *net.UDPAddr) bool {
+	var expected *net.UDPAddr
+	if channel == 0 {
+		expected = t.serverRTPAddr
+	} else {
+		expected = t.serverRTCPAddr
+	}
+	return expected != nil && addr != nil && addr.Port == expected.Port
+}
+
+// readLoop читает пакеты из UDP сокета, чередуя два буфера (double buffer)
+func (t *UDPTransport) readLoop(conn *net.UDPConn, channel int) {
+	buffers := [2][]byte{make([]byte, 65536), make([]byte, 65536)}
+	idx := 0
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+
+		conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+		buf := buffers[idx]
+		idx = 1 - idx
+
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			select {
+			case t.packets <- rtpPacket{channel: channel, err: err}:
+			case <-t.stop:
+			}
+			return
+		}
+
+		if !t.anyPort && !t.sourcePortAllowed(channel, addr) {
+			continue
+		}
+
+		payload := make([]byte, n)
+		copy(payload, buf[:n])
+
+		if channel == 0 {
+			t.mu.Lock()
+			t.stats.Packets++
+			t.stats.Bytes += uint64(n)
+			t.mu.Unlock()
+		}
+
+		select {
+		case t.packets <- rtpPacket{channel: channel, payload: payload}:
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// ReadPacket возвращает следующий принятый RTP/RTCP пакет
+func (t *UDPTransport) ReadPacket() (int, []byte, error) {
+	select {
+	case pkt := <-t.packets:
+		return pkt.channel, pkt.payload, pkt.err
+	case <-t.stop:
+		return 0, nil, fmt.Errorf("transport closed")
+	}
+}
+
+// Stats возвращает накопленную статистику RTP трека
+func (t *UDPTransport) Stats() TrackStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// SendRTCP отправляет RTCP пакет на серверный RTCP порт, согласованный в ответе на SETUP
+func (t *UDPTransport) SendRTCP(payload []byte) error {
+	if t.serverRTCPAddr == nil {
+		return fmt.Errorf("server RTCP address is not known yet")
+	}
+	_, err := t.rtcpConn.WriteToUDP(payload, t.serverRTCPAddr)
+	return err
+}
+
+// Close останавливает чтение и закрывает сокеты
+func (t *UDPTransport) Close() error {
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+	if t.rtpConn != nil {
+		t.rtpConn.Close()
+	}
+	if t.rtcpConn != nil {
+		t.rtcpConn.Close()
+	}
+	return nil
+}
+
+// InterleavedTransport - транспорт, принимающий RTP/RTCP внутри TCP соединения RTSP
+type InterleavedTransport struct {
+	reader      *bufio.Reader
+	writer      io.Writer
+	rtpChannel  int
+	rtcpChannel int
+
+	mu    sync.Mutex
+	stats TrackStats
+}
+
+// NewInterleavedTransport создает транспорт поверх существующего RTSP TCP соединения.
+// writer используется для отправки исходящих RTCP пакетов в том же TCP соединении (см. SendRTCP)
+func NewInterleavedTransport(reader *bufio.Reader, writer io.Writer, rtpChannel, rtcpChannel int) *InterleavedTransport {
+	return &InterleavedTransport{
+		reader:      reader,
+		writer:      writer,
+		rtpChannel:  rtpChannel,
+		rtcpChannel: rtcpChannel,
+	}
+}
+
+// Header возвращает значение заголовка Transport для SETUP (interleaved=0-1)
+func (t *InterleavedTransport) Header() string {
+	return fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", t.rtpChannel, t.rtcpChannel)
+}
+
+// ApplyServerReply для interleaved транспорта ничего разбирать не требуется
+func (t *InterleavedTransport) ApplyServerReply(serverTransport string) error {
+	for _, field := range strings.Split(serverTransport, ";") {
+		field = strings.TrimSpace(field)
+		if strings.HasPrefix(field, "interleaved=") {
+			parts := strings.SplitN(strings.TrimPrefix(field, "interleaved="), "-", 2)
+			if rtp, err := strconv.Atoi(parts[0]); err == nil {
+				t.rtpChannel = rtp
+			}
+			if len(parts) == 2 {
+				if rtcp, err := strconv.Atoi(parts[1]); err == nil {
+					t.rtcpChannel = rtcp
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Start для interleaved транспорта не требует отдельных горутин - чтение идет синхронно из ReadPacket
+func (t *InterleavedTransport) Start() error {
+	return nil
+}
+
+// ReadPacket читает один кадр `$` + channel + 16-bit length + payload и возвращает его
+func (t *InterleavedTransport) ReadPacket() (int, []byte, error) {
+	for {
+		marker, err := t.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		if marker != '$' {
+			// Не кадр RTP/RTCP (например, ответ RTSP на keepalive) - пропускаем байт
+			continue
+		}
+
+		channelByte, err := t.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		lengthBuf := make([]byte, 2)
+		if _, err := readFull(t.reader, lengthBuf); err != nil {
+			return 0, nil, err
+		}
+		length := binary.BigEndian.Uint16(lengthBuf)
+
+		payload := make([]byte, length)
+		if _, err := readFull(t.reader, payload); err != nil {
+			return 0, nil, err
+		}
+
+		channel := int(channelByte)
+		if channel == t.rtpChannel {
+			t.mu.Lock()
+			t.stats.Packets++
+			t.stats.Bytes += uint64(length)
+			t.mu.Unlock()
+		}
+
+		return channel, payload, nil
+	}
+}
+
+// readFull дочитывает ровно len(buf) байт из bufio.Reader
+func readFull(reader *bufio.Reader, buf []byte) (int, error) {
+	read := 0
+	for read < len(buf) {
+		n, err := reader.Read(buf[read:])
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+// Stats возвращает накопленную статистику RTP трека
+func (t *InterleavedTransport) Stats() TrackStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// SendRTCP оборачивает RTCP пакет в interleaved-кадр ('$' + channel + 16-bit length)
+// и пишет его в то же TCP соединение, что используется для RTSP сигнализации
+func (t *InterleavedTransport) SendRTCP(payload []byte) error {
+	frame := make([]byte, 4+len(payload))
+	frame[0] = '$'
+	frame[1] = byte(t.rtcpChannel)
+	binary.BigEndian.PutUint16(frame[2:4], uint16(len(payload)))
+	copy(frame[4:], payload)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.writer.Write(frame)
+	return err
+}
+
+// Close для interleaved транспорта не закрывает RTSP соединение - оно общее с сигнализацией
+func (t *InterleavedTransport) Close() error {
+	return nil
+}
+
+// MulticastTransport - транспорт, принимающий RTP/RTCP из мультикаст группы, адрес
+// которой сервер указывает в ответе на SETUP (Transport: ...;destination=...;port=X-Y;ttl=Z),
+// а не назначает клиент сам, как в UDPTransport
+type MulticastTransport struct {
+	groupIP  net.IP
+	rtpPort  int
+	rtcpPort int
+	ttl      int
+
+	rtpConn  *net.UDPConn
+	rtcpConn *net.UDPConn
+
+	packets chan rtpPacket
+	stop    chan struct{}
+
+	mu    sync.Mutex
+	stats TrackStats
+}
+
+// NewMulticastTransport создает мультикаст транспорт. Группу и порты он узнает только из
+// ответа сервера на SETUP - до ApplyServerReply сокеты не открыты
+func NewMulticastTransport() *MulticastTransport {
+	return &MulticastTransport{
+		packets: make(chan rtpPacket, 256),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Header возвращает значение заголовка Transport для SETUP - без client_port, поскольку
+// адрес и порты мультикаст группы выбирает сервер
+func (t *MulticastTransport) Header() string {
+	return "RTP/AVP;multicast"
+}
+
+// ApplyServerReply разбирает destination=/port=/ttl= из ответа сервера и подключается к
+// указанной мультикаст группе
+func (t *MulticastTransport) ApplyServerReply(serverTransport string) error {
+	var rtpPort, rtcpPort int
+	for _, field := range strings.Split(serverTransport, ";") {
+		field = strings.TrimSpace(field)
+		switch {
+		case strings.HasPrefix(field, "destination="):
+			t.groupIP = net.ParseIP(strings.TrimPrefix(field, "destination="))
+		case strings.HasPrefix(field, "port="):
+			parts := strings.SplitN(strings.TrimPrefix(field, "port="), "-", 2)
+			if p, err := strconv.Atoi(parts[0]); err == nil {
+				rtpPort = p
+			}
+			rtcpPort = rtpPort + 1
+			if len(parts) == 2 {
+				if p, err := strconv.Atoi(parts[1]); err == nil {
+					rtcpPort = p
+				}
+			}
+		case strings.HasPrefix(field, "ttl="):
+			if v, err := strconv.Atoi(strings.TrimPrefix(field, "ttl=")); err == nil {
+				t.ttl = v
+			}
+		}
+	}
+	if t.groupIP == nil || rtpPort == 0 {
+		return fmt.Errorf("multicast Transport reply is missing destination/port: %q", serverTransport)
+	}
+	t.rtpPort, t.rtcpPort = rtpPort, rtcpPort
+
+	rtpConn, err := net.ListenMulticastUDP("udp4", nil, &net.UDPAddr{IP: t.groupIP, Port: t.rtpPort})
+	if err != nil {
+		return fmt.Errorf("failed to join multicast group %s:%d: %w", t.groupIP, t.rtpPort, err)
+	}
+	rtcpConn, err := net.ListenMulticastUDP("udp4", nil, &net.UDPAddr{IP: t.groupIP, Port: t.rtcpPort})
+	if err != nil {
+		rtpConn.Close()
+		return fmt.Errorf("failed to join multicast group %s:%d: %w", t.groupIP, t.rtcpPort, err)
+	}
+	t.rtpConn, t.rtcpConn = rtpConn, rtcpConn
+	return nil
+}
+
+// Start запускает горутины чтения RTP и RTCP сокетов мультикаст группы
+func (t *MulticastTransport) Start() error {
+	if t.rtpConn == nil || t.rtcpConn == nil {
+		return fmt.Errorf("multicast group is not joined yet, call ApplyServerReply first")
+	}
+	go t.readLoop(t.rtpConn, 0)
+	go t.readLoop(t.rtcpConn, 1)
+	return nil
+}
+
+// readLoop читает пакеты из сокета мультикаст группы - все отправители в группе
+// считаются доверенными, отдельной фильтрации по порту отправителя здесь нет
+func (t *MulticastTransport) readLoop(conn *net.UDPConn, channel int) {
+	buffers := [2][]byte{make([]byte, 65536), make([]byte, 65536)}
+	idx := 0
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+
+		conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+		buf := buffers[idx]
+		idx = 1 - idx
+
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			select {
+			case t.packets <- rtpPacket{channel: channel, err: err}:
+			case <-t.stop:
+			}
+			return
+		}
+
+		payload := make([]byte, n)
+		copy(payload, buf[:n])
+
+		if channel == 0 {
+			t.mu.Lock()
+			t.stats.Packets++
+			t.stats.Bytes += uint64(n)
+			t.mu.Unlock()
+		}
+
+		select {
+		case t.packets <- rtpPacket{channel: channel, payload: payload}:
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// ReadPacket возвращает следующий принятый RTP/RTCP пакет
+func (t *MulticastTransport) ReadPacket() (int, []byte, error) {
+	select {
+	case pkt := <-t.packets:
+		return pkt.channel, pkt.payload, pkt.err
+	case <-t.stop:
+		return 0, nil, fmt.Errorf("transport closed")
+	}
+}
+
+// Stats возвращает накопленную статистику RTP трека
+func (t *MulticastTransport) Stats() TrackStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// SendRTCP отправляет RTCP пакет в ту же мультикаст группу, из которой принимаются RTP/RTCP
+func (t *MulticastTransport) SendRTCP(payload []byte) error {
+	if t.rtcpConn == nil {
+		return fmt.Errorf("multicast group is not joined yet")
+	}
+	_, err := t.rtcpConn.WriteToUDP(payload, &net.UDPAddr{IP: t.groupIP, Port: t.rtcpPort})
+	return err
+}
+
+// Close покидает мультикаст группу и закрывает сокеты
+func (t *MulticastTransport) Close() error {
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+	if t.rtpConn != nil {
+		t.rtpConn.Close()
+	}
+	if t.rtcpConn != nil {
+		t.rtcpConn.Close()
+	}
+	return nil
+}
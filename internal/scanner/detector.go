@@ -4,13 +4,26 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols/onvif"
+	"github.com/local-video-server/internal/rtsp"
+	"github.com/local-video-server/pkg/events"
 	"github.com/local-video-server/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
 
+// rtspProbeConcurrency - предел одновременных устройств, опрашиваемых RTSP DESCRIBE пробой в
+// probeRTSPStreams. rtsp.Checker сам ограничивает параллельность внутри одного устройства
+// (MaxConcurrency/PerHostConcurrency), этот предел - только по количеству устройств разом
+const rtspProbeConcurrency = 8
+
+// NeighborCacheTTL - TTL записей обратного DNS резолва в NeighborCache, используемом
+// Detector'ом для обогащения устройств MAC/hostname
+const NeighborCacheTTL = 10 * time.Minute
+
 // Detector объединяет все методы обнаружения устройств
 type Detector struct {
 	config        *models.ScanConfig
@@ -18,17 +31,60 @@ type Detector struct {
 	networkScanner *NetworkScanner
 	onvifScanner  *ONVIFScanner
 	upnpScanner   *UPnPScanner
+	passiveScanner *PassiveScanner
+	neighborCache *utils.NeighborCache
+	rtspChecker   *rtsp.Checker
+
+	dispatcher *events.Dispatcher
+
+	// lastSeenDevices - устройства, найденные предыдущим вызовом Scan, по IP. Используется
+	// только для публикации DeviceLost - сравнивается с результатом текущего скана
+	mu              sync.Mutex
+	lastSeenDevices map[string]*models.Device
+
+	eventSeq uint64
 }
 
 // NewDetector создает новый экземпляр Detector
 func NewDetector(config *models.ScanConfig) *Detector {
+	portTimeout := config.PortTimeout
+	if portTimeout == 0 {
+		portTimeout = 2 * time.Second
+	}
+
 	return &Detector{
 		config:         config,
 		logger:         utils.GetLogger(),
 		networkScanner: NewNetworkScanner(config),
 		onvifScanner:   NewONVIFScanner(config),
 		upnpScanner:    NewUPnPScanner(config),
+		passiveScanner: NewPassiveScanner(config),
+		neighborCache:  utils.NewNeighborCache(NeighborCacheTTL),
+		rtspChecker:    rtsp.NewChecker(&models.RTSPConfig{Timeout: portTimeout}),
+	}
+}
+
+// SetDispatcher подключает dispatcher, на который Scan будет публиковать события
+// DeviceDiscovered/ProtocolDetected/DeviceLost/ScanCompleted. Без вызова SetDispatcher
+// события никуда не публикуются
+func (d *Detector) SetDispatcher(dispatcher *events.Dispatcher) {
+	d.dispatcher = dispatcher
+}
+
+// publishEvent публикует событие типа t на подключенный dispatcher. Не делает ничего, если
+// SetDispatcher не был вызван
+func (d *Detector) publishEvent(t events.Type, device *models.Device, protocol *models.Protocol) {
+	if d.dispatcher == nil {
+		return
 	}
+	seq := atomic.AddUint64(&d.eventSeq, 1)
+	d.dispatcher.Publish(events.Event{
+		ID:        fmt.Sprintf("%d-%d", time.Now().UnixNano(), seq),
+		Type:      t,
+		Timestamp: time.Now(),
+		Device:    device,
+		Protocol:  protocol,
+	})
 }
 
 // Scan выполняет полное сканирование сети всеми доступными методами
@@ -39,20 +95,36 @@ func (d *Detector) Scan(ctx context.Context, subnet string) ([]*models.Device, e
 	devicesMap := make(map[string]*models.Device)
 	var mu sync.Mutex
 
-	// Функция для объединения устройств
+	// Функция для объединения устройств. Публикация событий вынесена за пределы critical
+	// section - Dispatcher.Publish синхронно пишет в BoltDB, и держать mu на время этой
+	// записи означало бы сериализовать все параллельные сканеры на дисковом I/O
 	mergeDevice := func(device *models.Device) {
 		if device == nil {
 			return
 		}
+		d.enrichDevice(device)
 
 		mu.Lock()
-		defer mu.Unlock()
-
-		if existing, exists := devicesMap[device.IP]; exists {
+		existing, exists := devicesMap[device.IP]
+		var added []models.Protocol
+		if exists {
 			// Объединяем информацию об устройстве
-			d.mergeDevices(existing, device)
+			added = d.mergeDevices(existing, device)
 		} else {
 			devicesMap[device.IP] = device
+			existing = device
+		}
+		mu.Unlock()
+
+		if !exists {
+			d.publishEvent(events.DeviceDiscovered, existing, nil)
+			for i := range existing.Protocols {
+				d.publishEvent(events.ProtocolDetected, existing, &existing.Protocols[i])
+			}
+		} else {
+			for i := range added {
+				d.publishEvent(events.ProtocolDetected, existing, &added[i])
+			}
 		}
 	}
 
@@ -88,6 +160,24 @@ func (d *Detector) Scan(ctx context.Context, subnet string) ([]*models.Device, e
 			}
 			d.logger.Infof("ONVIF discovery completed: found %d devices", len(devices))
 		}()
+
+		// 2b. ONVIF GetProfiles/GetStreamUri, чтобы получить настоящие RTSP URI вместо
+		// угадывания пути в RTSPDetector (см. internal/protocols/onvif)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			byNetwork, fallback, defaults := onvif.CredentialConfig(d.config)
+
+			devices, err := onvif.Discover(ctx, d.config.DiscoveryTimeout, byNetwork, fallback, defaults)
+			if err != nil {
+				d.logger.Warnf("ONVIF stream probe failed: %v", err)
+				return
+			}
+			for _, device := range devices {
+				mergeDevice(device)
+			}
+			d.logger.Infof("ONVIF stream probe completed: found %d devices with stream URIs", len(devices))
+		}()
 	}
 
 	// 3. UPnP/SSDP Discovery (если включен)
@@ -107,21 +197,81 @@ func (d *Detector) Scan(ctx context.Context, subnet string) ([]*models.Device, e
 		}()
 	}
 
+	// 4. Пассивное обнаружение (если включено)
+	if d.config.EnablePassive {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			devices, err := d.passiveScanner.Discover(ctx)
+			if err != nil {
+				d.logger.Warnf("Passive discovery failed: %v", err)
+				return
+			}
+			for _, device := range devices {
+				mergeDevice(device)
+			}
+			d.logger.Infof("Passive discovery completed: found %d devices", len(devices))
+		}()
+	}
+
 	// Ждем завершения всех методов сканирования
 	wg.Wait()
 
+	// 5. Активный RTSP DESCRIBE пробой (если включен) - ONVIF GetStreamUri (2b выше) уже мог
+	// заполнить RTSPStreams настоящими URI; угадывание путей имеет смысл только для устройств,
+	// у которых он остался пуст
+	if d.config.CheckRTSP {
+		d.probeRTSPStreams(devicesMap)
+	}
+
 	// Преобразуем map в slice
 	devices := make([]*models.Device, 0, len(devicesMap))
 	for _, device := range devicesMap {
 		devices = append(devices, device)
 	}
 
+	d.reportLostDevices(devicesMap)
+	d.publishEvent(events.ScanCompleted, nil, nil)
+
 	d.logger.Infof("Comprehensive scan completed: found %d unique devices", len(devices))
 	return devices, nil
 }
 
-// mergeDevices объединяет информацию о двух устройствах с одинаковым IP
-func (d *Detector) mergeDevices(existing, new *models.Device) {
+// reportLostDevices публикует DeviceLost для устройств, которые были в результатах
+// предыдущего вызова Scan, но отсутствуют в текущем, и запоминает текущий результат для
+// следующего сравнения
+func (d *Detector) reportLostDevices(devicesMap map[string]*models.Device) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for ip, device := range d.lastSeenDevices {
+		if _, ok := devicesMap[ip]; !ok {
+			d.publishEvent(events.DeviceLost, device, nil)
+		}
+	}
+
+	d.lastSeenDevices = make(map[string]*models.Device, len(devicesMap))
+	for ip, device := range devicesMap {
+		d.lastSeenDevices[ip] = device
+	}
+}
+
+// enrichDevice заполняет MAC и Hostname устройства через NeighborCache, если сканер,
+// который его обнаружил, их не предоставил. PTR записи и вендор по MAC OUI приоритетно
+// разрешаются самим NeighborCache - Detector лишь не перезаписывает то, что уже известно
+func (d *Detector) enrichDevice(device *models.Device) {
+	mac, hostname := d.neighborCache.Lookup(device.IP)
+	if device.MAC == "" && mac != "" {
+		device.MAC = mac
+	}
+	if device.Hostname == "" && hostname != "" {
+		device.Hostname = hostname
+	}
+}
+
+// mergeDevices объединяет информацию о двух устройствах с одинаковым IP и возвращает
+// протоколы, которых не было у existing до объединения (для публикации ProtocolDetected)
+func (d *Detector) mergeDevices(existing, new *models.Device) []models.Protocol {
 	// Объединяем протоколы
 	protocolMap := make(map[string]models.Protocol)
 	for _, p := range existing.Protocols {
@@ -129,10 +279,12 @@ func (d *Detector) mergeDevices(existing, new *models.Device) {
 		protocolMap[key] = p
 	}
 
+	var added []models.Protocol
 	for _, p := range new.Protocols {
 		key := fmt.Sprintf("%s:%d", p.Type, p.Port)
 		if _, exists := protocolMap[key]; !exists {
 			existing.Protocols = append(existing.Protocols, p)
+			added = append(added, p)
 		}
 	}
 
@@ -149,11 +301,67 @@ func (d *Detector) mergeDevices(existing, new *models.Device) {
 	if existing.MAC == "" && new.MAC != "" {
 		existing.MAC = new.MAC
 	}
+	if new.PTZ {
+		existing.PTZ = true
+	}
+	if len(new.RTSPStreams) > 0 {
+		existing.RTSPStreams = append(existing.RTSPStreams, new.RTSPStreams...)
+	}
 
 	// Обновляем LastSeen
 	if new.DiscoveredAt.After(existing.DiscoveredAt) {
 		existing.LastSeen = time.Now()
 	}
+
+	return added
+}
+
+// probeRTSPStreams опрашивает DESCRIBE каждое устройство из devicesMap, у которого обнаружен
+// протокол RTSP (554/8554, см. NetworkScanner.fallbackProtocol и protocols.RTSPDetector) и еще
+// нет ни одного распознанного потока, перебирая те же per-device учетные данные, что и ONVIF
+// сканер (onvif.ResolveCredentials - Detector не заводит отдельный набор учетных данных для
+// RTSP). Выполняется после wg.Wait(), так что devicesMap в этот момент уже не меняется другими
+// горутинами - достаточно ограничить только число одновременно опрашиваемых устройств
+func (d *Detector) probeRTSPStreams(devicesMap map[string]*models.Device) {
+	byNetwork, fallback, defaults := onvif.CredentialConfig(d.config)
+
+	sem := make(chan struct{}, rtspProbeConcurrency)
+	var wg sync.WaitGroup
+
+	for _, device := range devicesMap {
+		port, ok := rtspPort(device)
+		if !ok || len(device.RTSPStreams) > 0 {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(device *models.Device, port int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			for _, cred := range onvif.ResolveCredentials(device.IP, byNetwork, fallback, defaults) {
+				streams, err := d.rtspChecker.DiscoverStreams(device.IP, port, cred.Username, cred.Password)
+				if err != nil || len(streams) == 0 {
+					continue
+				}
+				device.RTSPStreams = append(device.RTSPStreams, streams...)
+				return
+			}
+		}(device, port)
+	}
+
+	wg.Wait()
+}
+
+// rtspPort возвращает порт протокола RTSP устройства, если оно его рекламирует
+func rtspPort(device *models.Device) (int, bool) {
+	for _, p := range device.Protocols {
+		if p.Type == "RTSP" {
+			return p.Port, true
+		}
+	}
+	return 0, false
 }
 
 // ScanWithTimeout выполняет сканирование с таймаутом
@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -10,25 +11,59 @@ import (
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcap"
+	"github.com/local-video-server/internal/licensing"
+	"github.com/local-video-server/internal/metrics"
 	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols"
 	"github.com/local-video-server/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
 
 // NetworkScanner выполняет сканирование сети для обнаружения устройств
 type NetworkScanner struct {
-	config     *models.ScanConfig
-	logger     *logrus.Logger
+	config      *models.ScanConfig
+	logger      *logrus.Logger
 	activeHosts map[string]bool
-	mu         sync.RWMutex
+	mu          sync.RWMutex
+
+	// concurrency - адаптивный AIMD-контроллер параллельности сканирования (см.
+	// ratelimit.go), заменяет прежний фиксированный семафор на MaxConcurrency
+	concurrency *aimdController
+
+	// rateLimiter - token-bucket лимитер скорости dial-попыток, защищающий от ARP/SYN
+	// storm на широких сканированиях
+	rateLimiter *tokenBucket
+
+	// backoff - per-target экспоненциальный backoff, чтобы повторные dial к одному и
+	// тому же "упавшему" хосту не выполнялись всеми горутинами одновременно
+	backoff *hostBackoff
 }
 
 // NewNetworkScanner создает новый экземпляр NetworkScanner
 func NewNetworkScanner(config *models.ScanConfig) *NetworkScanner {
+	logger := utils.GetLogger()
+
+	maxConcurrency := config.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = 50
+	}
+	if maxConcurrency > licensing.HighConcurrencyThreshold && !licensing.DefaultGates().Allow(licensing.FeatureHighConcurrencyScan) {
+		logger.Debugf("Clamping scan concurrency from %d to %d: not licensed for %s edition",
+			maxConcurrency, licensing.HighConcurrencyThreshold, licensing.DefaultGates().Edition())
+		maxConcurrency = licensing.HighConcurrencyThreshold
+	}
+	minConcurrency := config.MinConcurrency
+	if minConcurrency == 0 {
+		minConcurrency = defaultMinConcurrency
+	}
+
 	return &NetworkScanner{
 		config:      config,
-		logger:      utils.GetLogger(),
+		logger:      logger,
 		activeHosts: make(map[string]bool),
+		concurrency: newAIMDController(minConcurrency, minConcurrency, maxConcurrency),
+		rateLimiter: newTokenBucket(config.RateLimit),
+		backoff:     newHostBackoff(),
 	}
 }
 
@@ -51,6 +86,7 @@ func (ns *NetworkScanner) ScanNetwork(ctx context.Context, subnet string) ([]*mo
 
 	// 2. Параллельное сканирование портов
 	devices := ns.scanPortsParallel(ctx, hosts)
+	metrics.DevicesDetected.Set(float64(len(devices)))
 
 	ns.logger.Infof("Scan completed. Found %d devices", len(devices))
 	return devices, nil
@@ -159,33 +195,56 @@ func (ns *NetworkScanner) getHostsFromARP(interfaceName, subnet string) ([]strin
 
 // scanPortsParallel выполняет параллельное сканирование портов
 func (ns *NetworkScanner) scanPortsParallel(ctx context.Context, hosts []string) []*models.Device {
-	var devices []*models.Device
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+	metrics.ActiveScans.Inc()
+	defer metrics.ActiveScans.Dec()
 
-	// Семафор для ограничения параллельности
-	semaphore := make(chan struct{}, ns.config.MaxConcurrency)
+	start := time.Now()
+	defer func() {
+		metrics.ScanDuration.Observe(time.Since(start).Seconds())
+	}()
 
 	// Канал для результатов
 	deviceChan := make(chan *models.Device, len(hosts))
 
-	// Запускаем сканирование для каждого хоста
+	go func() {
+		ns.scanHosts(ctx, hosts, deviceChan)
+		close(deviceChan)
+	}()
+
+	// Собираем результаты
+	var devices []*models.Device
+	for device := range deviceChan {
+		devices = append(devices, device)
+	}
+
+	return devices
+}
+
+// scanHosts запускает сканирование каждого хоста из hosts и отправляет найденные
+// устройства в deviceChan по мере обнаружения. Возвращается, когда все хосты
+// просканированы (или ctx отменен) - закрытие deviceChan остается на вызывающей стороне,
+// чтобы и scanPortsParallel (накапливающий batch), и ScanNetworkStream (отдающий канал
+// наружу как есть) могли переиспользовать одну и ту же логику обхода хостов
+func (ns *NetworkScanner) scanHosts(ctx context.Context, hosts []string, deviceChan chan<- *models.Device) {
+	var wg sync.WaitGroup
+
 	for _, host := range hosts {
 		select {
 		case <-ctx.Done():
 			ns.logger.Warnf("Scan cancelled")
-			return devices
+			return
 		default:
 		}
 
 		wg.Add(1)
 		go func(ip string) {
 			defer wg.Done()
-			
-			// Ограничиваем параллельность
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
 
+			// Ограничиваем параллельность через адаптивный AIMD-контроллер
+			ns.concurrency.acquire()
+			defer ns.concurrency.release()
+
+			metrics.HostsScanned.Inc()
 			device := ns.scanHost(ctx, ip)
 			if device != nil {
 				deviceChan <- device
@@ -193,20 +252,50 @@ func (ns *NetworkScanner) scanPortsParallel(ctx context.Context, hosts []string)
 		}(host)
 	}
 
-	// Закрываем канал после завершения всех горутин
+	wg.Wait()
+}
+
+// ScanNetworkStream работает как ScanNetwork, но вместо того, чтобы накапливать все
+// найденные устройства в памяти и возвращать их единым срезом, отправляет каждое
+// устройство в возвращаемый канал сразу по обнаружении. Это позволяет потоковым
+// получателям (см. pkg/export.ExportStream - NDJSON в stdout, Elasticsearch bulk API,
+// Kafka producer) начать обработку результатов, не дожидаясь завершения всего
+// сканирования. Оба канала закрываются по завершении сканирования; errChan получает не
+// более одного значения
+func (ns *NetworkScanner) ScanNetworkStream(ctx context.Context, subnet string) (<-chan *models.Device, <-chan error) {
+	deviceChan := make(chan *models.Device)
+	errChan := make(chan error, 1)
+
 	go func() {
-		wg.Wait()
-		close(deviceChan)
-	}()
+		defer close(deviceChan)
+		defer close(errChan)
 
-	// Собираем результаты
-	for device := range deviceChan {
-		mu.Lock()
-		devices = append(devices, device)
-		mu.Unlock()
-	}
+		ns.logger.Infof("Starting streaming network scan for subnet: %s", subnet)
 
-	return devices
+		hosts, err := ns.getActiveHosts(ctx, subnet)
+		if err != nil {
+			ns.logger.Warnf("Failed to get active hosts via ARP: %v, falling back to port scan", err)
+			hosts, err = utils.GetSubnetHosts(subnet)
+			if err != nil {
+				errChan <- fmt.Errorf("failed to get subnet hosts: %w", err)
+				return
+			}
+		}
+
+		ns.logger.Infof("Found %d potential hosts to scan", len(hosts))
+
+		metrics.ActiveScans.Inc()
+		defer metrics.ActiveScans.Dec()
+
+		start := time.Now()
+		defer func() {
+			metrics.ScanDuration.Observe(time.Since(start).Seconds())
+		}()
+
+		ns.scanHosts(ctx, hosts, deviceChan)
+	}()
+
+	return deviceChan, errChan
 }
 
 // scanHost сканирует один хост на наличие открытых портов
@@ -232,9 +321,9 @@ func (ns *NetworkScanner) scanHost(ctx context.Context, ip string) *models.Devic
 			defer wg.Done()
 
 			if ns.isPortOpen(ctx, ip, p) {
-				protocol := ns.detectProtocol(ip, p)
+				detected := ns.detectProtocol(ip, p)
 				mu.Lock()
-				device.Protocols = append(device.Protocols, protocol)
+				device.Protocols = append(device.Protocols, detected...)
 				mu.Unlock()
 			}
 		}(port)
@@ -250,21 +339,44 @@ func (ns *NetworkScanner) scanHost(ctx context.Context, ip string) *models.Devic
 	return device
 }
 
-// isPortOpen проверяет, открыт ли порт на хосте
+// isPortOpen проверяет, открыт ли порт на хосте. Перед dial ждет per-target backoff
+// (если ip недавно таймаутил) и token-bucket лимитер скорости, затем учитывает
+// результат в aimdController и hostBackoff
 func (ns *NetworkScanner) isPortOpen(ctx context.Context, ip string, port int) bool {
 	address := fmt.Sprintf("%s:%d", ip, port)
-	
+
 	// Создаем контекст с таймаутом
 	timeout := ns.config.PortTimeout
 	if timeout == 0 {
 		timeout = 2 * time.Second
 	}
 
+	if err := ns.backoff.wait(ctx, ip); err != nil {
+		return false
+	}
+	if err := ns.rateLimiter.wait(ctx); err != nil {
+		return false
+	}
+
 	dialer := &net.Dialer{
 		Timeout: timeout,
 	}
 
+	metrics.PortsProbed.Inc()
+	dialStart := time.Now()
 	conn, err := dialer.DialContext(ctx, "tcp", address)
+	metrics.PortDialLatency.Observe(time.Since(dialStart).Seconds())
+
+	timedOut := false
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			timedOut = true
+		}
+	}
+	ns.backoff.record(ip, timedOut)
+	ns.concurrency.record(timedOut)
+
 	if err != nil {
 		return false
 	}
@@ -272,11 +384,36 @@ func (ns *NetworkScanner) isPortOpen(ctx context.Context, ip string, port int) b
 	return true
 }
 
-// detectProtocol определяет протокол по порту
-func (ns *NetworkScanner) detectProtocol(ip string, port int) models.Protocol {
+// detectProtocol определяет протокол(ы) на порту через глобальный реестр
+// protocols.DefaultRegistry (дешевый banner-проб + дорогой Detect только для
+// совпавших детекторов, см. protocols.ProtocolDetectorRegistry), откатываясь на
+// грубую классификацию по номеру порта, если ни один зарегистрированный детектор не
+// подтвердил совпадение
+func (ns *NetworkScanner) detectProtocol(ip string, port int) []models.Protocol {
+	timeout := ns.config.PortTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+
+	if matched := protocols.DefaultRegistry().Probe(ip, port, timeout); len(matched) > 0 {
+		for _, protocol := range matched {
+			metrics.ProtocolDetections.WithLabelValues(protocol.Type).Inc()
+		}
+		return matched
+	}
+
+	fallback := ns.fallbackProtocol(ip, port)
+	metrics.ProtocolDetections.WithLabelValues(fallback.Type).Inc()
+	return []models.Protocol{fallback}
+}
+
+// fallbackProtocol грубо классифицирует порт по номеру - сохраняет прежнее поведение
+// detectProtocol для портов, для которых еще нет специализированного детектора в
+// реестре
+func (ns *NetworkScanner) fallbackProtocol(ip string, port int) models.Protocol {
 	protocol := models.Protocol{
-		Port:      port,
-		Available: true,
+		Port:       port,
+		Available:  true,
 		DetectedAt: time.Now(),
 	}
 
@@ -290,6 +427,12 @@ func (ns *NetworkScanner) detectProtocol(ip string, port int) models.Protocol {
 	case 80, 8080:
 		protocol.Type = "HTTP"
 		protocol.URL = fmt.Sprintf("http://%s:%d", ip, port)
+	case 9710, 9000:
+		protocol.Type = "SRT"
+		protocol.URL = fmt.Sprintf("srt://%s:%d", ip, port)
+	case 8889:
+		protocol.Type = "WHEP"
+		protocol.URL = fmt.Sprintf("http://%s:%d/whep", ip, port)
 	default:
 		protocol.Type = "UNKNOWN"
 		protocol.URL = fmt.Sprintf("tcp://%s:%d", ip, port)
@@ -300,7 +443,7 @@ func (ns *NetworkScanner) detectProtocol(ip string, port int) models.Protocol {
 
 // ScanPorts сканирует указанные порты на хосте
 func (ns *NetworkScanner) ScanPorts(ctx context.Context, ip string, ports []int) []models.Protocol {
-	var protocols []models.Protocol
+	var found []models.Protocol
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
@@ -310,14 +453,14 @@ func (ns *NetworkScanner) ScanPorts(ctx context.Context, ip string, ports []int)
 			defer wg.Done()
 
 			if ns.isPortOpen(ctx, ip, p) {
-				protocol := ns.detectProtocol(ip, p)
+				detected := ns.detectProtocol(ip, p)
 				mu.Lock()
-				protocols = append(protocols, protocol)
+				found = append(found, detected...)
 				mu.Unlock()
 			}
 		}(port)
 	}
 
 	wg.Wait()
-	return protocols
+	return found
 }
@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/local-video-server/internal/events"
 	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols/onvif"
+	"github.com/local-video-server/internal/registry"
 	"github.com/local-video-server/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -17,13 +21,20 @@ import (
 type ONVIFScanner struct {
 	config *models.ScanConfig
 	logger *logrus.Logger
+
+	// watching - отмена горутины events.Watch, запущенной Listen на каждое устройство с
+	// Events capability, по ключу deviceWatchKey. Позволяет не плодить вторую подписку на
+	// повторный Hello/ProbeMatch уже наблюдаемого устройства и остановить ее по Bye
+	watching   map[string]context.CancelFunc
+	watchingMu sync.Mutex
 }
 
 // NewONVIFScanner создает новый экземпляр ONVIFScanner
 func NewONVIFScanner(config *models.ScanConfig) *ONVIFScanner {
 	return &ONVIFScanner{
-		config: config,
-		logger: utils.GetLogger(),
+		config:   config,
+		logger:   utils.GetLogger(),
+		watching: make(map[string]context.CancelFunc),
 	}
 }
 
@@ -178,10 +189,8 @@ func (os *ONVIFScanner) buildProbeMessage() string {
 // parseProbeMatch парсит ProbeMatch ответ и извлекает информацию об устройстве
 func (os *ONVIFScanner) parseProbeMatch(data []byte, sourceIP string) (*models.Device, error) {
 	var envelope ProbeMatchMessage
-	
-	// Пробуем распарсить XML
-	err := xml.Unmarshal(data, &envelope)
-	if err != nil {
+
+	if err := xml.Unmarshal(data, &envelope); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal XML: %w", err)
 	}
 
@@ -194,22 +203,29 @@ func (os *ONVIFScanner) parseProbeMatch(data []byte, sourceIP string) (*models.D
 		return nil, fmt.Errorf("no ProbeMatch entries")
 	}
 
-	probeMatch := envelope.Body.ProbeMatches.ProbeMatch[0]
-	
-	// Извлекаем XAddrs (адреса устройств)
-	xaddrs := strings.TrimSpace(probeMatch.XAddrs)
-	if xaddrs == "" {
-		return nil, fmt.Errorf("no XAddrs in ProbeMatch")
+	pm := envelope.Body.ProbeMatches.ProbeMatch[0]
+	device, err := os.deviceFromAnnouncement(pm.EndpointReference.Address, pm.XAddrs, pm.Scopes, pm.Types, sourceIP)
+	if err != nil {
+		return nil, err
 	}
 
-	// Парсим первый адрес из XAddrs
-	addresses := strings.Split(xaddrs, " ")
-	if len(addresses) == 0 {
-		return nil, fmt.Errorf("empty XAddrs")
+	os.logger.Debugf("Found ONVIF device: %s at %s", device.IP, device.Protocols[0].URL)
+	return device, nil
+}
+
+// deviceFromAnnouncement строит models.Device из полей, общих для ProbeMatch/Hello: адрес
+// устройства в XAddrs (берется первый, если их несколько), EndpointReference для
+// deviceIdentity, Scopes/Types для производителя и модели. sourceIP используется, только
+// если сам XAddrs не дал валидного IP
+func (os *ONVIFScanner) deviceFromAnnouncement(endpointRef, xaddrs, scopes, types, sourceIP string) (*models.Device, error) {
+	xaddrs = strings.TrimSpace(xaddrs)
+	if xaddrs == "" {
+		return nil, fmt.Errorf("no XAddrs")
 	}
 
-	// Извлекаем IP из URL
+	addresses := strings.Split(xaddrs, " ")
 	deviceURL := addresses[0]
+
 	deviceIP, err := os.extractIPFromURL(deviceURL)
 	if err != nil {
 		// Используем source IP как fallback
@@ -218,6 +234,7 @@ func (os *ONVIFScanner) parseProbeMatch(data []byte, sourceIP string) (*models.D
 
 	device := &models.Device{
 		IP:           deviceIP,
+		EndpointRef:  strings.TrimSpace(endpointRef),
 		Protocols:    []models.Protocol{},
 		DiscoveredAt: time.Now(),
 	}
@@ -239,18 +256,15 @@ func (os *ONVIFScanner) parseProbeMatch(data []byte, sourceIP string) (*models.D
 	device.Protocols = append(device.Protocols, onvifProtocol)
 
 	// Парсим Scopes для получения дополнительной информации
-	scopes := strings.TrimSpace(probeMatch.Scopes)
-	if scopes != "" {
-		os.parseScopes(device, scopes)
+	if s := strings.TrimSpace(scopes); s != "" {
+		os.parseScopes(device, s)
 	}
 
 	// Парсим Types
-	types := strings.TrimSpace(probeMatch.Types)
-	if types != "" {
-		os.parseTypes(device, types)
+	if t := strings.TrimSpace(types); t != "" {
+		os.parseTypes(device, t)
 	}
 
-	os.logger.Debugf("Found ONVIF device: %s at %s", deviceIP, deviceURL)
 	return device, nil
 }
 
@@ -376,3 +390,314 @@ func (os *ONVIFScanner) devicesToSlice(devices map[string]*models.Device) []*mod
 	}
 	return result
 }
+
+// defaultProbeInterval - как часто Listen переотправляет Probe, если caller передал 0
+const defaultProbeInterval = 30 * time.Second
+
+// HelloMessage представляет WS-Discovery Hello уведомление (WS-Discovery 1.1, раздел 4.3) -
+// устройство рассылает его само при включении или появлении в сети, не дожидаясь Probe
+type HelloMessage struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Header  struct {
+		Action string `xml:"Action"`
+	} `xml:"Header"`
+	Body struct {
+		Hello struct {
+			EndpointReference struct {
+				Address string `xml:"Address"`
+			} `xml:"EndpointReference"`
+			Types  string `xml:"Types"`
+			Scopes string `xml:"Scopes"`
+			XAddrs string `xml:"XAddrs"`
+		} `xml:"Hello"`
+	} `xml:"Body"`
+}
+
+// ByeMessage представляет WS-Discovery Bye уведомление - устройство рассылает его само при
+// штатном выключении. Сообщает только EndpointReference, без XAddrs/Scopes
+type ByeMessage struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Header  struct {
+		Action string `xml:"Action"`
+	} `xml:"Header"`
+	Body struct {
+		Bye struct {
+			EndpointReference struct {
+				Address string `xml:"Address"`
+			} `xml:"EndpointReference"`
+		} `xml:"Bye"`
+	} `xml:"Body"`
+}
+
+// parseHello парсит Hello уведомление так же, как parseProbeMatch парсит ProbeMatch
+func (os *ONVIFScanner) parseHello(data []byte, sourceIP string) (*models.Device, error) {
+	var envelope HelloMessage
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal XML: %w", err)
+	}
+
+	hello := envelope.Body.Hello
+	return os.deviceFromAnnouncement(hello.EndpointReference.Address, hello.XAddrs, hello.Scopes, hello.Types, sourceIP)
+}
+
+// parseBye парсит Bye уведомление и возвращает EndpointReference устройства, которое
+// выключается
+func (os *ONVIFScanner) parseBye(data []byte) (string, error) {
+	var envelope ByeMessage
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return "", fmt.Errorf("failed to unmarshal XML: %w", err)
+	}
+
+	ref := strings.TrimSpace(envelope.Body.Bye.EndpointReference.Address)
+	if ref == "" {
+		return "", fmt.Errorf("no EndpointReference in Bye message")
+	}
+	return ref, nil
+}
+
+// Listen запускает персистентное прослушивание WS-Discovery вместо разового Probe/чтения
+// ответов, которым ограничивается Discover: подключается к мультикаст группе
+// 239.255.255.250:3702 на каждом не-loopback интерфейсе и работает, пока не отменен ctx,
+// получая как ProbeMatch (ответ на Probe), так и Hello/Bye - уведомления, которые устройство
+// рассылает само при включении/выключении, не дожидаясь чужого Probe. Probe дополнительно
+// переотправляется каждые probeInterval (по умолчанию defaultProbeInterval), чтобы подхватить
+// устройства, уже работавшие на момент запуска Listen. Каждое обнаруженное или пропавшее
+// устройство отражается в reg (AddDevice/RemoveDeviceByEndpointRef) - именно reg публикует
+// DeviceAdded/DeviceUpdated/DeviceRemoved, Listen эти события не дублирует. Listen блокирует
+// вызывающего, пока ctx не отменен
+func (os *ONVIFScanner) Listen(ctx context.Context, reg *registry.DeviceRegistry, probeInterval time.Duration) error {
+	if probeInterval <= 0 {
+		probeInterval = defaultProbeInterval
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	listening := 0
+	for i := range ifaces {
+		iface := ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		wg.Add(1)
+		listening++
+		go func() {
+			defer wg.Done()
+			os.listenOnInterface(ctx, iface, reg)
+		}()
+	}
+
+	if listening == 0 {
+		return fmt.Errorf("no multicast-capable network interface available for WS-Discovery")
+	}
+
+	go os.probeLoop(ctx, probeInterval)
+
+	wg.Wait()
+	return nil
+}
+
+// listenOnInterface читает WS-Discovery сообщения на одном интерфейсе, пока не отменен ctx
+// или сокет не закроется с ошибкой
+func (os *ONVIFScanner) listenOnInterface(ctx context.Context, iface net.Interface, reg *registry.DeviceRegistry) {
+	group := &net.UDPAddr{IP: net.ParseIP(WSDiscoveryMulticastIPv4), Port: WSDiscoveryPort}
+	conn, err := net.ListenMulticastUDP("udp4", &iface, group)
+	if err != nil {
+		os.logger.Debugf("WS-Discovery listen failed on interface %s: %v", iface.Name, err)
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buffer := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			os.logger.Debugf("WS-Discovery read error on %s: %v", iface.Name, err)
+			continue
+		}
+
+		os.handleMessage(ctx, buffer[:n], addr.IP.String(), reg)
+	}
+}
+
+// handleMessage определяет тип WS-Discovery сообщения по Action в заголовке конверта и
+// применяет его к reg: ProbeMatch/Hello добавляют или обновляют устройство, Bye - удаляют
+func (os *ONVIFScanner) handleMessage(ctx context.Context, data []byte, sourceIP string, reg *registry.DeviceRegistry) {
+	var envelope struct {
+		XMLName xml.Name `xml:"Envelope"`
+		Header  struct {
+			Action string `xml:"Action"`
+		} `xml:"Header"`
+	}
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		os.logger.Debugf("failed to unmarshal WS-Discovery message: %v", err)
+		return
+	}
+
+	switch {
+	case strings.Contains(envelope.Header.Action, "ProbeMatches"):
+		device, err := os.parseProbeMatch(data, sourceIP)
+		if err != nil {
+			os.logger.Debugf("failed to parse ProbeMatch: %v", err)
+			return
+		}
+		reg.AddDevice(device)
+		os.startEventWatch(ctx, device, reg)
+
+	case strings.HasSuffix(envelope.Header.Action, "/Hello"):
+		device, err := os.parseHello(data, sourceIP)
+		if err != nil {
+			os.logger.Debugf("failed to parse Hello: %v", err)
+			return
+		}
+		reg.AddDevice(device)
+		os.startEventWatch(ctx, device, reg)
+
+	case strings.HasSuffix(envelope.Header.Action, "/Bye"):
+		ref, err := os.parseBye(data)
+		if err != nil {
+			os.logger.Debugf("failed to parse Bye: %v", err)
+			return
+		}
+		reg.RemoveDeviceByEndpointRef(ref)
+		os.stopEventWatch(ref)
+	}
+}
+
+// deviceWatchKey возвращает ключ, по которому startEventWatch/stopEventWatch отличают одно
+// устройство от другого - тот же выбор EndpointRef-или-IP, что и registry.deviceIdentity.
+// WS-Discovery 1.1 требует EndpointReference в любом Hello/Bye/ProbeMatch, так что реальный
+// fallback на IP происходит только для устройств, уже нарушающих спецификацию - для них
+// watching может не найтись по ref из Bye (parseBye всегда отдает непустой ref, раз он вообще
+// вернул успех), и наблюдение останется активным до отмены ctx всего Listen
+func deviceWatchKey(device *models.Device) string {
+	if device.EndpointRef != "" {
+		return device.EndpointRef
+	}
+	return device.IP
+}
+
+// startEventWatch запускает events.Watch на Events сервисе device, если оно его
+// рекламирует через GetCapabilities, и еще не наблюдается. Учетные данные перебираются тем
+// же ResolveCredentials, что и остальной ONVIF код (см. onvif.enrichXAddrs). Работает в
+// отдельной горутине и не блокирует handleMessage - устройство без Events capability или не
+// ответившее ни одной парой учетных данных просто не дает событий, не мешая остальному Listen.
+// watchCtx - дочерний от ctx, переданного в Listen, поэтому отмена Listen останавливает и все
+// его активные подписки, а не только WS-Discovery листенеры
+func (os *ONVIFScanner) startEventWatch(ctx context.Context, device *models.Device, reg *registry.DeviceRegistry) {
+	if len(device.Protocols) == 0 {
+		return
+	}
+	key := deviceWatchKey(device)
+
+	os.watchingMu.Lock()
+	if _, exists := os.watching[key]; exists {
+		os.watchingMu.Unlock()
+		return
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	os.watching[key] = cancel
+	os.watchingMu.Unlock()
+
+	deviceServiceXAddr := device.Protocols[0].URL
+
+	go func() {
+		defer func() {
+			os.watchingMu.Lock()
+			delete(os.watching, key)
+			os.watchingMu.Unlock()
+		}()
+
+		candidates := onvif.CredentialCandidates(device.IP, os.config)
+
+		var caps onvif.Capabilities
+		var creds onvif.Credentials
+		var err error
+		for _, c := range candidates {
+			caps, err = onvif.NewClient(deviceServiceXAddr, c).GetCapabilities()
+			if err == nil {
+				creds = c
+				break
+			}
+		}
+		if err != nil {
+			os.logger.Debugf("ONVIF GetCapabilities failed for %s, not watching for events: %v", device.IP, err)
+			return
+		}
+		if caps.Events == "" {
+			os.logger.Debugf("ONVIF device %s does not advertise an Events service", device.IP)
+			return
+		}
+
+		if watchErr := events.Watch(watchCtx, caps.Events, creds, device.IP, device.EndpointRef, reg); watchErr != nil && watchCtx.Err() == nil {
+			os.logger.Debugf("ONVIF event watch for %s ended: %v", device.IP, watchErr)
+		}
+	}()
+}
+
+// stopEventWatch отменяет events.Watch, запущенный startEventWatch для endpointRef, если он
+// наблюдается - вызывается на WS-Discovery Bye, чтобы не long-poll'ить устройство, которое
+// само сообщило о выключении
+func (os *ONVIFScanner) stopEventWatch(endpointRef string) {
+	os.watchingMu.Lock()
+	cancel, exists := os.watching[endpointRef]
+	if exists {
+		delete(os.watching, endpointRef)
+	}
+	os.watchingMu.Unlock()
+
+	if exists {
+		cancel()
+	}
+}
+
+// probeLoop отправляет Probe сразу, а затем каждые interval, пока не отменен ctx
+func (os *ONVIFScanner) probeLoop(ctx context.Context, interval time.Duration) {
+	os.sendProbe()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			os.sendProbe()
+		}
+	}
+}
+
+// sendProbe рассылает один WS-Discovery Probe в мультикаст группу. Ответы приходят не сюда,
+// а в listenOnInterface - тот же сокет, которым эта группа уже прослушивается на каждом
+// интерфейсе, получает и ProbeMatch, раз они приходят на тот же мультикаст порт
+func (os *ONVIFScanner) sendProbe() {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		os.logger.Debugf("WS-Discovery periodic Probe failed to open socket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	multicastAddr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", WSDiscoveryMulticastIPv4, WSDiscoveryPort))
+	if err != nil {
+		os.logger.Debugf("WS-Discovery periodic Probe failed to resolve multicast address: %v", err)
+		return
+	}
+
+	if _, err := conn.WriteToUDP([]byte(os.buildProbeMessage()), multicastAddr); err != nil {
+		os.logger.Debugf("WS-Discovery periodic Probe failed to send: %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// Порты, на которых PassiveScanner слушает фоновый трафик устройств
+const (
+	WSDiscoveryPort = 3702
+	MDNSPort        = 5353
+	RTSPPort        = 554
+)
+
+// PassiveScanner обнаруживает устройства пассивно, разбирая трафик, который они сами
+// генерируют в сети (SSDP NOTIFY, ONVIF WS-Discovery Hello, mDNS анонсы, соединения к порту
+// RTSP), не отправляя ни одного запроса. В отличие от NetworkScanner/UPnPScanner/ONVIFScanner
+// он не может спровоцировать устройство ответить - только услышать то, что оно объявляет само
+type PassiveScanner struct {
+	config *models.ScanConfig
+	logger *logrus.Logger
+}
+
+// NewPassiveScanner создает новый экземпляр PassiveScanner
+func NewPassiveScanner(config *models.ScanConfig) *PassiveScanner {
+	return &PassiveScanner{
+		config: config,
+		logger: utils.GetLogger(),
+	}
+}
+
+// bpfFilter - захватываем только трафик протоколов, по которым можно опознать камеру
+const bpfFilter = "udp port 1900 or udp port 3702 or udp port 5353 or tcp port 554"
+
+// Discover слушает интерфейсы в течение DiscoveryTimeout и собирает устройства из
+// замеченного трафика. Отсутствие прав на захват пакетов на каком-либо интерфейсе не
+// приводит к ошибке всего обнаружения - интерфейс просто пропускается
+func (ps *PassiveScanner) Discover(ctx context.Context) ([]*models.Device, error) {
+	ps.logger.Infof("Starting passive discovery")
+
+	interfaces, err := utils.GetNetworkInterfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network interfaces: %w", err)
+	}
+
+	timeout := ps.config.DiscoveryTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	devicesMap := make(map[string]*models.Device)
+	var mu sync.Mutex
+	mergeDevice := func(device *models.Device) {
+		if device == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if existing, exists := devicesMap[device.IP]; exists {
+			ps.mergeDeviceInfo(existing, device)
+		} else {
+			devicesMap[device.IP] = device
+		}
+	}
+
+	var wg sync.WaitGroup
+	opened := 0
+	for _, iface := range interfaces {
+		handle, err := pcap.OpenLive(iface.Name, 1600, true, time.Second)
+		if err != nil {
+			ps.logger.Debugf("Passive discovery: failed to open interface %s: %v", iface.Name, err)
+			continue
+		}
+		if err := handle.SetBPFFilter(bpfFilter); err != nil {
+			ps.logger.Debugf("Passive discovery: failed to set BPF filter on %s: %v", iface.Name, err)
+			handle.Close()
+			continue
+		}
+
+		opened++
+		wg.Add(1)
+		go func(ifaceName string, handle *pcap.Handle) {
+			defer wg.Done()
+			defer handle.Close()
+			ps.sniff(ctx, handle, timeout, mergeDevice)
+		}(iface.Name, handle)
+	}
+
+	if opened == 0 {
+		ps.logger.Warnf("Passive discovery: no interface could be opened for capture (insufficient privileges?)")
+		return nil, nil
+	}
+
+	wg.Wait()
+
+	devices := make([]*models.Device, 0, len(devicesMap))
+	for _, device := range devicesMap {
+		devices = append(devices, device)
+	}
+
+	ps.logger.Infof("Passive discovery completed. Found %d devices", len(devices))
+	return devices, nil
+}
+
+// sniff читает пакеты с уже настроенного handle, пока не истечет timeout или не отменится ctx
+func (ps *PassiveScanner) sniff(ctx context.Context, handle *pcap.Handle, timeout time.Duration, mergeDevice func(*models.Device)) {
+	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			return
+		case packet, ok := <-packetSource.Packets():
+			if !ok || packet == nil {
+				continue
+			}
+			mergeDevice(ps.parsePacket(packet))
+		}
+	}
+}
+
+// parsePacket извлекает устройство из одного пакета, если он относится к одному из
+// распознаваемых протоколов. Возвращает nil, если пакет неинтересен
+func (ps *PassiveScanner) parsePacket(packet gopacket.Packet) *models.Device {
+	ipLayer := packet.Layer(layers.LayerTypeIPv4)
+	if ipLayer == nil {
+		return nil
+	}
+	ip := ipLayer.(*layers.IPv4)
+
+	var protocol models.Protocol
+	var deviceIP string
+
+	switch {
+	case packet.Layer(layers.LayerTypeUDP) != nil:
+		udp := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		switch {
+		case udp.DstPort == SSDPPort:
+			protocol = models.Protocol{Type: "SSDP", Port: SSDPPort, Available: true, DetectedAt: time.Now()}
+			deviceIP = ip.SrcIP.String()
+		case udp.SrcPort == WSDiscoveryPort || udp.DstPort == WSDiscoveryPort:
+			protocol = models.Protocol{Type: "WS-Discovery", Port: WSDiscoveryPort, Available: true, DetectedAt: time.Now()}
+			deviceIP = ip.SrcIP.String()
+		case udp.DstPort == MDNSPort:
+			if !ps.isRTSPMDNS(udp.Payload) {
+				return nil
+			}
+			protocol = models.Protocol{Type: "mDNS", Port: MDNSPort, Available: true, DetectedAt: time.Now()}
+			deviceIP = ip.SrcIP.String()
+		default:
+			return nil
+		}
+	case packet.Layer(layers.LayerTypeTCP) != nil:
+		tcp := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+		switch {
+		case tcp.SrcPort == layers.TCPPort(RTSPPort):
+			deviceIP = ip.SrcIP.String()
+		case tcp.DstPort == layers.TCPPort(RTSPPort):
+			deviceIP = ip.DstIP.String()
+		default:
+			return nil
+		}
+		protocol = models.Protocol{
+			Type:       "RTSP",
+			Port:       RTSPPort,
+			URL:        fmt.Sprintf("rtsp://%s:%d", deviceIP, RTSPPort),
+			Available:  true,
+			DetectedAt: time.Now(),
+		}
+	default:
+		return nil
+	}
+
+	if deviceIP == "" || net.ParseIP(deviceIP) == nil {
+		return nil
+	}
+
+	device := &models.Device{
+		IP:           deviceIP,
+		Protocols:    []models.Protocol{protocol},
+		DiscoveredAt: time.Now(),
+	}
+
+	if ethLayer := packet.Layer(layers.LayerTypeEthernet); ethLayer != nil {
+		eth := ethLayer.(*layers.Ethernet)
+		if ip.SrcIP.String() == deviceIP {
+			device.MAC = eth.SrcMAC.String()
+		} else {
+			device.MAC = eth.DstMAC.String()
+		}
+	}
+
+	return device
+}
+
+// isRTSPMDNS сообщает, рекламирует ли mDNS пакет сервис _rtsp._tcp - только такие
+// mDNS анонсы представляют интерес для обнаружения камер
+func (ps *PassiveScanner) isRTSPMDNS(payload []byte) bool {
+	return strings.Contains(strings.ToLower(string(payload)), "_rtsp._tcp")
+}
+
+// mergeDeviceInfo объединяет информацию об устройстве, замеченную в разных пакетах
+func (ps *PassiveScanner) mergeDeviceInfo(existing, new *models.Device) {
+	protocolMap := make(map[string]models.Protocol)
+	for _, p := range existing.Protocols {
+		protocolMap[p.Type] = p
+	}
+	for _, p := range new.Protocols {
+		if _, exists := protocolMap[p.Type]; !exists {
+			existing.Protocols = append(existing.Protocols, p)
+		}
+	}
+	if existing.MAC == "" && new.MAC != "" {
+		existing.MAC = new.MAC
+	}
+}
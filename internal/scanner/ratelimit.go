@@ -0,0 +1,308 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/internal/metrics"
+)
+
+const (
+	// defaultMinConcurrency - нижняя граница параллельности по умолчанию, если в
+	// конфигурации не задано MinConcurrency
+	defaultMinConcurrency = 5
+
+	// defaultRateLimit - скорость сканирования по умолчанию (попыток подключения в
+	// секунду), если в конфигурации не задано RateLimit
+	defaultRateLimit = 200
+
+	// aimdWindowSize - количество результатов dial, по которому aimdController
+	// пересчитывает долю таймаутов и решает, менять ли параллельность
+	aimdWindowSize = 20
+
+	// timeoutRateThreshold - доля таймаутов в окне, при превышении которой
+	// параллельность уменьшается вдвое
+	timeoutRateThreshold = 0.3
+
+	// concurrencyStep - аддитивный прирост параллельности за одно "хорошее" окно
+	concurrencyStep = 5
+
+	// stableWindowsForIncrease - количество подряд идущих окон с низкой долей
+	// таймаутов, после которых параллельность увеличивается
+	stableWindowsForIncrease = 3
+
+	// backoffBaseDelay - базовая задержка перед повторным обращением к хосту после
+	// первого таймаута
+	backoffBaseDelay = 50 * time.Millisecond
+
+	// maxHostBackoff - потолок per-target backoff, чтобы единичный "мертвый" хост не
+	// задерживал сканирование на неограниченное время
+	maxHostBackoff = 5 * time.Second
+
+	// maxBackoffShift - ограничение показателя степени в экспоненциальном backoff,
+	// чтобы 1<<shift не переполнялся
+	maxBackoffShift = 6
+)
+
+// dynamicSemaphore - счетный семафор с лимитом, который можно менять на лету. В отличие
+// от обычного буферизованного канала, используемого как семафор, позволяет
+// aimdController уменьшать/увеличивать допустимую параллельность без пересоздания
+// канала и без потери уже выданных разрешений
+type dynamicSemaphore struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	current int
+}
+
+func newDynamicSemaphore(limit int) *dynamicSemaphore {
+	s := &dynamicSemaphore{limit: limit}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *dynamicSemaphore) acquire() {
+	s.mu.Lock()
+	for s.current >= s.limit {
+		s.cond.Wait()
+	}
+	s.current++
+	s.mu.Unlock()
+}
+
+func (s *dynamicSemaphore) release() {
+	s.mu.Lock()
+	s.current--
+	s.cond.Signal()
+	s.mu.Unlock()
+}
+
+func (s *dynamicSemaphore) setLimit(limit int) {
+	s.mu.Lock()
+	s.limit = limit
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+func (s *dynamicSemaphore) getLimit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// aimdController управляет параллельностью сканирования по схеме Additive-Increase/
+// Multiplicative-Decrease (как congestion control в TCP): раз в aimdWindowSize
+// результатов dial оценивает долю таймаутов. Если она выше timeoutRateThreshold,
+// параллельность уменьшается вдвое (не ниже min); если держится низкой
+// stableWindowsForIncrease окон подряд, параллельность растет на concurrencyStep
+// (не выше max)
+type aimdController struct {
+	sem *dynamicSemaphore
+
+	mu          sync.Mutex
+	min, max    int
+	attempts    int
+	timeouts    int
+	goodWindows int
+}
+
+func newAIMDController(initial, min, max int) *aimdController {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if initial < min {
+		initial = min
+	}
+	if initial > max {
+		initial = max
+	}
+
+	c := &aimdController{
+		sem: newDynamicSemaphore(initial),
+		min: min,
+		max: max,
+	}
+	metrics.ScanConcurrency.Set(float64(initial))
+	return c
+}
+
+func (c *aimdController) acquire() { c.sem.acquire() }
+func (c *aimdController) release() { c.sem.release() }
+
+// record учитывает результат одной попытки dial (timedOut == true, если dial не
+// уложился в таймаут по вине перегрузки сети, а не просто закрытого порта) и раз в
+// aimdWindowSize попыток пересчитывает лимит параллельности
+func (c *aimdController) record(timedOut bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.attempts++
+	if timedOut {
+		c.timeouts++
+	}
+
+	if c.attempts < aimdWindowSize {
+		return
+	}
+
+	timeoutRate := float64(c.timeouts) / float64(c.attempts)
+	current := c.sem.getLimit()
+
+	if timeoutRate > timeoutRateThreshold {
+		next := current / 2
+		if next < c.min {
+			next = c.min
+		}
+		c.sem.setLimit(next)
+		c.goodWindows = 0
+	} else {
+		c.goodWindows++
+		if c.goodWindows >= stableWindowsForIncrease {
+			next := current + concurrencyStep
+			if next > c.max {
+				next = c.max
+			}
+			c.sem.setLimit(next)
+			c.goodWindows = 0
+		}
+	}
+
+	metrics.ScanConcurrency.Set(float64(c.sem.getLimit()))
+	c.attempts = 0
+	c.timeouts = 0
+}
+
+// tokenBucket - token-bucket лимитер скорости сканирования (попыток подключения в
+// секунду), чтобы широкие сканирования не генерировали ARP/SYN storm, который
+// коммутаторы начинают дропать
+type tokenBucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	capacity   float64
+	refillRate float64
+	last       time.Time
+}
+
+func newTokenBucket(ratePerSec int) *tokenBucket {
+	rate := float64(ratePerSec)
+	if rate <= 0 {
+		rate = defaultRateLimit
+	}
+	return &tokenBucket{
+		tokens:     rate,
+		capacity:   rate,
+		refillRate: rate,
+		last:       time.Now(),
+	}
+}
+
+// wait блокируется, пока не станет доступен токен, либо пока не отменится ctx
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.refillRate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+
+		missing := 1 - b.tokens
+		wait := time.Duration(missing / b.refillRate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// hostBackoff - экспоненциальный backoff на уровне отдельного хоста: если хост подряд
+// не отвечает вовремя, последующие попытки сканирования его оставшихся портов
+// откладываются, чтобы не долбить один и тот же "упавший" хост всеми горутинами сразу
+type hostBackoff struct {
+	mu    sync.Mutex
+	state map[string]*hostBackoffState
+}
+
+type hostBackoffState struct {
+	failures int
+	until    time.Time
+}
+
+func newHostBackoff() *hostBackoff {
+	return &hostBackoff{state: make(map[string]*hostBackoffState)}
+}
+
+// wait блокируется, если для ip сейчас действует backoff-пауза
+func (b *hostBackoff) wait(ctx context.Context, ip string) error {
+	b.mu.Lock()
+	st, ok := b.state[ip]
+	var until time.Time
+	if ok {
+		until = st.until
+	}
+	b.mu.Unlock()
+
+	if until.IsZero() {
+		return nil
+	}
+
+	d := time.Until(until)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// record учитывает результат dial к ip и обновляет его backoff-состояние: успех сразу
+// сбрасывает backoff, таймаут увеличивает его экспоненциально до maxHostBackoff
+func (b *hostBackoff) record(ip string, timedOut bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st, ok := b.state[ip]
+	if !ok {
+		st = &hostBackoffState{}
+		b.state[ip] = st
+	}
+
+	if !timedOut {
+		st.failures = 0
+		st.until = time.Time{}
+		return
+	}
+
+	st.failures++
+	shift := st.failures
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	delay := backoffBaseDelay * time.Duration(uint(1)<<uint(shift))
+	if delay > maxHostBackoff {
+		delay = maxHostBackoff
+	}
+	st.until = time.Now().Add(delay)
+}
@@ -0,0 +1,160 @@
+package scanner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// descriptionFetchTimeout - таймаут HTTP GET за XML описанием устройства по SSDP Location
+const descriptionFetchTimeout = 5 * time.Second
+
+// upnpRootXML - корневой элемент XML описания UPnP устройства (UPnP Device Architecture
+// 1.0, раздел 2.3)
+type upnpRootXML struct {
+	XMLName xml.Name      `xml:"root"`
+	Device  upnpDeviceXML `xml:"device"`
+}
+
+// upnpDeviceXML - элемент <device>, рекурсивный через вложенный <deviceList> для составных
+// устройств (например, InternetGatewayDevice с WANDevice/WANConnectionDevice внутри)
+type upnpDeviceXML struct {
+	FriendlyName    string `xml:"friendlyName"`
+	Manufacturer    string `xml:"manufacturer"`
+	ManufacturerURL string `xml:"manufacturerURL"`
+	ModelName       string `xml:"modelName"`
+	ModelNumber     string `xml:"modelNumber"`
+	SerialNumber    string `xml:"serialNumber"`
+	UDN             string `xml:"UDN"`
+
+	ServiceList struct {
+		Services []upnpServiceXML `xml:"service"`
+	} `xml:"serviceList"`
+
+	DeviceList struct {
+		Devices []upnpDeviceXML `xml:"device"`
+	} `xml:"deviceList"`
+}
+
+// upnpServiceXML - элемент <service> из <serviceList>
+type upnpServiceXML struct {
+	ServiceType string `xml:"serviceType"`
+	ServiceID   string `xml:"serviceId"`
+	ControlURL  string `xml:"controlURL"`
+	EventSubURL string `xml:"eventSubURL"`
+	SCPDURL     string `xml:"SCPDURL"`
+}
+
+// fetchDescription выполняет HTTP GET на location (SSDP заголовок Location) и разбирает
+// тело как XML описание устройства UPnP
+func (us *UPnPScanner) fetchDescription(client *http.Client, location string) (*upnpDeviceXML, error) {
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch device description from %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching device description from %s", resp.StatusCode, location)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device description from %s: %w", location, err)
+	}
+
+	var root upnpRootXML
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse device description from %s: %w", location, err)
+	}
+
+	return &root.Device, nil
+}
+
+// enrichFromDescription запрашивает XML описание устройства по Location UPnP протокола (если
+// он известен) и заполняет Manufacturer/Model/Hostname и Services. Ошибка запроса не
+// прерывает Discover целиком - устройство остается с тем, что уже было получено из SSDP
+func (us *UPnPScanner) enrichFromDescription(device *models.Device) {
+	location := ""
+	for _, p := range device.Protocols {
+		if p.Type == "UPnP" && p.URL != "" {
+			location = p.URL
+			break
+		}
+	}
+	if location == "" {
+		return
+	}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		us.logger.Debugf("Failed to parse UPnP Location %s: %v", location, err)
+		return
+	}
+
+	client := &http.Client{Timeout: descriptionFetchTimeout}
+	desc, err := us.fetchDescription(client, location)
+	if err != nil {
+		us.logger.Debugf("Failed to fetch UPnP device description: %v", err)
+		return
+	}
+
+	applyDeviceDescription(device, desc, base)
+}
+
+// applyDeviceDescription переносит поля XML описания в models.Device и собирает Services из
+// этого устройства и всех вложенных в deviceList, разрешая относительные
+// controlURL/eventSubURL/SCPDURL относительно base (адреса, по которому было получено само
+// описание)
+func applyDeviceDescription(device *models.Device, desc *upnpDeviceXML, base *url.URL) {
+	if desc.FriendlyName != "" {
+		device.Hostname = desc.FriendlyName
+	}
+	if desc.Manufacturer != "" {
+		device.Manufacturer = desc.Manufacturer
+	}
+	if desc.ModelName != "" {
+		device.Model = desc.ModelName
+	}
+
+	device.Services = append(device.Services, collectServices(desc, base)...)
+}
+
+// collectServices обходит устройство и все его вложенные deviceList рекурсивно и возвращает
+// сервисы каждого в виде models.UPnPService с уже абсолютными URL
+func collectServices(desc *upnpDeviceXML, base *url.URL) []models.UPnPService {
+	services := make([]models.UPnPService, 0, len(desc.ServiceList.Services))
+	for _, s := range desc.ServiceList.Services {
+		services = append(services, models.UPnPService{
+			ServiceType: s.ServiceType,
+			ServiceID:   s.ServiceID,
+			ControlURL:  resolveURL(base, s.ControlURL),
+			EventSubURL: resolveURL(base, s.EventSubURL),
+			SCPDURL:     resolveURL(base, s.SCPDURL),
+		})
+	}
+
+	for i := range desc.DeviceList.Devices {
+		services = append(services, collectServices(&desc.DeviceList.Devices[i], base)...)
+	}
+
+	return services
+}
+
+// resolveURL разрешает ref (зачастую относительный путь вида "/upnp/control/ContentDir")
+// относительно base. Пустой ref возвращает пустую строку, невалидный ref возвращается как есть
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(parsed).String()
+}
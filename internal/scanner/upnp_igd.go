@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/scanner/igd"
+)
+
+// enrichGateway ищет среди Services устройства сервис WANIPConnection/WANPPPConnection
+// (Internet Gateway Device) и, если он найден, заполняет ExternalIP и PortMappings через
+// pkg/scanner/igd.Client. Устройства, не являющиеся шлюзом, не трогает. Ошибка опроса шлюза не
+// прерывает Discover целиком - устройство остается с тем, что уже было получено из SSDP/XML
+func (us *UPnPScanner) enrichGateway(device *models.Device) {
+	service, ok := findWANConnectionService(device.Services)
+	if !ok {
+		return
+	}
+
+	client := igd.NewClient(service.ControlURL, service.ServiceType)
+
+	externalIP, err := client.GetExternalIPAddress()
+	if err != nil {
+		us.logger.Debugf("GetExternalIPAddress failed for %s: %v", service.ControlURL, err)
+	} else {
+		device.ExternalIP = externalIP
+	}
+
+	mappings, err := client.ListPortMappings()
+	if err != nil {
+		us.logger.Debugf("ListPortMappings failed for %s: %v", service.ControlURL, err)
+	}
+	for _, m := range mappings {
+		device.PortMappings = append(device.PortMappings, models.PortMapping{
+			RemoteHost:     m.RemoteHost,
+			ExternalPort:   m.ExternalPort,
+			Protocol:       m.Protocol,
+			InternalPort:   m.InternalPort,
+			InternalClient: m.InternalClient,
+			Enabled:        m.Enabled,
+			Description:    m.Description,
+			LeaseDuration:  m.LeaseDuration,
+		})
+	}
+}
+
+// findWANConnectionService ищет в services первый сервис WANIPConnection:1 или
+// WANPPPConnection:1 - Internet Gateway Device может предоставлять любой из двух в зависимости
+// от того, как WAN соединение поднято (выделенный IP либо PPPoE/PPTP туннель)
+func findWANConnectionService(services []models.UPnPService) (models.UPnPService, bool) {
+	for _, s := range services {
+		if s.ServiceType == igd.WANIPConnectionServiceType || s.ServiceType == igd.WANPPPConnectionServiceType {
+			return s, true
+		}
+	}
+	return models.UPnPService{}, false
+}
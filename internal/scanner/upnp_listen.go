@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/registry"
+)
+
+// defaultSSDPProbeInterval - как часто Listen переотправляет M-SEARCH, если caller передал 0
+const defaultSSDPProbeInterval = 60 * time.Second
+
+// Listen запускает персистентное прослушивание SSDP вместо разового Discover - тот же принцип,
+// что и scanner.ONVIFScanner.Listen для WS-Discovery: на каждом не-loopback интерфейсе
+// подключается к мультикаст группе 239.255.255.250:1900 и получает NOTIFY ssdp:alive/ssdp:byebye,
+// которые устройство рассылает само при включении/выключении, не дожидаясь чужого M-SEARCH.
+// Параллельно отдельный unicast сокет переотправляет M-SEARCH каждые probeInterval (по умолчанию
+// defaultSSDPProbeInterval), чтобы подхватить устройства, уже работавшие на момент запуска Listen
+// и не рассылающие NOTIFY до истечения своего CACHE-CONTROL max-age. Каждое обнаруженное
+// устройство отражается в reg через AddDevice/RemoveDevice - именно reg публикует
+// DeviceAdded/DeviceUpdated/DeviceRemoved, Listen эти события не дублирует. Listen блокирует
+// вызывающего, пока ctx не отменен
+func (us *UPnPScanner) Listen(ctx context.Context, reg *registry.DeviceRegistry, probeInterval time.Duration) error {
+	if probeInterval <= 0 {
+		probeInterval = defaultSSDPProbeInterval
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	listening := 0
+	for i := range ifaces {
+		iface := ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		listening++
+		go us.listenNotifyOnInterface(ctx, iface, reg)
+	}
+
+	if listening == 0 {
+		return fmt.Errorf("no multicast-capable network interface available for SSDP")
+	}
+
+	us.searchLoop(ctx, probeInterval, reg)
+	return nil
+}
+
+// listenNotifyOnInterface читает SSDP NOTIFY сообщения на одном интерфейсе, пока не отменен
+// ctx или сокет не закроется с ошибкой
+func (us *UPnPScanner) listenNotifyOnInterface(ctx context.Context, iface net.Interface, reg *registry.DeviceRegistry) {
+	group := &net.UDPAddr{IP: net.ParseIP(SSDPMulticastIPv4), Port: SSDPPort}
+	conn, err := net.ListenMulticastUDP("udp4", &iface, group)
+	if err != nil {
+		us.logger.Debugf("SSDP NOTIFY listen failed on interface %s: %v", iface.Name, err)
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buffer := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			us.logger.Debugf("SSDP NOTIFY read error on %s: %v", iface.Name, err)
+			continue
+		}
+
+		us.handleNotify(buffer[:n], addr.IP.String(), reg)
+	}
+}
+
+// handleNotify разбирает одно NOTIFY сообщение по NTS заголовку: ssdp:alive добавляет или
+// обновляет устройство в reg (после того же обогащения Location/Services/внешним IP, что и
+// Discover), ssdp:byebye удаляет его по IP отправителя - у SSDP, в отличие от WS-Discovery, нет
+// устойчивого идентификатора вроде EndpointReference, переживающего смену IP по DHCP, так что
+// RemoveDevice (не RemoveDeviceByEndpointRef) - единственный вариант. ssdp:byebye обычно не
+// содержит Location, так что сравнение идет по тому же sourceIP, под которым устройство
+// регистрировалось, если только его предыдущий ssdp:alive/M-SEARCH ответ не указывал в Location
+// другой хост - в этом (редком для потребительских камер) случае запись остается в реестре до
+// истечения PruneStale TTL, как и для пропущенного WS-Discovery Bye (см. deviceWatchKey)
+func (us *UPnPScanner) handleNotify(data []byte, sourceIP string, reg *registry.DeviceRegistry) {
+	response := string(data)
+	if !strings.HasPrefix(response, "NOTIFY") {
+		return
+	}
+
+	ssdpResp := us.parseSSDPHeaders(response)
+	switch ssdpResp.NTS {
+	case "ssdp:alive":
+		device := us.deviceFromSSDP(ssdpResp, sourceIP)
+		go us.enrichAndRegister(device, reg)
+	case "ssdp:byebye":
+		reg.RemoveDevice(sourceIP)
+	}
+}
+
+// enrichAndRegister запрашивает описание/сервисы/внешний IP устройства, как Discover, но
+// только если оно еще не известно reg - ssdp:alive повторяется каждые несколько минут, пока
+// устройство работает, и M-SEARCH переотправляется каждый probeInterval, так что без этой
+// проверки уже зарегистрированное устройство опрашивалось бы заново на каждое такое сообщение.
+// Для уже известного устройства вместо повторного обогащения просто обновляется LastSeen/Online
+// через UpdateDeviceState с no-op функцией. Запускается в отдельной горутине (см. handleNotify/
+// readSearchResponses), чтобы HTTP запросы за description.xml не задерживали чтение следующих
+// NOTIFY/M-SEARCH ответов
+func (us *UPnPScanner) enrichAndRegister(device *models.Device, reg *registry.DeviceRegistry) {
+	if _, exists := reg.GetDevice(device.IP); exists {
+		reg.UpdateDeviceState(device.IP, func(*models.Device) {})
+		return
+	}
+
+	us.enrichFromDescription(device)
+	us.enrichStreams(device)
+	us.enrichGateway(device)
+	reg.AddDevice(device)
+}
+
+// searchLoop держит один unicast UDP сокет на все время жизни Listen: ответы на M-SEARCH
+// приходят unicast на сокет отправителя, а не в мультикаст группу, поэтому, в отличие от
+// WS-Discovery probeLoop (который переиспользует уже слушающий мультикаст сокет), отправка и
+// чтение здесь обязаны делить один и тот же сокет. Блокирует вызывающего, пока не отменен ctx
+func (us *UPnPScanner) searchLoop(ctx context.Context, interval time.Duration, reg *registry.DeviceRegistry) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		us.logger.Debugf("SSDP periodic M-SEARCH failed to open socket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go us.readSearchResponses(ctx, conn, reg)
+
+	us.sendSearch(conn)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			us.sendSearch(conn)
+		}
+	}
+}
+
+// sendSearch рассылает M-SEARCH для каждого upnpSearchTargets в мультикаст группу через conn
+func (us *UPnPScanner) sendSearch(conn *net.UDPConn) {
+	multicastAddr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", SSDPMulticastIPv4, SSDPPort))
+	if err != nil {
+		us.logger.Debugf("SSDP periodic M-SEARCH failed to resolve multicast address: %v", err)
+		return
+	}
+
+	for _, st := range upnpSearchTargets {
+		msearch := us.buildMSearchRequest(st, fmt.Sprintf("%s:%d", SSDPMulticastIPv4, SSDPPort))
+		if _, err := conn.WriteToUDP([]byte(msearch), multicastAddr); err != nil {
+			us.logger.Debugf("SSDP periodic M-SEARCH failed to send for %s: %v", st, err)
+		}
+	}
+}
+
+// readSearchResponses читает unicast ответы на M-SEARCH с conn и обогащает+регистрирует их в
+// reg так же, как ssdp:alive NOTIFY. Работает, пока conn не закроется (Listen закрывает его по
+// отмене ctx)
+func (us *UPnPScanner) readSearchResponses(ctx context.Context, conn *net.UDPConn, reg *registry.DeviceRegistry) {
+	buffer := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			us.logger.Debugf("SSDP M-SEARCH response read error: %v", err)
+			continue
+		}
+
+		device, err := us.parseSSDPResponse(buffer[:n], addr.IP.String())
+		if err != nil {
+			us.logger.Debugf("Failed to parse SSDP M-SEARCH response: %v", err)
+			continue
+		}
+
+		go us.enrichAndRegister(device, reg)
+	}
+}
@@ -0,0 +1,210 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// soapActionTimeout - таймаут отдельного SOAP запроса к уже найденному UPnP сервису
+const soapActionTimeout = 5 * time.Second
+
+// Типы сервисов UPnP A/V Architecture, для которых этот сканер умеет строить SOAP запросы
+// (UPnP ContentDirectory:1 / AVTransport:1 Service Template)
+const (
+	contentDirectoryServiceType = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	avTransportServiceType      = "urn:schemas-upnp-org:service:AVTransport:1"
+)
+
+// upnpSOAPCall отправляет SOAP 1.1 запрос action с аргументами argsXML на controlURL сервиса
+// serviceType (как huin/goupnp/soap.SOAPClient.PerformAction) и возвращает разобранное тело
+// ответа <Body>...</Body>
+func upnpSOAPCall(controlURL, serviceType, action, argsXML string) ([]byte, error) {
+	envelope := fmt.Sprintf(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:%s xmlns:u="%s">%s</u:%s>
+  </s:Body>
+</s:Envelope>`, action, serviceType, argsXML, action)
+
+	req, err := http.NewRequest(http.MethodPost, controlURL, bytes.NewBufferString(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SOAP request: %w", err)
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, serviceType, action))
+
+	client := &http.Client{Timeout: soapActionTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SOAP request to %s failed: %w", controlURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SOAP response from %s: %w", controlURL, err)
+	}
+
+	return data, nil
+}
+
+type browseResponseXML struct {
+	Body struct {
+		BrowseResponse struct {
+			Result string `xml:"Result"`
+		} `xml:"BrowseResponse"`
+	} `xml:"Body"`
+}
+
+// didlLiteXML - DIDL-Lite документ (ContentDirectory:1, раздел 2.3.12), который ContentDirectory
+// возвращает как экранированную строку внутри элемента <Result> ответа Browse
+type didlLiteXML struct {
+	XMLName xml.Name      `xml:"DIDL-Lite"`
+	Items   []didlItemXML `xml:"item"`
+}
+
+type didlItemXML struct {
+	Title string   `xml:"title"`
+	Class string   `xml:"class"`
+	Res   []string `xml:"res"`
+}
+
+// ContentDirectoryBrowse выполняет действие Browse (ContentDirectory:1, раздел 2.7.4.2) над
+// objectID ("0" - корень) и возвращает прямые медиа URL (элементы <res>) найденных объектов
+func ContentDirectoryBrowse(service models.UPnPService, objectID string) ([]string, error) {
+	args := fmt.Sprintf(`<ObjectID>%s</ObjectID>
+<BrowseFlag>BrowseDirectChildren</BrowseFlag>
+<Filter>*</Filter>
+<StartingIndex>0</StartingIndex>
+<RequestedCount>0</RequestedCount>
+<SortCriteria></SortCriteria>`, objectID)
+
+	data, err := upnpSOAPCall(service.ControlURL, service.ServiceType, "Browse", args)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp browseResponseXML
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse Browse response: %w", err)
+	}
+
+	var didl didlLiteXML
+	if err := xml.Unmarshal([]byte(resp.Body.BrowseResponse.Result), &didl); err != nil {
+		return nil, fmt.Errorf("failed to parse DIDL-Lite result: %w", err)
+	}
+
+	urls := make([]string, 0, len(didl.Items))
+	for _, item := range didl.Items {
+		urls = append(urls, item.Res...)
+	}
+	return urls, nil
+}
+
+type getMediaInfoResponseXML struct {
+	Body struct {
+		GetMediaInfoResponse struct {
+			CurrentURI string `xml:"CurrentURI"`
+		} `xml:"GetMediaInfoResponse"`
+	} `xml:"Body"`
+}
+
+// AVTransportGetMediaInfo выполняет действие GetMediaInfo (AVTransport:1, раздел 2.4.2) для
+// InstanceID 0 и возвращает CurrentURI - адрес потока, который сейчас проигрывается/выставлен
+// на этом AVTransport (MediaRenderer)
+func AVTransportGetMediaInfo(service models.UPnPService) (string, error) {
+	args := `<InstanceID>0</InstanceID>`
+
+	data, err := upnpSOAPCall(service.ControlURL, service.ServiceType, "GetMediaInfo", args)
+	if err != nil {
+		return "", err
+	}
+
+	var resp getMediaInfoResponseXML
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse GetMediaInfo response: %w", err)
+	}
+
+	if resp.Body.GetMediaInfoResponse.CurrentURI == "" {
+		return "", fmt.Errorf("empty CurrentURI in GetMediaInfo response")
+	}
+	return resp.Body.GetMediaInfoResponse.CurrentURI, nil
+}
+
+// enrichStreams опрашивает ContentDirectory/AVTransport сервисы устройства (если они есть) и
+// добавляет найденные видео URL как Protocol записи - чтобы UPnPScanner не просто обнаруживал
+// DLNA устройства, а сразу сообщал, какие потоки на них доступны. Ошибка опроса отдельного
+// сервиса не прерывает обход остальных
+func (us *UPnPScanner) enrichStreams(device *models.Device) {
+	for _, service := range device.Services {
+		switch service.ServiceType {
+		case contentDirectoryServiceType:
+			urls, err := ContentDirectoryBrowse(service, "0")
+			if err != nil {
+				us.logger.Debugf("ContentDirectory Browse failed for %s: %v", service.ControlURL, err)
+				continue
+			}
+			for _, streamURL := range urls {
+				addStreamProtocol(device, streamURL)
+			}
+
+		case avTransportServiceType:
+			streamURL, err := AVTransportGetMediaInfo(service)
+			if err != nil {
+				us.logger.Debugf("AVTransport GetMediaInfo failed for %s: %v", service.ControlURL, err)
+				continue
+			}
+			addStreamProtocol(device, streamURL)
+		}
+	}
+}
+
+// addStreamProtocol добавляет streamURL как Protocol запись устройства, если такой URL еще
+// не был добавлен, определяя тип протокола по схеме/расширению URL
+func addStreamProtocol(device *models.Device, streamURL string) {
+	for _, p := range device.Protocols {
+		if p.URL == streamURL {
+			return
+		}
+	}
+
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return
+	}
+
+	protocolType := "HTTP"
+	switch {
+	case u.Scheme == "rtsp":
+		protocolType = "RTSP"
+	case strings.HasSuffix(u.Path, ".m3u8"):
+		protocolType = "HLS"
+	case strings.HasSuffix(u.Path, ".mpd"):
+		protocolType = "DASH"
+	}
+
+	port := 80
+	if u.Port() != "" {
+		fmt.Sscanf(u.Port(), "%d", &port)
+	} else if u.Scheme == "rtsp" {
+		port = 554
+	} else if u.Scheme == "https" {
+		port = 443
+	}
+
+	device.Protocols = append(device.Protocols, models.Protocol{
+		Type:       protocolType,
+		Port:       port,
+		URL:        streamURL,
+		Available:  true,
+		DetectedAt: time.Now(),
+	})
+}
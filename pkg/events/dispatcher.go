@@ -0,0 +1,217 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/pkg/utils"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DispatcherConfig - параметры Dispatcher'а (соответствует models.EventsConfig)
+type DispatcherConfig struct {
+	// QueuePath - путь к файлу BoltDB с очередями недоставленных событий
+	QueuePath string
+
+	// MaxBatchSize - максимальное число событий в одной доставке Sink'у
+	MaxBatchSize int
+
+	// FlushInterval - период, с которым накопленные события сбрасываются Sink'у, даже
+	// если MaxBatchSize не набран
+	FlushInterval time.Duration
+
+	// MaxQueueSize - максимальный размер очереди одного Sink'а, 0 - без ограничения
+	MaxQueueSize int
+
+	// RetryBackoff - начальная задержка между повторными попытками доставки партии
+	// (удваивается после каждой неудачи)
+	RetryBackoff time.Duration
+
+	// MaxRetries - сколько раз повторить доставку партии в рамках одного флаша, прежде
+	// чем оставить ее в очереди до следующего тика FlushInterval
+	MaxRetries int
+}
+
+func (c DispatcherConfig) withDefaults() DispatcherConfig {
+	if c.MaxBatchSize <= 0 {
+		c.MaxBatchSize = 20
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 5 * time.Second
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 2 * time.Second
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = 5
+	}
+	return c
+}
+
+// Dispatcher ставит события в персистентную очередь по одной на каждый зарегистрированный
+// Sink и асинхронно сбрасывает их партиями (см. DispatcherConfig.MaxBatchSize/FlushInterval),
+// повторяя неудачные доставки с экспоненциальной задержкой. Одно и то же событие доставляется
+// каждому Sink'у независимо - медленный или недоступный приемник не блокирует остальные
+type Dispatcher struct {
+	cfg    DispatcherConfig
+	db     *bolt.DB
+	logger *logrus.Logger
+
+	mu    sync.Mutex
+	sinks []*sinkWorker
+	wg    sync.WaitGroup
+}
+
+type sinkWorker struct {
+	sink    Sink
+	queue   *queue
+	trigger chan struct{}
+	done    chan struct{}
+}
+
+// NewDispatcher открывает (создавая при необходимости) файл BoltDB с очередями по пути
+// cfg.QueuePath
+func NewDispatcher(cfg DispatcherConfig) (*Dispatcher, error) {
+	cfg = cfg.withDefaults()
+
+	db, err := bolt.Open(cfg.QueuePath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event queue database %s: %w", cfg.QueuePath, err)
+	}
+
+	return &Dispatcher{cfg: cfg, db: db, logger: utils.GetLogger()}, nil
+}
+
+// Register подключает sink к диспетчеру и запускает его фоновый воркер флаша. Недоставленные
+// события, оставшиеся в очереди sink'а с прошлого запуска (тот же QueuePath и Sink.Name),
+// подхватываются автоматически
+func (d *Dispatcher) Register(sink Sink) error {
+	d.mu.Lock()
+	for _, existing := range d.sinks {
+		if existing.sink.Name() == sink.Name() {
+			d.mu.Unlock()
+			return fmt.Errorf("sink %q is already registered", sink.Name())
+		}
+	}
+	d.mu.Unlock()
+
+	q, err := newQueue(d.db, sink.Name(), d.cfg.MaxQueueSize)
+	if err != nil {
+		return err
+	}
+
+	w := &sinkWorker{
+		sink:    sink,
+		queue:   q,
+		trigger: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	d.mu.Lock()
+	d.sinks = append(d.sinks, w)
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	go d.runWorker(w)
+	return nil
+}
+
+// Publish ставит событие в очередь каждого зарегистрированного sink'а. Само событие не
+// блокирует вызывающего на доставке - она происходит асинхронно в фоновых воркерах
+func (d *Dispatcher) Publish(e Event) {
+	d.mu.Lock()
+	sinks := append([]*sinkWorker(nil), d.sinks...)
+	d.mu.Unlock()
+
+	for _, w := range sinks {
+		if err := w.queue.Push(e); err != nil {
+			d.logger.Errorf("failed to queue event %s for sink %s: %v", e.ID, w.sink.Name(), err)
+			continue
+		}
+		select {
+		case w.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// runWorker сбрасывает очередь sink'а партиями по сигналу Publish или по таймеру
+// FlushInterval, в зависимости от того, что наступит раньше
+func (d *Dispatcher) runWorker(w *sinkWorker) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.trigger:
+			d.flush(w)
+		case <-ticker.C:
+			d.flush(w)
+		}
+	}
+}
+
+// flush отправляет sink'у до MaxBatchSize самых старых событий его очереди, повторяя при
+// ошибке с экспоненциальной задержкой. Партия, не доставленная за MaxRetries попыток,
+// остается в очереди и будет повторена на следующем флаше
+func (d *Dispatcher) flush(w *sinkWorker) {
+	entries, err := w.queue.Peek(d.cfg.MaxBatchSize)
+	if err != nil {
+		d.logger.Errorf("failed to read queue for sink %s: %v", w.sink.Name(), err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	batch := make([]Event, len(entries))
+	keys := make([][]byte, len(entries))
+	for i, e := range entries {
+		batch[i] = e.Event
+		keys[i] = e.Key
+	}
+
+	backoff := d.cfg.RetryBackoff
+	for attempt := 0; attempt <= d.cfg.MaxRetries; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := w.sink.Send(ctx, batch)
+		cancel()
+
+		if err == nil {
+			if err := w.queue.Ack(keys); err != nil {
+				d.logger.Errorf("failed to ack delivered events for sink %s: %v", w.sink.Name(), err)
+			}
+			return
+		}
+
+		d.logger.Warnf("sink %s: delivery attempt %d/%d failed: %v", w.sink.Name(), attempt+1, d.cfg.MaxRetries+1, err)
+		if attempt < d.cfg.MaxRetries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	d.logger.Errorf("sink %s: giving up on batch of %d event(s) after %d attempts, will retry on next flush", w.sink.Name(), len(batch), d.cfg.MaxRetries+1)
+}
+
+// Close останавливает все воркеры, дожидаясь завершения уже начатых флашей, и закрывает файл
+// очереди. Недоставленные события остаются в файле и будут подхвачены при следующем
+// NewDispatcher с тем же QueuePath
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	sinks := d.sinks
+	d.mu.Unlock()
+
+	for _, w := range sinks {
+		close(w.done)
+	}
+	d.wg.Wait()
+	return d.db.Close()
+}
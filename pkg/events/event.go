@@ -0,0 +1,42 @@
+// Package events рассылает события обнаружения устройств (DeviceDiscovered,
+// ProtocolDetected, DeviceLost, ScanCompleted) во внешние системы через набор
+// зарегистрированных Sink'ов. Dispatcher ставит события в персистентную очередь (BoltDB, по
+// одному bucket'у на Sink) и сбрасывает их партиями с повторными попытками - поэтому доставка
+// переживает как временную недоступность приемника, так и перезапуск самого процесса
+package events
+
+import (
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// Type - тип события жизненного цикла обнаружения
+type Type string
+
+const (
+	// DeviceDiscovered - устройство обнаружено впервые в рамках текущего процесса
+	DeviceDiscovered Type = "device_discovered"
+
+	// ProtocolDetected - на уже известном или только что обнаруженном устройстве найден
+	// протокол, которого не было в предыдущем состоянии
+	ProtocolDetected Type = "protocol_detected"
+
+	// DeviceLost - устройство, видимое в прошлом скане, отсутствует в текущем
+	DeviceLost Type = "device_lost"
+
+	// ScanCompleted - цикл сканирования завершен
+	ScanCompleted Type = "scan_completed"
+)
+
+// Event - одно событие пайплайна сканирования, отправляемое во все зарегистрированные Sink'и
+type Event struct {
+	ID        string         `json:"id"`
+	Type      Type           `json:"type"`
+	Timestamp time.Time      `json:"timestamp"`
+	Device    *models.Device `json:"device,omitempty"`
+
+	// Protocol - протокол, из-за которого возникло событие (заполнен только для
+	// ProtocolDetected)
+	Protocol *models.Protocol `json:"protocol,omitempty"`
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// queue - персистентная ограниченная очередь недоставленных событий одного Sink'а, хранящая
+// их в собственном bucket'е общего файла BoltDB. Ключ - монотонно растущий seq id (см.
+// bolt.Bucket.NextSequence), поэтому Peek всегда возвращает события в порядке постановки
+type queue struct {
+	db      *bolt.DB
+	bucket  []byte
+	maxSize int
+
+	// size - число записей в очереди, поддерживается в памяти, чтобы trim не вычислял
+	// bucket.Stats().KeyN (полный проход по страницам bucket'а) на каждый Push
+	size int64
+}
+
+// queuedEvent - событие вместе с ключом, под которым оно хранится в очереди, нужным для Ack
+type queuedEvent struct {
+	Key   []byte
+	Event Event
+}
+
+func newQueue(db *bolt.DB, sinkName string, maxSize int) (*queue, error) {
+	bucket := []byte("events/" + sinkName)
+	var size int64
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		size = int64(b.Stats().KeyN)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue bucket for sink %s: %w", sinkName, err)
+	}
+	return &queue{db: db, bucket: bucket, maxSize: maxSize, size: size}, nil
+}
+
+// Push добавляет событие в конец очереди, отбрасывая самые старые записи, если после
+// добавления очередь превышает maxSize
+func (q *queue) Push(e Event) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(q.bucket)
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("failed to allocate queue sequence: %w", err)
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", e.ID, err)
+		}
+		if err := b.Put(seqKey(seq), data); err != nil {
+			return err
+		}
+		atomic.AddInt64(&q.size, 1)
+
+		return q.trim(b)
+	})
+}
+
+// trim удаляет самые старые записи, пока их не останется не больше maxSize. maxSize <= 0
+// означает отсутствие ограничения. Использует поддерживаемый в памяти q.size вместо
+// b.Stats().KeyN, который требует полного прохода по страницам bucket'а
+func (q *queue) trim(b *bolt.Bucket) error {
+	if q.maxSize <= 0 {
+		return nil
+	}
+	for atomic.LoadInt64(&q.size) > int64(q.maxSize) {
+		c := b.Cursor()
+		k, _ := c.First()
+		if k == nil {
+			break
+		}
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+		atomic.AddInt64(&q.size, -1)
+	}
+	return nil
+}
+
+// Peek возвращает до n самых старых событий очереди, не удаляя их
+func (q *queue) Peek(n int) ([]queuedEvent, error) {
+	var result []queuedEvent
+	err := q.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(q.bucket).Cursor()
+		for k, v := c.First(); k != nil && len(result) < n; k, v = c.Next() {
+			var e Event
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("failed to decode queued event: %w", err)
+			}
+			key := make([]byte, len(k))
+			copy(key, k)
+			result = append(result, queuedEvent{Key: key, Event: e})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Ack удаляет из очереди события, доставленные приемнику
+func (q *queue) Ack(keys [][]byte) error {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(q.bucket)
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&q.size, -int64(len(keys)))
+	return nil
+}
+
+func seqKey(seq uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, seq)
+	return buf
+}
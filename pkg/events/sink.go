@@ -0,0 +1,16 @@
+package events
+
+import "context"
+
+// Sink - приемник партии событий. Dispatcher вызывает Send с накопленной партией (размер
+// ограничен DispatcherConfig.MaxBatchSize) и сам отвечает за повтор при ошибке - реализации
+// Sink не обязаны делать это самостоятельно. WebhookSender и StdoutSink - готовые реализации;
+// приемники вроде NATS/MQTT подключаются так же, просто реализовав этот интерфейс
+type Sink interface {
+	// Name - уникальное имя приемника, используется как ключ его персистентной очереди
+	Name() string
+
+	// Send доставляет партию событий. Ошибка означает, что партия не подтверждена и
+	// останется в очереди для повторной попытки
+	Send(ctx context.Context, batch []Event) error
+}
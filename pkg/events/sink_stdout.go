@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// StdoutSink пишет каждое событие партии как отдельную строку JSON в w - простой Sink для
+// отладки и локальной разработки без настоящего приемника вебхуков
+type StdoutSink struct {
+	name string
+	w    io.Writer
+}
+
+// NewStdoutSink создает StdoutSink с именем name, пишущий в w (обычно os.Stdout)
+func NewStdoutSink(name string, w io.Writer) *StdoutSink {
+	return &StdoutSink{name: name, w: w}
+}
+
+// Name возвращает имя приемника
+func (s *StdoutSink) Name() string {
+	return s.name
+}
+
+// Send пишет каждое событие партии отдельной строкой
+func (s *StdoutSink) Send(ctx context.Context, batch []Event) error {
+	for _, e := range batch {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", e.ID, err)
+		}
+		if _, err := fmt.Fprintln(s.w, string(data)); err != nil {
+			return fmt.Errorf("failed to write event %s: %w", e.ID, err)
+		}
+	}
+	return nil
+}
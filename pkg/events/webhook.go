@@ -0,0 +1,78 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookTimeout - таймаут одного HTTP POST запроса к приемнику
+const webhookTimeout = 10 * time.Second
+
+// WebhookSender - Sink, доставляющий партию событий одним HTTP POST запросом с телом в виде
+// JSON массива событий. Если задан Secret, тело подписывается HMAC-SHA256 и подпись кладется
+// в заголовок X-LVS-Signature в формате "sha256=<hex>" (как это делает GitHub для своих
+// вебхуков), чтобы приемник мог проверить, что запрос пришел действительно от этого сервера
+type WebhookSender struct {
+	name   string
+	target string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookSender создает WebhookSender с именем name (используется как ключ очереди и в
+// логах), адресом target и необязательным secret для подписи тела запроса
+func NewWebhookSender(name, target, secret string) *WebhookSender {
+	return &WebhookSender{
+		name:   name,
+		target: target,
+		secret: secret,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Name возвращает имя приемника
+func (s *WebhookSender) Name() string {
+	return s.name
+}
+
+// Send кодирует batch в JSON массив и отправляет его POST запросом на target
+func (s *WebhookSender) Send(ctx context.Context, batch []Event) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode event batch: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set("X-LVS-Signature", "sha256="+signHMACSHA256(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request to %s failed: %w", s.target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned status %d", s.target, resp.StatusCode)
+	}
+	return nil
+}
+
+// signHMACSHA256 возвращает hex-кодированную HMAC-SHA256 подпись body с ключом secret
+func signHMACSHA256(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,13 +1,16 @@
 package export
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
+	"github.com/local-video-server/internal/metrics"
 	"github.com/local-video-server/internal/models"
 	"gopkg.in/yaml.v3"
 )
@@ -17,6 +20,14 @@ type Exporter interface {
 	Export(devices []*models.Device, filename string) error
 }
 
+// WriterExporter - экспортеры, которые умеют писать результат сразу в io.Writer, без
+// привязки к файлу на диске. Реализуют его только построчные форматы (JSON, NDJSON),
+// для которых имеет смысл подключить на выходе stdout, pipe в jq, Elasticsearch bulk API
+// или Kafka producer - см. ExportToWriter и ExportStream
+type WriterExporter interface {
+	ExportWriter(devices []*models.Device, w io.Writer) error
+}
+
 // JSONExporter - экспорт в JSON
 type JSONExporter struct{}
 
@@ -28,7 +39,12 @@ func (e *JSONExporter) Export(devices []*models.Device, filename string) error {
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
+	return e.ExportWriter(devices, file)
+}
+
+// ExportWriter экспортирует устройства единым JSON-массивом в переданный io.Writer
+func (e *JSONExporter) ExportWriter(devices []*models.Device, w io.Writer) error {
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 
 	if err := encoder.Encode(devices); err != nil {
@@ -146,13 +162,53 @@ func (e *YAMLExporter) Export(devices []*models.Device, filename string) error {
 	return nil
 }
 
-// ExportToFile экспортирует устройства в указанный формат
+// NDJSONExporter - экспорт в формате NDJSON (newline-delimited JSON): каждое устройство
+// записывается отдельной строкой вместо одного общего массива, что позволяет
+// обрабатывать результат построчно - в jq, Elasticsearch bulk API, Kafka producer - не
+// дожидаясь записи всех устройств и не держа весь список в памяти на стороне читателя
+type NDJSONExporter struct{}
+
+// Export экспортирует устройства построчно в NDJSON файл
+func (e *NDJSONExporter) Export(devices []*models.Device, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	return e.ExportWriter(devices, file)
+}
+
+// ExportWriter экспортирует устройства построчно в переданный io.Writer
+func (e *NDJSONExporter) ExportWriter(devices []*models.Device, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, device := range devices {
+		if err := encoder.Encode(device); err != nil {
+			return fmt.Errorf("failed to encode device %s: %w", device.IP, err)
+		}
+	}
+	return nil
+}
+
+// stdoutFilename - условное имя файла, означающее "писать в stdout вместо файла на
+// диске" для ExportToFile - удобно для конвейеров вида `scan --export -| jq .`
+const stdoutFilename = "-"
+
+// ExportToFile экспортирует устройства в указанный формат. filename, равный "-",
+// означает stdout - в этом случае вызов делегируется в ExportToWriter, что позволяет
+// пайпить результат в jq, Elasticsearch bulk API или Kafka producer без временного файла
 func ExportToFile(devices []*models.Device, format string, filename string) error {
+	if filename == stdoutFilename {
+		return ExportToWriter(devices, format, os.Stdout)
+	}
+
 	var exporter Exporter
 
 	switch format {
 	case "json":
 		exporter = &JSONExporter{}
+	case "ndjson", "jsonl":
+		exporter = &NDJSONExporter{}
 	case "csv":
 		exporter = &CSVExporter{}
 	case "xml":
@@ -160,10 +216,79 @@ func ExportToFile(devices []*models.Device, format string, filename string) erro
 	case "yaml", "yml":
 		exporter = &YAMLExporter{}
 	default:
-		return fmt.Errorf("unsupported format: %s (supported: json, csv, xml, yaml)", format)
+		return fmt.Errorf("unsupported format: %s (supported: json, ndjson, csv, xml, yaml)", format)
+	}
+
+	start := time.Now()
+	err := exporter.Export(devices, filename)
+	metrics.ExportDuration.WithLabelValues(format).Observe(time.Since(start).Seconds())
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.ExportsTotal.WithLabelValues(format, result).Inc()
+
+	return err
+}
+
+// ExportToWriter работает как ExportToFile, но пишет результат в переданный io.Writer
+// вместо того, чтобы создавать файл - так, например, вызывающий код передает os.Stdout,
+// когда пользователь указал "-" как путь экспорта. Поддерживает только построчные
+// форматы (json, ndjson), для которых есть реализация WriterExporter
+func ExportToWriter(devices []*models.Device, format string, w io.Writer) error {
+	var exporter WriterExporter
+
+	switch format {
+	case "json":
+		exporter = &JSONExporter{}
+	case "ndjson", "jsonl":
+		exporter = &NDJSONExporter{}
+	default:
+		return fmt.Errorf("unsupported streaming format: %s (supported: json, ndjson)", format)
 	}
 
-	return exporter.Export(devices, filename)
+	start := time.Now()
+	err := exporter.ExportWriter(devices, w)
+	metrics.ExportDuration.WithLabelValues(format).Observe(time.Since(start).Seconds())
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.ExportsTotal.WithLabelValues(format, result).Inc()
+
+	return err
+}
+
+// ExportStream построчно записывает в w устройства, поступающие из ch, в формате NDJSON,
+// по мере их обнаружения - не дожидаясь закрытия канала и не накапливая список устройств
+// в памяти. Это стандартная форма для долгоживущих discovery-инструментов: результат
+// можно сразу пайпить в jq, Elasticsearch bulk API или Kafka producer. Завершается, когда
+// ch закрывается, или раньше, если отменяется ctx. Если w - *os.File, раз в fsyncInterval
+// (если > 0) выполняется File.Sync, чтобы читатель на другом конце пайпа не ждал буферизации
+func ExportStream(ctx context.Context, ch <-chan *models.Device, w io.Writer, fsyncInterval time.Duration) error {
+	encoder := json.NewEncoder(w)
+	file, canSync := w.(*os.File)
+	lastSync := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case device, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := encoder.Encode(device); err != nil {
+				return fmt.Errorf("failed to encode device %s: %w", device.IP, err)
+			}
+			if canSync && fsyncInterval > 0 && time.Since(lastSync) >= fsyncInterval {
+				_ = file.Sync()
+				lastSync = time.Now()
+			}
+		}
+	}
 }
 
 // ExportToMultipleFormats экспортирует устройства в несколько форматов одновременно
@@ -173,6 +298,8 @@ func ExportToMultipleFormats(devices []*models.Device, baseFilename string, form
 		switch format {
 		case "json":
 			ext = ".json"
+		case "ndjson", "jsonl":
+			ext = ".ndjson"
 		case "csv":
 			ext = ".csv"
 		case "xml":
@@ -0,0 +1,163 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// csvHeader - колонки CSV отчета. Устройство со своими протоколами и потоками
+// разворачивается в несколько строк, каждая описывает одну обнаруженную сущность
+var csvHeader = []string{
+	"IP", "MAC", "Hostname", "Manufacturer", "Model",
+	"Kind", "Type", "URL", "Detail", "Available",
+	"DiscoveredAt", "LastSeen",
+}
+
+// CSVFormatter выводит одну строку на протокол/RTSP-поток/RTMP-поток/MJPEG-поток устройства
+// (или одну строку на устройство, если ни одного из них не обнаружено), что делает
+// отчет пригодным для открытия в табличных редакторах и для обработки awk/pandas
+type CSVFormatter struct {
+	writer io.Writer
+}
+
+func newCSVFormatter(cfg options) *CSVFormatter {
+	return &CSVFormatter{writer: cfg.writer}
+}
+
+// PrintDevices выводит устройства в виде плоской CSV таблицы
+func (f *CSVFormatter) PrintDevices(devices []*models.Device) {
+	w := csv.NewWriter(f.writer)
+	defer w.Flush()
+
+	if err := w.Write(csvHeader); err != nil {
+		printDiagnostic("error", fmt.Sprintf("failed to write CSV header: %v", err))
+		return
+	}
+
+	for _, device := range devices {
+		rows := deviceCSVRows(device)
+		for _, row := range rows {
+			if err := w.Write(row); err != nil {
+				printDiagnostic("error", fmt.Sprintf("failed to write CSV row for %s: %v", device.IP, err))
+				return
+			}
+		}
+	}
+}
+
+// deviceCSVRows разворачивает одно устройство в одну или несколько CSV строк: по одной
+// на протокол, RTSP поток, RTMP поток и MJPEG поток, либо единственную строку "device",
+// если ничего из этого не обнаружено
+func deviceCSVRows(device *models.Device) [][]string {
+	base := []string{
+		device.IP,
+		device.MAC,
+		device.Hostname,
+		device.Manufacturer,
+		device.Model,
+	}
+	discoveredAt := formatCSVTime(device.DiscoveredAt)
+	lastSeen := formatCSVTime(device.LastSeen)
+
+	var rows [][]string
+
+	for _, protocol := range device.Protocols {
+		rows = append(rows, append(append([]string{}, base...),
+			"protocol",
+			protocol.Type,
+			protocol.URL,
+			fmt.Sprintf("port=%d", protocol.Port),
+			strconv.FormatBool(protocol.Available),
+			discoveredAt,
+			lastSeen,
+		))
+	}
+
+	for _, stream := range device.RTSPStreams {
+		rows = append(rows, append(append([]string{}, base...),
+			"rtsp_stream",
+			stream.Codec,
+			stream.URL,
+			fmt.Sprintf("resolution=%s fps=%.2f", stream.Resolution, stream.FPS),
+			strconv.FormatBool(stream.Available),
+			discoveredAt,
+			lastSeen,
+		))
+	}
+
+	for _, stream := range device.RTMPStreams {
+		rows = append(rows, append(append([]string{}, base...),
+			"rtmp_stream",
+			stream.Codec,
+			stream.URL,
+			fmt.Sprintf("resolution=%s fps=%.2f", stream.Resolution, stream.FPS),
+			strconv.FormatBool(stream.Available),
+			discoveredAt,
+			lastSeen,
+		))
+	}
+
+	for _, stream := range device.MJPEGStreams {
+		rows = append(rows, append(append([]string{}, base...),
+			"mjpeg_stream",
+			"MJPEG",
+			stream.URL,
+			fmt.Sprintf("resolution=%dx%d", stream.Width, stream.Height),
+			strconv.FormatBool(stream.Available),
+			discoveredAt,
+			lastSeen,
+		))
+	}
+
+	if len(rows) == 0 {
+		rows = append(rows, append(append([]string{}, base...),
+			"device", "", "", "",
+			strconv.FormatBool(false),
+			discoveredAt,
+			lastSeen,
+		))
+	}
+
+	return rows
+}
+
+// formatCSVTime форматирует time.Time для CSV, оставляя пустую ячейку для нулевого значения
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// PrintSummary для CSV не выводит ничего в stdout, чтобы не нарушать табличный формат
+func (f *CSVFormatter) PrintSummary(devices []*models.Device) {}
+
+// PrintProgress выводит ход сканирования диагностической строкой в stderr
+func (f *CSVFormatter) PrintProgress(current, total int, message string) {
+	printDiagnostic("progress", fmt.Sprintf("[%d/%d] %s", current, total, message))
+}
+
+// PrintError выводит ошибку диагностической строкой в stderr
+func (f *CSVFormatter) PrintError(message string) {
+	printDiagnostic("error", message)
+}
+
+// PrintWarning выводит предупреждение диагностической строкой в stderr
+func (f *CSVFormatter) PrintWarning(message string) {
+	printDiagnostic("warning", message)
+}
+
+// PrintSuccess выводит успешное сообщение диагностической строкой в stderr
+func (f *CSVFormatter) PrintSuccess(message string) {
+	printDiagnostic("success", message)
+}
+
+// PrintInfo выводит информационное сообщение диагностической строкой в stderr
+func (f *CSVFormatter) PrintInfo(message string) {
+	printDiagnostic("info", message)
+}
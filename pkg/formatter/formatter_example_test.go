@@ -9,8 +9,11 @@ import (
 
 // ExampleFormatter демонстрирует использование форматтера
 func ExampleFormatter() {
-	// Создаем форматтер с цветами и детальным выводом
-	f := formatter.NewFormatter(true, false)
+	// Создаем текстовый форматтер с цветами и табличным выводом
+	f, err := formatter.NewFormatter("text", formatter.WithColors(true), formatter.WithDetailed(false))
+	if err != nil {
+		return
+	}
 
 	// Создаем тестовые данные
 	devices := []*models.Device{
@@ -43,8 +46,11 @@ func ExampleFormatter() {
 
 // ExampleFormatterDetailed демонстрирует детальный вывод
 func ExampleFormatterDetailed() {
-	// Создаем форматтер с детальным выводом
-	f := formatter.NewFormatter(true, true)
+	// Создаем текстовый форматтер с детальным выводом
+	f, err := formatter.NewFormatter("text", formatter.WithColors(true), formatter.WithDetailed(true))
+	if err != nil {
+		return
+	}
 
 	devices := []*models.Device{
 		{
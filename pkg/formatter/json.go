@@ -0,0 +1,56 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// JSONFormatter выводит устройства единым JSON-массивом в writer, заданный опциями
+type JSONFormatter struct {
+	writer io.Writer
+}
+
+func newJSONFormatter(cfg options) *JSONFormatter {
+	return &JSONFormatter{writer: cfg.writer}
+}
+
+// PrintDevices выводит устройства как один отформатированный JSON-массив
+func (f *JSONFormatter) PrintDevices(devices []*models.Device) {
+	encoder := json.NewEncoder(f.writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(devices); err != nil {
+		printDiagnostic("error", fmt.Sprintf("failed to encode devices as JSON: %v", err))
+	}
+}
+
+// PrintSummary для структурированных форматов не выводит ничего в stdout, чтобы не
+// нарушать единый JSON-документ - сводка доступна через PrintDevices
+func (f *JSONFormatter) PrintSummary(devices []*models.Device) {}
+
+// PrintProgress выводит ход сканирования диагностической строкой в stderr
+func (f *JSONFormatter) PrintProgress(current, total int, message string) {
+	printDiagnostic("progress", fmt.Sprintf("[%d/%d] %s", current, total, message))
+}
+
+// PrintError выводит ошибку диагностической строкой в stderr
+func (f *JSONFormatter) PrintError(message string) {
+	printDiagnostic("error", message)
+}
+
+// PrintWarning выводит предупреждение диагностической строкой в stderr
+func (f *JSONFormatter) PrintWarning(message string) {
+	printDiagnostic("warning", message)
+}
+
+// PrintSuccess выводит успешное сообщение диагностической строкой в stderr
+func (f *JSONFormatter) PrintSuccess(message string) {
+	printDiagnostic("success", message)
+}
+
+// PrintInfo выводит информационное сообщение диагностической строкой в stderr
+func (f *JSONFormatter) PrintInfo(message string) {
+	printDiagnostic("info", message)
+}
@@ -0,0 +1,59 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// NDJSONFormatter выводит одно устройство на строку в виде JSON (newline-delimited JSON),
+// удобном для потоковой обработки большими партиями без буферизации всего массива
+type NDJSONFormatter struct {
+	writer io.Writer
+}
+
+func newNDJSONFormatter(cfg options) *NDJSONFormatter {
+	return &NDJSONFormatter{writer: cfg.writer}
+}
+
+// PrintDevices выводит каждое устройство отдельной строкой JSON
+func (f *NDJSONFormatter) PrintDevices(devices []*models.Device) {
+	encoder := json.NewEncoder(f.writer)
+	for _, device := range devices {
+		if err := encoder.Encode(device); err != nil {
+			printDiagnostic("error", fmt.Sprintf("failed to encode device %s as NDJSON: %v", device.IP, err))
+			return
+		}
+	}
+}
+
+// PrintSummary в NDJSON не выводит ничего, чтобы не примешивать строку, не являющуюся
+// устройством, в поток данных
+func (f *NDJSONFormatter) PrintSummary(devices []*models.Device) {}
+
+// PrintProgress выводит ход сканирования диагностической строкой в stderr
+func (f *NDJSONFormatter) PrintProgress(current, total int, message string) {
+	printDiagnostic("progress", fmt.Sprintf("[%d/%d] %s", current, total, message))
+}
+
+// PrintError выводит ошибку диагностической строкой в stderr
+func (f *NDJSONFormatter) PrintError(message string) {
+	printDiagnostic("error", message)
+}
+
+// PrintWarning выводит предупреждение диагностической строкой в stderr
+func (f *NDJSONFormatter) PrintWarning(message string) {
+	printDiagnostic("warning", message)
+}
+
+// PrintSuccess выводит успешное сообщение диагностической строкой в stderr
+func (f *NDJSONFormatter) PrintSuccess(message string) {
+	printDiagnostic("success", message)
+}
+
+// PrintInfo выводит информационное сообщение диагностической строкой в stderr
+func (f *NDJSONFormatter) PrintInfo(message string) {
+	printDiagnostic("info", message)
+}
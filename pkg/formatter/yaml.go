@@ -0,0 +1,56 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/local-video-server/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLFormatter выводит устройства единым YAML документом в writer, заданный опциями
+type YAMLFormatter struct {
+	writer io.Writer
+}
+
+func newYAMLFormatter(cfg options) *YAMLFormatter {
+	return &YAMLFormatter{writer: cfg.writer}
+}
+
+// PrintDevices выводит устройства как один YAML документ
+func (f *YAMLFormatter) PrintDevices(devices []*models.Device) {
+	encoder := yaml.NewEncoder(f.writer)
+	defer encoder.Close()
+	if err := encoder.Encode(devices); err != nil {
+		printDiagnostic("error", fmt.Sprintf("failed to encode devices as YAML: %v", err))
+	}
+}
+
+// PrintSummary для структурированных форматов не выводит ничего в stdout, чтобы не
+// нарушать единый YAML-документ - сводка доступна через PrintDevices
+func (f *YAMLFormatter) PrintSummary(devices []*models.Device) {}
+
+// PrintProgress выводит ход сканирования диагностической строкой в stderr
+func (f *YAMLFormatter) PrintProgress(current, total int, message string) {
+	printDiagnostic("progress", fmt.Sprintf("[%d/%d] %s", current, total, message))
+}
+
+// PrintError выводит ошибку диагностической строкой в stderr
+func (f *YAMLFormatter) PrintError(message string) {
+	printDiagnostic("error", message)
+}
+
+// PrintWarning выводит предупреждение диагностической строкой в stderr
+func (f *YAMLFormatter) PrintWarning(message string) {
+	printDiagnostic("warning", message)
+}
+
+// PrintSuccess выводит успешное сообщение диагностической строкой в stderr
+func (f *YAMLFormatter) PrintSuccess(message string) {
+	printDiagnostic("success", message)
+}
+
+// PrintInfo выводит информационное сообщение диагностической строкой в stderr
+func (f *YAMLFormatter) PrintInfo(message string) {
+	printDiagnostic("info", message)
+}
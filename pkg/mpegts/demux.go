@@ -0,0 +1,170 @@
+package mpegts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/asticode/go-astits"
+	"github.com/local-video-server/internal/rtsp"
+)
+
+// tsPacketSize - размер одного пакета MPEG-TS (ISO/IEC 13818-1)
+const tsPacketSize = 188
+
+// pcrClockHz - частота тактового генератора PCR (ISO/IEC 13818-1, Annex J)
+const pcrClockHz = 90000
+
+// opusFormatIdentifier - значение registration descriptor'а ("Opus" в ASCII, 0x4F707573),
+// которым в MPEG-TS сигнализируется Opus аудио трек, т.к. у него нет выделенного stream_type
+// (ETSI TS 103 491)
+const opusFormatIdentifier = 0x4F707573
+
+// AccessUnit - один PES пакет (видео кадр или аудио фрейм), извлеченный из элементарного
+// потока одной дорожки MPEG-TS
+type AccessUnit struct {
+	PID     uint16
+	Data    []byte
+	PTS     time.Duration
+	IsVideo bool
+	IsAudio bool
+}
+
+// demuxer разбирает MPEG-TS, строит StreamInfo по первому увиденному PMT и публикует PES
+// данные видео/аудио дорожек как AccessUnit. Битрейт оценивается по разнице меток PCR
+// между двумя последовательными обновлениями - стандартный способ для потоков без
+// явного заголовка битрейта (используется так же в MediaInfo/ffprobe)
+type demuxer struct {
+	dmx *astits.Demuxer
+
+	videoPID   uint16
+	audioPID   uint16
+	audioCodec string
+
+	lastPCR       int64
+	bytesSincePCR int
+	bitrate       int
+}
+
+func newDemuxer(ctx context.Context, r io.Reader) *demuxer {
+	return &demuxer{dmx: astits.NewDemuxer(ctx, r)}
+}
+
+// streamInfo читает поток до первого PMT и возвращает StreamInfo с найденными видео/аудио
+// дорожками. Должен вызываться перед run, т.к. PMT приходит в начале передачи
+func (d *demuxer) streamInfo() (*rtsp.StreamInfo, error) {
+	info := &rtsp.StreamInfo{
+		VideoTracks: []rtsp.VideoTrack{},
+		AudioTracks: []rtsp.AudioTrack{},
+	}
+
+	for {
+		data, err := d.dmx.NextData()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PMT: %w", err)
+		}
+		if data.PMT == nil {
+			continue
+		}
+
+		for _, es := range data.PMT.ElementaryStreams {
+			switch es.StreamType {
+			case astits.StreamTypeH264Video:
+				d.videoPID = es.ElementaryPID
+				info.VideoTracks = append(info.VideoTracks, rtsp.VideoTrack{Codec: "H.264"})
+			case astits.StreamTypeH265Video:
+				d.videoPID = es.ElementaryPID
+				info.VideoTracks = append(info.VideoTracks, rtsp.VideoTrack{Codec: "H.265"})
+			case astits.StreamTypeAACAudio, astits.StreamTypeAACLATMAudio:
+				d.audioPID = es.ElementaryPID
+				d.audioCodec = "AAC"
+				info.AudioTracks = append(info.AudioTracks, rtsp.AudioTrack{Codec: "AAC"})
+			case astits.StreamTypePrivateData:
+				if hasOpusDescriptor(es.ElementaryStreamDescriptors) {
+					d.audioPID = es.ElementaryPID
+					d.audioCodec = "Opus"
+					info.AudioTracks = append(info.AudioTracks, rtsp.AudioTrack{Codec: "Opus"})
+				}
+			}
+		}
+
+		if d.videoPID != 0 || d.audioPID != 0 {
+			return info, nil
+		}
+	}
+}
+
+// hasOpusDescriptor сообщает, содержит ли набор дескрипторов элементарного потока
+// registration descriptor со значением opusFormatIdentifier
+func hasOpusDescriptor(descriptors []*astits.Descriptor) bool {
+	for _, desc := range descriptors {
+		if desc.Registration != nil && desc.Registration.FormatIdentifier == opusFormatIdentifier {
+			return true
+		}
+	}
+	return false
+}
+
+// run читает PES и "сырые" TS пакеты до ошибки (обычно - закрытие сокета через Stop),
+// публикуя access unit'ы видео/аудио дорожек в out и обновляя оценку битрейта по PCR
+func (d *demuxer) run(ctx context.Context, out chan<- AccessUnit) error {
+	for {
+		data, err := d.dmx.NextData()
+		if err != nil {
+			return err
+		}
+
+		if data.FirstPacket != nil {
+			d.trackBitrate(data.FirstPacket)
+		}
+
+		if data.PES == nil || (data.PID != d.videoPID && data.PID != d.audioPID) {
+			continue
+		}
+
+		au := AccessUnit{
+			PID:     data.PID,
+			Data:    data.PES.Data,
+			IsVideo: data.PID == d.videoPID,
+			IsAudio: data.PID == d.audioPID,
+		}
+		if pts := d.pts(data.PES); pts != nil {
+			au.PTS = *pts
+		}
+
+		select {
+		case out <- au:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pts извлекает PTS из опционального PES заголовка, переводя его из 90kHz тактов в time.Duration
+func (d *demuxer) pts(pes *astits.PESData) *time.Duration {
+	if pes.Header == nil || pes.Header.OptionalHeader == nil || pes.Header.OptionalHeader.PTS == nil {
+		return nil
+	}
+	pts := time.Duration(pes.Header.OptionalHeader.PTS.Base) * time.Second / pcrClockHz
+	return &pts
+}
+
+// trackBitrate пересчитывает оценку битрейта по дельте между двумя соседними метками PCR
+// (ISO/IEC 13818-1, Annex J): битрейт = байты, переданные между метками / время между ними
+func (d *demuxer) trackBitrate(p *astits.Packet) {
+	d.bytesSincePCR += tsPacketSize
+	if p.AdaptationField == nil || !p.AdaptationField.HasPCR || p.AdaptationField.PCR == nil {
+		return
+	}
+
+	pcr := p.AdaptationField.PCR.Base
+	if d.lastPCR != 0 {
+		if delta := pcr - d.lastPCR; delta > 0 {
+			seconds := float64(delta) / pcrClockHz
+			d.bitrate = int(float64(d.bytesSincePCR*8) / seconds)
+		}
+	}
+	d.lastPCR = pcr
+	d.bytesSincePCR = 0
+}
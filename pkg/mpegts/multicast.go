@@ -0,0 +1,71 @@
+package mpegts
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/ipv4"
+
+	"github.com/local-video-server/pkg/utils"
+)
+
+// joinMulticastOnAllInterfaces открывает UDP сокет на порту group и присоединяется к
+// multicast группе на каждом интерфейсе из utils.GetNetworkInterfaces (loopback туда не
+// попадает - GetNetworkInterfaces уже его отфильтровывает).
+// На некоторых платформах JoinGroup на части интерфейсов может не работать (например,
+// интерфейс не подключен к сети, где живет источник) - такие интерфейсы просто
+// пропускаются. Если не получилось присоединиться ни на одном, в качестве последней
+// попытки используется только первый интерфейс из списка
+func joinMulticastOnAllInterfaces(group *net.UDPAddr) (*ipv4.PacketConn, error) {
+	conn, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", group.Port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open multicast socket: %w", err)
+	}
+	pconn := ipv4.NewPacketConn(conn)
+
+	interfaces, err := utils.GetNetworkInterfaces()
+	if err != nil || len(interfaces) == 0 {
+		if err := pconn.JoinGroup(nil, group); err != nil {
+			pconn.Close()
+			return nil, fmt.Errorf("failed to join multicast group %s: %w", group, err)
+		}
+		return pconn, nil
+	}
+
+	joined := 0
+	for _, ni := range interfaces {
+		iface, err := net.InterfaceByName(ni.Name)
+		if err != nil {
+			continue
+		}
+		if err := pconn.JoinGroup(iface, group); err == nil {
+			joined++
+		}
+	}
+
+	if joined == 0 {
+		iface, err := net.InterfaceByName(interfaces[0].Name)
+		if err != nil {
+			pconn.Close()
+			return nil, fmt.Errorf("failed to resolve fallback interface %s: %w", interfaces[0].Name, err)
+		}
+		if err := pconn.JoinGroup(iface, group); err != nil {
+			pconn.Close()
+			return nil, fmt.Errorf("failed to join multicast group %s on fallback interface %s: %w", group, interfaces[0].Name, err)
+		}
+	}
+
+	return pconn, nil
+}
+
+// packetConnReader адаптирует ipv4.PacketConn к io.Reader, которого ждет astits.NewDemuxer.
+// Каждый Read возвращает один принятый UDP датаграм - это валидный короткий Read по
+// контракту io.Reader, а astits сам буферизует байты до границы TS пакета (188 байт)
+type packetConnReader struct {
+	conn *ipv4.PacketConn
+}
+
+func (r *packetConnReader) Read(p []byte) (int, error) {
+	n, _, _, err := r.conn.ReadFrom(p)
+	return n, err
+}
@@ -0,0 +1,49 @@
+package mpegts
+
+import "time"
+
+// opusFrameDurationsMs - длительность одного Opus фрейма в миллисекундах для каждого из 32
+// возможных значений config (TOC >> 3), согласно таблице конфигураций RFC 6716, раздел 3.1
+var opusFrameDurationsMs = [32]float64{
+	10, 20, 40, 80, // SILK NB
+	10, 20, 40, 80, // SILK MB
+	10, 20, 40, 80, // SILK WB
+	10, 20, // Hybrid SWB
+	10, 20, // Hybrid FB
+	2.5, 5, 10, 20, // CELT NB
+	2.5, 5, 10, 20, // CELT WB
+	2.5, 5, 10, 20, // CELT SWB
+	2.5, 5, 10, 20, // CELT FB
+}
+
+// opusPacketDuration вычисляет длительность одного Opus пакета по его TOC (table-of-contents)
+// байту (RFC 6716, раздел 3.1): старшие 5 бит (toc>>3) задают config и тем самым
+// длительность фрейма из opusFrameDurationsMs, младшие 2 бита (toc&3) задают код
+// фрейминга и, соответственно, число фреймов в пакете:
+//
+//	0    - один фрейм
+//	1, 2 - два фрейма равной длины
+//	3    - произвольное число фреймов, счетчик которых - младшие 6 бит следующего байта
+//
+// nextByte/hasNextByte нужны только для кода 3; для остальных кодов они игнорируются
+func opusPacketDuration(toc byte, nextByte byte, hasNextByte bool) time.Duration {
+	frameMs := opusFrameDurationsMs[toc>>3]
+
+	frameCount := 1
+	switch toc & 3 {
+	case 1, 2:
+		frameCount = 2
+	case 3:
+		if hasNextByte {
+			frameCount = int(nextByte & 0x3F)
+		}
+	}
+
+	return time.Duration(frameMs * float64(frameCount) * float64(time.Millisecond))
+}
+
+// opusPacketDurationSamples - то же самое, что opusPacketDuration, но в сэмплах тактовой
+// частоты 48kHz, которую RFC 7587 закрепляет за RTP payload type audio/opus
+func opusPacketDurationSamples(toc byte, nextByte byte, hasNextByte bool) int {
+	return int(opusPacketDuration(toc, nextByte, hasNextByte).Seconds() * 48000)
+}
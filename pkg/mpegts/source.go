@@ -0,0 +1,101 @@
+package mpegts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/ipv4"
+
+	"github.com/local-video-server/internal/rtsp"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// Source читает multicast (или unicast) MPEG-TS поток напрямую по UDP, в обход RTSP -
+// многие IP камеры и энкодеры публикуют поток в multicast группу udp:// или rtp://, не
+// поднимая RTSP сервер вовсе. Демультиплексирование делает go-astits, а видео/аудио
+// access unit'ы публикуются в канал для downstream WebRTC/HLS republishing, как это уже
+// делает hls.Repackager для RTP
+type Source struct {
+	rawURL string
+
+	mu   sync.Mutex
+	conn *ipv4.PacketConn
+	dmx  *demuxer
+	done chan struct{}
+}
+
+// NewSource создает источник для указанного udp:// или rtp:// URL. Подключение
+// начинается при вызове Start
+func NewSource(rawURL string) *Source {
+	return &Source{rawURL: rawURL}
+}
+
+// Start присоединяется к multicast группе на всех сетевых интерфейсах (см.
+// joinMulticastOnAllInterfaces), дожидается PMT, чтобы построить StreamInfo, и запускает
+// фоновую горутину, публикующую access unit'ы в возвращаемый канал
+func (s *Source) Start(ctx context.Context) (*rtsp.StreamInfo, <-chan AccessUnit, error) {
+	addr, err := parseMulticastAddr(s.rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := joinMulticastOnAllInterfaces(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dmx := newDemuxer(ctx, &packetConnReader{conn: conn})
+	info, err := dmx.streamInfo()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to read stream info from %s: %w", s.rawURL, err)
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.dmx = dmx
+	s.done = make(chan struct{})
+	s.mu.Unlock()
+
+	out := make(chan AccessUnit, 256)
+	go s.run(ctx, out)
+
+	return info, out, nil
+}
+
+func (s *Source) run(ctx context.Context, out chan<- AccessUnit) {
+	logger := utils.GetLogger()
+	defer close(out)
+	defer close(s.done)
+	defer s.conn.Close()
+
+	if err := s.dmx.run(ctx, out); err != nil {
+		logger.Debugf("mpegts source %s ended: %v", s.rawURL, err)
+	}
+}
+
+// Stop закрывает multicast сокет, останавливая фоновое чтение, и дожидается завершения
+// горутины, запущенной Start
+func (s *Source) Stop() {
+	s.mu.Lock()
+	conn := s.conn
+	done := s.done
+	s.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	conn.Close()
+	<-done
+}
+
+// Bitrate возвращает последнюю оценку битрейта потока в битах в секунду, посчитанную по
+// дельтам PCR (см. demuxer.trackBitrate). Возвращает 0, пока не замечены две метки PCR
+func (s *Source) Bitrate() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dmx == nil {
+		return 0
+	}
+	return s.dmx.bitrate
+}
@@ -0,0 +1,27 @@
+package mpegts
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// parseMulticastAddr парсит "udp://host:port" или "rtp://host:port" в UDP адрес группы.
+// В обоих случаях payload внутри - сырой MPEG-TS без RTP заголовка: это отличает такие
+// источники от rtsp.Client, который работает с настоящим RTP поверх TCP/UDP транспорта,
+// установленного через RTSP SETUP
+func parseMulticastAddr(rawURL string) (*net.UDPAddr, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multicast URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "udp" && u.Scheme != "rtp" {
+		return nil, fmt.Errorf("unsupported scheme %q, expected udp:// or rtp://", u.Scheme)
+	}
+
+	addr, err := net.ResolveUDPAddr("udp4", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %q: %w", u.Host, err)
+	}
+	return addr, nil
+}
@@ -0,0 +1,187 @@
+// Package igd реализует минимальный клиент для сервисов WANIPConnection:1/WANPPPConnection:1
+// Internet Gateway Device (UPnP IGD 1.0/2.0): получение внешнего IPv4 адреса шлюза, чтение
+// текущей таблицы port-forward и добавление/удаление записей в ней. Используется
+// internal/scanner.UPnPScanner, который находит control URL этих сервисов через обычное
+// SSDP/XML-описание обнаружение, а также CLI командой port-map
+package igd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// escapeXML экранирует текст, подставляемый в SOAP аргументы через fmt.Sprintf, - поля вроде
+// Description приходят от вызывающего кода (в т.ч. напрямую из флага CLI --description) и могут
+// содержать символы, значимые для XML
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// Типы сервисов Internet Gateway Device, которые умеет опрашивать этот клиент (WANIPConnection -
+// IGD с выделенным IP, WANPPPConnection - IGD за PPPoE/PPTP туннелем)
+const (
+	WANIPConnectionServiceType  = "urn:schemas-upnp-org:service:WANIPConnection:1"
+	WANPPPConnectionServiceType = "urn:schemas-upnp-org:service:WANPPPConnection:1"
+)
+
+// errSpecifiedArrayIndexInvalid - UPnPError код 713 (WANIPConnection:1, таблица 2.4.18),
+// которым сервис отвечает на GetGenericPortMappingEntry, когда index вышел за пределы таблицы -
+// это нормальный сигнал конца перечисления, а не ошибка
+const errSpecifiedArrayIndexInvalid = "713"
+
+// PortMapping - одна запись таблицы port-forward шлюза (WANIPConnection:1, раздел 2.4.16-2.4.18)
+type PortMapping struct {
+	RemoteHost     string
+	ExternalPort   int
+	Protocol       string // "TCP" или "UDP"
+	InternalPort   int
+	InternalClient string
+	Enabled        bool
+	Description    string
+	LeaseDuration  int // в секундах, 0 - бессрочно
+}
+
+// Client - клиент одного сервиса WANIPConnection/WANPPPConnection конкретного шлюза
+type Client struct {
+	controlURL  string
+	serviceType string
+}
+
+// NewClient создает Client для сервиса по controlURL с заданным serviceType (одна из констант
+// WANIPConnectionServiceType/WANPPPConnectionServiceType)
+func NewClient(controlURL, serviceType string) *Client {
+	return &Client{controlURL: controlURL, serviceType: serviceType}
+}
+
+// GetExternalIPAddress выполняет действие GetExternalIPAddress (WANIPConnection:1, раздел 2.4.18)
+// и возвращает внешний IPv4 адрес шлюза
+func (c *Client) GetExternalIPAddress() (string, error) {
+	data, err := c.call("GetExternalIPAddress", "")
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Body struct {
+			GetExternalIPAddressResponse struct {
+				NewExternalIPAddress string `xml:"NewExternalIPAddress"`
+			} `xml:"GetExternalIPAddressResponse"`
+		} `xml:"Body"`
+	}
+	if err := parseSOAPResponse(data, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse GetExternalIPAddress response: %w", err)
+	}
+
+	ip := resp.Body.GetExternalIPAddressResponse.NewExternalIPAddress
+	if ip == "" {
+		return "", fmt.Errorf("empty NewExternalIPAddress in GetExternalIPAddress response")
+	}
+	return ip, nil
+}
+
+// GetGenericPortMappingEntry выполняет действие GetGenericPortMappingEntry (WANIPConnection:1,
+// раздел 2.4.16) для записи с заданным index в таблице port-forward шлюза. Возвращает
+// (PortMapping{}, false, nil), когда index вышел за пределы таблицы (UPnPError 713)
+func (c *Client) GetGenericPortMappingEntry(index int) (PortMapping, bool, error) {
+	args := fmt.Sprintf(`<NewPortMappingIndex>%d</NewPortMappingIndex>`, index)
+
+	data, err := c.call("GetGenericPortMappingEntry", args)
+	if err != nil {
+		if code, ok := upnpErrorCode(err); ok && code == errSpecifiedArrayIndexInvalid {
+			return PortMapping{}, false, nil
+		}
+		return PortMapping{}, false, err
+	}
+
+	var resp struct {
+		Body struct {
+			GetGenericPortMappingEntryResponse struct {
+				NewRemoteHost     string `xml:"NewRemoteHost"`
+				NewExternalPort   int    `xml:"NewExternalPort"`
+				NewProtocol       string `xml:"NewProtocol"`
+				NewInternalPort   int    `xml:"NewInternalPort"`
+				NewInternalClient string `xml:"NewInternalClient"`
+				NewEnabled        int    `xml:"NewEnabled"`
+				NewDescription    string `xml:"NewPortMappingDescription"`
+				NewLeaseDuration  int    `xml:"NewLeaseDuration"`
+			} `xml:"GetGenericPortMappingEntryResponse"`
+		} `xml:"Body"`
+	}
+	if err := parseSOAPResponse(data, &resp); err != nil {
+		return PortMapping{}, false, fmt.Errorf("failed to parse GetGenericPortMappingEntry response: %w", err)
+	}
+
+	entry := resp.Body.GetGenericPortMappingEntryResponse
+	return PortMapping{
+		RemoteHost:     entry.NewRemoteHost,
+		ExternalPort:   entry.NewExternalPort,
+		Protocol:       entry.NewProtocol,
+		InternalPort:   entry.NewInternalPort,
+		InternalClient: entry.NewInternalClient,
+		Enabled:        entry.NewEnabled != 0,
+		Description:    entry.NewDescription,
+		LeaseDuration:  entry.NewLeaseDuration,
+	}, true, nil
+}
+
+// maxPortMappingEntries ограничивает перебор GetGenericPortMappingEntry на случай шлюза,
+// который никогда не отвечает UPnPError 713 на индекс за пределами таблицы (наблюдается у
+// некоторых бытовых роутеров с неполной реализацией IGD)
+const maxPortMappingEntries = 1000
+
+// ListPortMappings перечисляет всю таблицу port-forward шлюза, вызывая
+// GetGenericPortMappingEntry с возрастающим index, пока сервис не ответит UPnPError 713
+func (c *Client) ListPortMappings() ([]PortMapping, error) {
+	var mappings []PortMapping
+	for index := 0; index < maxPortMappingEntries; index++ {
+		entry, ok, err := c.GetGenericPortMappingEntry(index)
+		if err != nil {
+			return mappings, err
+		}
+		if !ok {
+			break
+		}
+		mappings = append(mappings, entry)
+	}
+	return mappings, nil
+}
+
+// AddPortMapping выполняет действие AddPortMapping (WANIPConnection:1, раздел 2.4.15), создавая
+// или заменяя запись порта m.ExternalPort/m.Protocol, перенаправленную на
+// m.InternalClient:m.InternalPort
+func (c *Client) AddPortMapping(m PortMapping) error {
+	enabled := 0
+	if m.Enabled {
+		enabled = 1
+	}
+
+	args := fmt.Sprintf(`<NewRemoteHost>%s</NewRemoteHost>
+<NewExternalPort>%d</NewExternalPort>
+<NewProtocol>%s</NewProtocol>
+<NewInternalPort>%d</NewInternalPort>
+<NewInternalClient>%s</NewInternalClient>
+<NewEnabled>%d</NewEnabled>
+<NewPortMappingDescription>%s</NewPortMappingDescription>
+<NewLeaseDuration>%d</NewLeaseDuration>`,
+		escapeXML(m.RemoteHost), m.ExternalPort, escapeXML(m.Protocol), m.InternalPort, escapeXML(m.InternalClient),
+		enabled, escapeXML(m.Description), m.LeaseDuration)
+
+	_, err := c.call("AddPortMapping", args)
+	return err
+}
+
+// DeletePortMapping выполняет действие DeletePortMapping (WANIPConnection:1, раздел 2.4.17),
+// удаляя запись externalPort/protocol из таблицы port-forward шлюза
+func (c *Client) DeletePortMapping(remoteHost string, externalPort int, protocol string) error {
+	args := fmt.Sprintf(`<NewRemoteHost>%s</NewRemoteHost>
+<NewExternalPort>%d</NewExternalPort>
+<NewProtocol>%s</NewProtocol>`, escapeXML(remoteHost), externalPort, escapeXML(protocol))
+
+	_, err := c.call("DeletePortMapping", args)
+	return err
+}
@@ -0,0 +1,105 @@
+package igd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// soapActionTimeout - таймаут отдельного SOAP запроса к сервису шлюза
+const soapActionTimeout = 5 * time.Second
+
+// call отправляет SOAP 1.1 запрос action с аргументами argsXML на c.controlURL и возвращает тело
+// ответа. UPnPError (HTTP 500 с SOAP Fault) возвращается как *soapFault, а не как транспортная
+// ошибка, чтобы вызывающий код мог отличить "действие выполнено, но шлюз вернул код ошибки" (как
+// SpecifiedArrayIndexInvalid в конце перечисления GetGenericPortMappingEntry) от сбоя сети
+func (c *Client) call(action, argsXML string) ([]byte, error) {
+	envelope := fmt.Sprintf(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:%s xmlns:u="%s">%s</u:%s>
+  </s:Body>
+</s:Envelope>`, action, c.serviceType, argsXML, action)
+
+	req, err := http.NewRequest(http.MethodPost, c.controlURL, bytes.NewBufferString(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SOAP request: %w", err)
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, c.serviceType, action))
+
+	client := &http.Client{Timeout: soapActionTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SOAP request to %s failed: %w", c.controlURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SOAP response from %s: %w", c.controlURL, err)
+	}
+
+	if resp.StatusCode == http.StatusInternalServerError {
+		return nil, parseSOAPFault(data)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, c.controlURL)
+	}
+
+	return data, nil
+}
+
+// soapFault - SOAP Fault с вложенным UPnPError (UPnP Device Architecture 1.0, раздел 3.2.2),
+// которым сервис отвечает на действие, которое не может выполнить (например,
+// SpecifiedArrayIndexInvalid при переборе таблицы port-forward за ее концом)
+type soapFault struct {
+	Code string
+	Desc string
+}
+
+func (f *soapFault) Error() string {
+	return fmt.Sprintf("UPnPError %s: %s", f.Code, f.Desc)
+}
+
+// upnpErrorCode возвращает код UPnPError (например, "713"), если err - это *soapFault
+func upnpErrorCode(err error) (string, bool) {
+	fault, ok := err.(*soapFault)
+	if !ok {
+		return "", false
+	}
+	return fault.Code, true
+}
+
+// parseSOAPFault разбирает тело SOAP Fault и извлекает вложенный UPnPError
+func parseSOAPFault(data []byte) error {
+	var fault struct {
+		Body struct {
+			Fault struct {
+				Detail struct {
+					UPnPError struct {
+						ErrorCode        string `xml:"errorCode"`
+						ErrorDescription string `xml:"errorDescription"`
+					} `xml:"UPnPError"`
+				} `xml:"detail"`
+			} `xml:"Fault"`
+		} `xml:"Body"`
+	}
+
+	if err := xml.Unmarshal(data, &fault); err != nil {
+		return fmt.Errorf("failed to parse SOAP fault: %w", err)
+	}
+
+	return &soapFault{
+		Code: fault.Body.Fault.Detail.UPnPError.ErrorCode,
+		Desc: fault.Body.Fault.Detail.UPnPError.ErrorDescription,
+	}
+}
+
+// parseSOAPResponse разбирает тело успешного SOAP ответа в v
+func parseSOAPResponse(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
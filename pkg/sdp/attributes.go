@@ -0,0 +1,205 @@
+package sdp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseSessionOrMediaAttribute разбирает значение поля a= (без префикса "a="). Атрибуты,
+// специфичные для media (rtpmap, fmtp, rtcp-fb, candidate, ssrc, mid, setup, fingerprint),
+// применяются к media, если она уже встретилась в потоке строк, иначе (group, ice-ufrag/pwd
+// до первой m= секции) - к сессии в целом
+func parseSessionOrMediaAttribute(value string, sd *SessionDescription, media *MediaDescription) {
+	name, rest, hasValue := strings.Cut(value, ":")
+	if !hasValue {
+		name = value
+		rest = ""
+	}
+
+	switch name {
+	case "group":
+		sd.Groups = append(sd.Groups, parseGroup(rest))
+	case "ice-ufrag":
+		if media != nil {
+			media.ICEUfrag = rest
+		} else {
+			sd.ICEUfrag = rest
+		}
+	case "ice-pwd":
+		if media != nil {
+			media.ICEPwd = rest
+		} else {
+			sd.ICEPwd = rest
+		}
+	case "mid":
+		if media != nil {
+			media.Mid = rest
+		}
+	case "rtpmap":
+		if media != nil {
+			if rtpmap, ok := parseRTPMap(rest); ok {
+				media.RTPMaps = append(media.RTPMaps, rtpmap)
+			}
+		}
+	case "fmtp":
+		if media != nil {
+			if fmtp, ok := parseFMTP(rest); ok {
+				media.FMTP = append(media.FMTP, fmtp)
+			}
+		}
+	case "rtcp-fb":
+		if media != nil {
+			if fb, ok := parseRTCPFeedback(rest); ok {
+				media.RTCPFeedback = append(media.RTCPFeedback, fb)
+			}
+		}
+	case "candidate":
+		if media != nil {
+			if candidate, ok := parseCandidate(rest); ok {
+				media.Candidates = append(media.Candidates, candidate)
+			}
+		}
+	case "fingerprint":
+		if media != nil {
+			if fp, ok := parseFingerprint(rest); ok {
+				media.Fingerprint = &fp
+			}
+		}
+	case "setup":
+		if media != nil {
+			media.Setup = rest
+		}
+	case "ssrc":
+		if media != nil {
+			if ssrc, ok := parseSSRC(rest); ok {
+				media.SSRC = append(media.SSRC, ssrc)
+			}
+		}
+	}
+}
+
+// parseGroup разбирает "a=group:BUNDLE audio video"
+func parseGroup(rest string) Group {
+	parts := strings.Fields(rest)
+	g := Group{}
+	if len(parts) > 0 {
+		g.Semantics = parts[0]
+	}
+	if len(parts) > 1 {
+		g.Identification = append([]string(nil), parts[1:]...)
+	}
+	return g
+}
+
+// parseRTPMap разбирает "a=rtpmap:<payload> <encoding name>/<clock rate>[/<encoding params>]"
+func parseRTPMap(rest string) (RTPMap, bool) {
+	parts := strings.Fields(rest)
+	if len(parts) < 2 {
+		return RTPMap{}, false
+	}
+	payloadType, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return RTPMap{}, false
+	}
+
+	fields := strings.Split(parts[1], "/")
+	rtpmap := RTPMap{PayloadType: payloadType, EncodingName: fields[0]}
+	if len(fields) > 1 {
+		rtpmap.ClockRate, _ = strconv.Atoi(fields[1])
+	}
+	if len(fields) > 2 {
+		rtpmap.EncodingParams = fields[2]
+	}
+	return rtpmap, true
+}
+
+// parseFMTP разбирает "a=fmtp:<payload> <params>"
+func parseFMTP(rest string) (FMTP, bool) {
+	payloadStr, params, ok := strings.Cut(rest, " ")
+	if !ok {
+		return FMTP{}, false
+	}
+	payloadType, err := strconv.Atoi(payloadStr)
+	if err != nil {
+		return FMTP{}, false
+	}
+	return FMTP{PayloadType: payloadType, Params: strings.TrimSpace(params)}, true
+}
+
+// parseRTCPFeedback разбирает "a=rtcp-fb:<payload> <type>[ <parameter>]", payload может быть "*"
+func parseRTCPFeedback(rest string) (RTCPFeedback, bool) {
+	parts := strings.Fields(rest)
+	if len(parts) < 2 {
+		return RTCPFeedback{}, false
+	}
+	fb := RTCPFeedback{PayloadType: parts[0], Type: parts[1]}
+	if len(parts) > 2 {
+		fb.Parameter = strings.Join(parts[2:], " ")
+	}
+	return fb, true
+}
+
+// parseCandidate разбирает "a=candidate:<foundation> <component> <transport> <priority>
+// <address> <port> typ <type> [raddr <related-address> rport <related-port>] ...". address
+// может быть IPv4, IPv6 или mDNS .local именем (RFC 8839, раздел 5.1.3)
+func parseCandidate(rest string) (Candidate, bool) {
+	parts := strings.Fields(rest)
+	if len(parts) < 8 {
+		return Candidate{}, false
+	}
+
+	c := Candidate{
+		Foundation: parts[0],
+		Transport:  parts[2],
+		Address:    parts[4],
+	}
+	c.Component, _ = strconv.Atoi(parts[1])
+	c.Priority, _ = strconv.ParseInt(parts[3], 10, 64)
+	c.Port, _ = strconv.Atoi(parts[5])
+
+	// parts[6] должно быть литералом "typ", parts[7] - сам тип
+	if parts[6] == "typ" {
+		c.Type = parts[7]
+	}
+
+	for i := 8; i+1 < len(parts); i += 2 {
+		switch parts[i] {
+		case "raddr":
+			c.RelatedAddress = parts[i+1]
+		case "rport":
+			c.RelatedPort, _ = strconv.Atoi(parts[i+1])
+		}
+	}
+
+	return c, true
+}
+
+// parseFingerprint разбирает "a=fingerprint:<hash-function> <fingerprint>"
+func parseFingerprint(rest string) (Fingerprint, bool) {
+	hashFunc, value, ok := strings.Cut(rest, " ")
+	if !ok {
+		return Fingerprint{}, false
+	}
+	return Fingerprint{HashFunction: hashFunc, Value: value}, true
+}
+
+// parseSSRC разбирает "a=ssrc:<ssrc-id> <attribute>[:<value>]"
+func parseSSRC(rest string) (SSRC, bool) {
+	idStr, attr, ok := strings.Cut(rest, " ")
+	if !ok {
+		return SSRC{}, false
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return SSRC{}, false
+	}
+
+	s := SSRC{ID: uint32(id)}
+	if name, value, hasValue := strings.Cut(attr, ":"); hasValue {
+		s.Attribute = name
+		s.Value = value
+	} else {
+		s.Attribute = attr
+	}
+	return s, true
+}
@@ -0,0 +1,244 @@
+// Package sdp разбирает Session Description Protocol (RFC 4566) в типизированные структуры -
+// как на уровне сессии (v=/o=/s=/c=/t=), так и по каждой m= секции (транспорт, форматы,
+// a=rtpmap/fmtp/rtcp-fb/candidate/fingerprint/setup/mid/group/ice-ufrag/ice-pwd/ssrc). В
+// отличие от internal/rtsp.ParseSDP, который извлекает только параметры видео/аудио кодеков
+// для RTSP камер, этот пакет - общего назначения и используется также для разбора
+// WebRTC offer/answer (см. internal/protocols.WebRTCDetector)
+package sdp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SessionDescription - разобранное SDP сообщение
+type SessionDescription struct {
+	Origin         Origin
+	SessionName    string
+	ConnectionData *ConnectionData
+	Media          []MediaDescription
+
+	// Groups - a=group:BUNDLE и другие группировки m= секций на уровне сессии (RFC 5888)
+	Groups []Group
+
+	// ICEUfrag/ICEPwd - ice-ufrag/ice-pwd на уровне сессии (RFC 8839), действуют для всех
+	// m= секций, у которых нет собственных
+	ICEUfrag string
+	ICEPwd   string
+}
+
+// Origin - поле o= (RFC 4566, раздел 5.2)
+type Origin struct {
+	Username       string
+	SessionID      string
+	SessionVersion string
+	NetType        string
+	AddrType       string
+	UnicastAddress string
+}
+
+// ConnectionData - поле c= (RFC 4566, раздел 5.7), может встречаться на уровне сессии и/или
+// внутри каждой m= секции
+type ConnectionData struct {
+	NetType  string
+	AddrType string
+	Address  string
+}
+
+// Group - a=group (RFC 5888), например "a=group:BUNDLE audio video"
+type Group struct {
+	Semantics      string
+	Identification []string
+}
+
+// MediaDescription - одна m= секция и ее атрибуты
+type MediaDescription struct {
+	Type     string // audio, video, application, ...
+	Port     int
+	Protocol string // RTP/AVP, RTP/SAVPF, UDP/TLS/RTP/SAVPF, DTLS/SCTP, ...
+	Formats  []string
+
+	// ConnectionData - c= внутри m= секции, переопределяет сессионное, если задано
+	ConnectionData *ConnectionData
+
+	Mid          string
+	RTPMaps      []RTPMap
+	FMTP         []FMTP
+	RTCPFeedback []RTCPFeedback
+	Candidates   []Candidate
+	Fingerprint  *Fingerprint
+	Setup        string
+
+	ICEUfrag string
+	ICEPwd   string
+	SSRC     []SSRC
+}
+
+// RTPMap - a=rtpmap (RFC 4566, раздел 6)
+type RTPMap struct {
+	PayloadType    int
+	EncodingName   string
+	ClockRate      int
+	EncodingParams string // например число каналов аудио
+}
+
+// FMTP - a=fmtp (RFC 4566, раздел 6), Params - необработанная строка параметров после payload type
+type FMTP struct {
+	PayloadType int
+	Params      string
+}
+
+// RTCPFeedback - a=rtcp-fb (RFC 4585)
+type RTCPFeedback struct {
+	PayloadType string // payload type или "*" для всех форматов m= секции
+	Type        string
+	Parameter   string
+}
+
+// Candidate - a=candidate (RFC 8839, раздел 5.1)
+type Candidate struct {
+	Foundation     string
+	Component      int
+	Transport      string
+	Priority       int64
+	Address        string // может быть IPv4, IPv6 или mDNS-имя вида "<uuid>.local"
+	Port           int
+	Type           string // host, srflx, prflx, relay
+	RelatedAddress string
+	RelatedPort    int
+}
+
+// Fingerprint - a=fingerprint (RFC 8122)
+type Fingerprint struct {
+	HashFunction string
+	Value        string
+}
+
+// SSRC - a=ssrc (RFC 5576), Attribute/Value - разобранный "attribute:value" после ssrc id
+type SSRC struct {
+	ID        uint32
+	Attribute string
+	Value     string
+}
+
+// Parse разбирает сырое SDP сообщение raw. Строки могут быть разделены "\r\n" или "\n";
+// строки, продолженные с отступом (не по RFC 4566, но встречается в прошивках некоторых
+// камер), склеиваются с предыдущей
+func Parse(raw string) (*SessionDescription, error) {
+	sd := &SessionDescription{}
+	var currentMedia *MediaDescription
+
+	for _, line := range unfoldLines(raw) {
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+		value := line[2:]
+
+		switch line[0] {
+		case 'o':
+			sd.Origin = parseOrigin(value)
+		case 's':
+			sd.SessionName = value
+		case 'c':
+			cd := parseConnectionData(value)
+			if currentMedia != nil {
+				currentMedia.ConnectionData = cd
+			} else {
+				sd.ConnectionData = cd
+			}
+		case 'm':
+			media, ok := parseMediaLine(value)
+			if !ok {
+				// Пропускаем некорректную m= секцию, но продолжаем разбор остальных -
+				// один неправильно сформированный m= в SDP не должен терять уже
+				// разобранные до него секции
+				currentMedia = nil
+				continue
+			}
+			sd.Media = append(sd.Media, *media)
+			currentMedia = &sd.Media[len(sd.Media)-1]
+		case 'a':
+			parseSessionOrMediaAttribute(value, sd, currentMedia)
+		}
+	}
+
+	return sd, nil
+}
+
+// unfoldLines разбивает raw на строки по CRLF/LF, отбрасывает пустые строки и склеивает
+// строки, начинающиеся с пробела или табуляции, с предыдущей непустой строкой
+func unfoldLines(raw string) []string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	rawLines := strings.Split(raw, "\n")
+
+	var lines []string
+	for _, line := range rawLines {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += strings.TrimLeft(line, " \t")
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+// parseOrigin разбирает значение поля o=: "<username> <sess-id> <sess-version> <nettype>
+// <addrtype> <unicast-address>"
+func parseOrigin(value string) Origin {
+	parts := strings.Fields(value)
+	o := Origin{}
+	if len(parts) > 0 {
+		o.Username = parts[0]
+	}
+	if len(parts) > 1 {
+		o.SessionID = parts[1]
+	}
+	if len(parts) > 2 {
+		o.SessionVersion = parts[2]
+	}
+	if len(parts) > 3 {
+		o.NetType = parts[3]
+	}
+	if len(parts) > 4 {
+		o.AddrType = parts[4]
+	}
+	if len(parts) > 5 {
+		o.UnicastAddress = parts[5]
+	}
+	return o
+}
+
+// parseConnectionData разбирает значение поля c=: "<nettype> <addrtype> <connection-address>"
+func parseConnectionData(value string) *ConnectionData {
+	parts := strings.Fields(value)
+	if len(parts) < 3 {
+		return nil
+	}
+	// connection-address может нести суффикс TTL/числа адресов через "/" (например
+	// "224.2.1.1/127/3" для multicast) - для наших целей достаточно самого адреса
+	address := strings.SplitN(parts[2], "/", 2)[0]
+	return &ConnectionData{NetType: parts[0], AddrType: parts[1], Address: address}
+}
+
+// parseMediaLine разбирает значение поля m=: "<media> <port> <proto> <fmt> ...". Возвращает
+// ok=false, если в строке меньше 3 обязательных полей
+func parseMediaLine(value string) (*MediaDescription, bool) {
+	parts := strings.Fields(value)
+	if len(parts) < 3 {
+		return nil, false
+	}
+
+	// порт может быть указан как "<port>/<number of ports>" для нескольких RTP/RTCP пар
+	portStr := strings.SplitN(parts[1], "/", 2)[0]
+	port, _ := strconv.Atoi(portStr)
+
+	return &MediaDescription{
+		Type:     parts[0],
+		Port:     port,
+		Protocol: parts[2],
+		Formats:  append([]string(nil), parts[3:]...),
+	}, true
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readARPTable читает таблицу ARP-соседей ядра из /proc/net/arp. Формат файла -
+// фиксированные колонки, разделенные пробелами, с заголовком в первой строке:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        eth0
+func readARPTable() (map[string]string, error) {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /proc/net/arp: %w", err)
+	}
+	defer f.Close()
+
+	table := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // пропускаем заголовок
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		ip := fields[0]
+		mac := fields[3]
+		if mac == "" || mac == "00:00:00:00:00:00" {
+			continue
+		}
+		table[ip] = mac
+	}
+
+	return table, scanner.Err()
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// arpEntryPattern разбирает строки вывода "arp -a" как на macOS/BSD
+// ("? (192.168.1.1) at aa:bb:cc:dd:ee:ff on en0 ifscope [ethernet]"), так и на Windows
+// ("  192.168.1.1          aa-bb-cc-dd-ee-ff     dynamic")
+var arpEntryPattern = regexp.MustCompile(`(\d+\.\d+\.\d+\.\d+).*?([0-9A-Fa-f]{2}[:\-][0-9A-Fa-f]{2}[:\-][0-9A-Fa-f]{2}[:\-][0-9A-Fa-f]{2}[:\-][0-9A-Fa-f]{2}[:\-][0-9A-Fa-f]{2})`)
+
+// readARPTable читает таблицу ARP-соседей через системную команду "arp -a", поскольку
+// на macOS/Windows нет аналога /proc/net/arp
+func readARPTable() (map[string]string, error) {
+	out, err := exec.Command("arp", "-a").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run arp -a: %w", err)
+	}
+
+	table := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		match := arpEntryPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		ip, mac := match[1], strings.ReplaceAll(match[2], "-", ":")
+		table[ip] = strings.ToLower(mac)
+	}
+
+	return table, nil
+}
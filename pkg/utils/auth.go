@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"crypto/md5"
+	"fmt"
+)
+
+// MD5Hash вычисляет MD5 хеш строки в виде hex - используется при построении Digest
+// аутентификации (RFC 2617) в RTSP и HTTP клиентах
+func MD5Hash(data string) string {
+	hash := md5.Sum([]byte(data))
+	return fmt.Sprintf("%x", hash)
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// arpRefreshInterval - как часто перечитывается таблица ARP-соседей ОС. Сама таблица
+// меняется сравнительно редко, поэтому нет смысла читать /proc/net/arp или звать
+// "arp -a" на каждый Lookup
+const arpRefreshInterval = 5 * time.Second
+
+// ouiVendors - известные префиксы MAC адресов (OUI) производителей IP камер, используемые
+// для синтеза резервного hostname, если обратный DNS не дал результата. Список не
+// претендует на полноту - это тот же принцип "known manufacturers", что и в
+// scanner.UPnPScanner.parseServerHeader
+var ouiVendors = map[string]string{
+	"00:40:8c": "Hikvision",
+	"4c:11:bf": "Hikvision",
+	"bc:ad:28": "Dahua",
+	"3c:ef:8c": "Dahua",
+	"00:40:9d": "Axis",
+	"ac:cc:8e": "Axis",
+	"00:07:32": "Bosch",
+}
+
+// ptrEntry - кэшированный результат обратного DNS резолва одного IP
+type ptrEntry struct {
+	hostname string
+	expires  time.Time
+}
+
+// NeighborCache заполняет MAC и hostname для IP адресов, объединяя таблицу ARP-соседей ОС
+// с обратным DNS резолвом. Приоритет при заполнении hostname: PTR-запись из DNS важнее
+// hostname, синтезированного из вендора по MAC - второй используется только как fallback,
+// когда PTR ничего не вернул. PTR результаты кэшируются с заданным TTL, чтобы повторные
+// сканирования не били по резолверу на каждый IP заново
+type NeighborCache struct {
+	ttl time.Duration
+
+	mu           sync.Mutex
+	arpTable     map[string]string
+	arpUpdatedAt time.Time
+	ptrCache     map[string]ptrEntry
+}
+
+// NewNeighborCache создает кэш соседей с заданным TTL для PTR записей
+func NewNeighborCache(ttl time.Duration) *NeighborCache {
+	return &NeighborCache{
+		ttl:      ttl,
+		ptrCache: make(map[string]ptrEntry),
+	}
+}
+
+// Lookup возвращает MAC и hostname для IP адреса. MAC берется из таблицы ARP-соседей ОС
+// (если есть запись), hostname - из обратного DNS с фоллбеком на вендора по MAC OUI.
+// Отсутствие записи в любом из источников не является ошибкой - просто возвращается
+// пустая строка для соответствующего значения
+func (c *NeighborCache) Lookup(ip string) (mac, hostname string) {
+	mac = c.macFor(ip)
+
+	if ptr := c.ptrFor(ip); ptr != "" {
+		return mac, ptr
+	}
+
+	if vendor := vendorFromMAC(mac); vendor != "" {
+		return mac, vendor
+	}
+
+	return mac, ""
+}
+
+// macFor возвращает MAC адрес из таблицы ARP-соседей, обновляя ее не чаще, чем раз в
+// arpRefreshInterval
+func (c *NeighborCache) macFor(ip string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.arpUpdatedAt) > arpRefreshInterval {
+		if table, err := readARPTable(); err == nil {
+			c.arpTable = table
+			c.arpUpdatedAt = time.Now()
+		}
+	}
+
+	return c.arpTable[ip]
+}
+
+// ptrFor возвращает закэшированный (или свежий) результат обратного DNS резолва
+func (c *NeighborCache) ptrFor(ip string) string {
+	c.mu.Lock()
+	if entry, ok := c.ptrCache[ip]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.hostname
+	}
+	c.mu.Unlock()
+
+	hostname, _ := ResolveHostname(ip)
+
+	c.mu.Lock()
+	c.ptrCache[ip] = ptrEntry{hostname: hostname, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return hostname
+}
+
+// vendorFromMAC определяет производителя по первым трем октетам MAC адреса (OUI).
+// Возвращает пустую строку, если вендор неизвестен или MAC не указан
+func vendorFromMAC(mac string) string {
+	if len(mac) < 8 {
+		return ""
+	}
+	return ouiVendors[strings.ToLower(mac[:8])]
+}
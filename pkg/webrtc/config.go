@@ -0,0 +1,25 @@
+package webrtc
+
+// Config - параметры публикации WebRTC, аналогичные типичному деплою pion/webrtc за NAT
+type Config struct {
+	ICEServers []string // STUN/TURN серверы, например "stun:stun.l.google.com:19302"
+	PublicIP   string   // публичный IP сервера, подставляется в host-кандидаты (SetNAT1To1IPs)
+	PortMin    uint16   // начало диапазона UDP портов для ICE (0 - использовать системный диапазон)
+	PortMax    uint16   // конец диапазона UDP портов для ICE
+
+	// Transport - режим RTSP транспорта к источнику: "auto", "tcp", "udp" или
+	// "udp-multicast". Пустое значение равносильно "tcp"
+	Transport string
+
+	// AnyPort - в режимах "udp"/"auto" принимать RTP/RTCP с любого порта отправителя
+	// вместо строгой проверки server_port, согласованного в SETUP
+	AnyPort bool
+}
+
+// DefaultConfig возвращает конфигурацию без TURN/PublicIP - подходит для локальной сети,
+// где клиент и сервер находятся в одном сегменте
+func DefaultConfig() Config {
+	return Config{
+		ICEServers: []string{"stun:stun.l.google.com:19302"},
+	}
+}
@@ -0,0 +1,260 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/local-video-server/internal/rtsp"
+	"github.com/local-video-server/pkg/utils"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// Publisher подключается к RTSP потокам, обнаруженным сканером, и переиздает их видео
+// дорожку браузерам через WebRTC. RTP пакеты перекладываются в TrackLocalStaticRTP
+// напрямую (без полной депакетизации в access unit'ы и обратно), поскольку формат полезной
+// нагрузки H.264/H.265 RTP (RFC 6184/7798), который понимает наш RTSP клиент, - это ровно
+// то, что ожидает WebRTC получатель
+type Publisher struct {
+	cfg Config
+	api *webrtc.API
+}
+
+// NewPublisher создает Publisher с заданной ICE конфигурацией
+func NewPublisher(cfg Config) (*Publisher, error) {
+	settingEngine := webrtc.SettingEngine{}
+	if cfg.PublicIP != "" {
+		settingEngine.SetNAT1To1IPs([]string{cfg.PublicIP}, webrtc.ICECandidateTypeHost)
+	}
+	if cfg.PortMin != 0 || cfg.PortMax != 0 {
+		if err := settingEngine.SetEphemeralUDPPortRange(cfg.PortMin, cfg.PortMax); err != nil {
+			return nil, fmt.Errorf("invalid UDP port range [%d, %d]: %w", cfg.PortMin, cfg.PortMax, err)
+		}
+	}
+
+	api := webrtc.NewAPI(webrtc.WithSettingEngine(settingEngine))
+	return &Publisher{cfg: cfg, api: api}, nil
+}
+
+// Session - одна активная публикация RTSP потока в WebRTC: RTSP соединение плюс
+// PeerConnection, раздающий его браузеру
+type Session struct {
+	pc         *webrtc.PeerConnection
+	rtspClient *rtsp.Client
+	videoTrack *webrtc.TrackLocalStaticRTP
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// Answer возвращает локальное SDP-описание (ответ на offer браузера), которое нужно
+// вернуть клиенту
+func (s *Session) Answer() *webrtc.SessionDescription {
+	return s.pc.LocalDescription()
+}
+
+// Close завершает RTSP соединение и закрывает PeerConnection
+func (s *Session) Close() error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+	return s.pc.Close()
+}
+
+// videoCodecCapability сопоставляет нормализованный кодек из SDP (см. rtsp.normalizeCodec)
+// с MIME типом, который понимает pion/webrtc
+func videoCodecCapability(codec string) (webrtc.RTPCodecCapability, error) {
+	switch codec {
+	case "H.264":
+		return webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000}, nil
+	case "H.265":
+		return webrtc.RTPCodecCapability{MimeType: "video/H265", ClockRate: 90000}, nil
+	default:
+		return webrtc.RTPCodecCapability{}, fmt.Errorf("unsupported video codec for WebRTC egress: %s", codec)
+	}
+}
+
+// Start подключается к указанному RTSP потоку (OPTIONS/DESCRIBE/SETUP/PLAY, как в
+// internal/hls.Repackager), создает PeerConnection с одной видео дорожкой и отвечает на
+// переданный SDP offer браузера. Аудио пока не публикуется: исходный поток обычно несет
+// AAC, а WebRTC ожидает Opus/PCMA/PCMU, так что без транскодирования (например, через
+// ffmpeg) аудио дорожку добавить нельзя - см. похожее ограничение в internal/hls
+func (p *Publisher) Start(ctx context.Context, rtspURL, username, password string, offer webrtc.SessionDescription) (*Session, error) {
+	client, err := rtsp.NewClient(rtspURL, username, password, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RTSP client: %w", err)
+	}
+
+	if _, err := client.Options(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("OPTIONS failed: %w", err)
+	}
+
+	describeResp, err := client.Describe()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("DESCRIBE failed: %w", err)
+	}
+	if describeResp.StatusCode != 200 {
+		client.Close()
+		return nil, fmt.Errorf("DESCRIBE returned status %d", describeResp.StatusCode)
+	}
+
+	streamInfo, err := rtsp.ParseSDP(describeResp.Body)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to parse SDP: %w", err)
+	}
+	if len(streamInfo.VideoTracks) == 0 {
+		client.Close()
+		return nil, fmt.Errorf("no video track found in SDP for %s", rtspURL)
+	}
+
+	codecCapability, err := videoCodecCapability(streamInfo.VideoTracks[0].Codec)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	videoTrack, err := webrtc.NewTrackLocalStaticRTP(codecCapability, "video", "lvs-"+rtspURL)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create WebRTC video track: %w", err)
+	}
+
+	pc, err := p.api.NewPeerConnection(webrtc.Configuration{
+		ICEServers: iceServers(p.cfg.ICEServers),
+	})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create PeerConnection: %w", err)
+	}
+
+	if _, err := pc.AddTrack(videoTrack); err != nil {
+		client.Close()
+		pc.Close()
+		return nil, fmt.Errorf("failed to add video track: %w", err)
+	}
+
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		client.Close()
+		pc.Close()
+		return nil, fmt.Errorf("failed to set remote description: %w", err)
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		client.Close()
+		pc.Close()
+		return nil, fmt.Errorf("failed to create answer: %w", err)
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		client.Close()
+		pc.Close()
+		return nil, fmt.Errorf("failed to set local description: %w", err)
+	}
+
+	select {
+	case <-gatherComplete:
+	case <-ctx.Done():
+		client.Close()
+		pc.Close()
+		return nil, ctx.Err()
+	}
+
+	client.SetAnyPort(p.cfg.AnyPort)
+	if _, err := setupAndPlay(client, p.cfg.Transport); err != nil {
+		client.Close()
+		pc.Close()
+		return nil, err
+	}
+	client.StartKeepalive(rtsp.DefaultKeepaliveInterval)
+
+	session := &Session{
+		pc:         pc,
+		rtspClient: client,
+		videoTrack: videoTrack,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go session.pump(rtspURL)
+
+	return session, nil
+}
+
+// pump читает RTP пакеты из RTSP соединения и пересылает их в видео дорожку WebRTC,
+// пока соединение не завершится или сессия не будет остановлена
+func (s *Session) pump(rtspURL string) {
+	logger := utils.GetLogger()
+	defer close(s.done)
+	defer s.rtspClient.Close()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		channel, payload, err := s.rtspClient.ReadPacket()
+		if err != nil {
+			logger.Debugf("WebRTC publisher: RTSP stream for %s ended: %v", rtspURL, err)
+			return
+		}
+		if channel != 0 {
+			continue // интересует только видео канал (канал 0, как в internal/hls)
+		}
+
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(payload); err != nil {
+			logger.Debugf("WebRTC publisher: failed to unmarshal RTP packet: %v", err)
+			continue
+		}
+
+		if err := s.videoTrack.WriteRTP(packet); err != nil {
+			logger.Debugf("WebRTC publisher: failed to write RTP packet to track: %v", err)
+		}
+	}
+}
+
+// iceServers конвертирует список STUN/TURN URL в конфигурацию pion/webrtc
+func iceServers(urls []string) []webrtc.ICEServer {
+	if len(urls) == 0 {
+		return nil
+	}
+	return []webrtc.ICEServer{{URLs: urls}}
+}
+
+// setupAndPlay выбирает SETUP+PLAY согласно transport ("auto"/"tcp"/"udp"/"udp-multicast",
+// пусто - то же, что "tcp"), зеркалируя hls.Repackager.setupAndPlay
+func setupAndPlay(client *rtsp.Client, transport string) (rtsp.TransportMode, error) {
+	if transport == "auto" {
+		_, mode, err := client.PlayAuto(rtsp.DefaultAutoFallbackWait)
+		if err != nil {
+			return 0, fmt.Errorf("auto transport failed: %w", err)
+		}
+		return mode, nil
+	}
+
+	mode := rtsp.TransportModeInterleaved
+	if transport != "" {
+		parsed, err := rtsp.ParseTransportMode(transport)
+		if err != nil {
+			return 0, err
+		}
+		mode = parsed
+	}
+
+	if _, err := client.SetupTransport(mode); err != nil {
+		return 0, fmt.Errorf("SETUP failed: %w", err)
+	}
+	if _, err := client.Play(); err != nil {
+		return 0, fmt.Errorf("PLAY failed: %w", err)
+	}
+	return mode, nil
+}
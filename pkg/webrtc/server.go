@@ -0,0 +1,108 @@
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/local-video-server/pkg/utils"
+	"github.com/pion/webrtc/v3"
+)
+
+// StreamResolver находит RTSP URL и учетные данные обнаруженного устройства по его IP,
+// как и одноименный тип в internal/hls - определен отдельно, чтобы pkg/webrtc не зависел
+// от internal/hls
+type StreamResolver func(deviceIP string) (rtspURL, username, password string, ok bool)
+
+// Manager обслуживает WHEP-подобный обмен SDP: принимает offer браузера и создает
+// Publisher.Session для запрошенного устройства
+type Manager struct {
+	resolver  StreamResolver
+	publisher *Publisher
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewManager создает менеджер WebRTC публикаций с заданными ICE параметрами
+func NewManager(resolver StreamResolver, cfg Config) (*Manager, error) {
+	publisher, err := NewPublisher(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WebRTC publisher: %w", err)
+	}
+	return &Manager{
+		resolver:  resolver,
+		publisher: publisher,
+		sessions:  make(map[string]*Session),
+	}, nil
+}
+
+// Handler возвращает http.Handler, обслуживающий POST /webrtc/{deviceIP}/offer: тело
+// запроса - SDP offer (application/sdp), тело ответа - SDP answer
+func (m *Manager) Handler() http.Handler {
+	return http.HandlerFunc(m.serveHTTP)
+}
+
+func (m *Manager) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := utils.GetLogger()
+
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/webrtc/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "offer" {
+		http.NotFound(w, req)
+		return
+	}
+	deviceIP := parts[0]
+
+	rtspURL, username, password, ok := m.resolver(deviceIP)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no known RTSP stream for device %s", deviceIP), http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read SDP offer", http.StatusBadRequest)
+		return
+	}
+
+	offer := webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  string(body),
+	}
+
+	session, err := m.publisher.Start(req.Context(), rtspURL, username, password, offer)
+	if err != nil {
+		logger.Debugf("WebRTC offer for %s failed: %v", deviceIP, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	m.mu.Lock()
+	if existing, ok := m.sessions[deviceIP]; ok {
+		go existing.Close()
+	}
+	m.sessions[deviceIP] = session
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(session.Answer().SDP))
+}
+
+// Close останавливает все активные сессии
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for deviceIP, session := range m.sessions {
+		session.Close()
+		delete(m.sessions, deviceIP)
+	}
+}
@@ -0,0 +1,156 @@
+package camera
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// DiscoverySource is a pluggable way of finding cameras on a network interface. Unlike the
+// one-shot internal/scanner.Detector.Scan, a source streams devices back as it finds them so a
+// DiscoveryManager can fan several sources out concurrently and a long-running daemon can react
+// to each device as soon as it's seen, instead of waiting for the slowest source to finish.
+type DiscoverySource interface {
+	// Name identifies this source for logging and models.DiscoveryConfig.Sources ("portscan",
+	// "onvif", "mdns", "ssdp").
+	Name() string
+
+	// Discover starts probing iface and returns a channel of devices found on it. The channel
+	// is closed once the source has nothing more to report; Discover should stop and close it
+	// promptly once ctx is canceled.
+	Discover(ctx context.Context, iface string) (<-chan *models.Device, error)
+}
+
+// DiscoveryManager runs a set of DiscoverySources concurrently on one interface, deduplicates
+// the devices they report by MAC (falling back to IP when a source can't determine MAC), and
+// merges partial results from different sources into a single models.Device per camera - e.g.
+// mDNS supplies a hostname, ONVIF supplies manufacturer/model, and the port scanner confirms
+// which protocols actually answer.
+type DiscoveryManager struct {
+	sources []DiscoverySource
+}
+
+// NewDiscoveryManager creates a manager that fans out to the given sources.
+func NewDiscoveryManager(sources ...DiscoverySource) *DiscoveryManager {
+	return &DiscoveryManager{sources: sources}
+}
+
+// Discover starts every configured source on iface and returns a single merged stream of
+// devices. A device is pushed to the output channel every time a source reports something new
+// about it (first sighting, or an update merged into an already-reported device), which suits a
+// long-running daemon that wants to react to discovery incrementally rather than a one-shot
+// scan that returns a finished list.
+func (m *DiscoveryManager) Discover(ctx context.Context, iface string) (<-chan *models.Device, error) {
+	out := make(chan *models.Device)
+
+	var mu sync.Mutex
+	seen := make(map[string]*models.Device)
+
+	var wg sync.WaitGroup
+	for _, src := range m.sources {
+		ch, err := src.Discover(ctx, iface)
+		if err != nil {
+			utils.GetLogger().Warnf("discovery source %s failed to start on %s: %v", src.Name(), iface, err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(ch <-chan *models.Device) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case device, ok := <-ch:
+					if !ok {
+						return
+					}
+					mu.Lock()
+					merged := mergeDiscovered(seen, device)
+					mu.Unlock()
+
+					select {
+					case out <- merged:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(ch)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// deviceKey returns the key DiscoveryManager deduplicates devices by: MAC when known, otherwise
+// IP (multiple sources reporting the same camera before its MAC is known will still collide on
+// IP, which is the best we can do without it).
+func deviceKey(d *models.Device) string {
+	if d.MAC != "" {
+		return "mac:" + strings.ToLower(d.MAC)
+	}
+	return "ip:" + d.IP
+}
+
+// mergeDiscovered records device under seen, merging it into any device already seen under the
+// same key, and returns the (possibly merged) device that should be emitted.
+func mergeDiscovered(seen map[string]*models.Device, device *models.Device) *models.Device {
+	key := deviceKey(device)
+	existing, ok := seen[key]
+	if !ok {
+		seen[key] = device
+		return device
+	}
+	mergeDeviceFields(existing, device)
+	return existing
+}
+
+// mergeDeviceFields copies fields new knows about onto existing, preferring existing's values
+// where both are set and appending rather than replacing slices - this is how an ONVIF-derived
+// device picks up the port scanner's protocol list, or a port-scanner-derived device picks up
+// ONVIF's manufacturer/model, without either source's data being discarded.
+func mergeDeviceFields(existing, new *models.Device) {
+	if existing.MAC == "" {
+		existing.MAC = new.MAC
+	}
+	if existing.Hostname == "" {
+		existing.Hostname = new.Hostname
+	}
+	if existing.Manufacturer == "" {
+		existing.Manufacturer = new.Manufacturer
+	}
+	if existing.Model == "" {
+		existing.Model = new.Model
+	}
+	existing.Protocols = mergeProtocols(existing.Protocols, new.Protocols)
+	existing.RTSPStreams = append(existing.RTSPStreams, new.RTSPStreams...)
+	existing.RTMPStreams = append(existing.RTMPStreams, new.RTMPStreams...)
+	existing.MJPEGStreams = append(existing.MJPEGStreams, new.MJPEGStreams...)
+	existing.LastSeen = time.Now()
+}
+
+// mergeProtocols appends protocols from b that existing doesn't already have one of the same
+// Type for, so the same source re-reporting the same protocol on every tick doesn't grow the
+// slice without bound.
+func mergeProtocols(a, b []models.Protocol) []models.Protocol {
+	have := make(map[string]bool, len(a))
+	for _, p := range a {
+		have[p.Type] = true
+	}
+	for _, p := range b {
+		if !have[p.Type] {
+			a = append(a, p)
+			have[p.Type] = true
+		}
+	}
+	return a
+}
@@ -0,0 +1,245 @@
+package camera
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// mdnsMulticastAddr is the multicast address/port mDNS queries and responses use (RFC 6762,
+// section 3).
+const mdnsMulticastAddr = "224.0.0.251:5353"
+
+// mdnsQueryTimeout bounds how long Discover collects responses after sending its queries.
+const mdnsQueryTimeout = 3 * time.Second
+
+// mdnsServiceTypes are the DNS-SD service types this source browses for (RFC 6763) - the
+// service names cameras/NVRs from these vendors are known to advertise over mDNS.
+var mdnsServiceTypes = []string{
+	"_rtsp._tcp.local.",
+	"_axis-video._tcp.local.",
+	"_dahua-video._tcp.local.",
+}
+
+const (
+	dnsTypePTR = 12
+	dnsTypeSRV = 33
+	dnsTypeA   = 1
+	dnsClassIN = 1
+)
+
+// MDNSDiscovery finds cameras advertising themselves over mDNS/DNS-SD (RFC 6762/6763) by
+// sending a PTR query for each service type in mdnsServiceTypes and reading the SRV/A records
+// responders include alongside their PTR answer.
+type MDNSDiscovery struct{}
+
+// NewMDNSDiscovery creates an mDNS discovery source.
+func NewMDNSDiscovery() *MDNSDiscovery {
+	return &MDNSDiscovery{}
+}
+
+// Name identifies this source in models.DiscoveryConfig.Sources.
+func (d *MDNSDiscovery) Name() string {
+	return "mdns"
+}
+
+// Discover sends one mDNS query per service type in mdnsServiceTypes on iface and streams a
+// models.Device for every responder whose answer includes an A record.
+func (d *MDNSDiscovery) Discover(ctx context.Context, iface string) (<-chan *models.Device, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("mDNS discovery: interface %s: %w", iface, err)
+	}
+
+	dst, err := net.ResolveUDPAddr("udp4", mdnsMulticastAddr)
+	if err != nil {
+		return nil, fmt.Errorf("mDNS discovery: resolve multicast address: %w", err)
+	}
+	conn, err := net.ListenMulticastUDP("udp4", ifi, dst)
+	if err != nil {
+		return nil, fmt.Errorf("mDNS discovery: open UDP socket on %s: %w", iface, err)
+	}
+
+	for i, serviceType := range mdnsServiceTypes {
+		query := encodeMDNSQuery(uint16(i+1), serviceType, dnsTypePTR)
+		if _, err := conn.WriteToUDP(query, dst); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("mDNS discovery: send query for %s: %w", serviceType, err)
+		}
+	}
+
+	out := make(chan *models.Device)
+	go func() {
+		defer close(out)
+		defer conn.Close()
+
+		deadline := time.Now().Add(mdnsQueryTimeout)
+		seen := make(map[string]bool)
+		buf := make([]byte, 8192)
+
+		for {
+			if ctx.Err() != nil || time.Now().After(deadline) {
+				return
+			}
+			conn.SetReadDeadline(deadline)
+
+			n, _, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			device, err := parseMDNSResponse(buf[:n])
+			if err != nil || device == nil || seen[device.IP] {
+				continue
+			}
+			seen[device.IP] = true
+
+			select {
+			case out <- device:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// encodeMDNSQuery builds a DNS query message (RFC 1035, section 4.1) with one question for
+// name/qtype, class IN.
+func encodeMDNSQuery(id uint16, name string, qtype uint16) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[0:2], id)
+	binary.BigEndian.PutUint16(msg[4:6], 1) // QDCOUNT
+
+	msg = append(msg, encodeDNSName(name)...)
+	qtypeClass := make([]byte, 4)
+	binary.BigEndian.PutUint16(qtypeClass[0:2], qtype)
+	binary.BigEndian.PutUint16(qtypeClass[2:4], dnsClassIN)
+	return append(msg, qtypeClass...)
+}
+
+// encodeDNSName encodes a dotted domain name as length-prefixed labels terminated by a zero
+// byte (RFC 1035, section 3.1).
+func encodeDNSName(name string) []byte {
+	var buf []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+	return append(buf, 0)
+}
+
+// parseMDNSResponse decodes a DNS response's answer/additional records looking for an A record
+// (the device's IPv4 address) and, if present, an SRV record (its advertised hostname).
+func parseMDNSResponse(data []byte) (*models.Device, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("mDNS: response too short")
+	}
+	qdcount := int(binary.BigEndian.Uint16(data[4:6]))
+	ancount := int(binary.BigEndian.Uint16(data[6:8]))
+	nscount := int(binary.BigEndian.Uint16(data[8:10]))
+	arcount := int(binary.BigEndian.Uint16(data[10:12]))
+
+	pos := 12
+	for i := 0; i < qdcount; i++ {
+		_, next, err := decodeDNSName(data, pos)
+		if err != nil {
+			return nil, err
+		}
+		pos = next + 4 // QTYPE + QCLASS
+	}
+
+	var ip, hostname string
+	for i := 0; i < ancount+nscount+arcount; i++ {
+		_, next, err := decodeDNSName(data, pos)
+		if err != nil {
+			return nil, err
+		}
+		pos = next
+		if pos+10 > len(data) {
+			return nil, fmt.Errorf("mDNS: truncated resource record")
+		}
+		rrType := binary.BigEndian.Uint16(data[pos : pos+2])
+		rdlength := int(binary.BigEndian.Uint16(data[pos+8 : pos+10]))
+		pos += 10
+		if pos+rdlength > len(data) {
+			return nil, fmt.Errorf("mDNS: truncated resource data")
+		}
+		rdata := data[pos : pos+rdlength]
+
+		switch rrType {
+		case dnsTypeA:
+			if len(rdata) == 4 {
+				ip = net.IP(rdata).String()
+			}
+		case dnsTypeSRV:
+			if len(rdata) > 6 {
+				target, _, err := decodeDNSName(data, pos+6)
+				if err == nil {
+					hostname = strings.TrimSuffix(target, ".")
+				}
+			}
+		}
+		pos += rdlength
+	}
+
+	if ip == "" {
+		return nil, nil
+	}
+	return &models.Device{
+		IP:           ip,
+		Hostname:     hostname,
+		Protocols:    []models.Protocol{{Type: "mDNS", Available: true, DetectedAt: time.Now()}},
+		DiscoveredAt: time.Now(),
+	}, nil
+}
+
+// decodeDNSName decodes a (possibly compressed, RFC 1035 section 4.1.4) domain name starting
+// at data[pos] and returns it along with the position just past it in the original message
+// (not past any pointer it followed).
+func decodeDNSName(data []byte, pos int) (string, int, error) {
+	var labels []string
+	originalPos := -1
+	cur := pos
+
+	for {
+		if cur >= len(data) {
+			return "", 0, fmt.Errorf("mDNS: name extends past end of message")
+		}
+		length := data[cur]
+
+		if length&0xC0 == 0xC0 {
+			if cur+1 >= len(data) {
+				return "", 0, fmt.Errorf("mDNS: truncated name pointer")
+			}
+			if originalPos == -1 {
+				originalPos = cur + 2
+			}
+			cur = int(binary.BigEndian.Uint16([]byte{length & 0x3F, data[cur+1]}))
+			continue
+		}
+
+		if length == 0 {
+			cur++
+			break
+		}
+
+		cur++
+		if cur+int(length) > len(data) {
+			return "", 0, fmt.Errorf("mDNS: truncated label")
+		}
+		labels = append(labels, string(data[cur:cur+int(length)]))
+		cur += int(length)
+	}
+
+	if originalPos != -1 {
+		cur = originalPos
+	}
+	return strings.Join(labels, ".") + ".", cur, nil
+}
@@ -0,0 +1,52 @@
+package camera
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols/onvif"
+)
+
+// discoveryTimeout bounds how long Discover waits for WS-Discovery ProbeMatch responses.
+const discoveryTimeout = 3 * time.Second
+
+// ONVIFDiscovery finds ONVIF cameras on a given network interface via WS-Discovery and
+// enriches each result with its device information, media profiles and RTSP URIs. It
+// delegates the WS-Discovery Probe and the per-device SOAP calls (GetDeviceInformation,
+// GetProfiles, GetStreamUri, GetNetworkInterfaces, Digest/WSSE auth) to
+// internal/protocols/onvif, the same client the main scan pipeline uses.
+type ONVIFDiscovery struct {
+	creds onvif.Credentials
+}
+
+// NewONVIFDiscovery creates a discoverer that authenticates follow-up SOAP requests with
+// creds (a zero-value Credentials means anonymous, matching cameras with no auth
+// configured).
+func NewONVIFDiscovery(creds Credentials) *ONVIFDiscovery {
+	return &ONVIFDiscovery{creds: onvif.Credentials{Username: creds.Username, Password: creds.Password}}
+}
+
+// Name identifies this source in models.DiscoveryConfig.Sources.
+func (d *ONVIFDiscovery) Name() string {
+	return "onvif"
+}
+
+// Discover sends a WS-Discovery Probe on the given interface and streams a populated
+// models.Device (Manufacturer, Model, MAC, one RTSPStreamInfo per media profile) for every
+// device that answers. A device that fails enrichment is skipped rather than failing Discover
+// as a whole, so one unreachable camera doesn't block the rest.
+func (d *ONVIFDiscovery) Discover(ctx context.Context, iface string) (<-chan *models.Device, error) {
+	devices, err := onvif.DiscoverOnInterface(ctx, iface, discoveryTimeout, nil, d.creds, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ONVIF discovery on %s failed: %w", iface, err)
+	}
+
+	out := make(chan *models.Device, len(devices))
+	for _, device := range devices {
+		out <- device
+	}
+	close(out)
+	return out, nil
+}
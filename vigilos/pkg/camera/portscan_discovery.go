@@ -0,0 +1,88 @@
+package camera
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/scanner"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// PortScanDiscovery finds cameras by scanning an interface's local subnet for open
+// ports/protocol banners, delegating the actual scan to internal/scanner.NetworkScanner - the
+// same ARP host discovery and protocol detector registry the one-shot CLI scan uses, wrapped to
+// satisfy DiscoverySource's streaming interface instead of running to completion.
+type PortScanDiscovery struct {
+	scanner *scanner.NetworkScanner
+}
+
+// NewPortScanDiscovery creates a port-scan discovery source configured like a one-shot scan
+// would be (concurrency, rate limit, timeouts - see models.ScanConfig).
+func NewPortScanDiscovery(config *models.ScanConfig) *PortScanDiscovery {
+	return &PortScanDiscovery{scanner: scanner.NewNetworkScanner(config)}
+}
+
+// Name identifies this source in models.DiscoveryConfig.Sources.
+func (d *PortScanDiscovery) Name() string {
+	return "portscan"
+}
+
+// Discover resolves iface to its IPv4 subnet and streams every device NetworkScanner finds on
+// it.
+func (d *PortScanDiscovery) Discover(ctx context.Context, iface string) (<-chan *models.Device, error) {
+	subnet, err := interfaceSubnet(iface)
+	if err != nil {
+		return nil, fmt.Errorf("portscan discovery on %s: %w", iface, err)
+	}
+
+	devices, errs := d.scanner.ScanNetworkStream(ctx, subnet)
+	out := make(chan *models.Device)
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case device, ok := <-devices:
+				if !ok {
+					return
+				}
+				select {
+				case out <- device:
+				case <-ctx.Done():
+					return
+				}
+			case err, ok := <-errs:
+				if ok && err != nil {
+					utils.GetLogger().Warnf("portscan discovery on %s: %v", iface, err)
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// interfaceSubnet returns the CIDR of iface's first IPv4 address, the format
+// scanner.NetworkScanner.ScanNetworkStream expects for its subnet argument.
+func interfaceSubnet(iface string) (string, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return "", fmt.Errorf("interface %s: %w", iface, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("interface %s addresses: %w", iface, err)
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.To4() == nil {
+			continue
+		}
+		return ipnet.String(), nil
+	}
+	return "", fmt.Errorf("interface %s has no IPv4 address", iface)
+}
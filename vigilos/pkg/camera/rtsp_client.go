@@ -0,0 +1,199 @@
+package camera
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/local-video-server/internal/rtsp"
+)
+
+// initialReconnectBackoff/maxReconnectBackoff bound the exponential backoff used by Play
+// when the RTSP connection drops.
+const (
+	initialReconnectBackoff = 1 * time.Second
+	maxReconnectBackoff     = 30 * time.Second
+	rtspTimeout             = 10 * time.Second
+)
+
+// Credentials holds the username/password used for RTSP Basic/Digest auth.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// RTPPacket is one RTP packet read off an interleaved RTSP channel.
+type RTPPacket struct {
+	Channel int
+	Payload []byte
+}
+
+// RTSPClient probes and plays RTSP streams. It delegates the wire protocol (OPTIONS,
+// DESCRIBE, SETUP, PLAY, Basic/Digest auth, keepalive) to rtsp.Client and adds
+// reconnect-with-backoff on top for long-running playback.
+type RTSPClient struct {
+	// transport is the RTSP transport mode to request: "auto", "tcp", "udp" or
+	// "udp-multicast". Empty means "tcp", matching the client's behavior before this
+	// field existed.
+	transport string
+	// anyPort accepts RTP/RTCP from any source port in "udp"/"auto" mode instead of only
+	// the port negotiated in SETUP - needed for cameras behind NAT.
+	anyPort bool
+}
+
+// NewRTSPClient creates a client that connects with the given RTSP transport mode
+// ("auto", "tcp", "udp", "udp-multicast", or "" for "tcp") and any-port policy.
+func NewRTSPClient(transport string, anyPort bool) *RTSPClient {
+	return &RTSPClient{transport: transport, anyPort: anyPort}
+}
+
+// Probe performs OPTIONS/DESCRIBE and parses the SDP into a rtsp.StreamInfo, without
+// starting playback. It does not shell out to ffprobe.
+func (c *RTSPClient) Probe(ctx context.Context, url string, creds Credentials) (*rtsp.StreamInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := rtsp.NewClient(url, creds.Username, creds.Password, rtspTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RTSP client: %w", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Options(); err != nil {
+		return nil, fmt.Errorf("OPTIONS failed: %w", err)
+	}
+
+	describeResp, err := client.Describe()
+	if err != nil {
+		return nil, fmt.Errorf("DESCRIBE failed: %w", err)
+	}
+	if describeResp.StatusCode != 200 {
+		return nil, fmt.Errorf("DESCRIBE returned status %d", describeResp.StatusCode)
+	}
+
+	streamInfo, err := rtsp.ParseSDP(describeResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SDP: %w", err)
+	}
+	return streamInfo, nil
+}
+
+// Play connects, starts playback over the interleaved TCP transport, and streams RTP
+// packets on the returned channel. If the connection drops, Play transparently
+// reconnects with exponential backoff instead of giving up. The channel is closed once
+// ctx is cancelled or the URL/credentials are rejected outright.
+func (c *RTSPClient) Play(ctx context.Context, url string, creds Credentials) (<-chan RTPPacket, error) {
+	client, err := c.connect(url, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	packets := make(chan RTPPacket, 256)
+	go c.run(ctx, url, creds, client, packets)
+	return packets, nil
+}
+
+// connect performs OPTIONS/DESCRIBE/SETUP(interleaved)/PLAY and starts the RTSP
+// keepalive loop on the returned client.
+func (c *RTSPClient) connect(url string, creds Credentials) (*rtsp.Client, error) {
+	client, err := rtsp.NewClient(url, creds.Username, creds.Password, rtspTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RTSP client: %w", err)
+	}
+	if _, err := client.Options(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("OPTIONS failed: %w", err)
+	}
+	if _, err := client.Describe(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("DESCRIBE failed: %w", err)
+	}
+	client.SetAnyPort(c.anyPort)
+	if _, err := c.setupAndPlay(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+	client.StartKeepalive(rtsp.DefaultKeepaliveInterval)
+	return client, nil
+}
+
+// setupAndPlay выбирает SETUP+PLAY согласно c.transport ("auto"/"tcp"/"udp"/"udp-multicast",
+// пусто - то же, что "tcp"), зеркалируя hls.Repackager.setupAndPlay
+func (c *RTSPClient) setupAndPlay(client *rtsp.Client) (rtsp.TransportMode, error) {
+	if c.transport == "auto" {
+		_, mode, err := client.PlayAuto(rtsp.DefaultAutoFallbackWait)
+		if err != nil {
+			return 0, fmt.Errorf("auto transport failed: %w", err)
+		}
+		return mode, nil
+	}
+
+	mode := rtsp.TransportModeInterleaved
+	if c.transport != "" {
+		parsed, err := rtsp.ParseTransportMode(c.transport)
+		if err != nil {
+			return 0, err
+		}
+		mode = parsed
+	}
+
+	if _, err := client.SetupTransport(mode); err != nil {
+		return 0, fmt.Errorf("SETUP failed: %w", err)
+	}
+	if _, err := client.Play(); err != nil {
+		return 0, fmt.Errorf("PLAY failed: %w", err)
+	}
+	return mode, nil
+}
+
+// run reads RTP packets off client until it errors out, then reconnects with
+// exponential backoff and keeps going until ctx is cancelled.
+func (c *RTSPClient) run(ctx context.Context, url string, creds Credentials, client *rtsp.Client, packets chan<- RTPPacket) {
+	defer close(packets)
+	backoff := initialReconnectBackoff
+
+	for {
+	readLoop:
+		for {
+			select {
+			case <-ctx.Done():
+				client.Close()
+				return
+			default:
+			}
+
+			channel, payload, err := client.ReadPacket()
+			if err != nil {
+				break readLoop
+			}
+
+			select {
+			case packets <- RTPPacket{Channel: channel, Payload: payload}:
+			case <-ctx.Done():
+				client.Close()
+				return
+			}
+		}
+		client.Close()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		newClient, err := c.connect(url, creds)
+		if err != nil {
+			if backoff < maxReconnectBackoff {
+				backoff *= 2
+				if backoff > maxReconnectBackoff {
+					backoff = maxReconnectBackoff
+				}
+			}
+			continue
+		}
+		client = newClient
+		backoff = initialReconnectBackoff
+	}
+}
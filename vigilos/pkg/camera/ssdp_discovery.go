@@ -0,0 +1,162 @@
+package camera
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/local-video-server/internal/models"
+)
+
+// ssdpMulticastAddr is the multicast address/port every SSDP control point listens on (UPnP
+// Device Architecture 2.0, annex A).
+const ssdpMulticastAddr = "239.255.255.250:1900"
+
+// ssdpSearchTarget is the device type this source searches for - UPnP MediaServer devices,
+// the closest standard ST a camera/NVR advertising DLNA/UPnP is likely to use.
+const ssdpSearchTarget = "urn:schemas-upnp-org:device:MediaServer:1"
+
+// ssdpSearchTimeout bounds how long Discover waits for M-SEARCH responses.
+const ssdpSearchTimeout = 3 * time.Second
+
+// ssdpSearchTemplate is the M-SEARCH request (UPnP Device Architecture 2.0, section 1.3.2).
+// MX tells responders to randomize their reply delay across that many seconds, to avoid all
+// devices answering at once.
+const ssdpSearchTemplate = "M-SEARCH * HTTP/1.1\r\n" +
+	"HOST: 239.255.255.250:1900\r\n" +
+	"MAN: \"ssdp:discover\"\r\n" +
+	"MX: 2\r\n" +
+	"ST: %s\r\n\r\n"
+
+// SSDPDiscovery finds UPnP media server devices by sending an M-SEARCH request and collecting
+// HTTP-like responses, then fetching each device's description XML for its friendly name and
+// manufacturer (UPnP Device Architecture 2.0, section 1).
+type SSDPDiscovery struct{}
+
+// NewSSDPDiscovery creates an SSDP discovery source.
+func NewSSDPDiscovery() *SSDPDiscovery {
+	return &SSDPDiscovery{}
+}
+
+// Name identifies this source in models.DiscoveryConfig.Sources.
+func (d *SSDPDiscovery) Name() string {
+	return "ssdp"
+}
+
+// Discover sends an M-SEARCH on iface and streams one models.Device per responder, enriched
+// with its description document where available.
+func (d *SSDPDiscovery) Discover(ctx context.Context, iface string) (<-chan *models.Device, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("SSDP discovery: interface %s: %w", iface, err)
+	}
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpMulticastAddr)
+	if err != nil {
+		return nil, fmt.Errorf("SSDP discovery: resolve multicast address: %w", err)
+	}
+	conn, err := net.ListenMulticastUDP("udp4", ifi, dst)
+	if err != nil {
+		return nil, fmt.Errorf("SSDP discovery: open UDP socket on %s: %w", iface, err)
+	}
+
+	search := fmt.Sprintf(ssdpSearchTemplate, ssdpSearchTarget)
+	if _, err := conn.WriteToUDP([]byte(search), dst); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSDP discovery: send M-SEARCH: %w", err)
+	}
+
+	out := make(chan *models.Device)
+	go func() {
+		defer close(out)
+		defer conn.Close()
+
+		deadline := time.Now().Add(ssdpSearchTimeout)
+		seen := make(map[string]bool)
+		buf := make([]byte, 8192)
+
+		for {
+			if ctx.Err() != nil || time.Now().After(deadline) {
+				return
+			}
+			conn.SetReadDeadline(deadline)
+
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if seen[addr.IP.String()] {
+				continue
+			}
+			seen[addr.IP.String()] = true
+
+			device := parseSSDPResponse(buf[:n], addr.IP.String())
+			if device == nil {
+				continue
+			}
+
+			select {
+			case out <- device:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// parseSSDPResponse turns one M-SEARCH HTTP-like response into a models.Device, fetching its
+// LOCATION description document for the friendly name/manufacturer when reachable.
+func parseSSDPResponse(data []byte, ip string) *models.Device {
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(string(data)+"\r\n")), nil)
+	if err != nil {
+		return nil
+	}
+
+	device := &models.Device{
+		IP:           ip,
+		Protocols:    []models.Protocol{{Type: "SSDP", Available: true, URL: resp.Header.Get("Location"), DetectedAt: time.Now()}},
+		DiscoveredAt: time.Now(),
+	}
+
+	if location := resp.Header.Get("Location"); location != "" {
+		if desc, err := fetchSSDPDescription(location); err == nil {
+			device.Manufacturer = desc.Manufacturer
+			device.Model = desc.ModelName
+			if desc.FriendlyName != "" {
+				device.Hostname = desc.FriendlyName
+			}
+		}
+	}
+
+	return device
+}
+
+// ssdpDescription is the subset of a UPnP device description document this source reads
+// (UPnP Device Architecture 2.0, section 2.3).
+type ssdpDescription struct {
+	Manufacturer string `xml:"device>manufacturer"`
+	ModelName    string `xml:"device>modelName"`
+	FriendlyName string `xml:"device>friendlyName"`
+}
+
+func fetchSSDPDescription(location string) (*ssdpDescription, error) {
+	client := http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("fetch description %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+
+	var desc ssdpDescription
+	if err := xml.NewDecoder(resp.Body).Decode(&desc); err != nil {
+		return nil, fmt.Errorf("decode description %s: %w", location, err)
+	}
+	return &desc, nil
+}
@@ -0,0 +1,30 @@
+package media
+
+import (
+	"time"
+
+	"github.com/local-video-server/internal/models"
+	"github.com/local-video-server/internal/protocols"
+)
+
+// HLSGenerator creates HLS segments and playlists, and can also probe an existing HLS/DASH
+// source - the same way an IP camera's own HLS endpoint would be probed - before relaying it.
+type HLSGenerator struct {
+	detector *protocols.HLSDetector
+}
+
+func NewHLSGenerator() *HLSGenerator {
+	return &HLSGenerator{detector: protocols.NewHLSDetector()}
+}
+
+func (g *HLSGenerator) GenerateSegment() error {
+	// TODO: segment size 2s, H.264/H.265 profiles.
+	return nil
+}
+
+// Probe fetches manifestURL (an HLS .m3u8 master/media playlist or a DASH .mpd) and parses it
+// into a models.HLSStreamInfo, delegating the manifest/container parsing to
+// protocols.HLSDetector.CheckStream - the same detector the scan pipeline uses.
+func (g *HLSGenerator) Probe(manifestURL string, timeout time.Duration) (*models.HLSStreamInfo, error) {
+	return g.detector.CheckStream(manifestURL, timeout)
+}
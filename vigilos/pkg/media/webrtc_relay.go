@@ -0,0 +1,401 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/pkg/utils"
+	"github.com/local-video-server/pkg/webrtc"
+	pionwebrtc "github.com/pion/webrtc/v3"
+)
+
+// shutdownTimeout bounds how long Stop waits for the HTTP server to drain in-flight
+// requests before forcing a shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// StreamResolver finds the RTSP URL and credentials of a discovered device by stream ID,
+// as in pkg/webrtc.StreamResolver and internal/hls.StreamResolver - defined separately so
+// this package doesn't depend on internal/hls or own a device cache. A deployment is free
+// to use the device IP as the stream ID.
+type StreamResolver func(streamID string) (rtspURL, username, password string, ok bool)
+
+// whipPublication is one browser's WHIP-published stream: the PeerConnection receiving
+// its media, and the local tracks WHEP viewers attach to for republishing. Only H264
+// video and Opus audio are relayed, matching what the RTSP pipeline produces elsewhere in
+// this codebase - passthrough only, no transcoding.
+type whipPublication struct {
+	pc *pionwebrtc.PeerConnection
+
+	mu         sync.Mutex
+	videoTrack *pionwebrtc.TrackLocalStaticRTP
+	audioTrack *pionwebrtc.TrackLocalStaticRTP
+}
+
+func (p *whipPublication) tracks() (video, audio *pionwebrtc.TrackLocalStaticRTP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.videoTrack, p.audioTrack
+}
+
+func (p *whipPublication) Close() error { return p.pc.Close() }
+
+// closerFunc adapts a plain func() error to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// WebRTCRelay is a WHIP/WHEP gateway: it serves POST /whep/{streamID} for browsers to
+// pull a stream (resolving it to a discovered device's RTSP stream via resolver, or to a
+// live WHIP publication for that streamID) and POST /whip/{streamID} for browsers to push
+// one in. Both directions use H264/Opus passthrough - RTP packets are relayed into
+// TrackLocalStaticRTP as-is, never re-encoded.
+type WebRTCRelay struct {
+	resolver   StreamResolver
+	listenAddr string
+	api        *pionwebrtc.API
+	publisher  *webrtc.Publisher
+
+	server *http.Server
+
+	mu           sync.Mutex
+	whepSessions map[string]io.Closer
+	whipTracks   map[string]*whipPublication
+}
+
+// NewWebRTCRelay creates a relay listening on listenAddr, resolving WHEP requests for
+// streams it hasn't seen published via WHIP through resolver, with the given ICE servers/
+// public IP/UDP port range applied to every PeerConnection it creates.
+func NewWebRTCRelay(resolver StreamResolver, cfg webrtc.Config, listenAddr string) (*WebRTCRelay, error) {
+	publisher, err := webrtc.NewPublisher(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WebRTC publisher: %w", err)
+	}
+
+	settingEngine := pionwebrtc.SettingEngine{}
+	if cfg.PublicIP != "" {
+		settingEngine.SetNAT1To1IPs([]string{cfg.PublicIP}, pionwebrtc.ICECandidateTypeHost)
+	}
+	if cfg.PortMin != 0 || cfg.PortMax != 0 {
+		if err := settingEngine.SetEphemeralUDPPortRange(cfg.PortMin, cfg.PortMax); err != nil {
+			return nil, fmt.Errorf("invalid UDP port range [%d, %d]: %w", cfg.PortMin, cfg.PortMax, err)
+		}
+	}
+
+	return &WebRTCRelay{
+		resolver:     resolver,
+		listenAddr:   listenAddr,
+		api:          pionwebrtc.NewAPI(pionwebrtc.WithSettingEngine(settingEngine)),
+		publisher:    publisher,
+		whepSessions: make(map[string]io.Closer),
+		whipTracks:   make(map[string]*whipPublication),
+	}, nil
+}
+
+// Handler returns the relay's WHIP/WHEP http.Handler, for embedding into a larger mux
+// instead of having Start bind its own listener.
+func (r *WebRTCRelay) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/whep/", r.handleWHEP)
+	mux.HandleFunc("/whip/", r.handleWHIP)
+	return mux
+}
+
+// Start binds listenAddr and serves WHIP/WHEP requests in the background until Stop is
+// called.
+func (r *WebRTCRelay) Start() error {
+	ln, err := net.Listen("tcp", r.listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to bind WebRTC relay on %s: %w", r.listenAddr, err)
+	}
+
+	r.server = &http.Server{Handler: r.Handler()}
+	go func() {
+		if err := r.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			utils.GetLogger().Errorf("WebRTC relay HTTP server stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop closes every active WHIP publication and WHEP viewer session, then shuts down the
+// HTTP server.
+func (r *WebRTCRelay) Stop() error {
+	r.mu.Lock()
+	for id, session := range r.whepSessions {
+		session.Close()
+		delete(r.whepSessions, id)
+	}
+	for id, pub := range r.whipTracks {
+		pub.Close()
+		delete(r.whipTracks, id)
+	}
+	r.mu.Unlock()
+
+	if r.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return r.server.Shutdown(ctx)
+}
+
+// streamIDFromPath extracts {streamID} from a "/whep/{streamID}" or "/whip/{streamID}"
+// request path.
+func streamIDFromPath(path, prefix string) string {
+	return strings.Trim(strings.TrimPrefix(path, prefix), "/")
+}
+
+func readOffer(req *http.Request) (pionwebrtc.SessionDescription, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return pionwebrtc.SessionDescription{}, fmt.Errorf("failed to read SDP offer: %w", err)
+	}
+	return pionwebrtc.SessionDescription{Type: pionwebrtc.SDPTypeOffer, SDP: string(body)}, nil
+}
+
+func writeAnswer(w http.ResponseWriter, answer *pionwebrtc.SessionDescription) {
+	w.Header().Set("Content-Type", "application/sdp")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(answer.SDP))
+}
+
+// closeOnICEFailure registers a handler that runs onFailure once pc's ICE connection
+// fails or closes, so an abandoned browser tab doesn't leak the PeerConnection and its
+// media pump goroutines.
+func closeOnICEFailure(pc *pionwebrtc.PeerConnection, onFailure func()) {
+	pc.OnICEConnectionStateChange(func(state pionwebrtc.ICEConnectionState) {
+		if state == pionwebrtc.ICEConnectionStateFailed || state == pionwebrtc.ICEConnectionStateClosed {
+			onFailure()
+		}
+	})
+}
+
+// handleWHEP serves POST /whep/{streamID}: the request body is a browser SDP offer, the
+// response an SDP answer. If streamID has a live WHIP publication, the viewer is attached
+// to its tracks; otherwise streamID is resolved to a discovered device's RTSP stream and
+// played in through pkg/webrtc.Publisher, same as the main scan pipeline's WHEP egress.
+func (r *WebRTCRelay) handleWHEP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	streamID := streamIDFromPath(req.URL.Path, "/whep/")
+	if streamID == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	offer, err := readOffer(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	pub, live := r.whipTracks[streamID]
+	r.mu.Unlock()
+
+	var (
+		session io.Closer
+		answer  *pionwebrtc.SessionDescription
+	)
+	if live {
+		session, answer, err = r.startWHEPFromPublication(req.Context(), pub, offer)
+	} else {
+		rtspURL, username, password, ok := r.resolver(streamID)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no known stream for %s", streamID), http.StatusNotFound)
+			return
+		}
+		webrtcSession, startErr := r.publisher.Start(req.Context(), rtspURL, username, password, offer)
+		err = startErr
+		if webrtcSession != nil {
+			session = webrtcSession
+			answer = webrtcSession.Answer()
+		}
+	}
+	if err != nil {
+		utils.GetLogger().Debugf("WHEP offer for %s failed: %v", streamID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	r.mu.Lock()
+	if old, ok := r.whepSessions[streamID]; ok {
+		go old.Close()
+	}
+	r.whepSessions[streamID] = session
+	r.mu.Unlock()
+
+	writeAnswer(w, answer)
+}
+
+// startWHEPFromPublication answers a WHEP offer by attaching the viewer's PeerConnection
+// to a live WHIP publication's tracks, so browsers publishing and browsers viewing meet
+// in-process without a round trip through RTSP.
+func (r *WebRTCRelay) startWHEPFromPublication(ctx context.Context, pub *whipPublication, offer pionwebrtc.SessionDescription) (io.Closer, *pionwebrtc.SessionDescription, error) {
+	pc, err := r.api.NewPeerConnection(pionwebrtc.Configuration{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create PeerConnection: %w", err)
+	}
+
+	videoTrack, audioTrack := pub.tracks()
+	if videoTrack != nil {
+		if _, err := pc.AddTrack(videoTrack); err != nil {
+			pc.Close()
+			return nil, nil, fmt.Errorf("failed to add video track: %w", err)
+		}
+	}
+	if audioTrack != nil {
+		if _, err := pc.AddTrack(audioTrack); err != nil {
+			pc.Close()
+			return nil, nil, fmt.Errorf("failed to add audio track: %w", err)
+		}
+	}
+
+	answer, err := answerOffer(ctx, pc, offer)
+	if err != nil {
+		pc.Close()
+		return nil, nil, err
+	}
+
+	closeOnICEFailure(pc, func() { pc.Close() })
+
+	return closerFunc(pc.Close), answer, nil
+}
+
+// handleWHIP serves POST /whip/{streamID}: the request body is a browser SDP offer
+// publishing media, the response an SDP answer. Incoming H264/Opus RTP packets are
+// relayed as-is into local tracks that handleWHEP attaches future viewers to.
+func (r *WebRTCRelay) handleWHIP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	streamID := streamIDFromPath(req.URL.Path, "/whip/")
+	if streamID == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	offer, err := readOffer(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pub, answer, err := r.startWHIP(req.Context(), streamID, offer)
+	if err != nil {
+		utils.GetLogger().Debugf("WHIP offer for %s failed: %v", streamID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	r.mu.Lock()
+	if old, ok := r.whipTracks[streamID]; ok {
+		go old.Close()
+	}
+	r.whipTracks[streamID] = pub
+	r.mu.Unlock()
+
+	writeAnswer(w, answer)
+}
+
+// startWHIP creates a PeerConnection for streamID and, for every track the browser ends
+// up sending, starts relaying its RTP packets into a matching local track.
+func (r *WebRTCRelay) startWHIP(ctx context.Context, streamID string, offer pionwebrtc.SessionDescription) (*whipPublication, *pionwebrtc.SessionDescription, error) {
+	pc, err := r.api.NewPeerConnection(pionwebrtc.Configuration{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create PeerConnection: %w", err)
+	}
+
+	pub := &whipPublication{pc: pc}
+
+	pc.OnTrack(func(track *pionwebrtc.TrackRemote, _ *pionwebrtc.RTPReceiver) {
+		local, err := pionwebrtc.NewTrackLocalStaticRTP(track.Codec().RTPCodecCapability, track.Kind().String(), "whip-"+streamID)
+		if err != nil {
+			utils.GetLogger().Debugf("WHIP publication %s: failed to create local track for %s: %v", streamID, track.Kind(), err)
+			return
+		}
+
+		pub.mu.Lock()
+		switch track.Kind() {
+		case pionwebrtc.RTPCodecTypeVideo:
+			pub.videoTrack = local
+		case pionwebrtc.RTPCodecTypeAudio:
+			pub.audioTrack = local
+		}
+		pub.mu.Unlock()
+
+		go relayTrack(streamID, track, local)
+	})
+
+	answer, err := answerOffer(ctx, pc, offer)
+	if err != nil {
+		pc.Close()
+		return nil, nil, err
+	}
+
+	closeOnICEFailure(pc, func() {
+		r.mu.Lock()
+		if r.whipTracks[streamID] == pub {
+			delete(r.whipTracks, streamID)
+		}
+		r.mu.Unlock()
+		pc.Close()
+	})
+
+	return pub, answer, nil
+}
+
+// relayTrack reads RTP packets off remote until it ends and writes each one, unchanged,
+// into local - the passthrough relay for both WHIP ingestion and WHEP republishing.
+func relayTrack(streamID string, remote *pionwebrtc.TrackRemote, local *pionwebrtc.TrackLocalStaticRTP) {
+	logger := utils.GetLogger()
+	for {
+		packet, _, err := remote.ReadRTP()
+		if err != nil {
+			logger.Debugf("WHIP publication %s: %s track ended: %v", streamID, remote.Kind(), err)
+			return
+		}
+		if err := local.WriteRTP(packet); err != nil {
+			logger.Debugf("WHIP publication %s: failed to relay %s packet: %v", streamID, remote.Kind(), err)
+		}
+	}
+}
+
+// answerOffer runs the common SetRemoteDescription/CreateAnswer/SetLocalDescription/wait-
+// for-ICE-gathering sequence shared by WHIP and WHEP.
+func answerOffer(ctx context.Context, pc *pionwebrtc.PeerConnection, offer pionwebrtc.SessionDescription) (*pionwebrtc.SessionDescription, error) {
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		return nil, fmt.Errorf("failed to set remote description: %w", err)
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create answer: %w", err)
+	}
+
+	gatherComplete := pionwebrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return nil, fmt.Errorf("failed to set local description: %w", err)
+	}
+
+	select {
+	case <-gatherComplete:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return pc.LocalDescription(), nil
+}
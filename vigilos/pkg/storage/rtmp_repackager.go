@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/local-video-server/internal/hls"
+	"github.com/local-video-server/internal/protocols"
+	"github.com/local-video-server/pkg/utils"
+)
+
+// rtmpMediaTimescale - тактовая частота PTS/DTS, которую ожидает hls.Muxer (90 кГц, как у
+// RTP часов H.264/AAC). RTMP timestamp'ы несет в миллисекундах (Adobe RTMP specification
+// 1.0, раздел 5.3.1.1), поэтому ReadTag's Timestamp домножается на это значение перед
+// передачей в Muxer
+const rtmpMediaTimescale = 90
+
+// rtmpRepackager - RTMP аналог hls.Repackager: держит открытый protocols.RTMPStream и режет
+// приходящие видео/аудио теги в скользящее окно сегментов MPEG-TS, используя тот же
+// hls.Muxer/hls.Segment/hls.BuildPlaylist, что и RTSP путь, - так запись получает один формат
+// сегментов независимо от транспорта источника. Реализует segmentSource (local_recorder.go)
+type rtmpRepackager struct {
+	ip, appName, streamName string
+	port                    int
+	cfg                     hls.Config
+
+	mu       sync.Mutex
+	segments []hls.Segment
+	nextSeq  uint64
+
+	stream *protocols.RTMPStream
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// newRTMPRepackager создает репакер для указанного RTMP потока (appName/streamName на
+// ip:port). Подключение и перепаковка запускаются вызовом Start
+func newRTMPRepackager(ip string, port int, appName, streamName string, cfg hls.Config) *rtmpRepackager {
+	return &rtmpRepackager{
+		ip:         ip,
+		port:       port,
+		appName:    appName,
+		streamName: streamName,
+		cfg:        cfg,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start подключается к RTMP источнику (handshake + connect/createStream/play, см.
+// protocols.RTMPDetector.OpenStream) и запускает фоновую горутину, которая читает теги и
+// режет их на сегменты HLS
+func (r *rtmpRepackager) Start() error {
+	stream, err := protocols.NewRTMPDetector().OpenStream(r.ip, r.port, r.appName, r.streamName, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to open RTMP stream: %w", err)
+	}
+	r.stream = stream
+
+	go r.run()
+	return nil
+}
+
+// Done возвращает канал, закрываемый по завершении горутины run - как по вызову Stop, так и
+// при обрыве/ошибке самого RTMP источника
+func (r *rtmpRepackager) Done() <-chan struct{} {
+	return r.done
+}
+
+// Stop останавливает перепаковку и закрывает RTMP соединение, дожидаясь завершения горутины
+func (r *rtmpRepackager) Stop() {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	<-r.done
+}
+
+// Playlist возвращает текущий index.m3u8, собранный по сегментам в окне. Возвращает false,
+// пока не накоплен ни один сегмент
+func (r *rtmpRepackager) Playlist() (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.segments) == 0 {
+		return "", false
+	}
+	return hls.BuildPlaylist(r.segments, r.targetDuration()), true
+}
+
+// targetDuration возвращает EXT-X-TARGETDURATION - округленную вверх длительность самого
+// долгого сегмента в текущем окне (RFC 8216, раздел 4.3.3.1)
+func (r *rtmpRepackager) targetDuration() int {
+	max := 0.0
+	for _, seg := range r.segments {
+		if seg.Duration > max {
+			max = seg.Duration
+		}
+	}
+	return int(max) + 1
+}
+
+// cutSegment завершает текущий muxer и добавляет его в скользящее окно, отбрасывая самые
+// старые сегменты сверх Config.SegmentCount
+func (r *rtmpRepackager) cutSegment(muxer *hls.Muxer, start time.Time) {
+	if muxer == nil {
+		return
+	}
+	duration := time.Since(start).Seconds()
+	if duration <= 0 {
+		duration = 0.1
+	}
+
+	r.mu.Lock()
+	segment := hls.Segment{Seq: r.nextSeq, Duration: duration, Data: muxer.Bytes()}
+	r.nextSeq++
+	r.segments = append(r.segments, segment)
+	if len(r.segments) > r.cfg.SegmentCount {
+		r.segments = r.segments[len(r.segments)-r.cfg.SegmentCount:]
+	}
+	r.mu.Unlock()
+
+	if r.cfg.OnSegment != nil {
+		r.cfg.OnSegment(segment)
+	}
+}
+
+// run читает RTMP теги и режет сегменты на границах ключевых кадров не раньше, чем пройдет
+// Config.SegmentDuration - то же правило нарезки, что у hls.Repackager.run для RTSP. hasAudio
+// становится true, как только придет первый аудио тег, и применяется к следующему создаваемому
+// muxer'у - если аудио приходит позже первого видео кадра текущего сегмента, этот один сегмент
+// останется видео-only в PMT, что приемлемо для скользящего окна записи
+func (r *rtmpRepackager) run() {
+	logger := utils.GetLogger()
+	defer close(r.done)
+	defer r.stream.Close()
+
+	tags := make(chan protocols.RTMPTag, 256)
+	go func() {
+		defer close(tags)
+		for {
+			tag, ok, err := r.stream.ReadTag()
+			if err != nil {
+				return
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case tags <- tag:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+
+	var muxer *hls.Muxer
+	var segmentStart time.Time
+	hasAudio := false
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case tag, ok := <-tags:
+			if !ok {
+				logger.Debugf("RTMP recorder: stream %s/%s ended", r.appName, r.streamName)
+				return
+			}
+			if !tag.Video {
+				hasAudio = true
+			}
+
+			if muxer == nil || (tag.Video && tag.Keyframe && time.Since(segmentStart) >= r.cfg.SegmentDuration) {
+				r.cutSegment(muxer, segmentStart)
+				muxer = hls.NewMuxer(hasAudio)
+				segmentStart = time.Now()
+			}
+
+			pts := uint64(tag.Timestamp) * rtmpMediaTimescale
+			if tag.Video {
+				muxer.WriteVideoNALUs(tag.NALUs, pts, pts, tag.Keyframe)
+			} else {
+				muxer.WriteAudioFrame(hls.BuildADTSFrame(tag.AudioObjectType, tag.AudioSampleRate, tag.AudioChannels, tag.AudioFrame), pts)
+			}
+		}
+	}
+}